@@ -0,0 +1,43 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go-gin-auth-api-starter-kit/docs"
+	"go-gin-auth-api-starter-kit/routes"
+	"os"
+
+	"github.com/gin-gonic/gin"
+)
+
+func main() {
+	// Parse command line flags
+	check := flag.Bool("check", false, "Exit with an error if a registered route is missing from the specification")
+	flag.Parse()
+
+	// Register the routes exactly like the server does (no database is needed for that)
+	gin.SetMode(gin.ReleaseMode)
+	router := gin.New()
+	routes.SetupRoutes(router)
+
+	if *check {
+		if missing := docs.MissingRoutes(router.Routes()); len(missing) > 0 {
+			fmt.Fprintln(os.Stderr, "Routes missing from the OpenAPI specification:")
+			for _, route := range missing {
+				fmt.Fprintln(os.Stderr, "  "+route)
+			}
+			os.Exit(1)
+		}
+		fmt.Println("All routes are documented")
+		return
+	}
+
+	// Print the specification, e.g. to generate client SDKs
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(docs.Spec()); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -3,8 +3,9 @@ package main
 import (
 	"flag"
 	"go-gin-auth-api-starter-kit/config"
+	"go-gin-auth-api-starter-kit/pkg/logger"
 	"go-gin-auth-api-starter-kit/pkg/seeder"
-	"log"
+	"log/slog"
 )
 
 func main() {
@@ -12,6 +13,10 @@ func main() {
 	force := flag.Bool("force", false, "Force reseed by deleting existing users")
 	flag.Parse()
 
+	// Log as structured JSON, like the server
+	logger.Init()
+	config.Logger = logger.NewGormLogger()
+
 	// Connect to database
 	config.ConnectDB()
 
@@ -24,6 +29,6 @@ func main() {
 	}
 
 	if err != nil {
-		log.Fatalf("Error seeding users: %v", err)
+		logger.Fatal("Error seeding users", slog.Any("error", err))
 	}
 }
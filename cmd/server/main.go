@@ -3,13 +3,31 @@ package main
 
 // Import necessary packages
 import (
-	"go-gin-auth-api-starter-kit/config" // Our database configuration
-	"go-gin-auth-api-starter-kit/models" // Our data models (like User)
-	"go-gin-auth-api-starter-kit/routes" // Our API routes
-	"log"                                // For logging errors
+	"context"                                 // For stopping background work
+	"errors"                                  // For comparing errors
+	"go-gin-auth-api-starter-kit/config"      // Our database configuration
+	"go-gin-auth-api-starter-kit/middleware"  // Our middleware
+	"go-gin-auth-api-starter-kit/models"      // Our data models (like User)
+	"go-gin-auth-api-starter-kit/pkg/logger"  // Structured logging
+	"go-gin-auth-api-starter-kit/pkg/metrics" // Prometheus metrics
+	"go-gin-auth-api-starter-kit/pkg/pubsub"  // Live delivery of notifications
+	"go-gin-auth-api-starter-kit/pkg/storage" // File storage for attachments
+	"go-gin-auth-api-starter-kit/pkg/tracing" // OpenTelemetry tracing
+	"go-gin-auth-api-starter-kit/routes"      // Our API routes
+	"go-gin-auth-api-starter-kit/services"    // Our background jobs
+	"log/slog"                                // For logging
+	"net/http"                                // For the HTTP server
+	"os"                                      // For OS signals
+	"os/signal"                               // For catching Ctrl+C
+	"sync"                                    // For waiting on background work
+	"syscall"                                 // For SIGTERM
+	"time"                                    // For the shutdown timeout
 
 	"github.com/gin-gonic/gin" // Web framework for Go
 	"github.com/joho/godotenv" // For loading environment variables
+
+	// Traces incoming HTTP requests
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 )
 
 // The main function is where our application starts running
@@ -18,40 +36,235 @@ func main() {
 	// This helps us keep sensitive information like database passwords secure
 	err := godotenv.Load()
 
+	// Log as structured JSON (configured with LOG_LEVEL and LOG_FORMAT)
+	logger.Init()
+	config.Logger = logger.NewGormLogger()
+
+	// Trace every GORM query as a child of the request's span
+	config.Plugins = append(config.Plugins, tracing.GormPlugin{})
+
 	// If we can't load the .env file, stop the application
 	if err != nil {
-		log.Fatal("Error loading .env file")
+		logger.Fatal("Error loading .env file", slog.Any("error", err))
+	}
+
+	// Stop background work and the server when we receive Ctrl+C or SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Set up OpenTelemetry tracing (configured with OTEL_TRACES_EXPORTER)
+	shutdownTracing, err := tracing.Init(ctx)
+	if err != nil {
+		logger.Fatal("Tracing setup failed", slog.Any("error", err))
+	}
+
+	// Open the file storage for attachments (configured with STORAGE_DRIVER)
+	if err := storage.Init(ctx); err != nil {
+		logger.Fatal("Storage setup failed", slog.Any("error", err))
+	}
+
+	// Connect the message broker that pushes notifications to open streams (configured with PUBSUB_DRIVER)
+	if err := pubsub.Init(ctx); err != nil {
+		logger.Fatal("Pub/sub setup failed", slog.Any("error", err))
+	}
+
+	// Send Gin's debug route listing through our logger as well
+	gin.DebugPrintRouteFunc = func(httpMethod, absolutePath, handlerName string, _ int) {
+		slog.Debug("route registered", slog.String("method", httpMethod), slog.String("path", absolutePath), slog.String("handler", handlerName))
 	}
 
 	// Create a new Gin router
 	// This will handle all our web requests
-	router := gin.Default()
+	router := gin.New()
 
-	// Create a simple test route
-	// When someone visits the homepage ("/"), we send a welcome message
-	router.GET("/", func(c *gin.Context) {
-		c.JSON(200, gin.H{
-			"message": "Go Gin Auth! Server Working AIR Hot Reload!",
-		})
-	})
+	// Only believe X-Forwarded-For and X-Real-IP from our own reverse proxies
+	// (TRUSTED_PROXIES, none by default); otherwise anyone could pick the client
+	// IP that /metrics access, audit records and access logs go by
+	if err := router.SetTrustedProxies(config.GetEnvList("TRUSTED_PROXIES", nil)); err != nil {
+		logger.Fatal("Invalid TRUSTED_PROXIES", slog.Any("error", err))
+	}
+
+	// Assign a request ID, start a trace span (continuing the caller's traceparent),
+	// make request metadata available to the services, write structured access logs,
+	// record metrics and turn panics into 500 responses
+	router.Use(
+		middleware.RequestID(),
+		otelgin.Middleware(tracing.ServiceName()),
+		middleware.RequestContext(),
+		middleware.Logger(),
+		middleware.Metrics(),
+		middleware.Recovery(),
+	)
 
 	// Connect to our database using the configuration
 	config.ConnectDB()
 
+	// Expose connection pool statistics on /metrics
+	if sqlDB, err := config.DB.DB(); err == nil {
+		if err := metrics.RegisterDB(sqlDB, config.GetEnv("DB_NAME", "postgres")); err != nil {
+			slog.Warn("Failed to register DB metrics", slog.Any("error", err))
+		}
+	}
+
 	// Create the User table in our database if it doesn't exist
 	if err := config.DB.AutoMigrate(&models.User{}); err != nil {
-		log.Fatalf("User migration failed: %v", err)
+		logger.Fatal("User migration failed", slog.Any("error", err))
+	}
+
+	// Create the Tag and Category tables before Post, which references them
+	if err := config.DB.AutoMigrate(&models.Tag{}, &models.Category{}); err != nil {
+		logger.Fatal("Tag/Category migration failed", slog.Any("error", err))
+	}
+
+	// Create the Post table in our database if it doesn't exist, then add the
+	// columns of its post_tags join table
+	if err := config.DB.AutoMigrate(&models.Post{}, &models.PostTag{}); err != nil {
+		logger.Fatal("Post migration failed", slog.Any("error", err))
+	}
+
+	// Create the PostShare table in our database if it doesn't exist
+	if err := config.DB.AutoMigrate(&models.PostShare{}); err != nil {
+		logger.Fatal("PostShare migration failed", slog.Any("error", err))
+	}
+
+	// Create the PostSlugAlias table in our database if it doesn't exist
+	if err := config.DB.AutoMigrate(&models.PostSlugAlias{}); err != nil {
+		logger.Fatal("PostSlugAlias migration failed", slog.Any("error", err))
+	}
+
+	// Create the Attachment table in our database if it doesn't exist
+	if err := config.DB.AutoMigrate(&models.Attachment{}); err != nil {
+		logger.Fatal("Attachment migration failed", slog.Any("error", err))
+	}
+
+	// Create the PostRevision table in our database if it doesn't exist
+	if err := config.DB.AutoMigrate(&models.PostRevision{}); err != nil {
+		logger.Fatal("PostRevision migration failed", slog.Any("error", err))
+	}
+
+	// Create the reaction and bookmark tables in our database if they don't exist
+	if err := config.DB.AutoMigrate(&models.PostReaction{}, &models.PostReactionCount{}, &models.Bookmark{}); err != nil {
+		logger.Fatal("Reaction/Bookmark migration failed", slog.Any("error", err))
+	}
+
+	// Create the Follow and FeedItem tables in our database if they don't exist
+	if err := config.DB.AutoMigrate(&models.Follow{}, &models.FeedItem{}); err != nil {
+		logger.Fatal("Follow/FeedItem migration failed", slog.Any("error", err))
 	}
 
-	// Create the Post table in our database if it doesn't exist
-	if err := config.DB.AutoMigrate(&models.Post{}); err != nil {
-		log.Fatalf("Post migration failed: %v", err)
+	// Create the Mention table in our database if it doesn't exist
+	if err := config.DB.AutoMigrate(&models.Mention{}); err != nil {
+		logger.Fatal("Mention migration failed", slog.Any("error", err))
+	}
+
+	// Create the Comment table in our database if it doesn't exist
+	if err := config.DB.AutoMigrate(&models.Comment{}); err != nil {
+		logger.Fatal("Comment migration failed", slog.Any("error", err))
+	}
+
+	// Create the Notification and NotificationPreference tables in our database if they don't exist
+	if err := config.DB.AutoMigrate(&models.Notification{}, &models.NotificationPreference{}); err != nil {
+		logger.Fatal("Notification/NotificationPreference migration failed", slog.Any("error", err))
+	}
+
+	// Create the Job table in our database if it doesn't exist
+	if err := config.DB.AutoMigrate(&models.Job{}); err != nil {
+		logger.Fatal("Job migration failed", slog.Any("error", err))
+	}
+
+	// Create the OutboxEvent and ProcessedEvent tables in our database if they don't exist
+	if err := config.DB.AutoMigrate(&models.OutboxEvent{}, &models.ProcessedEvent{}); err != nil {
+		logger.Fatal("OutboxEvent/ProcessedEvent migration failed", slog.Any("error", err))
+	}
+
+	// Create the Webhook and WebhookDelivery tables in our database if they don't exist
+	if err := config.DB.AutoMigrate(&models.Webhook{}, &models.WebhookDelivery{}); err != nil {
+		logger.Fatal("Webhook/WebhookDelivery migration failed", slog.Any("error", err))
+	}
+
+	// Create the AuditLog table in our database if it doesn't exist
+	if err := config.DB.AutoMigrate(&models.AuditLog{}); err != nil {
+		logger.Fatal("AuditLog migration failed", slog.Any("error", err))
+	}
+
+	// Render the content of posts written before rendering happened on save
+	if err := services.RenderMissingPostContent(ctx); err != nil {
+		logger.Fatal("Rendering post content failed", slog.Any("error", err))
+	}
+
+	// Give a slug to posts created before posts had slugs
+	if err := services.AssignMissingPostSlugs(ctx); err != nil {
+		logger.Fatal("Assigning post slugs failed", slog.Any("error", err))
+	}
+
+	// Feed links must not come from the client's Host header in production
+	if err := services.CheckFeedBaseURL(config.GetEnv("GIN_MODE", gin.Mode()) == gin.ReleaseMode); err != nil {
+		logger.Fatal("Invalid feed configuration", slog.Any("error", err))
 	}
 
 	// Set up all our API routes (like login, register, etc.)
 	routes.SetupRoutes(router)
 
+	// Background loops stop when ctx is cancelled; shutdown waits for them
+	var background sync.WaitGroup
+	runInBackground := func(run func(context.Context)) {
+		background.Add(1)
+		go func() {
+			defer background.Done()
+			run(ctx)
+		}()
+	}
+
+	// Run background jobs, including the scheduled trash purge, audit log pruning and avatar resizing
+	// Set JOB_WORKER_ENABLED=false when they run in a separate `worker` process
+	if config.GetEnvBool("JOB_WORKER_ENABLED", true) {
+		runInBackground(services.RunJobWorker)
+	}
+
+	// Publish scheduled posts when their time comes
+	if config.GetEnvBool("POST_SCHEDULER_ENABLED", true) {
+		runInBackground(services.StartPostScheduler)
+	}
+
+	// Hand domain events from the outbox to their handlers
+	if config.GetEnvBool("OUTBOX_DISPATCHER_ENABLED", true) {
+		runInBackground(services.StartOutboxDispatcher)
+	}
+
+	// Send queued webhook deliveries and retry the failed ones
+	if config.GetEnvBool("WEBHOOK_DELIVERY_ENABLED", true) {
+		runInBackground(services.StartWebhookDelivery)
+	}
+
 	// Start the web server on port 8080
 	// This makes our application available to receive requests
-	router.Run(":8080")
+	server := &http.Server{Addr: ":8080", Handler: router}
+	// Shutdown doesn't wait for event streams; closing the broker ends them
+	server.RegisterOnShutdown(pubsub.Default.Close)
+	go func() {
+		slog.Info("Server listening", slog.String("addr", server.Addr))
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Fatal("Server failed", slog.Any("error", err))
+		}
+	}()
+
+	// Wait for a shutdown signal, then give in-flight requests a moment to finish
+	<-ctx.Done()
+	slog.Info("Shutting down server")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		slog.Error("Server shutdown failed", slog.Any("error", err))
+	}
+
+	// Let running jobs finish (bounded by JOB_SHUTDOWN_TIMEOUT), and the
+	// scheduler, outbox dispatcher and webhook delivery finish their current round
+	background.Wait()
+
+	// Flush any spans that have not been exported yet
+	flushCtx, cancelFlush := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelFlush()
+	if err := shutdownTracing(flushCtx); err != nil {
+		slog.Error("Tracing shutdown failed", slog.Any("error", err))
+	}
 }
@@ -0,0 +1,63 @@
+// The worker command runs background jobs outside the API server. Start the
+// server with JOB_WORKER_ENABLED=false and run as many workers as needed.
+package main
+
+import (
+	"context"
+	"go-gin-auth-api-starter-kit/config"
+	"go-gin-auth-api-starter-kit/pkg/logger"
+	"go-gin-auth-api-starter-kit/pkg/pubsub"
+	"go-gin-auth-api-starter-kit/pkg/storage"
+	"go-gin-auth-api-starter-kit/pkg/tracing"
+	"go-gin-auth-api-starter-kit/services"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	// Load .env when there is one; in containers the environment is usually set directly
+	envErr := godotenv.Load()
+
+	// Log as structured JSON, like the server
+	logger.Init()
+	config.Logger = logger.NewGormLogger()
+	config.Plugins = append(config.Plugins, tracing.GormPlugin{})
+	if envErr != nil && !os.IsNotExist(envErr) {
+		logger.Fatal("Error loading .env file", slog.Any("error", envErr))
+	}
+
+	// Stop claiming jobs on Ctrl+C or SIGTERM; running jobs get JOB_SHUTDOWN_TIMEOUT to finish
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	shutdownTracing, err := tracing.Init(ctx)
+	if err != nil {
+		logger.Fatal("Tracing setup failed", slog.Any("error", err))
+	}
+
+	// Jobs use the same storage and broker as the server
+	if err := storage.Init(ctx); err != nil {
+		logger.Fatal("Storage setup failed", slog.Any("error", err))
+	}
+	if err := pubsub.Init(ctx); err != nil {
+		logger.Fatal("Pub/sub setup failed", slog.Any("error", err))
+	}
+
+	// The server creates the tables; the worker only connects
+	config.ConnectDB()
+
+	services.RunJobWorker(ctx)
+	pubsub.Default.Close()
+
+	// Flush any spans that have not been exported yet
+	flushCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := shutdownTracing(flushCtx); err != nil {
+		slog.Error("Tracing shutdown failed", slog.Any("error", err))
+	}
+}
@@ -3,26 +3,31 @@ package config
 
 // Import necessary packages
 import (
-	"fmt" // For string formatting
-	"log" // For logging errors
-	"os"  // For environment variables
+	"fmt"      // For string formatting
+	"log/slog" // For structured logging
+	"os"       // For environment variables
 
 	"github.com/joho/godotenv" // For loading .env file
 	"gorm.io/driver/postgres"  // PostgreSQL driver for GORM
 	"gorm.io/gorm"             // GORM ORM library
+	gormlogger "gorm.io/gorm/logger"
 )
 
+// Logger is the GORM logger used for new connections
+// It is set by main so that query logs go through slog
+var Logger gormlogger.Interface
+
+// Plugins are installed on new connections (e.g. query tracing)
+// Like Logger, they are set by main before calling ConnectDB
+var Plugins []gorm.Plugin
+
 // DB is a global variable that holds our database connection
 var DB *gorm.DB
 
-// ConnectDB establishes a connection to the PostgreSQL database
-func ConnectDB() {
-	// Load environment variables from .env file
-	err := godotenv.Load()
-	if err != nil {
-		log.Fatal("Error loading .env file")
-	}
-
+// DSN is the connection string of the PostgreSQL database, built from the
+// DB_* environment variables. It is also used by connections GORM doesn't
+// manage, such as the one listening for pub/sub notifications.
+func DSN() string {
 	// Get database connection details from environment variables
 	dbHost := os.Getenv("DB_HOST")         // Database server address
 	dbPort := os.Getenv("DB_PORT")         // Database server port
@@ -32,17 +37,36 @@ func ConnectDB() {
 
 	// Create the database connection string
 	// This string contains all the information needed to connect to the database
-	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable",
+	return fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable",
 		dbHost, dbUser, dbPassword, dbName, dbPort)
+}
+
+// ConnectDB establishes a connection to the PostgreSQL database
+func ConnectDB() {
+	// Load environment variables from .env file
+	err := godotenv.Load()
+	if err != nil {
+		slog.Error("Error loading .env file", slog.Any("error", err))
+		os.Exit(1)
+	}
 
 	// Open a connection to the database using GORM
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	db, err := gorm.Open(postgres.Open(DSN()), &gorm.Config{Logger: Logger})
 	if err != nil {
 		// If connection fails, stop the application
-		log.Fatal("Failed to connect to DB: ", err)
+		slog.Error("Failed to connect to DB", slog.Any("error", err))
+		os.Exit(1)
+	}
+
+	// Install plugins such as query tracing
+	for _, plugin := range Plugins {
+		if err := db.Use(plugin); err != nil {
+			slog.Error("Failed to install GORM plugin", slog.String("plugin", plugin.Name()), slog.Any("error", err))
+			os.Exit(1)
+		}
 	}
 
 	// Store the database connection in our global variable
 	DB = db
-	fmt.Println("Database connection established")
+	slog.Info("Database connection established")
 }
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GetEnv returns the value of an environment variable, or fallback when it is unset or empty
+func GetEnv(key, fallback string) string {
+	if value := strings.TrimSpace(os.Getenv(key)); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// GetEnvInt reads an integer environment variable, falling back when it is missing or malformed
+func GetEnvInt(key string, fallback int) int {
+	value, err := strconv.Atoi(GetEnv(key, ""))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// GetEnvBool reads a boolean environment variable (true/false, 1/0, yes/no)
+func GetEnvBool(key string, fallback bool) bool {
+	switch strings.ToLower(GetEnv(key, "")) {
+	case "1", "true", "yes", "on":
+		return true
+	case "0", "false", "no", "off":
+		return false
+	default:
+		return fallback
+	}
+}
+
+// GetEnvDuration reads a duration such as "30s" or "24h" from the environment
+func GetEnvDuration(key string, fallback time.Duration) time.Duration {
+	value, err := time.ParseDuration(GetEnv(key, ""))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// GetEnvList splits a comma separated environment variable into trimmed, non-empty values
+func GetEnvList(key string, fallback []string) []string {
+	raw := GetEnv(key, "")
+	if raw == "" {
+		return fallback
+	}
+
+	var values []string
+	for _, part := range strings.Split(raw, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			values = append(values, part)
+		}
+	}
+	return values
+}
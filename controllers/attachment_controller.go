@@ -0,0 +1,214 @@
+package controllers
+
+import (
+	"errors"
+	"go-gin-auth-api-starter-kit/models"
+	"go-gin-auth-api-starter-kit/pkg/storage"
+	"go-gin-auth-api-starter-kit/services"
+	"go-gin-auth-api-starter-kit/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// multipartOverhead is room for the multipart boundaries and headers around an uploaded file
+const multipartOverhead = 64 << 10
+
+// AttachmentResponse is how an attachment is returned to clients
+type AttachmentResponse struct {
+	ID          uint   `json:"id"`
+	PostID      uint   `json:"post_id"`
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	SHA256      string `json:"sha256"`
+	Width       *int   `json:"width,omitempty"`
+	Height      *int   `json:"height,omitempty"`
+	// Uploader is null once their account has been purged
+	Uploader  *PostAuthor `json:"uploader"`
+	CreatedAt string      `json:"created_at"`
+	// URL downloads the file without authentication until URLExpiresAt
+	URL          string `json:"url"`
+	URLExpiresAt string `json:"url_expires_at"`
+}
+
+// attachmentResponse formats an attachment with a freshly signed download URL
+func attachmentResponse(attachment models.Attachment) AttachmentResponse {
+	token, expires := services.AttachmentURL(attachment)
+	response := AttachmentResponse{
+		ID:           attachment.ID,
+		PostID:       attachment.PostID,
+		Filename:     attachment.Filename,
+		ContentType:  attachment.ContentType,
+		Size:         attachment.Size,
+		SHA256:       attachment.SHA256,
+		Width:        attachment.Width,
+		Height:       attachment.Height,
+		CreatedAt:    attachment.CreatedAt.Format("2006-01-02 15:04:05"),
+		URL:          "/api/v1/files/" + token,
+		URLExpiresAt: expires.Format("2006-01-02 15:04:05"),
+	}
+	if attachment.User != nil {
+		uploader := postAuthor(*attachment.User)
+		response.Uploader = &uploader
+	}
+	return response
+}
+
+// respondAttachmentError maps attachment service errors to HTTP responses
+func respondAttachmentError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post or attachment not found"})
+	case errors.Is(err, services.ErrEmptyAttachment):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrAttachmentTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrAttachmentTypeNotAllowed):
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+	default:
+		respondPostError(c, err, fallback)
+	}
+}
+
+// UploadAttachment attaches a file sent as the multipart field "file" to a post
+func UploadAttachment(c *gin.Context) {
+	id, ok := idParam(c, "id", "Invalid post ID")
+	if !ok {
+		return
+	}
+
+	// Stop reading oversized uploads early instead of spooling them to disk
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, services.AttachmentMaxSize()+multipartOverhead)
+	header, err := c.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			respondAttachmentError(c, services.ErrAttachmentTooLarge, "")
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A multipart file field named \"file\" is required"})
+		return
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read uploaded file"})
+		return
+	}
+	defer file.Close()
+
+	attachment, created, err := services.UploadAttachment(c.Request.Context(), currentActor(c), id, services.AttachmentUpload{
+		Filename: header.Filename,
+		Content:  file,
+		Size:     header.Size,
+	})
+	if err != nil {
+		respondAttachmentError(c, err, "Failed to upload attachment")
+		return
+	}
+
+	status := http.StatusCreated
+	if !created {
+		status = http.StatusOK
+	}
+	c.JSON(status, gin.H{"attachment": attachmentResponse(attachment)})
+}
+
+// ListPostAttachments returns the attachments of a post
+func ListPostAttachments(c *gin.Context) {
+	id, ok := idParam(c, "id", "Invalid post ID")
+	if !ok {
+		return
+	}
+
+	attachments, err := services.ListPostAttachments(c.Request.Context(), currentActor(c), id)
+	if err != nil {
+		respondAttachmentError(c, err, "Failed to list attachments")
+		return
+	}
+
+	response := make([]AttachmentResponse, 0, len(attachments))
+	for _, attachment := range attachments {
+		response = append(response, attachmentResponse(attachment))
+	}
+	c.JSON(http.StatusOK, gin.H{"attachments": response})
+}
+
+// GetPostAttachment returns a single attachment of a post
+func GetPostAttachment(c *gin.Context) {
+	id, ok := idParam(c, "id", "Invalid post ID")
+	if !ok {
+		return
+	}
+	attachmentID, ok := idParam(c, "attachment_id", "Invalid attachment ID")
+	if !ok {
+		return
+	}
+
+	attachment, err := services.GetPostAttachment(c.Request.Context(), currentActor(c), id, attachmentID)
+	if err != nil {
+		respondAttachmentError(c, err, "Failed to fetch attachment")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"attachment": attachmentResponse(attachment)})
+}
+
+// DeleteAttachment removes an attachment from a post
+func DeleteAttachment(c *gin.Context) {
+	id, ok := idParam(c, "id", "Invalid post ID")
+	if !ok {
+		return
+	}
+	attachmentID, ok := idParam(c, "attachment_id", "Invalid attachment ID")
+	if !ok {
+		return
+	}
+
+	if err := services.DeleteAttachment(c.Request.Context(), currentActor(c), id, attachmentID); err != nil {
+		respondAttachmentError(c, err, "Failed to delete attachment")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Attachment deleted successfully"})
+}
+
+// DownloadAttachment serves the file behind a signed download URL. It needs no authentication.
+func DownloadAttachment(c *gin.Context) {
+	download, err := services.OpenAttachment(c.Request.Context(), c.Param("token"))
+	if err != nil {
+		switch {
+		case errors.Is(err, utils.ErrInvalidSignedToken),
+			errors.Is(err, gorm.ErrRecordNotFound),
+			errors.Is(err, storage.ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+		case errors.Is(err, utils.ErrExpiredSignedToken):
+			c.JSON(http.StatusGone, gin.H{"error": "Download link has expired"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to download file"})
+		}
+		return
+	}
+
+	if download.RedirectURL != "" {
+		c.Redirect(http.StatusFound, download.RedirectURL)
+		return
+	}
+	defer download.Body.Close()
+
+	// The content never changes for a hash, so the hash is a strong ETag
+	etag := `"` + download.Attachment.SHA256 + `"`
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", "private, max-age=3600")
+	if c.GetHeader("If-None-Match") == etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	// Never let the browser second-guess the sniffed type or run scripts from a file
+	c.DataFromReader(http.StatusOK, download.Attachment.Size, download.Attachment.ContentType, download.Body, map[string]string{
+		"Content-Disposition":     download.Disposition,
+		"X-Content-Type-Options":  "nosniff",
+		"Content-Security-Policy": "sandbox",
+	})
+}
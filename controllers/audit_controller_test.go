@@ -0,0 +1,64 @@
+package controllers
+
+import (
+	"go-gin-auth-api-starter-kit/models"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAuditLogFilter(t *testing.T) {
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet,
+		"/?event=auth.login.failed,post.created&actor_id=7&target_type=post&target_id=42&ip=10.0.0.1&request_id=abc&from=2025-01-01T00:00:00Z", nil)
+
+	filter, err := auditLogFilter(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(filter.Events, []string{"auth.login.failed", "post.created"}) ||
+		filter.ActorID == nil || *filter.ActorID != 7 ||
+		filter.TargetType != "post" || filter.TargetID != "42" || filter.IP != "10.0.0.1" || filter.RequestID != "abc" ||
+		filter.From == nil || !filter.From.Equal(from) || filter.To != nil {
+		t.Errorf("auditLogFilter() = %+v", filter)
+	}
+
+	for _, query := range []string{"actor_id=me", "from=yesterday", "to=2025-01-01"} {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodGet, "/?"+query, nil)
+		if _, err := auditLogFilter(c); err == nil {
+			t.Errorf("auditLogFilter(%s) accepted an invalid value", query)
+		}
+	}
+}
+
+func TestAuditLogCSVRow(t *testing.T) {
+	actorID := uint(7)
+	entry := models.AuditLog{
+		ID:            3,
+		CreatedAt:     time.Date(2025, 1, 2, 3, 4, 5, 0, time.FixedZone("CET", 3600)),
+		Event:         models.AuditLoginFailed,
+		ActorID:       &actorID,
+		ActorUsername: "=HYPERLINK(\"http://evil\")",
+		IP:            "10.0.0.1",
+		UserAgent:     "-curl",
+		Metadata:      models.JSONMap{"reason": "bad password"},
+	}
+	want := []string{
+		"3", "2025-01-02T02:04:05Z", models.AuditLoginFailed, "7", "'=HYPERLINK(\"http://evil\")",
+		"", "", "10.0.0.1", "'-curl", "", `{"reason":"bad password"}`,
+	}
+	if got := auditLogCSVRow(entry); !reflect.DeepEqual(got, want) {
+		t.Errorf("auditLogCSVRow() = %q, want %q", got, want)
+	}
+
+	// Anonymous entries have no actor
+	if got := auditLogCSVRow(models.AuditLog{})[3]; got != "" {
+		t.Errorf("actor of an anonymous entry = %q, want empty", got)
+	}
+}
@@ -3,6 +3,7 @@ package controllers
 
 // Import necessary packages
 import (
+	"errors"                               // For comparing errors
 	"go-gin-auth-api-starter-kit/models"   // Our data models
 	"go-gin-auth-api-starter-kit/services" // Our business logic
 	"net/http"                             // For HTTP status codes
@@ -10,21 +11,46 @@ import (
 	"github.com/gin-gonic/gin" // Web framework
 )
 
+// RegisterRequest holds the data needed to create an account
+type RegisterRequest struct {
+	Username string `json:"username" binding:"required"`
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required"`
+}
+
+// LoginRequest holds the credentials used to log in
+type LoginRequest struct {
+	Email    string `json:"email" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// LoginResponse is returned after a successful login
+type LoginResponse struct {
+	Token string `json:"token"`
+}
+
+// DashboardResponse is returned by the dashboard endpoint
+type DashboardResponse struct {
+	Message  string `json:"message"`
+	Username string `json:"username"`
+}
+
 // Register handles new user registration
 // It receives user data and creates a new account
 func Register(c *gin.Context) {
-	// Create a variable to hold the user data
-	var user models.User
+	// Create a variable to hold the registration data
+	var request RegisterRequest
 
-	// Try to read the JSON data from the request into our user variable
-	if err := c.ShouldBindJSON(&user); err != nil {
+	// Try to read the JSON data from the request into our request variable
+	if err := c.ShouldBindJSON(&request); err != nil {
 		// If there's an error reading the JSON, send a bad request response
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
 	// Try to register the new user using our service
-	newUser, err := services.Register(user)
+	user := models.User{Username: request.Username, Email: request.Email, Password: request.Password}
+	newUser, err := services.Register(c.Request.Context(), user)
 
 	if err != nil {
 		// If registration fails, send an error response
@@ -32,18 +58,15 @@ func Register(c *gin.Context) {
 		return
 	}
 
-	// If everything went well, send back the created user with a success status
-	c.JSON(http.StatusCreated, gin.H{"user": newUser})
+	// If everything went well, send back the created user (without the password hash)
+	c.JSON(http.StatusCreated, gin.H{"user": userResponse(newUser)})
 }
 
 // Login handles user authentication
 // It checks if the provided email and password are correct
 func Login(c *gin.Context) {
 	// Create a structure to hold login credentials
-	var credentials struct {
-		Email    string `json:"email"`
-		Password string `json:"password"`
-	}
+	var credentials LoginRequest
 
 	// Try to read the JSON data from the request
 	if err := c.ShouldBindJSON(&credentials); err != nil {
@@ -53,15 +76,20 @@ func Login(c *gin.Context) {
 	}
 
 	// Try to login using our service
-	token, err := services.Login(credentials.Email, credentials.Password)
+	token, err := services.Login(c.Request.Context(), credentials.Email, credentials.Password)
 	if err != nil {
-		// If login fails, send an unauthorized response
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		if errors.Is(err, services.ErrInvalidCredentials) {
+			// If the credentials are wrong, send an unauthorized response
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+			return
+		}
+		// Anything else (e.g. the database is down) is a server error
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to login"})
 		return
 	}
 
 	// If login is successful, send back the authentication token
-	c.JSON(http.StatusOK, gin.H{"token": token})
+	c.JSON(http.StatusOK, LoginResponse{Token: token})
 }
 
 // Dashboard handles the post-login page
@@ -75,8 +103,8 @@ func Dashboard(c *gin.Context) {
 	}
 
 	// Return a welcome message with the username
-	c.JSON(http.StatusOK, gin.H{
-		"message":  "Welcome to your dashboard!",
-		"username": username,
+	c.JSON(http.StatusOK, DashboardResponse{
+		Message:  "Welcome to your dashboard!",
+		Username: username.(string),
 	})
 }
@@ -0,0 +1,248 @@
+package controllers
+
+import (
+	"errors"
+	"go-gin-auth-api-starter-kit/models"
+	"go-gin-auth-api-starter-kit/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// CommentRequest is the body accepted when creating a comment
+type CommentRequest struct {
+	Body string `json:"body" binding:"required"`
+	// ParentID makes the comment a reply to another comment on the same post
+	ParentID *uint `json:"parent_id"`
+}
+
+// CommentUpdateRequest is the body accepted when editing a comment
+type CommentUpdateRequest struct {
+	Body string `json:"body" binding:"required"`
+}
+
+// CommentAuthor identifies who wrote a comment
+type CommentAuthor struct {
+	ID       uint   `json:"id"`
+	Username string `json:"username"`
+	// AvatarURLs maps each avatar size in pixels to the URL of the image
+	AvatarURLs map[string]string `json:"avatar_urls"`
+}
+
+// CommentResponse is how a comment is returned to clients, with its replies nested
+type CommentResponse struct {
+	ID       uint  `json:"id"`
+	PostID   uint  `json:"post_id"`
+	ParentID *uint `json:"parent_id"`
+	Depth    int   `json:"depth"`
+	// Author is null for deleted comments
+	Author    *CommentAuthor    `json:"author"`
+	Body      string            `json:"body"`
+	Deleted   bool              `json:"deleted"`
+	CreatedAt string            `json:"created_at"`
+	EditedAt  *string           `json:"edited_at"`
+	Replies   []CommentResponse `json:"replies"`
+}
+
+// commentResponse formats a single comment, hiding the content of deleted ones
+func commentResponse(comment models.Comment) CommentResponse {
+	response := CommentResponse{
+		ID:        comment.ID,
+		PostID:    comment.PostID,
+		ParentID:  comment.ParentID,
+		Depth:     comment.Depth,
+		Body:      comment.Body,
+		CreatedAt: comment.CreatedAt.Format("2006-01-02 15:04:05"),
+		Replies:   []CommentResponse{},
+	}
+
+	if comment.DeletedAt.Valid {
+		response.Deleted = true
+		response.Body = models.DeletedCommentPlaceholder
+		return response
+	}
+
+	if comment.User != nil {
+		response.Author = &CommentAuthor{ID: comment.User.ID, Username: comment.User.Username, AvatarURLs: services.AvatarURLs(*comment.User)}
+	}
+	if comment.EditedAt != nil {
+		editedAt := comment.EditedAt.Format("2006-01-02 15:04:05")
+		response.EditedAt = &editedAt
+	}
+	return response
+}
+
+// commentTrees nests flat comments (ordered oldest first) under their parents and
+// returns the trees rooted at the given comment IDs
+func commentTrees(comments []models.Comment, rootIDs []uint) []CommentResponse {
+	children := map[uint][]models.Comment{}
+	byID := map[uint]models.Comment{}
+	for _, comment := range comments {
+		byID[comment.ID] = comment
+		if comment.ParentID != nil {
+			children[*comment.ParentID] = append(children[*comment.ParentID], comment)
+		}
+	}
+
+	var build func(models.Comment) CommentResponse
+	build = func(comment models.Comment) CommentResponse {
+		response := commentResponse(comment)
+		for _, child := range children[comment.ID] {
+			response.Replies = append(response.Replies, build(child))
+		}
+		return response
+	}
+
+	trees := make([]CommentResponse, 0, len(rootIDs))
+	for _, id := range rootIDs {
+		if comment, ok := byID[id]; ok {
+			trees = append(trees, build(comment))
+		}
+	}
+	return trees
+}
+
+// ListComments returns a page of top-level comments on a post, each with its replies nested
+func ListComments(c *gin.Context) {
+	postID, ok := idParam(c, "id", "Invalid post ID")
+	if !ok {
+		return
+	}
+
+	page, perPage := pagination(c)
+	comments, total, err := services.ListCommentThreads(c.Request.Context(), currentActor(c), postID, page, perPage)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list comments"})
+		return
+	}
+
+	var rootIDs []uint
+	for _, comment := range comments {
+		if comment.ParentID == nil {
+			rootIDs = append(rootIDs, comment.ID)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"comments":   commentTrees(comments, rootIDs),
+		"pagination": paginationMeta(page, perPage, total),
+	})
+}
+
+// GetComment returns one comment together with all of its replies
+func GetComment(c *gin.Context) {
+	postID, ok := idParam(c, "id", "Invalid post ID")
+	if !ok {
+		return
+	}
+	commentID, ok := idParam(c, "comment_id", "Invalid comment ID")
+	if !ok {
+		return
+	}
+
+	comments, err := services.GetCommentThread(c.Request.Context(), currentActor(c), postID, commentID)
+	if err != nil {
+		respondCommentError(c, err, "Failed to fetch comment")
+		return
+	}
+
+	trees := commentTrees(comments, []uint{commentID})
+	if len(trees) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"comment": trees[0]})
+}
+
+// CreateComment adds a comment (or a reply) to a post
+func CreateComment(c *gin.Context) {
+	postID, ok := idParam(c, "id", "Invalid post ID")
+	if !ok {
+		return
+	}
+
+	var request CommentRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	comment, err := services.CreateComment(c.Request.Context(), currentActor(c), postID, request.ParentID, request.Body)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+			return
+		}
+		respondCommentError(c, err, "Failed to create comment")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"comment": commentResponse(comment)})
+}
+
+// UpdateComment lets the author edit a comment during the edit window
+func UpdateComment(c *gin.Context) {
+	postID, ok := idParam(c, "id", "Invalid post ID")
+	if !ok {
+		return
+	}
+	commentID, ok := idParam(c, "comment_id", "Invalid comment ID")
+	if !ok {
+		return
+	}
+
+	var request CommentUpdateRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	comment, err := services.UpdateComment(c.Request.Context(), currentActor(c), postID, commentID, request.Body)
+	if err != nil {
+		respondCommentError(c, err, "Failed to update comment")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"comment": commentResponse(comment)})
+}
+
+// DeleteComment soft-deletes a comment; it stays in the thread as a placeholder
+func DeleteComment(c *gin.Context) {
+	postID, ok := idParam(c, "id", "Invalid post ID")
+	if !ok {
+		return
+	}
+	commentID, ok := idParam(c, "comment_id", "Invalid comment ID")
+	if !ok {
+		return
+	}
+
+	if err := services.DeleteComment(c.Request.Context(), currentActor(c), postID, commentID); err != nil {
+		respondCommentError(c, err, "Failed to delete comment")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Comment deleted successfully"})
+}
+
+// respondCommentError maps comment service errors to HTTP responses
+func respondCommentError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+	case errors.Is(err, services.ErrEmptyComment),
+		errors.Is(err, services.ErrParentCommentNotFound),
+		errors.Is(err, services.ErrCommentTooDeep):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrNotCommentAuthor),
+		errors.Is(err, services.ErrCommentEditWindowClosed):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
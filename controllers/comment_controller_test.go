@@ -0,0 +1,39 @@
+package controllers
+
+import (
+	"go-gin-auth-api-starter-kit/models"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func TestCommentTreesShowDeletedPlaceholders(t *testing.T) {
+	created := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	author := &models.User{Model: gorm.Model{ID: 7}, Username: "alice"}
+	rootID, deletedID := uint(1), uint(2)
+	comments := []models.Comment{
+		{Model: gorm.Model{ID: rootID, CreatedAt: created}, PostID: 9, UserID: &author.ID, User: author, Body: "root"},
+		{Model: gorm.Model{ID: deletedID, CreatedAt: created, DeletedAt: gorm.DeletedAt{Time: created, Valid: true}},
+			PostID: 9, UserID: &author.ID, User: author, ParentID: &rootID, RootID: &rootID, Depth: 1, Body: "secret"},
+		{Model: gorm.Model{ID: 3, CreatedAt: created}, PostID: 9, UserID: &author.ID, User: author,
+			ParentID: &deletedID, RootID: &rootID, Depth: 2, Body: "reply to a deleted comment"},
+	}
+
+	trees := commentTrees(comments, []uint{rootID})
+	if len(trees) != 1 || len(trees[0].Replies) != 1 || len(trees[0].Replies[0].Replies) != 1 {
+		t.Fatalf("trees = %+v, want root > deleted > reply", trees)
+	}
+
+	root, deleted, reply := trees[0], trees[0].Replies[0], trees[0].Replies[0].Replies[0]
+	if root.Deleted || root.Body != "root" || root.Author == nil || root.Author.Username != "alice" {
+		t.Errorf("root = %+v", root)
+	}
+	// The deleted comment keeps its place but none of its content
+	if !deleted.Deleted || deleted.Body != models.DeletedCommentPlaceholder || deleted.Author != nil {
+		t.Errorf("deleted comment = %+v, want a placeholder without an author", deleted)
+	}
+	if reply.Deleted || reply.Body != "reply to a deleted comment" || reply.Depth != 2 {
+		t.Errorf("reply = %+v", reply)
+	}
+}
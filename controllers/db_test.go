@@ -0,0 +1,55 @@
+package controllers
+
+import (
+	"go-gin-auth-api-starter-kit/config"
+	"os"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// openTestDB points config.DB at the PostgreSQL database in TEST_DATABASE_DSN,
+// creates the tables of the given models and empties them before and after
+// the test. Tests that need a database are skipped when the variable is unset:
+//
+//	TEST_DATABASE_DSN="host=localhost user=postgres password=postgres dbname=test sslmode=disable" go test ./...
+func openTestDB(t *testing.T, tables ...any) {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN is not set")
+	}
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: gormlogger.Discard})
+	if err != nil {
+		t.Fatalf("connecting to the test database: %v", err)
+	}
+	if err := db.AutoMigrate(tables...); err != nil {
+		t.Fatalf("migrating the test database: %v", err)
+	}
+
+	truncate := func() {
+		for _, table := range tables {
+			statement := &gorm.Statement{DB: db}
+			if err := statement.Parse(table); err != nil {
+				t.Fatalf("parsing %T: %v", table, err)
+			}
+			if err := db.Exec("TRUNCATE TABLE " + statement.Quote(statement.Schema.Table) + " RESTART IDENTITY CASCADE").Error; err != nil {
+				t.Fatalf("emptying %s: %v", statement.Schema.Table, err)
+			}
+		}
+	}
+	truncate()
+
+	previous := config.DB
+	config.DB = db
+	t.Cleanup(func() {
+		truncate()
+		config.DB = previous
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+}
@@ -0,0 +1,138 @@
+package controllers
+
+import (
+	"fmt"
+	"go-gin-auth-api-starter-kit/models"
+	"go-gin-auth-api-starter-kit/services"
+	"hash/fnv"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// postETag is the entity tag of a post as the current viewer sees it, e.g.
+// "42-3.9f86d081884c7d65": the post's ID and version, then a hash of the
+// viewer's reactions and bookmark. Those never change the version, so they
+// need their own part, or a client would keep a stale copy after reacting.
+func postETag(post models.Post) string {
+	return fmt.Sprintf(`"%d-%d.%s"`, post.ID, post.Version, engagementTag(post))
+}
+
+// engagementTag hashes the reaction counts of a post and whether the viewer
+// reacted to it or bookmarked it
+func engagementTag(post models.Post) string {
+	hash := fnv.New64a()
+	for _, reaction := range post.Reactions {
+		fmt.Fprintf(hash, "%s:%d:%t;", reaction.Emoji, reaction.Count, reaction.Reacted)
+	}
+	fmt.Fprintf(hash, "bookmarked:%t", post.Bookmarked)
+	return fmt.Sprintf("%016x", hash.Sum64())
+}
+
+// respondPost writes a single post together with its ETag
+func respondPost(c *gin.Context, status int, post models.Post) {
+	posts := []models.Post{post}
+	loadEngagement(c, posts)
+	writePost(c, status, posts[0])
+}
+
+// respondFetchedPost answers a read of post: 304 when the client already has
+// it as the caller sees it, the post with its ETag otherwise. The response
+// depends on who asks, so shared caches must not store it.
+func respondFetchedPost(c *gin.Context, post models.Post) {
+	posts := []models.Post{post}
+	loadEngagement(c, posts)
+	post = posts[0]
+
+	c.Header("Cache-Control", "private")
+	c.Writer.Header().Add("Vary", "Authorization")
+	if notModified(c, post) {
+		c.Header("ETag", postETag(post))
+		c.Status(http.StatusNotModified)
+		return
+	}
+	writePost(c, http.StatusOK, post)
+}
+
+// writePost writes a post whose engagement is loaded, together with its ETag
+func writePost(c *gin.Context, status int, post models.Post) {
+	c.Header("ETag", postETag(post))
+	c.JSON(status, gin.H{"post": renderedPostResponse(c, post)})
+}
+
+// ifMatch reads the If-Match header for the post with the given ID.
+// Tags for other posts, weak tags and malformed tags never match.
+func ifMatch(c *gin.Context, postID uint) services.IfMatch {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" {
+		return services.IfMatch{}
+	}
+
+	precondition := services.IfMatch{Present: true, Versions: []int{}}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			precondition.Any = true
+			continue
+		}
+
+		if id, version, ok := parsePostETag(tag); ok && id == postID {
+			precondition.Versions = append(precondition.Versions, version)
+		}
+	}
+	return precondition
+}
+
+// parsePostETag reads the post ID and version from a strong tag made by
+// postETag. Anything else, including trailing input, is rejected. The
+// engagement part may be left out, as it plays no part in edits.
+func parsePostETag(tag string) (uint, int, bool) {
+	value, ok := strings.CutPrefix(tag, `"`)
+	if !ok {
+		return 0, 0, false
+	}
+	if value, ok = strings.CutSuffix(value, `"`); !ok {
+		return 0, 0, false
+	}
+	value, engagement, hasEngagement := strings.Cut(value, ".")
+	if hasEngagement && !isEngagementTag(engagement) {
+		return 0, 0, false
+	}
+	idText, versionText, ok := strings.Cut(value, "-")
+	if !ok {
+		return 0, 0, false
+	}
+	id, err := strconv.ParseUint(idText, 10, 0)
+	if err != nil {
+		return 0, 0, false
+	}
+	version, err := strconv.ParseUint(versionText, 10, 31)
+	if err != nil {
+		return 0, 0, false
+	}
+	return uint(id), int(version), true
+}
+
+// isEngagementTag reports whether s has the form engagementTag returns
+func isEngagementTag(s string) bool {
+	if len(s) != 16 {
+		return false
+	}
+	_, err := strconv.ParseUint(s, 16, 64)
+	return err == nil
+}
+
+// notModified reports whether the client's If-None-Match already names post
+// at its current version and engagement
+func notModified(c *gin.Context, post models.Post) bool {
+	etag := postETag(post)
+	for _, tag := range strings.Split(c.GetHeader("If-None-Match"), ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == etag || tag == "*" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,122 @@
+package controllers
+
+import (
+	"go-gin-auth-api-starter-kit/models"
+	"go-gin-auth-api-starter-kit/services"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// testContext returns a gin context for a GET request with the given header
+func testContext(header, value string) *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	if value != "" {
+		c.Request.Header.Set(header, value)
+	}
+	return c
+}
+
+func TestPostETag(t *testing.T) {
+	post := models.Post{Model: gorm.Model{ID: 42}, Version: 3}
+	reacted := post
+	reacted.Reactions = []models.ReactionSummary{{Emoji: "👍", Count: 1, Reacted: true}}
+	reactedByOthers := post
+	reactedByOthers.Reactions = []models.ReactionSummary{{Emoji: "👍", Count: 1}}
+	bookmarked := post
+	bookmarked.Bookmarked = true
+	edited := post
+	edited.Version = 4
+
+	etag := postETag(post)
+	if id, version, ok := parsePostETag(etag); !ok || id != 42 || version != 3 {
+		t.Fatalf("parsePostETag(%s) = %d, %d, %v; want 42, 3, true", etag, id, version, ok)
+	}
+
+	// Anything the viewer sees change must change the tag
+	for name, changed := range map[string]models.Post{
+		"reacted":           reacted,
+		"reacted by others": reactedByOthers,
+		"bookmarked":        bookmarked,
+		"edited":            edited,
+	} {
+		if postETag(changed) == etag {
+			t.Errorf("%s post has the same ETag %s", name, etag)
+		}
+	}
+	if postETag(post) != etag {
+		t.Errorf("postETag is not stable")
+	}
+}
+
+func TestIfMatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   services.IfMatch
+	}{
+		{"absent", "", services.IfMatch{}},
+		{"blank", "   ", services.IfMatch{}},
+		{"one version", `"42-3"`, services.IfMatch{Present: true, Versions: []int{3}}},
+		{"with engagement", `"42-3.9f86d081884c7d65"`, services.IfMatch{Present: true, Versions: []int{3}}},
+		{"malformed engagement", `"42-3.xyz"`, services.IfMatch{Present: true, Versions: []int{}}},
+		{"several versions", `"42-3", "42-5"`, services.IfMatch{Present: true, Versions: []int{3, 5}}},
+		{"any", "*", services.IfMatch{Present: true, Any: true, Versions: []int{}}},
+		// Present but unmatchable, so the request fails instead of skipping the check
+		{"other post", `"7-3"`, services.IfMatch{Present: true, Versions: []int{}}},
+		{"weak tag", `W/"42-3"`, services.IfMatch{Present: true, Versions: []int{}}},
+		{"unquoted", `42-3`, services.IfMatch{Present: true, Versions: []int{}}},
+		{"garbage", `"abc"`, services.IfMatch{Present: true, Versions: []int{}}},
+		{"trailing input", `"42-3"garbage`, services.IfMatch{Present: true, Versions: []int{}}},
+		{"extra part", `"42-3-9"`, services.IfMatch{Present: true, Versions: []int{}}},
+		{"signed version", `"42-+3"`, services.IfMatch{Present: true, Versions: []int{}}},
+		{"missing version", `"42-"`, services.IfMatch{Present: true, Versions: []int{}}},
+		{"unterminated", `"42-3`, services.IfMatch{Present: true, Versions: []int{}}},
+		{"mixed", `"7-1", "42-2", W/"42-9"`, services.IfMatch{Present: true, Versions: []int{2}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ifMatch(testContext("If-Match", tt.header), 42)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ifMatch(%q) = %+v, want %+v", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNotModified(t *testing.T) {
+	post := models.Post{Model: gorm.Model{ID: 42}, Version: 3}
+	etag := postETag(post)
+	older := post
+	older.Version = 2
+	bookmarked := post
+	bookmarked.Bookmarked = true
+
+	tests := []struct {
+		name   string
+		header string
+		want   bool
+	}{
+		{"absent", "", false},
+		{"current", etag, true},
+		{"weak current", "W/" + etag, true},
+		{"older version", postETag(older), false},
+		{"one of several", postETag(older) + ", " + etag, true},
+		{"any", "*", true},
+		// A client that bookmarked the post since must not keep its old copy
+		{"before a bookmark", postETag(bookmarked), false},
+		{"without engagement", `"42-3"`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := notModified(testContext("If-None-Match", tt.header), post); got != tt.want {
+				t.Errorf("notModified(%q) = %v, want %v", tt.header, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,325 @@
+package controllers
+
+import (
+	"encoding/xml"
+	"go-gin-auth-api-starter-kit/models"
+	"go-gin-auth-api-starter-kit/services"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JSONFeed is a JSON Feed 1.1 document (https://jsonfeed.org/version/1.1)
+type JSONFeed struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageURL string         `json:"home_page_url"`
+	FeedURL     string         `json:"feed_url"`
+	Items       []JSONFeedItem `json:"items"`
+}
+
+// JSONFeedItem is one post in a JSON Feed
+type JSONFeedItem struct {
+	ID            string           `json:"id"`
+	URL           string           `json:"url"`
+	Title         string           `json:"title"`
+	ContentHTML   string           `json:"content_html"`
+	Summary       string           `json:"summary,omitempty"`
+	DatePublished string           `json:"date_published,omitempty"`
+	DateModified  string           `json:"date_modified"`
+	Authors       []JSONFeedAuthor `json:"authors,omitempty"`
+	Tags          []string         `json:"tags,omitempty"`
+}
+
+// JSONFeedAuthor names the author of a JSON Feed item
+type JSONFeedAuthor struct {
+	Name   string `json:"name"`
+	Avatar string `json:"avatar,omitempty"`
+}
+
+// rssFeed is an RSS 2.0 document
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	AtomNS  string     `xml:"xmlns:atom,attr"`
+	DCNS    string     `xml:"xmlns:dc,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	Self          atomLink  `xml:"atom:link"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	GUID        rssGUID  `xml:"guid"`
+	PubDate     string   `xml:"pubDate,omitempty"`
+	Creator     string   `xml:"dc:creator,omitempty"`
+	Categories  []string `xml:"category"`
+	Description string   `xml:"description"`
+}
+
+type rssGUID struct {
+	ID          string `xml:",chardata"`
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+}
+
+// atomFeed is an Atom 1.0 document (RFC 4287)
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Author  atomPerson  `xml:"author"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomEntry struct {
+	Title      string         `xml:"title"`
+	ID         string         `xml:"id"`
+	Updated    string         `xml:"updated"`
+	Published  string         `xml:"published,omitempty"`
+	Link       atomLink       `xml:"link"`
+	Author     *atomPerson    `xml:"author,omitempty"`
+	Categories []atomCategory `xml:"category"`
+	Summary    string         `xml:"summary,omitempty"`
+	Content    atomText       `xml:"content"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+// feedBaseURL is FEED_BASE_URL, or the scheme and host the request was made
+// to. The fallback is only allowed outside release mode, so forwarded headers
+// are not consulted: run behind a proxy with FEED_BASE_URL set.
+func feedBaseURL(c *gin.Context) string {
+	if base := services.FeedBaseURL(); base != "" {
+		return base
+	}
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + c.Request.Host
+}
+
+// feedPostID identifies a post in the feeds. It is the post's URL by ID,
+// which unlike the slug never changes.
+func feedPostID(base string, post models.Post) string {
+	return base + "/api/v1/posts/" + strconv.FormatUint(uint64(post.ID), 10)
+}
+
+// feedPostURL is the public address of a post, by slug when it has one
+func feedPostURL(base string, post models.Post) string {
+	if post.Slug != "" {
+		return base + "/api/v1/posts/by-slug/" + post.Slug
+	}
+	return feedPostID(base, post)
+}
+
+// feedAvatarURL is the absolute URL of the largest size of a user's avatar
+func feedAvatarURL(base string, user models.User) string {
+	sizes := services.AvatarSizes()
+	return base + services.AvatarURLs(user)[strconv.Itoa(sizes[len(sizes)-1])]
+}
+
+// loadFeed reads the feed filters, answers conditional requests and returns
+// the posts for the feed. It returns false when it has already responded.
+func loadFeed(c *gin.Context) ([]models.Post, time.Time, bool) {
+	lastModified, err := services.FeedLastModified(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build feed"})
+		return nil, time.Time{}, false
+	}
+
+	// HTTP dates have second precision
+	lastModified = lastModified.UTC().Truncate(time.Second)
+	if !lastModified.IsZero() {
+		c.Header("Last-Modified", lastModified.Format(http.TimeFormat))
+		if since, err := http.ParseTime(c.GetHeader("If-Modified-Since")); err == nil && !lastModified.After(since) {
+			c.Status(http.StatusNotModified)
+			return nil, time.Time{}, false
+		}
+	}
+
+	query := services.PostQuery{Tags: listQueryValues(c, "tag"), Author: c.Query("author")}
+	posts, err := services.ListFeedPosts(c.Request.Context(), query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build feed"})
+		return nil, time.Time{}, false
+	}
+	if lastModified.IsZero() {
+		lastModified = time.Now().UTC().Truncate(time.Second)
+	}
+	return posts, lastModified, true
+}
+
+// postTagNames lists the names of the tags of a post
+func postTagNames(post models.Post) []string {
+	names := make([]string, 0, len(post.Tags))
+	for _, tag := range post.Tags {
+		names = append(names, tag.Name)
+	}
+	return names
+}
+
+// postPublishedAt is when a post was published, falling back to its creation time
+func postPublishedAt(post models.Post) time.Time {
+	if post.PublishedAt != nil {
+		return post.PublishedAt.UTC()
+	}
+	return post.CreatedAt.UTC()
+}
+
+// respondXML writes an XML document with its declaration
+func respondXML(c *gin.Context, contentType string, document any) {
+	body, err := xml.MarshalIndent(document, "", "  ")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build feed"})
+		return
+	}
+	c.Data(http.StatusOK, contentType, append([]byte(xml.Header), body...))
+}
+
+// PostsRSS serves the latest public posts as RSS 2.0
+func PostsRSS(c *gin.Context) {
+	posts, lastModified, ok := loadFeed(c)
+	if !ok {
+		return
+	}
+
+	base := feedBaseURL(c)
+	feed := rssFeed{
+		Version: "2.0",
+		AtomNS:  "http://www.w3.org/2005/Atom",
+		DCNS:    "http://purl.org/dc/elements/1.1/",
+		Channel: rssChannel{
+			Title:         services.FeedTitle(),
+			Link:          base + "/",
+			Description:   "Latest posts from " + services.FeedTitle(),
+			Self:          atomLink{Href: base + c.Request.URL.RequestURI(), Rel: "self", Type: "application/rss+xml"},
+			LastBuildDate: lastModified.Format(time.RFC1123Z),
+			Items:         make([]rssItem, 0, len(posts)),
+		},
+	}
+	for _, post := range posts {
+		item := rssItem{
+			Title:       post.Title,
+			Link:        feedPostURL(base, post),
+			GUID:        rssGUID{ID: feedPostID(base, post), IsPermaLink: true},
+			PubDate:     postPublishedAt(post).Format(time.RFC1123Z),
+			Categories:  postTagNames(post),
+			Description: post.ContentHTML,
+		}
+		if post.Author != nil {
+			item.Creator = post.Author.Username
+		}
+		feed.Channel.Items = append(feed.Channel.Items, item)
+	}
+
+	respondXML(c, "application/rss+xml; charset=utf-8", feed)
+}
+
+// PostsAtom serves the latest public posts as an Atom feed
+func PostsAtom(c *gin.Context) {
+	posts, lastModified, ok := loadFeed(c)
+	if !ok {
+		return
+	}
+
+	base := feedBaseURL(c)
+	self := base + c.Request.URL.RequestURI()
+	feed := atomFeed{
+		Title:   services.FeedTitle(),
+		ID:      self,
+		Updated: lastModified.Format(time.RFC3339),
+		Links: []atomLink{
+			{Href: self, Rel: "self", Type: "application/atom+xml"},
+			{Href: base + "/", Rel: "alternate"},
+		},
+		// Entries without an author of their own fall back to the feed's
+		Author:  atomPerson{Name: services.FeedTitle()},
+		Entries: make([]atomEntry, 0, len(posts)),
+	}
+	for _, post := range posts {
+		entry := atomEntry{
+			Title:     post.Title,
+			ID:        feedPostID(base, post),
+			Updated:   post.UpdatedAt.UTC().Format(time.RFC3339),
+			Published: postPublishedAt(post).Format(time.RFC3339),
+			Link:      atomLink{Href: feedPostURL(base, post), Rel: "alternate"},
+			Summary:   post.Excerpt,
+			Content:   atomText{Type: "html", Body: post.ContentHTML},
+		}
+		if post.Author != nil {
+			entry.Author = &atomPerson{Name: post.Author.Username}
+		}
+		for _, name := range postTagNames(post) {
+			entry.Categories = append(entry.Categories, atomCategory{Term: name})
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+
+	respondXML(c, "application/atom+xml; charset=utf-8", feed)
+}
+
+// PostsJSONFeed serves the latest public posts as a JSON Feed 1.1
+func PostsJSONFeed(c *gin.Context) {
+	posts, _, ok := loadFeed(c)
+	if !ok {
+		return
+	}
+
+	base := feedBaseURL(c)
+	feed := JSONFeed{
+		Version:     "https://jsonfeed.org/version/1.1",
+		Title:       services.FeedTitle(),
+		HomePageURL: base + "/",
+		FeedURL:     base + c.Request.URL.RequestURI(),
+		Items:       make([]JSONFeedItem, 0, len(posts)),
+	}
+	for _, post := range posts {
+		item := JSONFeedItem{
+			ID:            feedPostID(base, post),
+			URL:           feedPostURL(base, post),
+			Title:         post.Title,
+			ContentHTML:   post.ContentHTML,
+			Summary:       post.Excerpt,
+			DatePublished: postPublishedAt(post).Format(time.RFC3339),
+			DateModified:  post.UpdatedAt.UTC().Format(time.RFC3339),
+			Tags:          postTagNames(post),
+		}
+		if post.Author != nil {
+			item.Authors = []JSONFeedAuthor{{Name: post.Author.Username, Avatar: feedAvatarURL(base, *post.Author)}}
+		}
+		feed.Items = append(feed.Items, item)
+	}
+
+	c.Header("Content-Type", "application/feed+json; charset=utf-8")
+	c.JSON(http.StatusOK, feed)
+}
@@ -0,0 +1,118 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"go-gin-auth-api-starter-kit/models"
+	"go-gin-auth-api-starter-kit/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// respondFollowError maps follow service errors to HTTP responses
+func respondFollowError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	case errors.Is(err, services.ErrCannotFollowSelf):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
+
+// FollowUser makes the caller follow a user. Repeating it changes nothing.
+func FollowUser(c *gin.Context) {
+	id, ok := idParam(c, "id", "Invalid user ID")
+	if !ok {
+		return
+	}
+
+	user, err := services.FollowUser(c.Request.Context(), currentActor(c), id)
+	if err != nil {
+		respondFollowError(c, err, "Failed to follow user")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"user": userResponse(user), "following": true})
+}
+
+// UnfollowUser stops the caller from following a user
+func UnfollowUser(c *gin.Context) {
+	id, ok := idParam(c, "id", "Invalid user ID")
+	if !ok {
+		return
+	}
+
+	user, err := services.UnfollowUser(c.Request.Context(), currentActor(c), id)
+	if err != nil {
+		respondFollowError(c, err, "Failed to unfollow user")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"user": userResponse(user), "following": false})
+}
+
+// ListFollowers returns a page of the users following a user
+func ListFollowers(c *gin.Context) {
+	listFollows(c, services.ListFollowers)
+}
+
+// ListFollowing returns a page of the users a user follows
+func ListFollowing(c *gin.Context) {
+	listFollows(c, services.ListFollowing)
+}
+
+// listFollows answers ListFollowers and ListFollowing with the users list returns
+func listFollows(c *gin.Context, list func(ctx context.Context, userID uint, page, perPage int) ([]models.User, int64, error)) {
+	id, ok := idParam(c, "id", "Invalid user ID")
+	if !ok {
+		return
+	}
+
+	page, perPage := pagination(c)
+	users, total, err := list(c.Request.Context(), id, page, perPage)
+	if err != nil {
+		respondFollowError(c, err, "Failed to list users")
+		return
+	}
+
+	response := make([]UserResponse, 0, len(users))
+	for _, user := range users {
+		response = append(response, userResponse(user))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"users":      response,
+		"pagination": paginationMeta(page, perPage, total),
+	})
+}
+
+// GetHomeFeed returns the caller's home feed: published posts by the users
+// they follow, newest first. Pass next_cursor as ?cursor= for the next page.
+func GetHomeFeed(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultPerPage)))
+	if err != nil || limit < 1 || limit > maxPerPage {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errInvalidQuery("limit").Error()})
+		return
+	}
+	if !renderParam(c) {
+		return
+	}
+
+	posts, next, err := services.HomeFeed(c.Request.Context(), currentActor(c), c.Query("cursor"), limit)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidFeedCursor) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load feed"})
+		return
+	}
+
+	var nextCursor *string
+	if next != "" {
+		nextCursor = &next
+	}
+	c.JSON(http.StatusOK, gin.H{"posts": renderedPostResponses(c, posts), "next_cursor": nextCursor})
+}
@@ -0,0 +1,105 @@
+package controllers
+
+import (
+	"errors"
+	"go-gin-auth-api-starter-kit/models"
+	"go-gin-auth-api-starter-kit/repositories"
+	"go-gin-auth-api-starter-kit/services"
+	"net/http"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// jobStatuses are the values accepted by the status filter
+var jobStatuses = []string{models.JobPending, models.JobRunning, models.JobSucceeded, models.JobDead}
+
+// JobResponse is how a background job is returned to admins
+type JobResponse struct {
+	ID       uint           `json:"id"`
+	Type     string         `json:"type"`
+	Payload  models.JSONMap `json:"payload"`
+	Priority int            `json:"priority"`
+	// Status is pending, running, succeeded or dead
+	Status      string `json:"status"`
+	Attempts    int    `json:"attempts"`
+	MaxAttempts int    `json:"max_attempts"`
+	RunAt       string `json:"run_at"`
+	// LockedBy is the worker running the job
+	LockedBy   string  `json:"locked_by"`
+	LastError  string  `json:"last_error"`
+	FinishedAt *string `json:"finished_at"`
+	CreatedAt  string  `json:"created_at"`
+}
+
+// jobResponse formats a job for the API
+func jobResponse(job models.Job) JobResponse {
+	response := JobResponse{
+		ID:          job.ID,
+		Type:        job.Type,
+		Payload:     job.Payload,
+		Priority:    job.Priority,
+		Status:      job.Status,
+		Attempts:    job.Attempts,
+		MaxAttempts: job.MaxAttempts,
+		RunAt:       job.RunAt.Format("2006-01-02 15:04:05"),
+		LockedBy:    job.LockedBy,
+		LastError:   job.LastError,
+		FinishedAt:  formatOptionalTime(job.FinishedAt),
+		CreatedAt:   job.CreatedAt.Format("2006-01-02 15:04:05"),
+	}
+	if response.Payload == nil {
+		response.Payload = models.JSONMap{}
+	}
+	return response
+}
+
+// ListJobs returns a page of background jobs, newest first, filtered by
+// ?status= and ?type= (admin only)
+func ListJobs(c *gin.Context) {
+	filter := repositories.JobFilter{Status: c.Query("status"), Type: c.Query("type")}
+	if filter.Status != "" && !slices.Contains(jobStatuses, filter.Status) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errInvalidQuery("status").Error()})
+		return
+	}
+
+	page, perPage := pagination(c)
+	jobs, total, err := services.ListJobs(c.Request.Context(), filter, page, perPage)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list jobs"})
+		return
+	}
+
+	response := make([]JobResponse, 0, len(jobs))
+	for _, job := range jobs {
+		response = append(response, jobResponse(job))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"jobs":       response,
+		"pagination": paginationMeta(page, perPage, total),
+	})
+}
+
+// RetryJob puts a dead job back in the queue (admin only)
+func RetryJob(c *gin.Context) {
+	id, ok := idParam(c, "id", "Invalid job ID")
+	if !ok {
+		return
+	}
+
+	job, err := services.RetryDeadJob(c.Request.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+		case errors.Is(err, services.ErrJobNotDead):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retry job"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"job": jobResponse(job)})
+}
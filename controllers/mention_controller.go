@@ -0,0 +1,35 @@
+package controllers
+
+import (
+	"errors"
+	"go-gin-auth-api-starter-kit/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ListUserMentions returns a page of the posts that mention a user, newest
+// first. The user is given by username. The route names the segment :id
+// because Gin needs every wildcard at the same place in /users/... to share
+// a name.
+func ListUserMentions(c *gin.Context) {
+	if !renderParam(c) {
+		return
+	}
+	page, perPage := pagination(c)
+	posts, total, err := services.ListUserMentions(c.Request.Context(), currentActor(c), c.Param("id"), page, perPage)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list mentions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"posts":      renderedPostResponses(c, posts),
+		"pagination": paginationMeta(page, perPage, total),
+	})
+}
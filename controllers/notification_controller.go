@@ -0,0 +1,222 @@
+package controllers
+
+import (
+	"errors"
+	"go-gin-auth-api-starter-kit/models"
+	"go-gin-auth-api-starter-kit/services"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// notificationHeartbeat is how often an idle notification stream sends a
+// comment, so proxies don't close the connection
+const notificationHeartbeat = 25 * time.Second
+
+// NotificationPost identifies the post a notification is about
+type NotificationPost struct {
+	ID    uint   `json:"id"`
+	Title string `json:"title"`
+	Slug  string `json:"slug"`
+}
+
+// NotificationResponse is how a notification is returned to clients
+type NotificationResponse struct {
+	ID   uint   `json:"id"`
+	Type string `json:"type"`
+	// Actor is who caused the notification; null once their account is gone
+	Actor *PostAuthor `json:"actor"`
+	// Post is null when the notification isn't about a post, or the post is gone
+	Post      *NotificationPost `json:"post"`
+	CommentID *uint             `json:"comment_id"`
+	// Data holds details depending on the type, e.g. the emoji of a reaction
+	Data      models.JSONMap `json:"data"`
+	Read      bool           `json:"read"`
+	ReadAt    *string        `json:"read_at"`
+	CreatedAt string         `json:"created_at"`
+}
+
+// notificationResponse formats a notification for the API
+func notificationResponse(notification models.Notification) NotificationResponse {
+	response := NotificationResponse{
+		ID:        notification.ID,
+		Type:      notification.Type,
+		CommentID: notification.CommentID,
+		Data:      notification.Data,
+		Read:      notification.ReadAt != nil,
+		CreatedAt: notification.CreatedAt.Format("2006-01-02 15:04:05"),
+	}
+	if response.Data == nil {
+		response.Data = models.JSONMap{}
+	}
+	if notification.Actor != nil {
+		actor := postAuthor(*notification.Actor)
+		response.Actor = &actor
+	}
+	if notification.Post != nil {
+		response.Post = &NotificationPost{ID: notification.Post.ID, Title: notification.Post.Title, Slug: notification.Post.Slug}
+	}
+	if notification.ReadAt != nil {
+		readAt := notification.ReadAt.Format("2006-01-02 15:04:05")
+		response.ReadAt = &readAt
+	}
+	return response
+}
+
+// ListNotifications returns a page of the caller's notifications, newest
+// first. ?unread=true leaves out the ones already read.
+func ListNotifications(c *gin.Context) {
+	unreadOnly, err := strconv.ParseBool(c.DefaultQuery("unread", "false"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errInvalidQuery("unread").Error()})
+		return
+	}
+
+	page, perPage := pagination(c)
+	notifications, total, err := services.ListNotifications(c.Request.Context(), currentActor(c), unreadOnly, page, perPage)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch notifications"})
+		return
+	}
+
+	response := make([]NotificationResponse, 0, len(notifications))
+	for _, notification := range notifications {
+		response = append(response, notificationResponse(notification))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"notifications": response,
+		"pagination":    paginationMeta(page, perPage, total),
+	})
+}
+
+// GetUnreadNotificationCount returns how many of the caller's notifications are unread
+func GetUnreadNotificationCount(c *gin.Context) {
+	count, err := services.CountUnreadNotifications(c.Request.Context(), currentActor(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count notifications"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"unread": count})
+}
+
+// MarkNotificationRead marks one of the caller's notifications as read
+func MarkNotificationRead(c *gin.Context) {
+	id, ok := idParam(c, "id", "Invalid notification ID")
+	if !ok {
+		return
+	}
+
+	notification, err := services.MarkNotificationRead(c.Request.Context(), currentActor(c), id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Notification not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to mark notification as read"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"notification": notificationResponse(notification)})
+}
+
+// MarkAllNotificationsRead marks all of the caller's notifications as read
+func MarkAllNotificationsRead(c *gin.Context) {
+	marked, err := services.MarkAllNotificationsRead(c.Request.Context(), currentActor(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to mark notifications as read"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"marked": marked})
+}
+
+// GetNotificationPreferences returns which notification types are on for the caller
+func GetNotificationPreferences(c *gin.Context) {
+	preferences, err := services.NotificationPreferences(c.Request.Context(), currentActor(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch notification preferences"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"preferences": preferences})
+}
+
+// UpdateNotificationPreferences turns notification types on or off for the
+// caller. The body maps types to whether they are on; types left out keep
+// their setting.
+func UpdateNotificationPreferences(c *gin.Context) {
+	var input struct {
+		Preferences map[string]bool `json:"preferences" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	preferences, err := services.UpdateNotificationPreferences(c.Request.Context(), currentActor(c), input.Preferences)
+	if err != nil {
+		if errors.Is(err, services.ErrUnknownNotificationType) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notification preferences"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"preferences": preferences})
+}
+
+// StreamNotifications sends the caller's new notifications as server-sent
+// events until the client disconnects. Each event is named "notification",
+// carries the notification as JSON and has its ID as the event ID, so a
+// reconnecting client's Last-Event-ID header brings back what it missed.
+func StreamNotifications(c *gin.Context) {
+	var lastEventID uint
+	if raw := c.GetHeader("Last-Event-ID"); raw != "" {
+		id, err := strconv.ParseUint(raw, 10, 0)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Last-Event-ID header"})
+			return
+		}
+		lastEventID = uint(id)
+	}
+
+	ctx := c.Request.Context()
+	stream, err := services.StreamNotifications(ctx, currentActor(c), lastEventID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open notification stream"})
+		return
+	}
+	defer stream.Close()
+
+	c.Header("Content-Type", sse.ContentType)
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	// Keep nginx from buffering the stream
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(notificationHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-heartbeat.C:
+			if _, err := c.Writer.WriteString(": heartbeat\n\n"); err != nil {
+				return
+			}
+		case notification, ok := <-stream.Notifications:
+			if !ok {
+				return
+			}
+			c.Render(-1, sse.Event{
+				Id:    strconv.FormatUint(uint64(notification.ID), 10),
+				Event: "notification",
+				Data:  notificationResponse(notification),
+			})
+		}
+		c.Writer.Flush()
+	}
+}
@@ -0,0 +1,23 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestStreamNotificationsRejectsInvalidLastEventID(t *testing.T) {
+	for _, header := range []string{"abc", "-1", "1.5"} {
+		recorder := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(recorder)
+		c.Request = httptest.NewRequest(http.MethodGet, "/notifications/stream", nil)
+		c.Request.Header.Set("Last-Event-ID", header)
+
+		StreamNotifications(c)
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("Last-Event-ID %q: status %d, want 400", header, recorder.Code)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package controllers
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultPerPage = 20
+	maxPerPage     = 100
+)
+
+// pagination reads the page and per_page query parameters, applying defaults and limits
+func pagination(c *gin.Context) (page, perPage int) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	perPage, err = strconv.Atoi(c.DefaultQuery("per_page", strconv.Itoa(defaultPerPage)))
+	if err != nil || perPage < 1 {
+		perPage = defaultPerPage
+	}
+	if perPage > maxPerPage {
+		perPage = maxPerPage
+	}
+	return page, perPage
+}
+
+// paginationMeta builds the "pagination" object returned alongside list responses
+func paginationMeta(page, perPage int, total int64) gin.H {
+	return gin.H{
+		"page":     page,
+		"per_page": perPage,
+		"total":    total,
+	}
+}
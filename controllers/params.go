@@ -0,0 +1,31 @@
+package controllers
+
+import (
+	"fmt"
+	"go-gin-auth-api-starter-kit/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// idParam parses a numeric path parameter. When it is not a valid ID it
+// responds with 400 and message, and returns false.
+func idParam(c *gin.Context, name, message string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param(name), 10, 64)
+	if err != nil || id == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": message})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// errInvalidQuery describes a query parameter that could not be parsed
+func errInvalidQuery(param string) error {
+	return fmt.Errorf("invalid query parameter: %s", param)
+}
+
+// currentActor returns the authenticated user set by the auth middleware
+func currentActor(c *gin.Context) services.Actor {
+	return services.Actor{ID: c.GetUint("user_id"), Role: c.GetString("role")}
+}
@@ -1,94 +1,287 @@
 package controllers
 
 import (
-	"go-gin-auth-api-starter-kit/config"
+	"errors"
 	"go-gin-auth-api-starter-kit/models"
 	"go-gin-auth-api-starter-kit/services"
 	"net/http"
-	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
+// PostRequest is the body accepted when creating or updating a post
+type PostRequest struct {
+	Title   string `json:"title" binding:"required,max=255"`
+	Content string `json:"content"`
+	// ContentFormat is "plain" or "markdown", plain text by default. Updates
+	// replace the post, so omitting it switches the post back to plain text.
+	ContentFormat string `json:"content_format" binding:"omitempty,oneof=plain markdown"`
+	// Tags are created on first use. On update they replace the current
+	// tags, so omitting them removes all tags.
+	Tags       []string `json:"tags" binding:"max=20"`
+	CategoryID *uint    `json:"category_id"`
+	// Status is only read on create: "draft" (default) or "published".
+	// Use the publish/schedule/unpublish/archive endpoints afterwards.
+	Status string `json:"status" binding:"omitempty,oneof=draft published"`
+	// Visibility is "public", "internal", "private" or "unlisted", internal by
+	// default. Updates replace the post, so omitting it makes the post internal.
+	Visibility string `json:"visibility" binding:"omitempty,oneof=public internal private unlisted"`
+}
+
+// ScheduleRequest is the body accepted when scheduling a post
+type ScheduleRequest struct {
+	PublishAt time.Time `json:"publish_at" binding:"required"`
+}
+
+// PostAuthor identifies who wrote a post
+type PostAuthor struct {
+	ID       uint   `json:"id"`
+	Username string `json:"username"`
+	// AvatarURLs maps each avatar size in pixels to the URL of the image
+	AvatarURLs map[string]string `json:"avatar_urls"`
+}
+
+// postAuthor formats a user shown with a post, e.g. as its author
+func postAuthor(user models.User) PostAuthor {
+	return PostAuthor{ID: user.ID, Username: user.Username, AvatarURLs: services.AvatarURLs(user)}
+}
+
+// PostResponse is how a post is returned to clients
+type PostResponse struct {
+	ID            uint   `json:"id"`
+	Title         string `json:"title"`
+	Slug          string `json:"slug"`
+	Content       string `json:"content"`
+	ContentFormat string `json:"content_format"`
+	// ContentHTML is the sanitized HTML rendering, included with ?render=html
+	ContentHTML *string `json:"content_html,omitempty"`
+	Excerpt     string  `json:"excerpt"`
+	// ReadingTime is the estimated reading time in minutes
+	ReadingTime int               `json:"reading_time"`
+	Version     int               `json:"version"`
+	Author      *PostAuthor       `json:"author"`
+	Status      string            `json:"status"`
+	Visibility  string            `json:"visibility"`
+	Tags        []TagResponse     `json:"tags"`
+	Category    *CategoryResponse `json:"category"`
+	CreatedAt   string            `json:"created_at"`
+	// PublishedAt is null until the post is first published
+	PublishedAt *string `json:"published_at"`
+	// ScheduledAt is set while the post is scheduled
+	ScheduledAt *string `json:"scheduled_at"`
+	// Reactions counts the reactions to the post by emoji
+	Reactions []ReactionResponse `json:"reactions"`
+	// Bookmarked reports whether the current user bookmarked the post
+	Bookmarked bool `json:"bookmarked"`
+}
+
+// postResponse formats a post for the API
+func postResponse(post models.Post) PostResponse {
+	response := PostResponse{
+		ID:            post.ID,
+		Title:         post.Title,
+		Slug:          post.Slug,
+		Content:       post.Content,
+		ContentFormat: post.ContentFormat,
+		Excerpt:       post.Excerpt,
+		ReadingTime:   post.ReadingTime,
+		Version:       post.Version,
+		Status:        post.Status,
+		Visibility:    post.Visibility,
+		Tags:          tagResponses(post.Tags),
+		Category:      categoryResponse(post.Category),
+		CreatedAt:     post.CreatedAt.Format("2006-01-02 15:04:05"),
+		PublishedAt:   formatOptionalTime(post.PublishedAt),
+		ScheduledAt:   formatOptionalTime(post.ScheduledAt),
+		Reactions:     reactionResponses(post.Reactions),
+		Bookmarked:    post.Bookmarked,
+	}
+	if post.Author != nil {
+		author := postAuthor(*post.Author)
+		response.Author = &author
+	}
+	return response
+}
+
+// renderParam checks the render query parameter. It responds with 400 and
+// returns false when it has a value other than "html".
+func renderParam(c *gin.Context) bool {
+	if render := c.Query("render"); render != "" && render != "html" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errInvalidQuery("render").Error()})
+		return false
+	}
+	return true
+}
+
+// renderedPostResponse formats a post for the API, adding its HTML when the
+// client asked for it with ?render=html
+func renderedPostResponse(c *gin.Context, post models.Post) PostResponse {
+	response := postResponse(post)
+	if c.Query("render") == "html" {
+		response.ContentHTML = &post.ContentHTML
+	}
+	return response
+}
+
+// renderedPostResponses formats a list of posts like renderedPostResponse,
+// with the current user's reactions and bookmarks
+func renderedPostResponses(c *gin.Context, posts []models.Post) []PostResponse {
+	loadEngagement(c, posts)
+	response := make([]PostResponse, 0, len(posts))
+	for _, post := range posts {
+		response = append(response, renderedPostResponse(c, post))
+	}
+	return response
+}
+
+// formatOptionalTime formats t like the other timestamps in responses, keeping nil as null
+func formatOptionalTime(t *time.Time) *string {
+	if t == nil {
+		return nil
+	}
+	formatted := t.Format("2006-01-02 15:04:05")
+	return &formatted
+}
+
+// respondPostError maps service errors for post endpoints to HTTP responses
+func respondPostError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+	case errors.Is(err, services.ErrInvalidTag),
+		errors.Is(err, services.ErrCategoryNotFound),
+		errors.Is(err, services.ErrScheduleInPast):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrNotPostAuthor):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidPostTransition):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrPostVersionMismatch):
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrPreconditionRequired):
+		c.JSON(http.StatusPreconditionRequired, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
+
+// listQueryValues reads a query parameter that may be repeated or comma separated
+func listQueryValues(c *gin.Context, name string) []string {
+	var values []string
+	for _, raw := range c.QueryArray(name) {
+		for _, value := range strings.Split(raw, ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				values = append(values, value)
+			}
+		}
+	}
+	return values
+}
+
 func CreatePost(c *gin.Context) {
-	var post models.Post
-	if err := c.ShouldBindJSON(&post); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
+	var request PostRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	createdPost, err := services.CreatePost(post)
+
+	post := models.Post{
+		Title:         request.Title,
+		Content:       request.Content,
+		ContentFormat: request.ContentFormat,
+		CategoryID:    request.CategoryID,
+		Status:        request.Status,
+		Visibility:    request.Visibility,
+	}
+	createdPost, err := services.CreatePost(c.Request.Context(), currentActor(c), post, request.Tags)
 	if err != nil {
-		c.JSON(500, gin.H{"error": "Failed to create post"})
+		respondPostError(c, err, "Failed to create post")
 		return
 	}
-	c.JSON(201, gin.H{"post": createdPost})
+	respondPost(c, http.StatusCreated, createdPost)
 }
 
 func ListPosts(c *gin.Context) {
-	var posts []models.Post
+	query := services.PostQuery{
+		Tags:     listQueryValues(c, "tag"),
+		Category: c.Query("category"),
+		Status:   c.Query("status"),
+		Author:   c.Query("author"),
+	}
+	switch query.Status {
+	case "", models.PostStatusDraft, models.PostStatusScheduled, models.PostStatusPublished, models.PostStatusArchived:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": errInvalidQuery("status").Error()})
+		return
+	}
+	if !renderParam(c) {
+		return
+	}
 
-	if err := config.DB.Find(&posts).Error; err != nil {
+	posts, err := services.ListPosts(c.Request.Context(), currentActor(c), query)
+	if err != nil {
+		if errors.Is(err, services.ErrCategoryNotFound) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(
 			http.StatusInternalServerError,
 			gin.H{"error": "Failed to list posts"})
 		return
 	}
 
-	type PostResponse struct {
-		ID        uint   `json:"id"`
-		Title     string `json:"title"`
-		Content   string `json:"content"`
-		CreatedAt string `json:"created_at"`
-	}
-
-	var postResponse []PostResponse
+	c.JSON(http.StatusOK, gin.H{"posts": renderedPostResponses(c, posts)})
+}
 
-	for _, post := range posts {
-		postResponse = append(postResponse, PostResponse{
-			ID:        post.ID,
-			Title:     post.Title,
-			Content:   post.Content,
-			CreatedAt: post.CreatedAt.Format("2006-01-02 15:04:05"),
-		})
+func DeletePost(c *gin.Context) {
+	id, ok := idParam(c, "id", "Invalid post ID")
+	if !ok {
+		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"posts": postResponse})
+	if err := services.DeletePost(c.Request.Context(), currentActor(c), id, ifMatch(c, id)); err != nil {
+		respondPostError(c, err, "Failed to delete post")
+		return
+	}
 
+	c.JSON(http.StatusOK, gin.H{"message": "Post deleted successfully"})
 }
 
-func DeletePost(c *gin.Context) {
-	idParam := c.Param("id")
-	id, err := strconv.Atoi(idParam)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+func GetPost(c *gin.Context) {
+	id, ok := idParam(c, "id", "Invalid post ID")
+	if !ok {
+		return
+	}
+	if !renderParam(c) {
 		return
 	}
 
-	if err := services.DeletePost(uint(id)); err != nil {
+	post, err := services.GetPostByID(c.Request.Context(), currentActor(c), id)
+	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete post"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch post"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Post deleted successfully"})
-
+	respondFetchedPost(c, post)
 }
 
-func GetPost(c *gin.Context) {
-	idParam := c.Param("id")
-	id, err := strconv.Atoi(idParam)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+// GetPostBySlug finds a post by its slug. Former slugs of a post redirect
+// permanently to its current slug.
+func GetPostBySlug(c *gin.Context) {
+	if !renderParam(c) {
 		return
 	}
 
-	post, err := services.GetPostByID(uint(id))
+	post, moved, err := services.GetPostBySlug(c.Request.Context(), currentActor(c), c.Param("slug"))
 	if err != nil {
-		if err == gorm.ErrRecordNotFound {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
 			return
 		}
@@ -96,62 +289,83 @@ func GetPost(c *gin.Context) {
 		return
 	}
 
-	// Format the response
-	type PostResponse struct {
-		ID        uint   `json:"id"`
-		Title     string `json:"title"`
-		Content   string `json:"content"`
-		CreatedAt string `json:"created_at"`
-	}
-
-	response := PostResponse{
-		ID:        post.ID,
-		Title:     post.Title,
-		Content:   post.Content,
-		CreatedAt: post.CreatedAt.Format("2006-01-02 15:04:05"),
+	if moved {
+		location := "/api/v1/posts/by-slug/" + post.Slug
+		if c.Request.URL.RawQuery != "" {
+			location += "?" + c.Request.URL.RawQuery
+		}
+		c.Redirect(http.StatusMovedPermanently, location)
+		return
 	}
-
-	c.JSON(http.StatusOK, gin.H{"post": response})
+	respondFetchedPost(c, post)
 }
 
 func UpdatePost(c *gin.Context) {
-	idParam := c.Param("id")
-	id, err := strconv.Atoi(idParam)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+	id, ok := idParam(c, "id", "Invalid post ID")
+	if !ok {
 		return
 	}
 
-	var post models.Post
-	if err := c.ShouldBindJSON(&post); err != nil {
+	var request PostRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	updatedPost, err := services.UpdatePost(uint(id), post)
+	fields := services.PostFields{
+		Title:         request.Title,
+		Content:       request.Content,
+		ContentFormat: request.ContentFormat,
+		Tags:          request.Tags,
+		CategoryID:    request.CategoryID,
+		Visibility:    request.Visibility,
+	}
+	updatedPost, err := services.UpdatePost(c.Request.Context(), currentActor(c), id, fields, ifMatch(c, id))
 	if err != nil {
-		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update post"})
+		respondPostError(c, err, "Failed to update post")
 		return
 	}
 
-	// Format the response
-	type PostResponse struct {
-		ID        uint   `json:"id"`
-		Title     string `json:"title"`
-		Content   string `json:"content"`
-		CreatedAt string `json:"created_at"`
+	respondPost(c, http.StatusOK, updatedPost)
+}
+
+// transitionPost runs a workflow action on the post in the path and responds with the result
+func transitionPost(c *gin.Context, action string, publishAt *time.Time) {
+	id, ok := idParam(c, "id", "Invalid post ID")
+	if !ok {
+		return
 	}
 
-	response := PostResponse{
-		ID:        updatedPost.ID,
-		Title:     updatedPost.Title,
-		Content:   updatedPost.Content,
-		CreatedAt: updatedPost.CreatedAt.Format("2006-01-02 15:04:05"),
+	post, err := services.TransitionPost(c.Request.Context(), currentActor(c), id, action, publishAt)
+	if err != nil {
+		respondPostError(c, err, "Failed to "+action+" post")
+		return
+	}
+
+	respondPost(c, http.StatusOK, post)
+}
+
+// PublishPost makes a draft, scheduled or archived post public right away
+func PublishPost(c *gin.Context) {
+	transitionPost(c, services.PostActionPublish, nil)
+}
+
+// SchedulePost sets a draft to be published automatically at publish_at
+func SchedulePost(c *gin.Context) {
+	var request ScheduleRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
+	transitionPost(c, services.PostActionSchedule, &request.PublishAt)
+}
+
+// UnpublishPost turns a published or scheduled post back into a draft
+func UnpublishPost(c *gin.Context) {
+	transitionPost(c, services.PostActionUnpublish, nil)
+}
 
-	c.JSON(http.StatusOK, gin.H{"post": response})
+// ArchivePost hides a post from everyone but its author and admins
+func ArchivePost(c *gin.Context) {
+	transitionPost(c, services.PostActionArchive, nil)
 }
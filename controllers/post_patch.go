@@ -0,0 +1,155 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"go-gin-auth-api-starter-kit/services"
+	"io"
+	"net/http"
+	"strings"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// Content types accepted by PATCH /posts/:id
+const (
+	mergePatchContentType = "application/merge-patch+json"
+	jsonPatchContentType  = "application/json-patch+json"
+)
+
+// maxPatchSize limits the size of a PATCH body
+const maxPatchSize = 1 << 20
+
+// PostDocument is the JSON document a PATCH is applied to. The patched result
+// must still be a valid PostDocument.
+type PostDocument struct {
+	Title         string   `json:"title" binding:"required,max=255"`
+	Content       string   `json:"content"`
+	ContentFormat string   `json:"content_format" binding:"required,oneof=plain markdown"`
+	Tags          []string `json:"tags" binding:"max=20"`
+	CategoryID    *uint    `json:"category_id"`
+	Visibility    string   `json:"visibility" binding:"required,oneof=public internal private unlisted"`
+}
+
+// PostMergePatch documents an application/merge-patch+json body (RFC 7396).
+// Absent members are left unchanged and null clears a member.
+type PostMergePatch struct {
+	Title         *string  `json:"title,omitempty"`
+	Content       *string  `json:"content,omitempty"`
+	ContentFormat *string  `json:"content_format,omitempty"`
+	Tags          []string `json:"tags,omitempty"`
+	CategoryID    *uint    `json:"category_id,omitempty"`
+	Visibility    *string  `json:"visibility,omitempty"`
+}
+
+// JSONPatchOperation documents one operation of an application/json-patch+json body (RFC 6902)
+type JSONPatchOperation struct {
+	// Op is add, remove, replace, move, copy or test
+	Op    string `json:"op"`
+	Path  string `json:"path"`
+	Value any    `json:"value,omitempty"`
+	From  string `json:"from,omitempty"`
+}
+
+// patchError is a failed PATCH together with the status code it maps to
+type patchError struct {
+	status int
+	err    error
+}
+
+func (e *patchError) Error() string { return e.err.Error() }
+
+// applyPostPatch applies a merge patch or JSON patch to the fields of a post
+// and validates the result
+func applyPostPatch(contentType string, patch []byte, fields services.PostFields) (services.PostFields, error) {
+	document, err := json.Marshal(PostDocument{
+		Title:         fields.Title,
+		Content:       fields.Content,
+		ContentFormat: fields.ContentFormat,
+		Tags:          fields.Tags,
+		CategoryID:    fields.CategoryID,
+		Visibility:    fields.Visibility,
+	})
+	if err != nil {
+		return services.PostFields{}, err
+	}
+
+	var patched []byte
+	switch contentType {
+	case mergePatchContentType:
+		if !json.Valid(patch) {
+			return services.PostFields{}, &patchError{http.StatusBadRequest, errors.New("malformed merge patch")}
+		}
+		if patched, err = jsonpatch.MergePatch(document, patch); err != nil {
+			return services.PostFields{}, &patchError{http.StatusBadRequest, err}
+		}
+	case jsonPatchContentType:
+		operations, err := jsonpatch.DecodePatch(patch)
+		if err != nil {
+			return services.PostFields{}, &patchError{http.StatusBadRequest, err}
+		}
+		// Failed "test" operations and paths that don't exist conflict with the current state
+		if patched, err = operations.Apply(document); err != nil {
+			return services.PostFields{}, &patchError{http.StatusConflict, err}
+		}
+	}
+
+	var result PostDocument
+	decoder := json.NewDecoder(bytes.NewReader(patched))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&result); err != nil {
+		return services.PostFields{}, &patchError{http.StatusUnprocessableEntity, err}
+	}
+	if err := binding.Validator.ValidateStruct(&result); err != nil {
+		return services.PostFields{}, &patchError{http.StatusUnprocessableEntity, err}
+	}
+
+	return services.PostFields{
+		Title:         result.Title,
+		Content:       result.Content,
+		ContentFormat: result.ContentFormat,
+		Tags:          result.Tags,
+		CategoryID:    result.CategoryID,
+		Visibility:    result.Visibility,
+	}, nil
+}
+
+// PatchPost partially updates a post with a JSON Merge Patch or a JSON Patch
+func PatchPost(c *gin.Context) {
+	id, ok := idParam(c, "id", "Invalid post ID")
+	if !ok {
+		return
+	}
+
+	contentType := strings.ToLower(c.ContentType())
+	if contentType != mergePatchContentType && contentType != jsonPatchContentType {
+		c.Header("Accept-Patch", mergePatchContentType+", "+jsonPatchContentType)
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Content-Type must be " + mergePatchContentType + " or " + jsonPatchContentType})
+		return
+	}
+
+	patch, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxPatchSize))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read patch"})
+		return
+	}
+
+	post, err := services.PatchPost(c.Request.Context(), currentActor(c), id, ifMatch(c, id),
+		func(fields services.PostFields) (services.PostFields, error) {
+			return applyPostPatch(contentType, patch, fields)
+		})
+	if err != nil {
+		var patchErr *patchError
+		if errors.As(err, &patchErr) {
+			c.JSON(patchErr.status, gin.H{"error": patchErr.Error()})
+			return
+		}
+		respondPostError(c, err, "Failed to update post")
+		return
+	}
+
+	respondPost(c, http.StatusOK, post)
+}
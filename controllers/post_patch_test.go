@@ -0,0 +1,134 @@
+package controllers
+
+import (
+	"errors"
+	"go-gin-auth-api-starter-kit/services"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestApplyPostPatch(t *testing.T) {
+	categoryID := uint(4)
+	current := services.PostFields{
+		Title:         "Hello",
+		Content:       "Body",
+		ContentFormat: "plain",
+		Tags:          []string{"go"},
+		CategoryID:    &categoryID,
+		Visibility:    "internal",
+	}
+	with := func(change func(*services.PostFields)) services.PostFields {
+		fields := current
+		change(&fields)
+		return fields
+	}
+
+	tests := []struct {
+		name        string
+		contentType string
+		patch       string
+		want        services.PostFields
+		status      int
+	}{
+		{
+			name:        "merge patch changes only the given members",
+			contentType: mergePatchContentType,
+			patch:       `{"title": "Updated"}`,
+			want:        with(func(f *services.PostFields) { f.Title = "Updated" }),
+		},
+		{
+			name:        "merge patch null clears a member",
+			contentType: mergePatchContentType,
+			patch:       `{"category_id": null, "tags": null}`,
+			want:        with(func(f *services.PostFields) { f.CategoryID = nil; f.Tags = nil }),
+		},
+		{
+			name:        "empty merge patch",
+			contentType: mergePatchContentType,
+			patch:       `{}`,
+			want:        current,
+		},
+		{
+			name:        "malformed merge patch",
+			contentType: mergePatchContentType,
+			patch:       `{"title":`,
+			status:      http.StatusBadRequest,
+		},
+		{
+			name:        "merge patch removing a required member",
+			contentType: mergePatchContentType,
+			patch:       `{"title": null}`,
+			status:      http.StatusUnprocessableEntity,
+		},
+		{
+			name:        "merge patch with an invalid value",
+			contentType: mergePatchContentType,
+			patch:       `{"visibility": "everyone"}`,
+			status:      http.StatusUnprocessableEntity,
+		},
+		{
+			name:        "merge patch adding an unknown member",
+			contentType: mergePatchContentType,
+			patch:       `{"author_id": 1}`,
+			status:      http.StatusUnprocessableEntity,
+		},
+		{
+			name:        "json patch replace and add",
+			contentType: jsonPatchContentType,
+			patch:       `[{"op": "replace", "path": "/content_format", "value": "markdown"}, {"op": "add", "path": "/tags/-", "value": "web"}]`,
+			want: with(func(f *services.PostFields) {
+				f.ContentFormat = "markdown"
+				f.Tags = []string{"go", "web"}
+			}),
+		},
+		{
+			name:        "json patch passing test",
+			contentType: jsonPatchContentType,
+			patch:       `[{"op": "test", "path": "/title", "value": "Hello"}, {"op": "replace", "path": "/title", "value": "Updated"}]`,
+			want:        with(func(f *services.PostFields) { f.Title = "Updated" }),
+		},
+		{
+			name:        "json patch failing test",
+			contentType: jsonPatchContentType,
+			patch:       `[{"op": "test", "path": "/title", "value": "Other"}, {"op": "replace", "path": "/title", "value": "Updated"}]`,
+			status:      http.StatusConflict,
+		},
+		{
+			name:        "json patch on a missing path",
+			contentType: jsonPatchContentType,
+			patch:       `[{"op": "replace", "path": "/tags/5", "value": "web"}]`,
+			status:      http.StatusConflict,
+		},
+		{
+			name:        "malformed json patch",
+			contentType: jsonPatchContentType,
+			patch:       `{"op": "replace"}`,
+			status:      http.StatusBadRequest,
+		},
+		{
+			name:        "json patch with an invalid result",
+			contentType: jsonPatchContentType,
+			patch:       `[{"op": "replace", "path": "/content_format", "value": "html"}]`,
+			status:      http.StatusUnprocessableEntity,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := applyPostPatch(tt.contentType, []byte(tt.patch), current)
+			if tt.status != 0 {
+				var patchErr *patchError
+				if !errors.As(err, &patchErr) || patchErr.status != tt.status {
+					t.Fatalf("applyPostPatch error = %v, want status %d", err, tt.status)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("applyPostPatch: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("applyPostPatch = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package controllers
+
+import (
+	"errors"
+	"go-gin-auth-api-starter-kit/models"
+	"go-gin-auth-api-starter-kit/services"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ReactionResponse is how many users reacted to a post with one emoji
+type ReactionResponse struct {
+	Emoji string `json:"emoji"`
+	Count int    `json:"count"`
+	// Reacted reports whether the current user is one of them
+	Reacted bool `json:"reacted"`
+}
+
+// reactionResponses formats the reactions to a post for the API
+func reactionResponses(reactions []models.ReactionSummary) []ReactionResponse {
+	response := make([]ReactionResponse, 0, len(reactions))
+	for _, reaction := range reactions {
+		response = append(response, ReactionResponse{Emoji: reaction.Emoji, Count: reaction.Count, Reacted: reaction.Reacted})
+	}
+	return response
+}
+
+// loadEngagement fills in the reactions and bookmarks of posts for the
+// current user. They only decorate the posts, so a failure is logged and
+// the posts are returned without them.
+func loadEngagement(c *gin.Context, posts []models.Post) {
+	if err := services.LoadPostEngagement(c.Request.Context(), currentActor(c), posts); err != nil {
+		slog.ErrorContext(c.Request.Context(), "loading post reactions failed", slog.Any("error", err))
+	}
+}
+
+// respondReactions writes the reactions to a post with the emoji that can be used
+func respondReactions(c *gin.Context, reactions []models.ReactionSummary) {
+	c.JSON(http.StatusOK, gin.H{
+		"reactions": reactionResponses(reactions),
+		"available": services.ReactionEmojis(),
+	})
+}
+
+// respondReactionError maps reaction and bookmark service errors to HTTP responses
+func respondReactionError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+	case errors.Is(err, services.ErrInvalidReaction):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
+
+// ListPostReactions returns the reaction counts of a post the caller may read
+func ListPostReactions(c *gin.Context) {
+	id, ok := idParam(c, "id", "Invalid post ID")
+	if !ok {
+		return
+	}
+
+	reactions, err := services.ListPostReactions(c.Request.Context(), currentActor(c), id)
+	if err != nil {
+		respondReactionError(c, err, "Failed to fetch reactions")
+		return
+	}
+	respondReactions(c, reactions)
+}
+
+// AddReaction reacts to a post with the emoji in the path. Repeating it changes nothing.
+func AddReaction(c *gin.Context) {
+	id, ok := idParam(c, "id", "Invalid post ID")
+	if !ok {
+		return
+	}
+
+	reactions, err := services.AddReaction(c.Request.Context(), currentActor(c), id, c.Param("emoji"))
+	if err != nil {
+		respondReactionError(c, err, "Failed to add reaction")
+		return
+	}
+	respondReactions(c, reactions)
+}
+
+// RemoveReaction takes back the caller's reaction with the emoji in the path
+func RemoveReaction(c *gin.Context) {
+	id, ok := idParam(c, "id", "Invalid post ID")
+	if !ok {
+		return
+	}
+
+	reactions, err := services.RemoveReaction(c.Request.Context(), currentActor(c), id, c.Param("emoji"))
+	if err != nil {
+		respondReactionError(c, err, "Failed to remove reaction")
+		return
+	}
+	respondReactions(c, reactions)
+}
+
+// TopReactedPosts lists the published posts with the most reactions, overall
+// or within the last ?days days
+func TopReactedPosts(c *gin.Context) {
+	days, err := strconv.Atoi(c.DefaultQuery("days", "0"))
+	if err != nil || days < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errInvalidQuery("days").Error()})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil || limit < 1 || limit > services.TopReactedLimit {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errInvalidQuery("limit").Error()})
+		return
+	}
+	if !renderParam(c) {
+		return
+	}
+
+	query := services.PostQuery{
+		Tags:     listQueryValues(c, "tag"),
+		Category: c.Query("category"),
+		Author:   c.Query("author"),
+	}
+	posts, err := services.TopReactedPosts(c.Request.Context(), currentActor(c), query, days, limit)
+	if err != nil {
+		if errors.Is(err, services.ErrCategoryNotFound) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list posts"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"posts": renderedPostResponses(c, posts)})
+}
+
+// BookmarkPost adds a post to the caller's bookmarks. Repeating it changes nothing.
+func BookmarkPost(c *gin.Context) {
+	id, ok := idParam(c, "id", "Invalid post ID")
+	if !ok {
+		return
+	}
+
+	if err := services.BookmarkPost(c.Request.Context(), currentActor(c), id); err != nil {
+		respondReactionError(c, err, "Failed to bookmark post")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"bookmarked": true})
+}
+
+// UnbookmarkPost removes a post from the caller's bookmarks
+func UnbookmarkPost(c *gin.Context) {
+	id, ok := idParam(c, "id", "Invalid post ID")
+	if !ok {
+		return
+	}
+
+	if err := services.UnbookmarkPost(c.Request.Context(), currentActor(c), id); err != nil {
+		respondReactionError(c, err, "Failed to remove bookmark")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"bookmarked": false})
+}
+
+// ListBookmarks returns a page of the caller's bookmarked posts, most recently bookmarked first
+func ListBookmarks(c *gin.Context) {
+	if !renderParam(c) {
+		return
+	}
+	page, perPage := pagination(c)
+	posts, total, err := services.ListBookmarks(c.Request.Context(), currentActor(c), page, perPage)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list bookmarks"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"posts":      renderedPostResponses(c, posts),
+		"pagination": paginationMeta(page, perPage, total),
+	})
+}
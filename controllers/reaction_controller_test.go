@@ -0,0 +1,83 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"go-gin-auth-api-starter-kit/config"
+	"go-gin-auth-api-starter-kit/models"
+	"go-gin-auth-api-starter-kit/services"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestGetPostAfterEngagementIsNotNotModified(t *testing.T) {
+	openTestDB(t, &models.User{}, &models.Tag{}, &models.Category{}, &models.Post{}, &models.PostTag{}, &models.PostShare{},
+		&models.PostSlugAlias{}, &models.Attachment{}, &models.PostReaction{}, &models.PostReactionCount{},
+		&models.Bookmark{}, &models.Notification{}, &models.NotificationPreference{}, &models.OutboxEvent{},
+		&models.AuditLog{})
+	ctx := context.Background()
+
+	reader := models.User{Username: "bob", Email: "bob@example.com", Password: "x", Role: models.RoleUser}
+	if err := config.DB.Create(&reader).Error; err != nil {
+		t.Fatal(err)
+	}
+	post := models.Post{Title: "Hello", Status: models.PostStatusPublished, Visibility: models.PostVisibilityInternal}
+	if err := config.DB.Create(&post).Error; err != nil {
+		t.Fatal(err)
+	}
+	actor := services.Actor{ID: reader.ID, Role: reader.Role}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/posts/:id", func(c *gin.Context) {
+		c.Set("user_id", reader.ID)
+		c.Set("role", reader.Role)
+	}, GetPost)
+	get := func(ifNoneMatch string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/posts/%d", post.ID), nil)
+		if ifNoneMatch != "" {
+			request.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, request)
+		return w
+	}
+
+	first := get("")
+	if first.Code != http.StatusOK {
+		t.Fatalf("GET = %d, want 200", first.Code)
+	}
+	if got := first.Header().Get("Cache-Control"); got != "private" {
+		t.Errorf("Cache-Control = %q, want private", got)
+	}
+	if got := first.Header().Get("Vary"); got != "Authorization" {
+		t.Errorf("Vary = %q, want Authorization", got)
+	}
+	etag := first.Header().Get("ETag")
+	if w := get(etag); w.Code != http.StatusNotModified {
+		t.Fatalf("GET with the current ETag = %d, want 304", w.Code)
+	}
+
+	steps := []struct {
+		name  string
+		apply func() error
+	}{
+		{"react", func() error { _, err := services.AddReaction(ctx, actor, post.ID, "👍"); return err }},
+		{"bookmark", func() error { return services.BookmarkPost(ctx, actor, post.ID) }},
+		{"remove the reaction", func() error { _, err := services.RemoveReaction(ctx, actor, post.ID, "👍"); return err }},
+		{"remove the bookmark", func() error { return services.UnbookmarkPost(ctx, actor, post.ID) }},
+	}
+	for _, step := range steps {
+		if err := step.apply(); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		w := get(etag)
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET after %s with the previous ETag = %d, want 200", step.name, w.Code)
+		}
+		etag = w.Header().Get("ETag")
+	}
+}
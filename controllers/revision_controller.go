@@ -0,0 +1,192 @@
+package controllers
+
+import (
+	"errors"
+	"go-gin-auth-api-starter-kit/models"
+	"go-gin-auth-api-starter-kit/services"
+	"go-gin-auth-api-starter-kit/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// RevisionSummaryResponse describes a revision in the history list
+type RevisionSummaryResponse struct {
+	Number int    `json:"number"`
+	Title  string `json:"title"`
+	// Editor is null for system changes and deleted users
+	Editor       *PostAuthor `json:"editor"`
+	RestoredFrom *int        `json:"restored_from"`
+	CreatedAt    string      `json:"created_at"`
+}
+
+// RevisionResponse is a full revision, including its content
+type RevisionResponse struct {
+	Number       int         `json:"number"`
+	Title        string      `json:"title"`
+	Content      string      `json:"content"`
+	Editor       *PostAuthor `json:"editor"`
+	RestoredFrom *int        `json:"restored_from"`
+	CreatedAt    string      `json:"created_at"`
+}
+
+// RevisionDiffResponse is the line-level difference between two revisions
+type RevisionDiffResponse struct {
+	From    int              `json:"from"`
+	To      int              `json:"to"`
+	Title   []utils.DiffLine `json:"title"`
+	Content []utils.DiffLine `json:"content"`
+	Added   int              `json:"added"`
+	Removed int              `json:"removed"`
+}
+
+// revisionEditor formats the user who made a revision
+func revisionEditor(revision models.PostRevision) *PostAuthor {
+	if revision.Editor == nil {
+		return nil
+	}
+	editor := postAuthor(*revision.Editor)
+	return &editor
+}
+
+// revisionResponse formats a full revision
+func revisionResponse(revision models.PostRevision) RevisionResponse {
+	return RevisionResponse{
+		Number:       revision.Number,
+		Title:        revision.Title,
+		Content:      revision.Content,
+		Editor:       revisionEditor(revision),
+		RestoredFrom: revision.RestoredFrom,
+		CreatedAt:    revision.CreatedAt.Format("2006-01-02 15:04:05"),
+	}
+}
+
+// respondRevisionError maps service errors for revision endpoints to HTTP responses
+func respondRevisionError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post or revision not found"})
+	case errors.Is(err, services.ErrNotPostAuthor):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrPostVersionMismatch):
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrPreconditionRequired):
+		c.JSON(http.StatusPreconditionRequired, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
+
+// revisionQuery reads a required revision number from the query string
+func revisionQuery(c *gin.Context, name string) (int, bool) {
+	number, err := strconv.Atoi(c.Query(name))
+	if err != nil || number < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errInvalidQuery(name).Error()})
+		return 0, false
+	}
+	return number, true
+}
+
+// ListPostRevisions returns a page of a post's revisions, newest first
+func ListPostRevisions(c *gin.Context) {
+	postID, ok := idParam(c, "id", "Invalid post ID")
+	if !ok {
+		return
+	}
+
+	page, perPage := pagination(c)
+	revisions, total, err := services.ListPostRevisions(c.Request.Context(), currentActor(c), postID, page, perPage)
+	if err != nil {
+		respondRevisionError(c, err, "Failed to list revisions")
+		return
+	}
+
+	response := make([]RevisionSummaryResponse, 0, len(revisions))
+	for _, revision := range revisions {
+		response = append(response, RevisionSummaryResponse{
+			Number:       revision.Number,
+			Title:        revision.Title,
+			Editor:       revisionEditor(revision),
+			RestoredFrom: revision.RestoredFrom,
+			CreatedAt:    revision.CreatedAt.Format("2006-01-02 15:04:05"),
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"revisions":  response,
+		"pagination": paginationMeta(page, perPage, total),
+	})
+}
+
+// GetPostRevision returns a single revision with its content
+func GetPostRevision(c *gin.Context) {
+	postID, ok := idParam(c, "id", "Invalid post ID")
+	if !ok {
+		return
+	}
+	number, ok := idParam(c, "revision", "Invalid revision number")
+	if !ok {
+		return
+	}
+
+	revision, err := services.GetPostRevision(c.Request.Context(), currentActor(c), postID, int(number))
+	if err != nil {
+		respondRevisionError(c, err, "Failed to fetch revision")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"revision": revisionResponse(revision)})
+}
+
+// DiffPostRevisions compares two revisions given as ?from= and ?to=
+func DiffPostRevisions(c *gin.Context) {
+	postID, ok := idParam(c, "id", "Invalid post ID")
+	if !ok {
+		return
+	}
+	from, ok := revisionQuery(c, "from")
+	if !ok {
+		return
+	}
+	to, ok := revisionQuery(c, "to")
+	if !ok {
+		return
+	}
+
+	diff, err := services.DiffPostRevisions(c.Request.Context(), currentActor(c), postID, from, to)
+	if err != nil {
+		respondRevisionError(c, err, "Failed to diff revisions")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"diff": RevisionDiffResponse{
+		From:    diff.From.Number,
+		To:      diff.To.Number,
+		Title:   diff.Title,
+		Content: diff.Content,
+		Added:   diff.Added,
+		Removed: diff.Removed,
+	}})
+}
+
+// RestorePostRevision copies an old revision back onto the post as a new revision
+func RestorePostRevision(c *gin.Context) {
+	postID, ok := idParam(c, "id", "Invalid post ID")
+	if !ok {
+		return
+	}
+	number, ok := idParam(c, "revision", "Invalid revision number")
+	if !ok {
+		return
+	}
+
+	post, err := services.RestorePostRevision(c.Request.Context(), currentActor(c), postID, int(number), ifMatch(c, postID))
+	if err != nil {
+		respondRevisionError(c, err, "Failed to restore revision")
+		return
+	}
+
+	respondPost(c, http.StatusOK, post)
+}
@@ -0,0 +1,168 @@
+package controllers
+
+import (
+	"errors"
+	"go-gin-auth-api-starter-kit/models"
+	"go-gin-auth-api-starter-kit/services"
+	"go-gin-auth-api-starter-kit/utils"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ShareRequest is the body accepted when sharing a post with a user
+type ShareRequest struct {
+	UserID uint `json:"user_id" binding:"required"`
+}
+
+// ShareResponse is a user a post is shared with
+type ShareResponse struct {
+	User     PostAuthor `json:"user"`
+	SharedAt string     `json:"shared_at"`
+}
+
+// ShareLinkRequest is the body accepted when creating a share link
+type ShareLinkRequest struct {
+	// ExpiresIn is the lifetime of the link in seconds; SHARE_LINK_TTL when omitted
+	ExpiresIn int `json:"expires_in" binding:"omitempty,min=1"`
+}
+
+// ShareLinkResponse is a newly created share link
+type ShareLinkResponse struct {
+	Token string `json:"token"`
+	// URL is the path that serves the post to anyone holding the link
+	URL       string `json:"url"`
+	ExpiresAt string `json:"expires_at"`
+}
+
+// respondShareError maps share service errors to HTTP responses
+func respondShareError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrShareUserNotFound),
+		errors.Is(err, services.ErrInvalidShareLinkTTL):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		respondPostError(c, err, fallback)
+	}
+}
+
+// ListPostShares returns the users a post is shared with (author or admin only)
+func ListPostShares(c *gin.Context) {
+	id, ok := idParam(c, "id", "Invalid post ID")
+	if !ok {
+		return
+	}
+
+	shares, err := services.ListPostShares(c.Request.Context(), currentActor(c), id)
+	if err != nil {
+		respondShareError(c, err, "Failed to list shares")
+		return
+	}
+
+	response := make([]ShareResponse, 0, len(shares))
+	for _, share := range shares {
+		response = append(response, shareResponse(share))
+	}
+	c.JSON(http.StatusOK, gin.H{"shares": response})
+}
+
+// shareResponse formats a post share for the API
+func shareResponse(share models.PostShare) ShareResponse {
+	return ShareResponse{
+		User:     postAuthor(share.User),
+		SharedAt: share.CreatedAt.Format("2006-01-02 15:04:05"),
+	}
+}
+
+// SharePost lets a user read a private post
+func SharePost(c *gin.Context) {
+	id, ok := idParam(c, "id", "Invalid post ID")
+	if !ok {
+		return
+	}
+
+	var request ShareRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := services.SharePost(c.Request.Context(), currentActor(c), id, request.UserID); err != nil {
+		respondShareError(c, err, "Failed to share post")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Post shared successfully"})
+}
+
+// UnsharePost takes a user's access to a private post away
+func UnsharePost(c *gin.Context) {
+	id, ok := idParam(c, "id", "Invalid post ID")
+	if !ok {
+		return
+	}
+	userID, ok := idParam(c, "user_id", "Invalid user ID")
+	if !ok {
+		return
+	}
+
+	if err := services.UnsharePost(c.Request.Context(), currentActor(c), id, userID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Share not found"})
+			return
+		}
+		respondShareError(c, err, "Failed to unshare post")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Post unshared successfully"})
+}
+
+// CreateShareLink signs a link that grants read access to a post without an account
+func CreateShareLink(c *gin.Context) {
+	id, ok := idParam(c, "id", "Invalid post ID")
+	if !ok {
+		return
+	}
+
+	var request ShareLinkRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	ttl := time.Duration(request.ExpiresIn) * time.Second
+	token, expires, err := services.CreateShareLink(c.Request.Context(), currentActor(c), id, ttl)
+	if err != nil {
+		respondShareError(c, err, "Failed to create share link")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"share_link": ShareLinkResponse{
+		Token:     token,
+		URL:       "/api/v1/shared/posts/" + token,
+		ExpiresAt: expires.Format("2006-01-02 15:04:05"),
+	}})
+}
+
+// GetSharedPost returns the post a share link grants access to. It needs no authentication.
+func GetSharedPost(c *gin.Context) {
+	post, err := services.GetSharedPost(c.Request.Context(), c.Param("token"))
+	if err != nil {
+		switch {
+		case errors.Is(err, utils.ErrInvalidSignedToken):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Share link not found"})
+		case errors.Is(err, utils.ErrExpiredSignedToken):
+			c.JSON(http.StatusGone, gin.H{"error": "Share link has expired"})
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch post"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"post": renderedPostResponses(c, []models.Post{post})[0]})
+}
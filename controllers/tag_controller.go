@@ -0,0 +1,182 @@
+package controllers
+
+import (
+	"errors"
+	"go-gin-auth-api-starter-kit/models"
+	"go-gin-auth-api-starter-kit/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TagResponse is how a tag is returned to clients
+type TagResponse struct {
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+}
+
+// TagCountResponse is a tag together with the number of posts using it
+type TagCountResponse struct {
+	Name      string `json:"name"`
+	Slug      string `json:"slug"`
+	PostCount int64  `json:"post_count"`
+}
+
+// CategoryResponse is how a category is returned on a post
+type CategoryResponse struct {
+	ID       uint   `json:"id"`
+	Name     string `json:"name"`
+	Slug     string `json:"slug"`
+	ParentID *uint  `json:"parent_id"`
+}
+
+// CategoryTreeResponse is a category with its subcategories
+type CategoryTreeResponse struct {
+	ID       uint                   `json:"id"`
+	Name     string                 `json:"name"`
+	Slug     string                 `json:"slug"`
+	Children []CategoryTreeResponse `json:"children"`
+}
+
+// CategoryRequest is the body accepted when creating a category
+type CategoryRequest struct {
+	Name string `json:"name" binding:"required,max=128"`
+	// Slug defaults to the slugified name
+	Slug     string `json:"slug" binding:"max=128"`
+	ParentID *uint  `json:"parent_id"`
+}
+
+// RetagRequest is the body accepted by the bulk retagging endpoint
+type RetagRequest struct {
+	PostIDs []uint   `json:"post_ids" binding:"required,min=1,max=500"`
+	Add     []string `json:"add"`
+	Remove  []string `json:"remove"`
+}
+
+// tagResponses formats the tags of a post
+func tagResponses(tags []models.Tag) []TagResponse {
+	response := make([]TagResponse, 0, len(tags))
+	for _, tag := range tags {
+		response = append(response, TagResponse{Name: tag.Name, Slug: tag.Slug})
+	}
+	return response
+}
+
+// categoryResponse formats the category of a post, which may be unset
+func categoryResponse(category *models.Category) *CategoryResponse {
+	if category == nil {
+		return nil
+	}
+	return &CategoryResponse{
+		ID:       category.ID,
+		Name:     category.Name,
+		Slug:     category.Slug,
+		ParentID: category.ParentID,
+	}
+}
+
+// categoryTree nests a flat list of categories below their parents
+func categoryTree(categories []models.Category) []CategoryTreeResponse {
+	children := map[uint][]models.Category{}
+	var roots []models.Category
+	for _, category := range categories {
+		if category.ParentID == nil {
+			roots = append(roots, category)
+			continue
+		}
+		children[*category.ParentID] = append(children[*category.ParentID], category)
+	}
+
+	var build func([]models.Category) []CategoryTreeResponse
+	build = func(level []models.Category) []CategoryTreeResponse {
+		response := make([]CategoryTreeResponse, 0, len(level))
+		for _, category := range level {
+			response = append(response, CategoryTreeResponse{
+				ID:       category.ID,
+				Name:     category.Name,
+				Slug:     category.Slug,
+				Children: build(children[category.ID]),
+			})
+		}
+		return response
+	}
+	return build(roots)
+}
+
+// ListTags returns every tag with the number of posts the caller can list
+// using it, most used first
+func ListTags(c *gin.Context) {
+	tags, err := services.ListTags(c.Request.Context(), currentActor(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list tags"})
+		return
+	}
+
+	response := make([]TagCountResponse, 0, len(tags))
+	for _, tag := range tags {
+		response = append(response, TagCountResponse{Name: tag.Name, Slug: tag.Slug, PostCount: tag.PostCount})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tags": response})
+}
+
+// ListCategories returns the category tree
+func ListCategories(c *gin.Context) {
+	categories, err := services.ListCategories(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list categories"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"categories": categoryTree(categories)})
+}
+
+// CreateCategory adds a category to the tree (admin only)
+func CreateCategory(c *gin.Context) {
+	var request CategoryRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	category, err := services.CreateCategory(c.Request.Context(), request.Name, request.Slug, request.ParentID)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidCategory),
+			errors.Is(err, services.ErrCategoryNotFound):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrCategoryExists):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create category"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"category": categoryResponse(&category)})
+}
+
+// RetagPosts adds and removes tags on many posts at once (admin only)
+func RetagPosts(c *gin.Context) {
+	var request RetagRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(request.Add) == 0 && len(request.Remove) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Nothing to add or remove"})
+		return
+	}
+
+	updated, err := services.RetagPosts(c.Request.Context(), request.PostIDs, request.Add, request.Remove)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidTag) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retag posts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"updated_post_ids": updated})
+}
@@ -0,0 +1,166 @@
+package controllers
+
+import (
+	"errors"
+	"go-gin-auth-api-starter-kit/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// TrashedPostResponse is a deleted post in the trash listing
+type TrashedPostResponse struct {
+	Post      PostResponse `json:"post"`
+	DeletedAt string       `json:"deleted_at"`
+}
+
+// TrashedUserResponse is a deleted user in the trash listing
+type TrashedUserResponse struct {
+	User      UserResponse `json:"user"`
+	DeletedAt string       `json:"deleted_at"`
+}
+
+// ListPostTrash returns a page of the caller's deleted posts (all deleted posts for admins)
+func ListPostTrash(c *gin.Context) {
+	page, perPage := pagination(c)
+	posts, total, err := services.ListPostTrash(c.Request.Context(), currentActor(c), page, perPage)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list deleted posts"})
+		return
+	}
+
+	loadEngagement(c, posts)
+	response := make([]TrashedPostResponse, 0, len(posts))
+	for _, post := range posts {
+		response = append(response, TrashedPostResponse{
+			Post:      postResponse(post),
+			DeletedAt: post.DeletedAt.Time.Format("2006-01-02 15:04:05"),
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"posts":      response,
+		"pagination": paginationMeta(page, perPage, total),
+	})
+}
+
+// RestorePost takes a post out of the trash
+func RestorePost(c *gin.Context) {
+	id, ok := idParam(c, "id", "Invalid post ID")
+	if !ok {
+		return
+	}
+
+	post, err := services.RestorePost(c.Request.Context(), currentActor(c), id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Deleted post not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore post"})
+		return
+	}
+
+	respondPost(c, http.StatusOK, post)
+}
+
+// PurgePost permanently deletes a post (admin only)
+func PurgePost(c *gin.Context) {
+	id, ok := idParam(c, "id", "Invalid post ID")
+	if !ok {
+		return
+	}
+
+	if err := services.PurgePost(c.Request.Context(), id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to purge post"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Post permanently deleted"})
+}
+
+// respondUserTrashError maps service errors for user trash endpoints to HTTP responses
+func respondUserTrashError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	case errors.Is(err, services.ErrCannotDeleteSelf):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
+
+// DeleteUser moves a user to the trash (admin only)
+func DeleteUser(c *gin.Context) {
+	id, ok := idParam(c, "id", "Invalid user ID")
+	if !ok {
+		return
+	}
+
+	if err := services.DeleteUser(c.Request.Context(), currentActor(c), id); err != nil {
+		respondUserTrashError(c, err, "Failed to delete user")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User deleted successfully"})
+}
+
+// ListUserTrash returns a page of deleted users (admin only)
+func ListUserTrash(c *gin.Context) {
+	page, perPage := pagination(c)
+	users, total, err := services.ListUserTrash(c.Request.Context(), page, perPage)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list deleted users"})
+		return
+	}
+
+	response := make([]TrashedUserResponse, 0, len(users))
+	for _, user := range users {
+		response = append(response, TrashedUserResponse{
+			User:      userResponse(user),
+			DeletedAt: user.DeletedAt.Time.Format("2006-01-02 15:04:05"),
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"users":      response,
+		"pagination": paginationMeta(page, perPage, total),
+	})
+}
+
+// RestoreUser takes a user out of the trash (admin only)
+func RestoreUser(c *gin.Context) {
+	id, ok := idParam(c, "id", "Invalid user ID")
+	if !ok {
+		return
+	}
+
+	user, err := services.RestoreUser(c.Request.Context(), id)
+	if err != nil {
+		respondUserTrashError(c, err, "Failed to restore user")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"user": userResponse(user)})
+}
+
+// PurgeUser permanently deletes a user (admin only)
+func PurgeUser(c *gin.Context) {
+	id, ok := idParam(c, "id", "Invalid user ID")
+	if !ok {
+		return
+	}
+
+	if err := services.PurgeUser(c.Request.Context(), currentActor(c), id); err != nil {
+		respondUserTrashError(c, err, "Failed to purge user")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User permanently deleted"})
+}
@@ -1,39 +1,158 @@
 package controllers
 
 import (
-	"go-gin-auth-api-starter-kit/config"
+	"errors"
 	"go-gin-auth-api-starter-kit/models"
+	"go-gin-auth-api-starter-kit/services"
+	"go-gin-auth-api-starter-kit/utils"
+	"io"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
+// UserResponse is how a user is returned to clients
+// Password hashes are never included
+type UserResponse struct {
+	ID        uint   `json:"id"`
+	Username  string `json:"username"`
+	Email     string `json:"email"`
+	CreatedAt string `json:"created_at"`
+	// AvatarURLs maps each avatar size in pixels to the URL of the image
+	AvatarURLs     map[string]string `json:"avatar_urls"`
+	FollowersCount int               `json:"followers_count"`
+	FollowingCount int               `json:"following_count"`
+}
+
+// userResponse formats a user for the API
+func userResponse(user models.User) UserResponse {
+	return UserResponse{
+		ID:             user.ID,
+		Username:       user.Username,
+		Email:          user.Email,
+		CreatedAt:      user.CreatedAt.Format("2006-01-02 15:04:05"),
+		AvatarURLs:     services.AvatarURLs(user),
+		FollowersCount: user.FollowersCount,
+		FollowingCount: user.FollowingCount,
+	}
+}
+
 // ListUsers returns a list of all users
 // This is a protected route that requires authentication
 func ListUsers(c *gin.Context) {
-	var users []models.User
-	if err := config.DB.Find(&users).Error; err != nil {
+	users, err := services.ListUsers(c.Request.Context())
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch users"})
 		return
 	}
 
-	// Don't return password hashes in the response
-	type UserResponse struct {
-		ID        uint   `json:"id"`
-		Username  string `json:"username"`
-		Email     string `json:"email"`
-		CreatedAt string `json:"created_at"`
-	}
-
-	var response []UserResponse
+	response := make([]UserResponse, 0, len(users))
 	for _, user := range users {
-		response = append(response, UserResponse{
-			ID:        user.ID,
-			Username:  user.Username,
-			Email:     user.Email,
-			CreatedAt: user.CreatedAt.Format("2006-01-02 15:04:05"),
-		})
+		response = append(response, userResponse(user))
 	}
 
 	c.JSON(http.StatusOK, gin.H{"users": response})
 }
+
+// respondAvatarError maps avatar service errors to HTTP responses
+func respondAvatarError(c *gin.Context, err error, fallback string) {
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	case errors.Is(err, services.ErrAvatarTooLarge), errors.As(err, &tooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": services.ErrAvatarTooLarge.Error()})
+	case errors.Is(err, utils.ErrUnsupportedImage):
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+	case errors.Is(err, utils.ErrImageTooLarge):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidAvatarSize):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
+
+// UploadAvatar replaces the current user's avatar. The image is sent either
+// as the multipart field "file" or as the raw request body. It is resized in
+// the background, so the response still shows the current avatar.
+func UploadAvatar(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, services.AvatarMaxSize()+multipartOverhead)
+
+	var content io.Reader = c.Request.Body
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		header, err := c.FormFile("file")
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				respondAvatarError(c, err, "")
+				return
+			}
+			c.JSON(http.StatusBadRequest, gin.H{"error": "A multipart file field named \"file\" is required"})
+			return
+		}
+		file, err := header.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read uploaded file"})
+			return
+		}
+		defer file.Close()
+		content = file
+	}
+
+	user, err := services.SetAvatar(c.Request.Context(), currentActor(c), content)
+	if err != nil {
+		respondAvatarError(c, err, "Failed to update avatar")
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"user": userResponse(user)})
+}
+
+// DeleteAvatar removes the current user's avatar, bringing back their identicon
+func DeleteAvatar(c *gin.Context) {
+	user, err := services.RemoveAvatar(c.Request.Context(), currentActor(c))
+	if err != nil {
+		respondAvatarError(c, err, "Failed to remove avatar")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"user": userResponse(user)})
+}
+
+// GetAvatar serves a user's avatar, or their identicon when they have none.
+// It needs no authentication.
+func GetAvatar(c *gin.Context) {
+	id, ok := idParam(c, "id", "Invalid user ID")
+	if !ok {
+		return
+	}
+
+	sizes := services.AvatarSizes()
+	size := sizes[len(sizes)/2]
+	if raw := c.Query("size"); raw != "" {
+		var err error
+		if size, err = strconv.Atoi(raw); err != nil {
+			respondAvatarError(c, services.ErrInvalidAvatarSize, "")
+			return
+		}
+	}
+
+	avatar, err := services.GetAvatar(c.Request.Context(), id, size)
+	if err != nil {
+		respondAvatarError(c, err, "Failed to fetch avatar")
+		return
+	}
+	defer avatar.Body.Close()
+
+	// A versioned URL always shows the same picture; anything else may change with the next upload
+	cacheControl := "public, max-age=300"
+	if avatar.Version != "" && c.Query("v") == avatar.Version {
+		cacheControl = "public, max-age=31536000, immutable"
+	}
+	c.DataFromReader(http.StatusOK, -1, avatar.ContentType, avatar.Body, map[string]string{
+		"Cache-Control":          cacheControl,
+		"X-Content-Type-Options": "nosniff",
+	})
+}
@@ -0,0 +1,261 @@
+package controllers
+
+import (
+	"errors"
+	"go-gin-auth-api-starter-kit/models"
+	"go-gin-auth-api-starter-kit/services"
+	"net/http"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// WebhookRequest is the body accepted when creating or replacing a webhook
+type WebhookRequest struct {
+	URL         string `json:"url" binding:"required,max=2048"`
+	Description string `json:"description" binding:"max=255"`
+	// Secret signs deliveries. It is generated when a webhook is created
+	// without one, and kept when a webhook is replaced without one.
+	Secret string   `json:"secret" binding:"omitempty,min=16,max=128"`
+	Events []string `json:"events" binding:"required,min=1"`
+	// Active defaults to true for new webhooks and is kept when omitted on
+	// replace. Turning a webhook back on resets its failure count.
+	Active *bool `json:"active"`
+}
+
+// input converts the request for the service layer
+func (r WebhookRequest) input() services.WebhookInput {
+	return services.WebhookInput{URL: r.URL, Description: r.Description, Secret: r.Secret, Events: r.Events, Active: r.Active}
+}
+
+// WebhookResponse is how a webhook is returned to admins
+type WebhookResponse struct {
+	ID          uint     `json:"id"`
+	URL         string   `json:"url"`
+	Description string   `json:"description"`
+	Events      []string `json:"events"`
+	Active      bool     `json:"active"`
+	// ConsecutiveFailures counts the deliveries that failed for good in a row
+	ConsecutiveFailures int     `json:"consecutive_failures"`
+	DisabledAt          *string `json:"disabled_at"`
+	CreatedAt           string  `json:"created_at"`
+	UpdatedAt           string  `json:"updated_at"`
+	// Secret is only returned when the webhook is created
+	Secret string `json:"secret,omitempty"`
+}
+
+// webhookResponse formats a webhook for the API
+func webhookResponse(webhook models.Webhook) WebhookResponse {
+	return WebhookResponse{
+		ID:                  webhook.ID,
+		URL:                 webhook.URL,
+		Description:         webhook.Description,
+		Events:              slices.Concat([]string{}, webhook.Events),
+		Active:              webhook.Active,
+		ConsecutiveFailures: webhook.ConsecutiveFailures,
+		DisabledAt:          formatOptionalTime(webhook.DisabledAt),
+		CreatedAt:           webhook.CreatedAt.Format("2006-01-02 15:04:05"),
+		UpdatedAt:           webhook.UpdatedAt.Format("2006-01-02 15:04:05"),
+	}
+}
+
+// WebhookDeliveryResponse is an entry of a webhook's delivery log
+type WebhookDeliveryResponse struct {
+	ID      uint           `json:"id"`
+	EventID string         `json:"event_id"`
+	Event   string         `json:"event"`
+	Payload models.JSONMap `json:"payload"`
+	// Status is pending, succeeded or failed
+	Status        string  `json:"status"`
+	Attempts      int     `json:"attempts"`
+	NextAttemptAt *string `json:"next_attempt_at"`
+	LastAttemptAt *string `json:"last_attempt_at"`
+	// ResponseStatus is the HTTP status of the last attempt; null when no response arrived
+	ResponseStatus *int `json:"response_status"`
+	// ResponseBody is the start of the last response body
+	ResponseBody string `json:"response_body"`
+	Error        string `json:"error"`
+	DurationMs   int64  `json:"duration_ms"`
+	// RedeliveryOf is the delivery this one repeats
+	RedeliveryOf *uint  `json:"redelivery_of"`
+	CreatedAt    string `json:"created_at"`
+}
+
+// webhookDeliveryResponse formats a delivery for the API
+func webhookDeliveryResponse(delivery models.WebhookDelivery) WebhookDeliveryResponse {
+	return WebhookDeliveryResponse{
+		ID:             delivery.ID,
+		EventID:        delivery.EventID,
+		Event:          delivery.Event,
+		Payload:        delivery.Payload,
+		Status:         delivery.Status,
+		Attempts:       delivery.Attempts,
+		NextAttemptAt:  formatOptionalTime(delivery.NextAttemptAt),
+		LastAttemptAt:  formatOptionalTime(delivery.LastAttemptAt),
+		ResponseStatus: delivery.ResponseStatus,
+		ResponseBody:   delivery.ResponseBody,
+		Error:          delivery.Error,
+		DurationMs:     delivery.DurationMs,
+		RedeliveryOf:   delivery.RedeliveryOf,
+		CreatedAt:      delivery.CreatedAt.Format("2006-01-02 15:04:05"),
+	}
+}
+
+// respondWebhookError maps service errors for webhook endpoints to HTTP responses
+func respondWebhookError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+	case errors.Is(err, services.ErrInvalidWebhookURL),
+		errors.Is(err, services.ErrWebhookTargetNotAllowed),
+		errors.Is(err, services.ErrInvalidWebhookEvents):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrWebhookDisabled):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
+
+// CreateWebhook adds a webhook (admin only). The response is the only one
+// that includes the secret.
+func CreateWebhook(c *gin.Context) {
+	var request WebhookRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	webhook, err := services.CreateWebhook(c.Request.Context(), currentActor(c), request.input())
+	if err != nil {
+		respondWebhookError(c, err, "Failed to create webhook")
+		return
+	}
+
+	response := webhookResponse(webhook)
+	response.Secret = webhook.Secret
+	c.JSON(http.StatusCreated, gin.H{"webhook": response})
+}
+
+// ListWebhooks returns every webhook (admin only)
+func ListWebhooks(c *gin.Context) {
+	webhooks, err := services.ListWebhooks(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list webhooks"})
+		return
+	}
+
+	response := make([]WebhookResponse, 0, len(webhooks))
+	for _, webhook := range webhooks {
+		response = append(response, webhookResponse(webhook))
+	}
+	c.JSON(http.StatusOK, gin.H{"webhooks": response, "events": models.WebhookEvents})
+}
+
+// GetWebhook returns a webhook (admin only)
+func GetWebhook(c *gin.Context) {
+	id, ok := idParam(c, "id", "Invalid webhook ID")
+	if !ok {
+		return
+	}
+
+	webhook, err := services.GetWebhook(c.Request.Context(), id)
+	if err != nil {
+		respondWebhookError(c, err, "Failed to get webhook")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"webhook": webhookResponse(webhook)})
+}
+
+// UpdateWebhook replaces a webhook (admin only)
+func UpdateWebhook(c *gin.Context) {
+	id, ok := idParam(c, "id", "Invalid webhook ID")
+	if !ok {
+		return
+	}
+	var request WebhookRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	webhook, err := services.UpdateWebhook(c.Request.Context(), id, request.input())
+	if err != nil {
+		respondWebhookError(c, err, "Failed to update webhook")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"webhook": webhookResponse(webhook)})
+}
+
+// DeleteWebhook removes a webhook and its delivery log (admin only)
+func DeleteWebhook(c *gin.Context) {
+	id, ok := idParam(c, "id", "Invalid webhook ID")
+	if !ok {
+		return
+	}
+
+	if err := services.DeleteWebhook(c.Request.Context(), id); err != nil {
+		respondWebhookError(c, err, "Failed to delete webhook")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook deleted successfully"})
+}
+
+// ListWebhookDeliveries returns a page of a webhook's delivery log, newest
+// first, optionally filtered by ?status= (admin only)
+func ListWebhookDeliveries(c *gin.Context) {
+	id, ok := idParam(c, "id", "Invalid webhook ID")
+	if !ok {
+		return
+	}
+	status := c.Query("status")
+	if status != "" && !slices.Contains([]string{models.WebhookDeliveryPending, models.WebhookDeliverySucceeded, models.WebhookDeliveryFailed}, status) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errInvalidQuery("status").Error()})
+		return
+	}
+
+	page, perPage := pagination(c)
+	deliveries, total, err := services.ListWebhookDeliveries(c.Request.Context(), id, status, page, perPage)
+	if err != nil {
+		respondWebhookError(c, err, "Failed to list webhook deliveries")
+		return
+	}
+
+	response := make([]WebhookDeliveryResponse, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		response = append(response, webhookDeliveryResponse(delivery))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"deliveries": response,
+		"pagination": paginationMeta(page, perPage, total),
+	})
+}
+
+// RedeliverWebhook queues a delivery again with the same event ID and
+// payload (admin only)
+func RedeliverWebhook(c *gin.Context) {
+	id, ok := idParam(c, "id", "Invalid webhook ID")
+	if !ok {
+		return
+	}
+	deliveryID, ok := idParam(c, "delivery_id", "Invalid delivery ID")
+	if !ok {
+		return
+	}
+
+	delivery, err := services.RedeliverWebhook(c.Request.Context(), id, deliveryID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Webhook delivery not found"})
+			return
+		}
+		respondWebhookError(c, err, "Failed to redeliver webhook")
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"delivery": webhookDeliveryResponse(delivery)})
+}
@@ -0,0 +1,53 @@
+package docs
+
+import (
+	"embed"
+	"encoding/json"
+	"io/fs"
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+//go:embed swagger.html
+var swaggerHTML []byte
+
+// swaggerAssets holds the Swagger UI stylesheet and script, so /docs doesn't depend on a CDN
+//
+//go:embed swagger-ui/*.css swagger-ui/*.js
+var swaggerAssets embed.FS
+
+var (
+	specOnce sync.Once
+	specJSON []byte
+	specErr  error
+)
+
+// ServeSpec returns the OpenAPI document as JSON. It is built once and cached.
+func ServeSpec(c *gin.Context) {
+	specOnce.Do(func() {
+		specJSON, specErr = json.Marshal(Spec())
+	})
+	if specErr != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build API specification"})
+		return
+	}
+	c.Data(http.StatusOK, "application/json", specJSON)
+}
+
+// ServeUI returns the Swagger UI page, which loads /openapi.json
+func ServeUI(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", swaggerHTML)
+}
+
+// ServeAsset returns a Swagger UI stylesheet or script embedded in the binary
+func ServeAsset(c *gin.Context) {
+	name := c.Param("file")
+	if _, err := fs.Stat(swaggerAssets, "swagger-ui/"+name); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Asset not found"})
+		return
+	}
+	c.Header("Cache-Control", "public, max-age=86400")
+	c.FileFromFS("swagger-ui/"+name, http.FS(swaggerAssets))
+}
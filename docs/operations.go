@@ -0,0 +1,987 @@
+package docs
+
+import (
+	"go-gin-auth-api-starter-kit/controllers"
+	"go-gin-auth-api-starter-kit/models"
+	"net/http"
+)
+
+// paginationParams are accepted by every paginated list endpoint
+var paginationParams = []Param{
+	{Name: "page", In: "query", Type: "integer", Description: "Page number, starting at 1"},
+	{Name: "per_page", In: "query", Type: "integer", Description: "Items per page (max 100)"},
+}
+
+// PaginationResponse describes the "pagination" member of list responses
+type PaginationResponse struct {
+	Page    int   `json:"page"`
+	PerPage int   `json:"per_page"`
+	Total   int64 `json:"total"`
+}
+
+// auditFilterParams are the filters shared by the audit log list and export endpoints
+var auditFilterParams = []Param{
+	{Name: "event", In: "query", Description: "Comma separated event names, e.g. auth.login.failed"},
+	{Name: "actor_id", In: "query", Type: "integer"},
+	{Name: "target_type", In: "query"},
+	{Name: "target_id", In: "query"},
+	{Name: "ip", In: "query"},
+	{Name: "request_id", In: "query"},
+	{Name: "from", In: "query", Description: "Inclusive lower bound (RFC 3339)"},
+	{Name: "to", In: "query", Description: "Exclusive upper bound (RFC 3339)"},
+}
+
+// ifMatchParam is accepted by post updates, deletes and revision restores
+var ifMatchParam = Param{
+	Name: "If-Match", In: "header",
+	Description: "ETag from a previous read. Required when POST_REQUIRE_IF_MATCH is enabled.",
+}
+
+// renderParam asks for the rendered HTML of posts
+var renderParam = Param{
+	Name: "render", In: "query",
+	Description: "html adds content_html, the sanitized HTML rendering of the content",
+}
+
+// postTransitionResponses are returned by the post workflow endpoints
+var postTransitionResponses = append([]Response{
+	{Status: http.StatusOK, Body: map[string]any{"post": controllers.PostResponse{}}},
+}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusConflict, http.StatusInternalServerError)...)
+
+// ReactionsResponse is returned by the reaction endpoints
+type ReactionsResponse struct {
+	Reactions []controllers.ReactionResponse `json:"reactions"`
+	// Available are the emoji posts can be reacted with
+	Available []string `json:"available"`
+}
+
+// BookmarkResponse is returned by the bookmark endpoints
+type BookmarkResponse struct {
+	Bookmarked bool `json:"bookmarked"`
+}
+
+// FollowResponse is returned when following or unfollowing a user
+type FollowResponse struct {
+	User      controllers.UserResponse `json:"user"`
+	Following bool                     `json:"following"`
+}
+
+// HomeFeedResponse is a page of the home feed
+type HomeFeedResponse struct {
+	Posts []controllers.PostResponse `json:"posts"`
+	// NextCursor fetches the next page; null on the last page
+	NextCursor *string `json:"next_cursor"`
+}
+
+// NotificationPreferencesResponse maps every notification type to whether it is on
+type NotificationPreferencesResponse struct {
+	Preferences map[string]bool `json:"preferences"`
+}
+
+// feedDescription explains what the post feeds contain
+const feedDescription = "The latest FEED_SIZE published public posts, newest first. Supports conditional GET with If-Modified-Since."
+
+// feedParams are the filters accepted by the post feeds
+var feedParams = []Param{
+	{Name: "tag", In: "query", Description: "Tag slug or name; repeat or comma separate to match any of several tags"},
+	{Name: "author", In: "query", Description: "Username of the author"},
+	{Name: "If-Modified-Since", In: "header", Description: "Answer 304 if no post changed since this time"},
+}
+
+// feedResponses documents a feed served as contentType
+func feedResponses(contentType string, body any) []Response {
+	return append([]Response{
+		{Status: http.StatusOK, Body: body, ContentType: contentType},
+		{Status: http.StatusNotModified, Description: "No post changed since If-Modified-Since"},
+	}, errorResponses(http.StatusInternalServerError)...)
+}
+
+// operations documents every route registered in routes.SetupRoutes.
+// Keep it in sync: the server refuses to start in debug mode when a route is missing.
+var operations = []Operation{
+	// Service
+	{
+		Method: http.MethodGet, Path: "/", Tag: "service",
+		Summary:   "Health check",
+		Responses: []Response{{Status: http.StatusOK, Body: MessageResponse{}}},
+	},
+	{
+		Method: http.MethodGet, Path: "/metrics", Tag: "service",
+		Summary:     "Prometheus metrics",
+		Description: "Only reachable from METRICS_ALLOWED_CIDRS or with METRICS_TOKEN.",
+		Security:    []string{MetricsToken},
+		Responses: append([]Response{
+			{Status: http.StatusOK, Body: "", ContentType: "text/plain; version=0.0.4"},
+		}, errorResponses(http.StatusForbidden)...),
+	},
+	{
+		Method: http.MethodGet, Path: "/openapi.json", Tag: "service",
+		Summary:   "This OpenAPI document",
+		Responses: []Response{{Status: http.StatusOK, Body: map[string]any{}}},
+	},
+	{
+		Method: http.MethodGet, Path: "/docs", Tag: "service",
+		Summary:   "Swagger UI",
+		Responses: []Response{{Status: http.StatusOK, Body: "", ContentType: "text/html"}},
+	},
+	{
+		Method: http.MethodGet, Path: "/docs/assets/:file", Tag: "service",
+		Summary: "Swagger UI stylesheet or script",
+		Params:  []Param{{Name: "file", In: "path", Description: "swagger-ui.css or swagger-ui-bundle.js"}},
+		Responses: append([]Response{
+			{Status: http.StatusOK, Body: "", ContentType: "text/javascript"},
+		}, errorResponses(http.StatusNotFound)...),
+	},
+
+	// Feeds
+	{
+		Method: http.MethodGet, Path: "/feeds/posts.rss", Tag: "feeds",
+		Summary:     "RSS 2.0 feed of the latest public posts",
+		Description: feedDescription,
+		Params:      feedParams,
+		Responses:   feedResponses("application/rss+xml", ""),
+	},
+	{
+		Method: http.MethodGet, Path: "/feeds/posts.atom", Tag: "feeds",
+		Summary:     "Atom feed of the latest public posts",
+		Description: feedDescription,
+		Params:      feedParams,
+		Responses:   feedResponses("application/atom+xml", ""),
+	},
+	{
+		Method: http.MethodGet, Path: "/feeds/posts.json", Tag: "feeds",
+		Summary:     "JSON Feed 1.1 of the latest public posts",
+		Description: feedDescription,
+		Params:      feedParams,
+		Responses:   feedResponses("application/feed+json", controllers.JSONFeed{}),
+	},
+
+	// Authentication
+	{
+		Method: http.MethodPost, Path: "/api/v1/register", Tag: "auth",
+		Summary: "Create an account",
+		Request: controllers.RegisterRequest{},
+		Responses: append([]Response{
+			{Status: http.StatusCreated, Body: map[string]any{"user": controllers.UserResponse{}}},
+		}, errorResponses(http.StatusBadRequest, http.StatusInternalServerError)...),
+	},
+	{
+		Method: http.MethodPost, Path: "/api/v1/login", Tag: "auth",
+		Summary: "Exchange credentials for a JWT",
+		Request: controllers.LoginRequest{},
+		Responses: append([]Response{
+			{Status: http.StatusOK, Body: controllers.LoginResponse{}},
+		}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusInternalServerError)...),
+	},
+	{
+		Method: http.MethodGet, Path: "/api/v1/dashboard", Tag: "auth",
+		Summary:  "Greet the authenticated user",
+		Security: []string{BearerAuth},
+		Responses: append([]Response{
+			{Status: http.StatusOK, Body: controllers.DashboardResponse{}},
+		}, errorResponses(http.StatusUnauthorized)...),
+	},
+
+	// Users
+	{
+		Method: http.MethodGet, Path: "/api/v1/users", Tag: "users",
+		Summary:  "List users",
+		Security: []string{BearerAuth},
+		Responses: append([]Response{
+			{Status: http.StatusOK, Body: map[string]any{"users": []controllers.UserResponse{}}},
+		}, errorResponses(http.StatusUnauthorized, http.StatusInternalServerError)...),
+	},
+	{
+		Method: http.MethodGet, Path: "/api/v1/users/:id/avatar", Tag: "users",
+		Summary: "Get a user's avatar",
+		Description: "Needs no authentication. Serves the uploaded avatar as a JPEG, or a generated identicon PNG when there is none. " +
+			"URLs with the current version in v may be cached forever.",
+		Params: []Param{
+			{Name: "size", In: "query", Type: "integer", Description: "Edge length in pixels, one of AVATAR_SIZES (the middle size by default)"},
+			{Name: "v", In: "query", Description: "Version of the uploaded avatar, as included in avatar_urls"},
+		},
+		Responses: append([]Response{
+			{Status: http.StatusOK, Body: Binary{}, ContentType: "image/jpeg", Description: "The avatar (image/png for identicons)"},
+		}, errorResponses(http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError)...),
+	},
+	{
+		Method: http.MethodPut, Path: "/api/v1/me/avatar", Tag: "users",
+		Summary: "Upload your avatar",
+		Description: "Accepts a JPEG, PNG or WebP image of at most AVATAR_MAX_SIZE bytes, as the multipart field \"file\" or as the raw body. " +
+			"It is cropped to a square around its center, scaled to every size in AVATAR_SIZES and stored without metadata by a background job; " +
+			"the response shows the current avatar, which is replaced once the job has run.",
+		Security: []string{BearerAuth},
+		RequestTypes: map[string]any{
+			"multipart/form-data":      FileUpload{},
+			"application/octet-stream": Binary{},
+		},
+		Responses: append([]Response{
+			{Status: http.StatusAccepted, Body: map[string]any{"user": controllers.UserResponse{}}},
+		}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusRequestEntityTooLarge,
+			http.StatusUnsupportedMediaType, http.StatusUnprocessableEntity, http.StatusInternalServerError)...),
+	},
+	{
+		Method: http.MethodDelete, Path: "/api/v1/me/avatar", Tag: "users",
+		Summary:  "Remove your avatar",
+		Security: []string{BearerAuth},
+		Responses: append([]Response{
+			{Status: http.StatusOK, Body: map[string]any{"user": controllers.UserResponse{}}},
+		}, errorResponses(http.StatusUnauthorized, http.StatusInternalServerError)...),
+	},
+
+	// Follows and the home feed
+	{
+		Method: http.MethodPut, Path: "/api/v1/users/:id/follow", Tag: "follows",
+		Summary:     "Follow a user",
+		Description: "Their latest HOME_FEED_BACKFILL_POSTS posts join the caller's home feed. Following someone twice changes nothing.",
+		Security:    []string{BearerAuth},
+		Responses: append([]Response{
+			{Status: http.StatusOK, Body: FollowResponse{}},
+		}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusInternalServerError)...),
+	},
+	{
+		Method: http.MethodDelete, Path: "/api/v1/users/:id/follow", Tag: "follows",
+		Summary:     "Unfollow a user",
+		Description: "Their posts leave the caller's home feed.",
+		Security:    []string{BearerAuth},
+		Responses: append([]Response{
+			{Status: http.StatusOK, Body: FollowResponse{}},
+		}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusInternalServerError)...),
+	},
+	{
+		Method: http.MethodGet, Path: "/api/v1/users/:id/followers", Tag: "follows",
+		Summary:  "List the followers of a user",
+		Security: []string{BearerAuth},
+		Params:   paginationParams,
+		Responses: append([]Response{
+			{Status: http.StatusOK, Body: map[string]any{"users": []controllers.UserResponse{}, "pagination": PaginationResponse{}}},
+		}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusInternalServerError)...),
+	},
+	{
+		Method: http.MethodGet, Path: "/api/v1/users/:id/following", Tag: "follows",
+		Summary:  "List the users a user follows",
+		Security: []string{BearerAuth},
+		Params:   paginationParams,
+		Responses: append([]Response{
+			{Status: http.StatusOK, Body: map[string]any{"users": []controllers.UserResponse{}, "pagination": PaginationResponse{}}},
+		}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusInternalServerError)...),
+	},
+	{
+		Method: http.MethodGet, Path: "/api/v1/users/:id/mentions", Tag: "users",
+		Summary:      "List the posts that mention a user",
+		Description:  "Posts whose content mentions the user as @username, newest first, as far as the caller may read them.",
+		Security:     []string{BearerAuth},
+		OptionalAuth: true,
+		Params: append([]Param{
+			{Name: "id", In: "path", Description: "Username of the user (not their ID)"},
+			renderParam,
+		}, paginationParams...),
+		Responses: append([]Response{
+			{Status: http.StatusOK, Body: map[string]any{"posts": []controllers.PostResponse{}, "pagination": PaginationResponse{}}},
+		}, errorResponses(http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError)...),
+	},
+	{
+		Method: http.MethodGet, Path: "/api/v1/feed", Tag: "follows",
+		Summary: "Your home feed",
+		Description: "Published posts by the users the caller follows, newest first. Pass next_cursor back as cursor for the next page; " +
+			"it is null on the last page. Posts the caller can no longer read are left out, so a page may have fewer than limit posts.",
+		Security: []string{BearerAuth},
+		Params: []Param{
+			{Name: "cursor", In: "query", Description: "next_cursor of the previous page"},
+			{Name: "limit", In: "query", Type: "integer", Description: "Posts per page, 1 to 100 (20 by default)"},
+			renderParam,
+		},
+		Responses: append([]Response{
+			{Status: http.StatusOK, Body: HomeFeedResponse{}},
+		}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusInternalServerError)...),
+	},
+
+	// Notifications
+	{
+		Method: http.MethodGet, Path: "/api/v1/notifications", Tag: "notifications",
+		Summary:     "List your notifications",
+		Description: "Newest first. Notification types are comment, reply, reaction and mention.",
+		Security:    []string{BearerAuth},
+		Params: append([]Param{
+			{Name: "unread", In: "query", Type: "boolean", Description: "true leaves out notifications already read"},
+		}, paginationParams...),
+		Responses: append([]Response{
+			{Status: http.StatusOK, Body: map[string]any{"notifications": []controllers.NotificationResponse{}, "pagination": PaginationResponse{}}},
+		}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusInternalServerError)...),
+	},
+	{
+		Method: http.MethodGet, Path: "/api/v1/notifications/unread-count", Tag: "notifications",
+		Summary:  "Count your unread notifications",
+		Security: []string{BearerAuth},
+		Responses: append([]Response{
+			{Status: http.StatusOK, Body: map[string]any{"unread": int64(0)}},
+		}, errorResponses(http.StatusUnauthorized, http.StatusInternalServerError)...),
+	},
+	{
+		Method: http.MethodGet, Path: "/api/v1/notifications/stream", Tag: "notifications",
+		Summary: "Stream your new notifications",
+		Description: "Server-sent events. Every new notification is sent as a \"notification\" event with the notification as JSON data " +
+			"and its ID as the event ID. Idle streams get a comment every 25 seconds. A reconnecting client's Last-Event-ID " +
+			"header first brings back up to 100 notifications it missed.",
+		Security: []string{BearerAuth},
+		Params: []Param{
+			{Name: "Last-Event-ID", In: "header", Type: "integer", Description: "ID of the last notification received"},
+		},
+		Responses: append([]Response{
+			{Status: http.StatusOK, Body: controllers.NotificationResponse{}, ContentType: "text/event-stream", Description: "An endless stream of notification events"},
+		}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusInternalServerError)...),
+	},
+	{
+		Method: http.MethodPost, Path: "/api/v1/notifications/:id/read", Tag: "notifications",
+		Summary:  "Mark a notification as read",
+		Security: []string{BearerAuth},
+		Responses: append([]Response{
+			{Status: http.StatusOK, Body: map[string]any{"notification": controllers.NotificationResponse{}}},
+		}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusInternalServerError)...),
+	},
+	{
+		Method: http.MethodPost, Path: "/api/v1/notifications/read-all", Tag: "notifications",
+		Summary:     "Mark all your notifications as read",
+		Description: "marked is how many notifications were unread.",
+		Security:    []string{BearerAuth},
+		Responses: append([]Response{
+			{Status: http.StatusOK, Body: map[string]any{"marked": int64(0)}},
+		}, errorResponses(http.StatusUnauthorized, http.StatusInternalServerError)...),
+	},
+	{
+		Method: http.MethodGet, Path: "/api/v1/me/notification-preferences", Tag: "notifications",
+		Summary:     "Your notification preferences",
+		Description: "Maps every notification type to whether it is on. Types are on until turned off.",
+		Security:    []string{BearerAuth},
+		Responses: append([]Response{
+			{Status: http.StatusOK, Body: NotificationPreferencesResponse{}},
+		}, errorResponses(http.StatusUnauthorized, http.StatusInternalServerError)...),
+	},
+	{
+		Method: http.MethodPut, Path: "/api/v1/me/notification-preferences", Tag: "notifications",
+		Summary:     "Change your notification preferences",
+		Description: "Turns the given notification types on or off. Types left out keep their setting.",
+		Security:    []string{BearerAuth},
+		Request:     NotificationPreferencesResponse{},
+		Responses: append([]Response{
+			{Status: http.StatusOK, Body: NotificationPreferencesResponse{}},
+		}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusInternalServerError)...),
+	},
+
+	// Posts
+	{
+		Method: http.MethodGet, Path: "/api/v1/posts", Tag: "posts",
+		Summary:      "List posts",
+		Description:  "Returns published public and internal posts, published private posts shared with the caller, and the caller's own posts in any status. Unlisted posts are left out. Admins see every post. Without a login only published public posts are returned.",
+		Security:     []string{BearerAuth},
+		OptionalAuth: true,
+		Params: []Param{
+			{Name: "tag", In: "query", Description: "Tag slug or name; repeat or comma separate to match any of several tags"},
+			{Name: "category", In: "query", Description: "Category slug; posts in its subcategories are included"},
+			{Name: "status", In: "query", Description: "draft, scheduled, published or archived"},
+			{Name: "author", In: "query", Description: "Username of the author"},
+			renderParam,
+		},
+		Responses: append([]Response{
+			{Status: http.StatusOK, Body: map[string]any{"posts": []controllers.PostResponse{}}},
+		}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusInternalServerError)...),
+	},
+	{
+		Method: http.MethodPost, Path: "/api/v1/posts", Tag: "posts",
+		Summary:     "Create a post",
+		Description: "Posts start as drafts unless status is \"published\", are internal unless visibility says otherwise, and are plain text unless content_format is \"markdown\". The content is rendered to sanitized HTML when it is saved; @mentions of existing users and #hashtags are linked, and hashtags are added to the tags. Tags from hashtags follow the content: editing a hashtag out removes its tag unless it was also sent in tags.",
+		Security:    []string{BearerAuth},
+		Request:     controllers.PostRequest{},
+		Responses: append([]Response{
+			{Status: http.StatusCreated, Body: map[string]any{"post": controllers.PostResponse{}}},
+		}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusInternalServerError)...),
+	},
+	{
+		Method: http.MethodGet, Path: "/api/v1/posts/:id", Tag: "posts",
+		Summary: "Get a post",
+		Description: "The response carries the post's ETag, to be sent back in If-Match when changing it. " +
+			"The ETag also changes with the caller's reactions and bookmark, so responses are private to the caller.",
+		Security:     []string{BearerAuth},
+		OptionalAuth: true,
+		Params:       []Param{{Name: "If-None-Match", In: "header", Description: "Answer 304 if the post still has this ETag"}, renderParam},
+		Responses: append([]Response{
+			{Status: http.StatusOK, Body: map[string]any{"post": controllers.PostResponse{}}},
+			{Status: http.StatusNotModified, Description: "The post still has the given ETag"},
+		}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusInternalServerError)...),
+	},
+	{
+		Method: http.MethodGet, Path: "/api/v1/posts/by-slug/:slug", Tag: "posts",
+		Summary:      "Get a post by slug",
+		Description:  "A slug the post had before its title changed answers 301 with the current slug in Location.",
+		Security:     []string{BearerAuth},
+		OptionalAuth: true,
+		Params:       []Param{{Name: "If-None-Match", In: "header", Description: "Answer 304 if the post still has this ETag"}, renderParam},
+		Responses: append([]Response{
+			{Status: http.StatusOK, Body: map[string]any{"post": controllers.PostResponse{}}},
+			{Status: http.StatusMovedPermanently, Description: "The slug is a former slug of the post; Location has the current one"},
+			{Status: http.StatusNotModified, Description: "The post still has the given ETag"},
+		}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusInternalServerError)...),
+	},
+	{
+		Method: http.MethodPut, Path: "/api/v1/posts/:id", Tag: "posts",
+		Summary:     "Replace a post",
+		Description: "Full replacement: omitted fields are cleared and the tags are set to exactly the given list. Only the author or an admin can update a post. The status is not changed; an omitted visibility or content_format is reset to internal or plain. Use PATCH to keep the fields you leave out.",
+		Security:    []string{BearerAuth},
+		Params:      []Param{ifMatchParam},
+		Request:     controllers.PostRequest{},
+		Responses: append([]Response{
+			{Status: http.StatusOK, Body: map[string]any{"post": controllers.PostResponse{}}},
+		}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound,
+			http.StatusPreconditionFailed, http.StatusPreconditionRequired, http.StatusInternalServerError)...),
+	},
+	{
+		Method: http.MethodPatch, Path: "/api/v1/posts/:id", Tag: "posts",
+		Summary: "Partially update a post",
+		Description: "Accepts a JSON Merge Patch (RFC 7396) or a JSON Patch (RFC 6902) against the document " +
+			"{title, content, tags, category_id}, where tags leaves out the ones from hashtags in the content. " +
+			"In a merge patch absent members are unchanged and null clears a member. " +
+			"The patched document is validated before it is saved.",
+		Security: []string{BearerAuth},
+		Params:   []Param{ifMatchParam},
+		RequestTypes: map[string]any{
+			"application/merge-patch+json": controllers.PostMergePatch{},
+			"application/json-patch+json":  []controllers.JSONPatchOperation{},
+		},
+		Responses: append([]Response{
+			{Status: http.StatusOK, Body: map[string]any{"post": controllers.PostResponse{}}},
+		}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusConflict,
+			http.StatusPreconditionFailed, http.StatusUnsupportedMediaType, http.StatusUnprocessableEntity,
+			http.StatusPreconditionRequired, http.StatusInternalServerError)...),
+	},
+	{
+		Method: http.MethodDelete, Path: "/api/v1/posts/:id", Tag: "posts",
+		Summary:     "Delete a post",
+		Description: "Only the author or an admin can delete a post.",
+		Security:    []string{BearerAuth},
+		Params:      []Param{ifMatchParam},
+		Responses: append([]Response{
+			{Status: http.StatusOK, Body: MessageResponse{}},
+		}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound,
+			http.StatusPreconditionFailed, http.StatusPreconditionRequired, http.StatusInternalServerError)...),
+	},
+	{
+		Method: http.MethodPost, Path: "/api/v1/posts/:id/publish", Tag: "posts",
+		Summary:     "Publish a post now",
+		Description: "Allowed from draft, scheduled and archived.",
+		Security:    []string{BearerAuth},
+		Responses:   postTransitionResponses,
+	},
+	{
+		Method: http.MethodPost, Path: "/api/v1/posts/:id/schedule", Tag: "posts",
+		Summary:     "Schedule a post for publishing",
+		Description: "Allowed from draft and scheduled. The scheduler publishes the post once publish_at has passed.",
+		Security:    []string{BearerAuth},
+		Request:     controllers.ScheduleRequest{},
+		Responses:   postTransitionResponses,
+	},
+	{
+		Method: http.MethodPost, Path: "/api/v1/posts/:id/unpublish", Tag: "posts",
+		Summary:     "Turn a post back into a draft",
+		Description: "Allowed from published and scheduled.",
+		Security:    []string{BearerAuth},
+		Responses:   postTransitionResponses,
+	},
+	{
+		Method: http.MethodPost, Path: "/api/v1/posts/:id/archive", Tag: "posts",
+		Summary:     "Archive a post",
+		Description: "Archived posts are only visible to their author and admins.",
+		Security:    []string{BearerAuth},
+		Responses:   postTransitionResponses,
+	},
+
+	// Trash
+	{
+		Method: http.MethodGet, Path: "/api/v1/posts/trash", Tag: "trash",
+		Summary:     "List deleted posts",
+		Description: "Users see their own deleted posts; admins see all of them. Posts are purged TRASH_RETENTION_DAYS after deletion.",
+		Security:    []string{BearerAuth},
+		Params:      paginationParams,
+		Responses: append([]Response{
+			{Status: http.StatusOK, Body: map[string]any{"posts": []controllers.TrashedPostResponse{}, "pagination": PaginationResponse{}}},
+		}, errorResponses(http.StatusUnauthorized, http.StatusInternalServerError)...),
+	},
+	{
+		Method: http.MethodPost, Path: "/api/v1/posts/:id/restore", Tag: "trash",
+		Summary:     "Restore a deleted post",
+		Description: "Comments deleted together with the post are restored too.",
+		Security:    []string{BearerAuth},
+		Responses: append([]Response{
+			{Status: http.StatusOK, Body: map[string]any{"post": controllers.PostResponse{}}},
+		}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusInternalServerError)...),
+	},
+	{
+		Method: http.MethodDelete, Path: "/api/v1/admin/posts/:id/purge", Tag: "trash",
+		Summary:     "Permanently delete a post",
+		Description: "Removes the post with its comments, revisions, shares, slug aliases, attachments, reactions, bookmarks and tag links, whether or not it is in the trash.",
+		Security:    []string{BearerAuth},
+		Responses: append([]Response{
+			{Status: http.StatusOK, Body: MessageResponse{}},
+		}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusInternalServerError)...),
+	},
+	{
+		Method: http.MethodDelete, Path: "/api/v1/admin/users/:id", Tag: "trash",
+		Summary:     "Move a user to the trash",
+		Description: "Deleted users can no longer log in. Admins cannot delete themselves.",
+		Security:    []string{BearerAuth},
+		Responses: append([]Response{
+			{Status: http.StatusOK, Body: MessageResponse{}},
+		}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusInternalServerError)...),
+	},
+	{
+		Method: http.MethodGet, Path: "/api/v1/admin/users/trash", Tag: "trash",
+		Summary:  "List deleted users",
+		Security: []string{BearerAuth},
+		Params:   paginationParams,
+		Responses: append([]Response{
+			{Status: http.StatusOK, Body: map[string]any{"users": []controllers.TrashedUserResponse{}, "pagination": PaginationResponse{}}},
+		}, errorResponses(http.StatusUnauthorized, http.StatusForbidden, http.StatusInternalServerError)...),
+	},
+	{
+		Method: http.MethodPost, Path: "/api/v1/admin/users/:id/restore", Tag: "trash",
+		Summary:  "Restore a deleted user",
+		Security: []string{BearerAuth},
+		Responses: append([]Response{
+			{Status: http.StatusOK, Body: map[string]any{"user": controllers.UserResponse{}}},
+		}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusInternalServerError)...),
+	},
+	{
+		Method: http.MethodDelete, Path: "/api/v1/admin/users/:id/purge", Tag: "trash",
+		Summary:     "Permanently delete a user",
+		Description: "Their posts are kept without an author and their comments become \"[deleted]\" placeholders. Their follows, reactions, bookmarks and mentions are removed.",
+		Security:    []string{BearerAuth},
+		Responses: append([]Response{
+			{Status: http.StatusOK, Body: MessageResponse{}},
+		}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusInternalServerError)...),
+	},
+
+	// Sharing
+	{
+		Method: http.MethodGet, Path: "/api/v1/posts/:id/shares", Tag: "sharing",
+		Summary:     "List the users a post is shared with",
+		Description: "Only the author or an admin can see who a post is shared with.",
+		Security:    []string{BearerAuth},
+		Responses: append([]Response{
+			{Status: http.StatusOK, Body: map[string]any{"shares": []controllers.ShareResponse{}}},
+		}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusInternalServerError)...),
+	},
+	{
+		Method: http.MethodPost, Path: "/api/v1/posts/:id/shares", Tag: "sharing",
+		Summary:     "Share a post with a user",
+		Description: "The user can read the post once it is published, even while it is private. Sharing twice has no effect.",
+		Security:    []string{BearerAuth},
+		Request:     controllers.ShareRequest{},
+		Responses: append([]Response{
+			{Status: http.StatusOK, Body: MessageResponse{}},
+		}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusInternalServerError)...),
+	},
+	{
+		Method: http.MethodDelete, Path: "/api/v1/posts/:id/shares/:user_id", Tag: "sharing",
+		Summary:  "Stop sharing a post with a user",
+		Security: []string{BearerAuth},
+		Responses: append([]Response{
+			{Status: http.StatusOK, Body: MessageResponse{}},
+		}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusInternalServerError)...),
+	},
+	{
+		Method: http.MethodPost, Path: "/api/v1/posts/:id/share-links", Tag: "sharing",
+		Summary:     "Create a share link",
+		Description: "Signs a link that lets anyone read the post, whatever its status and visibility, until it expires. Links cannot be revoked one by one; rotating SIGNING_SECRET invalidates all of them.",
+		Security:    []string{BearerAuth},
+		Request:     controllers.ShareLinkRequest{},
+		Responses: append([]Response{
+			{Status: http.StatusCreated, Body: map[string]any{"share_link": controllers.ShareLinkResponse{}}},
+		}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusInternalServerError)...),
+	},
+	{
+		Method: http.MethodGet, Path: "/api/v1/shared/posts/:token", Tag: "sharing",
+		Summary:     "Read a post through a share link",
+		Description: "Needs no authentication: the signed token grants access.",
+		Params:      []Param{renderParam},
+		Responses: append([]Response{
+			{Status: http.StatusOK, Body: map[string]any{"post": controllers.PostResponse{}}},
+			{Status: http.StatusGone, Body: ErrorResponse{}, Description: "The share link has expired"},
+		}, errorResponses(http.StatusNotFound, http.StatusInternalServerError)...),
+	},
+
+	// Attachments
+	{
+		Method: http.MethodPost, Path: "/api/v1/posts/:id/attachments", Tag: "attachments",
+		Summary: "Upload a file to a post",
+		Description: "Author or admin only. The file is sent as the multipart field \"file\" and may be at most ATTACHMENT_MAX_SIZE bytes. " +
+			"Its type is sniffed from the content and must be in ATTACHMENT_ALLOWED_TYPES. " +
+			"Uploading content the post already has returns the existing attachment with 200.",
+		Security:     []string{BearerAuth},
+		RequestTypes: map[string]any{"multipart/form-data": FileUpload{}},
+		Responses: append([]Response{
+			{Status: http.StatusCreated, Body: map[string]any{"attachment": controllers.AttachmentResponse{}}},
+			{Status: http.StatusOK, Body: map[string]any{"attachment": controllers.AttachmentResponse{}}, Description: "The post already has this file"},
+		}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound,
+			http.StatusRequestEntityTooLarge, http.StatusUnsupportedMediaType, http.StatusInternalServerError)...),
+	},
+	{
+		Method: http.MethodGet, Path: "/api/v1/posts/:id/attachments", Tag: "attachments",
+		Summary:      "List the attachments of a post",
+		Description:  "Each attachment carries a signed download URL valid for ATTACHMENT_URL_TTL.",
+		Security:     []string{BearerAuth},
+		OptionalAuth: true,
+		Responses: append([]Response{
+			{Status: http.StatusOK, Body: map[string]any{"attachments": []controllers.AttachmentResponse{}}},
+		}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusInternalServerError)...),
+	},
+	{
+		Method: http.MethodGet, Path: "/api/v1/posts/:id/attachments/:attachment_id", Tag: "attachments",
+		Summary:      "Get an attachment with a fresh download URL",
+		Security:     []string{BearerAuth},
+		OptionalAuth: true,
+		Responses: append([]Response{
+			{Status: http.StatusOK, Body: map[string]any{"attachment": controllers.AttachmentResponse{}}},
+		}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusInternalServerError)...),
+	},
+	{
+		Method: http.MethodDelete, Path: "/api/v1/posts/:id/attachments/:attachment_id", Tag: "attachments",
+		Summary:     "Remove an attachment from a post",
+		Description: "Author or admin only. The stored file is deleted once no other attachment has the same content.",
+		Security:    []string{BearerAuth},
+		Responses: append([]Response{
+			{Status: http.StatusOK, Body: MessageResponse{}},
+		}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusInternalServerError)...),
+	},
+	{
+		Method: http.MethodGet, Path: "/api/v1/files/:token", Tag: "attachments",
+		Summary: "Download an attachment through a signed URL",
+		Description: "Needs no authentication: the signed token grants access until it expires. " +
+			"With STORAGE_REDIRECT_DOWNLOADS the response redirects to a presigned URL of the S3 bucket instead.",
+		Responses: append([]Response{
+			{Status: http.StatusOK, Body: Binary{}, ContentType: "application/octet-stream", Description: "The file, served with its sniffed content type"},
+			{Status: http.StatusFound, Description: "Redirect to a presigned storage URL"},
+			{Status: http.StatusNotModified, Description: "The client already has the file"},
+			{Status: http.StatusGone, Body: ErrorResponse{}, Description: "The download URL has expired"},
+		}, errorResponses(http.StatusNotFound, http.StatusInternalServerError)...),
+	},
+
+	// Reactions and bookmarks
+	{
+		Method: http.MethodGet, Path: "/api/v1/posts/:id/reactions", Tag: "reactions",
+		Summary:      "List the reactions to a post",
+		Description:  "Counts per emoji, with reacted set for the caller's own reactions. available lists the emoji in REACTION_EMOJIS.",
+		Security:     []string{BearerAuth},
+		OptionalAuth: true,
+		Responses: append([]Response{
+			{Status: http.StatusOK, Body: ReactionsResponse{}},
+		}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusInternalServerError)...),
+	},
+	{
+		Method: http.MethodPut, Path: "/api/v1/posts/:id/reactions/:emoji", Tag: "reactions",
+		Summary:     "React to a post",
+		Description: "The emoji must be one of REACTION_EMOJIS (URL-encoded). Reacting twice with the same emoji changes nothing.",
+		Security:    []string{BearerAuth},
+		Responses: append([]Response{
+			{Status: http.StatusOK, Body: ReactionsResponse{}},
+		}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusInternalServerError)...),
+	},
+	{
+		Method: http.MethodDelete, Path: "/api/v1/posts/:id/reactions/:emoji", Tag: "reactions",
+		Summary:     "Take back a reaction",
+		Description: "Removing a reaction the caller hasn't made changes nothing.",
+		Security:    []string{BearerAuth},
+		Responses: append([]Response{
+			{Status: http.StatusOK, Body: ReactionsResponse{}},
+		}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusInternalServerError)...),
+	},
+	{
+		Method: http.MethodGet, Path: "/api/v1/posts/top-reacted", Tag: "reactions",
+		Summary:      "List the most reacted posts",
+		Description:  "Published posts the caller may list, most reactions first. With days only reactions from that many days back count.",
+		Security:     []string{BearerAuth},
+		OptionalAuth: true,
+		Params: []Param{
+			{Name: "days", In: "query", Type: "integer", Description: "Only count reactions from the last days (all time by default)"},
+			{Name: "limit", In: "query", Type: "integer", Description: "Number of posts, 1 to 100 (10 by default)"},
+			{Name: "tag", In: "query", Description: "Tag slug or name; repeat or comma separate to match any of several tags"},
+			{Name: "category", In: "query", Description: "Category slug; posts in its subcategories are included"},
+			{Name: "author", In: "query", Description: "Username of the author"},
+			renderParam,
+		},
+		Responses: append([]Response{
+			{Status: http.StatusOK, Body: map[string]any{"posts": []controllers.PostResponse{}}},
+		}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusInternalServerError)...),
+	},
+	{
+		Method: http.MethodPut, Path: "/api/v1/posts/:id/bookmark", Tag: "reactions",
+		Summary:     "Bookmark a post",
+		Description: "Bookmarking a post twice changes nothing.",
+		Security:    []string{BearerAuth},
+		Responses: append([]Response{
+			{Status: http.StatusOK, Body: BookmarkResponse{}},
+		}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusInternalServerError)...),
+	},
+	{
+		Method: http.MethodDelete, Path: "/api/v1/posts/:id/bookmark", Tag: "reactions",
+		Summary:     "Remove a bookmark",
+		Description: "Also works for posts the caller can no longer read.",
+		Security:    []string{BearerAuth},
+		Responses: append([]Response{
+			{Status: http.StatusOK, Body: BookmarkResponse{}},
+		}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusInternalServerError)...),
+	},
+	{
+		Method: http.MethodGet, Path: "/api/v1/me/bookmarks", Tag: "reactions",
+		Summary:     "List your bookmarks",
+		Description: "Most recently bookmarked first. Bookmarked posts the caller can no longer read are left out.",
+		Security:    []string{BearerAuth},
+		Params:      append([]Param{renderParam}, paginationParams...),
+		Responses: append([]Response{
+			{Status: http.StatusOK, Body: map[string]any{"posts": []controllers.PostResponse{}, "pagination": PaginationResponse{}}},
+		}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusInternalServerError)...),
+	},
+
+	// Revisions
+	{
+		Method: http.MethodGet, Path: "/api/v1/posts/:id/revisions", Tag: "revisions",
+		Summary:     "List the revisions of a post",
+		Description: "Newest first. Only the author and admins can see the history.",
+		Security:    []string{BearerAuth},
+		Params:      paginationParams,
+		Responses: append([]Response{
+			{Status: http.StatusOK, Body: map[string]any{"revisions": []controllers.RevisionSummaryResponse{}, "pagination": PaginationResponse{}}},
+		}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusInternalServerError)...),
+	},
+	{
+		Method: http.MethodGet, Path: "/api/v1/posts/:id/revisions/diff", Tag: "revisions",
+		Summary:  "Line-level diff between two revisions",
+		Security: []string{BearerAuth},
+		Params: []Param{
+			{Name: "from", In: "query", Type: "integer", Required: true, Description: "Revision number to compare from"},
+			{Name: "to", In: "query", Type: "integer", Required: true, Description: "Revision number to compare to"},
+		},
+		Responses: append([]Response{
+			{Status: http.StatusOK, Body: map[string]any{"diff": controllers.RevisionDiffResponse{}}},
+		}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusInternalServerError)...),
+	},
+	{
+		Method: http.MethodGet, Path: "/api/v1/posts/:id/revisions/:revision", Tag: "revisions",
+		Summary:  "Get a revision",
+		Security: []string{BearerAuth},
+		Responses: append([]Response{
+			{Status: http.StatusOK, Body: map[string]any{"revision": controllers.RevisionResponse{}}},
+		}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusInternalServerError)...),
+	},
+	{
+		Method: http.MethodPost, Path: "/api/v1/posts/:id/revisions/:revision/restore", Tag: "revisions",
+		Summary: "Restore an old revision",
+		Description: "Copies the revision's title and content onto the post and records that as a new revision. " +
+			"Like an update, it fails when the post has changed since the If-Match ETag was read.",
+		Security: []string{BearerAuth},
+		Params:   []Param{ifMatchParam},
+		Responses: append([]Response{
+			{Status: http.StatusOK, Body: map[string]any{"post": controllers.PostResponse{}}},
+		}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound,
+			http.StatusPreconditionFailed, http.StatusPreconditionRequired, http.StatusInternalServerError)...),
+	},
+
+	// Tags and categories
+	{
+		Method: http.MethodGet, Path: "/api/v1/tags", Tag: "tags",
+		Summary:     "List tags with their post counts",
+		Description: "Counts only the posts the caller can list, as GET /api/v1/posts?tag= would return them.",
+		Security:    []string{BearerAuth},
+		Responses: append([]Response{
+			{Status: http.StatusOK, Body: map[string]any{"tags": []controllers.TagCountResponse{}}},
+		}, errorResponses(http.StatusUnauthorized, http.StatusInternalServerError)...),
+	},
+	{
+		Method: http.MethodGet, Path: "/api/v1/categories", Tag: "tags",
+		Summary:  "Get the category tree",
+		Security: []string{BearerAuth},
+		Responses: append([]Response{
+			{Status: http.StatusOK, Body: map[string]any{"categories": []controllers.CategoryTreeResponse{}}},
+		}, errorResponses(http.StatusUnauthorized, http.StatusInternalServerError)...),
+	},
+
+	// Comments
+	{
+		Method: http.MethodGet, Path: "/api/v1/posts/:id/comments", Tag: "comments",
+		Summary:      "List comment threads on a post",
+		Description:  "Paginates top-level comments; each one includes all of its replies. Deleted comments are returned as \"[deleted]\" placeholders.",
+		Security:     []string{BearerAuth},
+		OptionalAuth: true,
+		Params:       paginationParams,
+		Responses: append([]Response{
+			{Status: http.StatusOK, Body: map[string]any{"comments": []controllers.CommentResponse{}, "pagination": PaginationResponse{}}},
+		}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusInternalServerError)...),
+	},
+	{
+		Method: http.MethodPost, Path: "/api/v1/posts/:id/comments", Tag: "comments",
+		Summary:  "Comment on a post or reply to a comment",
+		Security: []string{BearerAuth},
+		Request:  controllers.CommentRequest{},
+		Responses: append([]Response{
+			{Status: http.StatusCreated, Body: map[string]any{"comment": controllers.CommentResponse{}}},
+		}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusInternalServerError)...),
+	},
+	{
+		Method: http.MethodGet, Path: "/api/v1/posts/:id/comments/:comment_id", Tag: "comments",
+		Summary:      "Get a comment with its replies",
+		Security:     []string{BearerAuth},
+		OptionalAuth: true,
+		Responses: append([]Response{
+			{Status: http.StatusOK, Body: map[string]any{"comment": controllers.CommentResponse{}}},
+		}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusInternalServerError)...),
+	},
+	{
+		Method: http.MethodPut, Path: "/api/v1/posts/:id/comments/:comment_id", Tag: "comments",
+		Summary:     "Edit a comment",
+		Description: "Only the author can edit, and only within COMMENT_EDIT_WINDOW of posting.",
+		Security:    []string{BearerAuth},
+		Request:     controllers.CommentUpdateRequest{},
+		Responses: append([]Response{
+			{Status: http.StatusOK, Body: map[string]any{"comment": controllers.CommentResponse{}}},
+		}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusInternalServerError)...),
+	},
+	{
+		Method: http.MethodDelete, Path: "/api/v1/posts/:id/comments/:comment_id", Tag: "comments",
+		Summary:     "Delete a comment",
+		Description: "Authors can delete their own comments; admins can delete any comment.",
+		Security:    []string{BearerAuth},
+		Responses: append([]Response{
+			{Status: http.StatusOK, Body: MessageResponse{}},
+		}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusInternalServerError)...),
+	},
+
+	// Administration
+	{
+		Method: http.MethodGet, Path: "/api/v1/admin/audit-logs", Tag: "admin",
+		Summary:  "Query the audit log",
+		Security: []string{BearerAuth},
+		Params:   append(append([]Param{}, auditFilterParams...), paginationParams...),
+		Responses: append([]Response{
+			{Status: http.StatusOK, Body: map[string]any{"audit_logs": []models.AuditLog{}, "pagination": PaginationResponse{}}},
+		}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusInternalServerError)...),
+	},
+	{
+		Method: http.MethodGet, Path: "/api/v1/admin/audit-logs/export", Tag: "admin",
+		Summary:  "Export the audit log as JSON Lines or CSV",
+		Security: []string{BearerAuth},
+		Params:   append([]Param{{Name: "format", In: "query", Description: "jsonl (default) or csv"}}, auditFilterParams...),
+		Responses: append([]Response{
+			{Status: http.StatusOK, Description: "One JSON encoded AuditLog per line", Body: models.AuditLog{}, ContentType: "application/x-ndjson"},
+		}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden)...),
+	},
+	{
+		Method: http.MethodPost, Path: "/api/v1/admin/categories", Tag: "admin",
+		Summary:  "Create a category",
+		Security: []string{BearerAuth},
+		Request:  controllers.CategoryRequest{},
+		Responses: append([]Response{
+			{Status: http.StatusCreated, Body: map[string]any{"category": controllers.CategoryResponse{}}},
+		}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusConflict, http.StatusInternalServerError)...),
+	},
+	{
+		Method: http.MethodPost, Path: "/api/v1/admin/posts/retag", Tag: "admin",
+		Summary:     "Add and remove tags on many posts",
+		Description: "Unknown post IDs are skipped; the response lists the posts that were updated.",
+		Security:    []string{BearerAuth},
+		Request:     controllers.RetagRequest{},
+		Responses: append([]Response{
+			{Status: http.StatusOK, Body: map[string]any{"updated_post_ids": []uint{}}},
+		}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusInternalServerError)...),
+	},
+
+	// Webhooks
+	{
+		Method: http.MethodGet, Path: "/api/v1/admin/webhooks", Tag: "webhooks",
+		Summary:     "List webhooks",
+		Description: "Also lists every event a webhook can subscribe to.",
+		Security:    []string{BearerAuth},
+		Responses: append([]Response{
+			{Status: http.StatusOK, Body: map[string]any{"webhooks": []controllers.WebhookResponse{}, "events": []string{}}},
+		}, errorResponses(http.StatusUnauthorized, http.StatusForbidden, http.StatusInternalServerError)...),
+	},
+	{
+		Method: http.MethodPost, Path: "/api/v1/admin/webhooks", Tag: "webhooks",
+		Summary:     "Create a webhook",
+		Description: "Events are POSTed to the URL as JSON, signed with the secret in X-Webhook-Signature (see pkg/webhook). Subscribe to \"*\" for every event. The secret is generated when left out and only returned in this response.",
+		Security:    []string{BearerAuth},
+		Request:     controllers.WebhookRequest{},
+		Responses: append([]Response{
+			{Status: http.StatusCreated, Body: map[string]any{"webhook": controllers.WebhookResponse{}}},
+		}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusInternalServerError)...),
+	},
+	{
+		Method: http.MethodGet, Path: "/api/v1/admin/webhooks/:id", Tag: "webhooks",
+		Summary:  "Get a webhook",
+		Security: []string{BearerAuth},
+		Responses: append([]Response{
+			{Status: http.StatusOK, Body: map[string]any{"webhook": controllers.WebhookResponse{}}},
+		}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusInternalServerError)...),
+	},
+	{
+		Method: http.MethodPut, Path: "/api/v1/admin/webhooks/:id", Tag: "webhooks",
+		Summary:     "Replace a webhook",
+		Description: "The secret is kept when left out, and so is active. Setting active to true on a disabled webhook turns it back on and resets its failure count.",
+		Security:    []string{BearerAuth},
+		Request:     controllers.WebhookRequest{},
+		Responses: append([]Response{
+			{Status: http.StatusOK, Body: map[string]any{"webhook": controllers.WebhookResponse{}}},
+		}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusInternalServerError)...),
+	},
+	{
+		Method: http.MethodDelete, Path: "/api/v1/admin/webhooks/:id", Tag: "webhooks",
+		Summary:     "Delete a webhook",
+		Description: "Its delivery log is deleted with it.",
+		Security:    []string{BearerAuth},
+		Responses: append([]Response{
+			{Status: http.StatusOK, Body: MessageResponse{}},
+		}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusInternalServerError)...),
+	},
+	{
+		Method: http.MethodGet, Path: "/api/v1/admin/webhooks/:id/deliveries", Tag: "webhooks",
+		Summary:     "List a webhook's deliveries",
+		Description: "The delivery log, newest first: every event sent to the webhook with its attempts and the last response.",
+		Security:    []string{BearerAuth},
+		Params: append([]Param{
+			{Name: "status", In: "query", Description: "pending, succeeded or failed"},
+		}, paginationParams...),
+		Responses: append([]Response{
+			{Status: http.StatusOK, Body: map[string]any{"deliveries": []controllers.WebhookDeliveryResponse{}, "pagination": PaginationResponse{}}},
+		}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusInternalServerError)...),
+	},
+	{
+		Method: http.MethodPost, Path: "/api/v1/admin/webhooks/:id/deliveries/:delivery_id/redeliver", Tag: "webhooks",
+		Summary:     "Redeliver an event",
+		Description: "Queues the event again as a new delivery with the same event ID and payload, so receivers can tell it is a repeat. Disabled webhooks must be turned back on first.",
+		Security:    []string{BearerAuth},
+		Responses: append([]Response{
+			{Status: http.StatusAccepted, Body: map[string]any{"delivery": controllers.WebhookDeliveryResponse{}}},
+		}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusConflict, http.StatusInternalServerError)...),
+	},
+
+	// Background jobs
+	{
+		Method: http.MethodGet, Path: "/api/v1/admin/jobs", Tag: "jobs",
+		Summary:     "List background jobs",
+		Description: "Newest first. Use status=dead to find the jobs that failed every attempt.",
+		Security:    []string{BearerAuth},
+		Params: append([]Param{
+			{Name: "status", In: "query", Description: "pending, running, succeeded or dead"},
+			{Name: "type", In: "query", Description: "Job type, e.g. trash.purge"},
+		}, paginationParams...),
+		Responses: append([]Response{
+			{Status: http.StatusOK, Body: map[string]any{"jobs": []controllers.JobResponse{}, "pagination": PaginationResponse{}}},
+		}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusInternalServerError)...),
+	},
+	{
+		Method: http.MethodPost, Path: "/api/v1/admin/jobs/:id/retry", Tag: "jobs",
+		Summary:     "Retry a dead job",
+		Description: "Queues the job again right away with a fresh set of attempts. Only dead jobs can be retried.",
+		Security:    []string{BearerAuth},
+		Responses: append([]Response{
+			{Status: http.StatusOK, Body: map[string]any{"job": controllers.JobResponse{}}},
+		}, errorResponses(http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusConflict, http.StatusInternalServerError)...),
+	},
+}
@@ -0,0 +1,164 @@
+package docs
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// schemaRegistry turns Go values into JSON Schema and collects named struct
+// types under components/schemas so they can be referenced with $ref
+type schemaRegistry struct {
+	components map[string]any
+}
+
+var (
+	timeType   = reflect.TypeOf(time.Time{})
+	mapAnyType = reflect.TypeOf(map[string]any{})
+	binaryType = reflect.TypeOf(Binary(nil))
+)
+
+// schemaFor describes example, which is a zero value of a DTO type, a slice of one,
+// or a map[string]any whose entries describe the members of an inline object
+func (r *schemaRegistry) schemaFor(example any) map[string]any {
+	value := reflect.ValueOf(example)
+	if value.Type() == mapAnyType {
+		// Inline envelope such as {"post": PostResponse{}}
+		properties := map[string]any{}
+		var required []string
+		for _, key := range value.MapKeys() {
+			properties[key.String()] = r.schemaFor(value.MapIndex(key).Interface())
+			required = append(required, key.String())
+		}
+		sort.Strings(required)
+		return map[string]any{"type": "object", "properties": properties, "required": required}
+	}
+	return r.schemaForType(value.Type())
+}
+
+// schemaForType describes a Go type
+func (r *schemaRegistry) schemaForType(t reflect.Type) map[string]any {
+	switch {
+	case t == timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case t == binaryType:
+		return map[string]any{"type": "string", "format": "binary"}
+	case t.Kind() == reflect.Pointer:
+		return nullable(r.schemaForType(t.Elem()))
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]any{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": r.schemaForType(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": r.schemaForType(t.Elem())}
+	case reflect.Interface:
+		return map[string]any{}
+	case reflect.Struct:
+		return r.structRef(t)
+	default:
+		return map[string]any{}
+	}
+}
+
+// structRef registers a named struct as a component and returns a reference to it
+func (r *schemaRegistry) structRef(t reflect.Type) map[string]any {
+	name := t.Name()
+	if name == "" {
+		return r.structSchema(t)
+	}
+
+	if _, done := r.components[name]; !done {
+		// Reserve the name first so recursive types terminate
+		r.components[name] = map[string]any{}
+		r.components[name] = r.structSchema(t)
+	}
+	return map[string]any{"$ref": "#/components/schemas/" + name}
+}
+
+// structSchema lists the JSON members of a struct, following embedded structs
+func (r *schemaRegistry) structSchema(t reflect.Type) map[string]any {
+	properties := map[string]any{}
+	var required []string
+	r.collectFields(t, properties, &required, hasBindingTags(t))
+	sort.Strings(required)
+
+	schema := map[string]any{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// collectFields adds the exported, JSON visible fields of t to properties.
+// For request types (those with binding tags) a field is required when its binding says so;
+// for response types every field that is always serialized is required.
+func (r *schemaRegistry) collectFields(t reflect.Type, properties map[string]any, required *[]string, isRequest bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+
+		// Embedded structs without a JSON name are flattened, like encoding/json does
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			r.collectFields(field.Type, properties, required, isRequest)
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		properties[name] = r.schemaForType(field.Type)
+
+		var isRequired bool
+		if isRequest {
+			isRequired = strings.Contains(field.Tag.Get("binding"), "required")
+		} else {
+			isRequired = !strings.Contains(options, "omitempty")
+		}
+		if isRequired {
+			*required = append(*required, name)
+		}
+	}
+}
+
+// hasBindingTags reports whether t is a request type validated by Gin's binding
+func hasBindingTags(t reflect.Type) bool {
+	for i := 0; i < t.NumField(); i++ {
+		if _, ok := t.Field(i).Tag.Lookup("binding"); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// nullable allows null in addition to what schema accepts (OpenAPI 3.1 style)
+func nullable(schema map[string]any) map[string]any {
+	if typ, ok := schema["type"].(string); ok {
+		copied := map[string]any{}
+		for key, value := range schema {
+			copied[key] = value
+		}
+		copied["type"] = []string{typ, "null"}
+		return copied
+	}
+	return map[string]any{"oneOf": []any{schema, map[string]any{"type": "null"}}}
+}
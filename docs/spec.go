@@ -0,0 +1,271 @@
+// Package docs builds the OpenAPI 3.1 description of the API from the route
+// table in operations.go and the request/response types used by the controllers,
+// and serves it together with a Swagger UI.
+package docs
+
+import (
+	"cmp"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Operation documents one route
+type Operation struct {
+	Method      string
+	Path        string // Gin syntax, e.g. /api/v1/posts/:id
+	Summary     string
+	Description string
+	Tag         string
+	// Security lists the accepted security schemes; empty means the route is public
+	Security []string
+	// OptionalAuth marks routes that also accept anonymous requests
+	OptionalAuth bool
+	Params       []Param
+	// Request is a prototype of the JSON request body (nil when there is none)
+	Request any
+	// RequestTypes maps content types to request body prototypes, for
+	// endpoints that accept more than one format; it takes precedence over Request
+	RequestTypes map[string]any
+	Responses    []Response
+}
+
+// Param documents a query or header parameter. Path parameters are derived
+// from the path; a Param with In "path" only describes one whose name is misleading.
+type Param struct {
+	Name        string
+	In          string // "query", "header" or "path"
+	Type        string // JSON Schema type, "string" by default
+	Description string
+	Required    bool
+}
+
+// Response documents one possible response
+type Response struct {
+	Status      int
+	Description string
+	// Body is a prototype of the response body: a DTO value, a slice of one, or a
+	// map[string]any envelope such as {"post": PostResponse{}}. Nil means no body.
+	Body any
+	// ContentType defaults to application/json
+	ContentType string
+}
+
+// ErrorResponse is the body of every error response
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// MessageResponse is returned by endpoints that only confirm an action
+type MessageResponse struct {
+	Message string `json:"message"`
+}
+
+// Binary documents raw file content, such as an upload or a download
+type Binary []byte
+
+// FileUpload is the multipart form accepted by upload endpoints
+type FileUpload struct {
+	File Binary `json:"file" binding:"required"`
+}
+
+const (
+	// BearerAuth is the JWT returned by /api/v1/login
+	BearerAuth = "bearerAuth"
+	// MetricsToken is the static METRICS_TOKEN accepted by /metrics
+	MetricsToken = "metricsToken"
+)
+
+var pathParam = regexp.MustCompile(`:([A-Za-z_][A-Za-z0-9_]*)`)
+
+// errorResponses documents the standard error responses for the given status codes
+func errorResponses(statuses ...int) []Response {
+	responses := make([]Response, 0, len(statuses))
+	for _, status := range statuses {
+		responses = append(responses, Response{Status: status, Description: http.StatusText(status), Body: ErrorResponse{}})
+	}
+	return responses
+}
+
+// Spec builds the complete OpenAPI document
+func Spec() map[string]any {
+	registry := &schemaRegistry{components: map[string]any{}}
+	paths := map[string]any{}
+
+	for _, operation := range operations {
+		path := openAPIPath(operation.Path)
+		item, ok := paths[path].(map[string]any)
+		if !ok {
+			item = map[string]any{}
+			paths[path] = item
+		}
+		item[strings.ToLower(operation.Method)] = buildOperation(registry, operation)
+	}
+
+	return map[string]any{
+		"openapi": "3.1.0",
+		"info": map[string]any{
+			"title":       "Go Gin Auth API",
+			"version":     "1.0.0",
+			"description": "Authentication, users and posts API built with Gin and GORM.",
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": registry.components,
+			"securitySchemes": map[string]any{
+				BearerAuth: map[string]any{
+					"type":         "http",
+					"scheme":       "bearer",
+					"bearerFormat": "JWT",
+					"description":  "JWT returned by POST /api/v1/login, sent as `Authorization: Bearer <token>`.",
+				},
+				MetricsToken: map[string]any{
+					"type":        "http",
+					"scheme":      "bearer",
+					"description": "Static token configured with METRICS_TOKEN.",
+				},
+			},
+		},
+	}
+}
+
+// buildOperation converts an Operation into an OpenAPI operation object
+func buildOperation(registry *schemaRegistry, operation Operation) map[string]any {
+	result := map[string]any{
+		"summary":     operation.Summary,
+		"operationId": operationID(operation),
+		"tags":        []string{operation.Tag},
+	}
+	if operation.Description != "" {
+		result["description"] = operation.Description
+	}
+
+	security := []any{}
+	for _, scheme := range operation.Security {
+		security = append(security, map[string]any{scheme: []string{}})
+	}
+	if operation.OptionalAuth {
+		// An empty requirement means no authentication
+		security = append(security, map[string]any{})
+	}
+	result["security"] = security
+
+	var parameters []any
+	for _, match := range pathParam.FindAllStringSubmatch(operation.Path, -1) {
+		parameter := map[string]any{
+			"name":     match[1],
+			"in":       "path",
+			"required": true,
+			"schema":   pathParamSchema(match[1]),
+		}
+		for _, param := range operation.Params {
+			if param.In == "path" && param.Name == match[1] {
+				parameter["schema"] = map[string]any{"type": cmp.Or(param.Type, "string")}
+				parameter["description"] = param.Description
+			}
+		}
+		parameters = append(parameters, parameter)
+	}
+	for _, param := range operation.Params {
+		if param.In == "path" {
+			continue
+		}
+		typ := param.Type
+		if typ == "" {
+			typ = "string"
+		}
+		parameter := map[string]any{
+			"name":     param.Name,
+			"in":       param.In,
+			"required": param.Required,
+			"schema":   map[string]any{"type": typ},
+		}
+		if param.Description != "" {
+			parameter["description"] = param.Description
+		}
+		parameters = append(parameters, parameter)
+	}
+	if len(parameters) > 0 {
+		result["parameters"] = parameters
+	}
+
+	requestTypes := operation.RequestTypes
+	if requestTypes == nil && operation.Request != nil {
+		requestTypes = map[string]any{"application/json": operation.Request}
+	}
+	if len(requestTypes) > 0 {
+		content := map[string]any{}
+		for contentType, prototype := range requestTypes {
+			content[contentType] = map[string]any{"schema": registry.schemaFor(prototype)}
+		}
+		result["requestBody"] = map[string]any{"required": true, "content": content}
+	}
+
+	responses := map[string]any{}
+	for _, response := range operation.Responses {
+		description := response.Description
+		if description == "" {
+			description = http.StatusText(response.Status)
+		}
+		entry := map[string]any{"description": description}
+		if response.Body != nil {
+			contentType := response.ContentType
+			if contentType == "" {
+				contentType = "application/json"
+			}
+			entry["content"] = map[string]any{contentType: map[string]any{"schema": registry.schemaFor(response.Body)}}
+		}
+		responses[fmt.Sprint(response.Status)] = entry
+	}
+	result["responses"] = responses
+
+	return result
+}
+
+// openAPIPath converts Gin's /posts/:id syntax to OpenAPI's /posts/{id}
+func openAPIPath(path string) string {
+	return pathParam.ReplaceAllString(path, "{$1}")
+}
+
+// pathParamSchema guesses the schema of a path parameter from its name
+func pathParamSchema(name string) map[string]any {
+	if name == "id" || strings.HasSuffix(name, "_id") || strings.HasSuffix(name, "Id") {
+		return map[string]any{"type": "integer", "minimum": 1}
+	}
+	return map[string]any{"type": "string"}
+}
+
+// operationID derives a stable identifier such as getApiV1PostsId
+func operationID(operation Operation) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(operation.Method))
+	for _, part := range strings.FieldsFunc(operation.Path, func(r rune) bool {
+		return r == '/' || r == ':' || r == '-' || r == '_' || r == '.'
+	}) {
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
+
+// MissingRoutes returns the routes registered on the router that have no entry in
+// the specification, formatted as "METHOD /path". It is empty when the spec is complete.
+func MissingRoutes(routes gin.RoutesInfo) []string {
+	documented := map[string]bool{}
+	for _, operation := range operations {
+		documented[operation.Method+" "+operation.Path] = true
+	}
+
+	var missing []string
+	for _, route := range routes {
+		key := route.Method + " " + route.Path
+		if !documented[key] {
+			missing = append(missing, key)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
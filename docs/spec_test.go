@@ -0,0 +1,66 @@
+package docs_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go-gin-auth-api-starter-kit/docs"
+	"go-gin-auth-api-starter-kit/routes"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	routes.SetupRoutes(router)
+	return router
+}
+
+func TestEveryRouteIsDocumented(t *testing.T) {
+	if missing := docs.MissingRoutes(newRouter().Routes()); len(missing) > 0 {
+		t.Fatalf("routes missing from the OpenAPI specification: %v", missing)
+	}
+}
+
+func TestSpecIsValidJSON(t *testing.T) {
+	body, err := json.Marshal(docs.Spec())
+	if err != nil {
+		t.Fatalf("marshal spec: %v", err)
+	}
+	var spec map[string]any
+	if err := json.Unmarshal(body, &spec); err != nil {
+		t.Fatalf("unmarshal spec: %v", err)
+	}
+	if spec["openapi"] != "3.1.0" {
+		t.Fatalf("openapi = %v, want 3.1.0", spec["openapi"])
+	}
+}
+
+func TestSwaggerUIAssetsAreEmbedded(t *testing.T) {
+	router := newRouter()
+	tests := []struct {
+		path   string
+		status int
+	}{
+		{"/docs", http.StatusOK},
+		{"/docs/assets/swagger-ui.css", http.StatusOK},
+		{"/docs/assets/swagger-ui-bundle.js", http.StatusOK},
+		{"/docs/assets/README.md", http.StatusNotFound},
+		{"/docs/assets/missing.js", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if w.Code != tt.status {
+				t.Fatalf("GET %s = %d, want %d", tt.path, w.Code, tt.status)
+			}
+			if tt.status == http.StatusOK && w.Body.Len() == 0 {
+				t.Fatalf("GET %s returned an empty body", tt.path)
+			}
+		})
+	}
+}
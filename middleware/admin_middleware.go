@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"go-gin-auth-api-starter-kit/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminMiddleware only lets administrators through
+// It must run after AuthMiddleware, which sets the role in the context
+func AdminMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("role") != models.RoleAdmin {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -1,47 +1,121 @@
 package middleware
 
 import (
+	"errors"
+	"go-gin-auth-api-starter-kit/pkg/metrics"
+	"go-gin-auth-api-starter-kit/pkg/requestctx"
+	"go-gin-auth-api-starter-kit/pkg/tracing"
+	"go-gin-auth-api-starter-kit/services"
 	"go-gin-auth-api-starter-kit/utils"
 	"net/http"
 	"strings"
 
+	"github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
-// AuthMiddleware validates JWT tokens and sets user context
+// AuthMiddleware validates JWT tokens, loads the user they belong to and sets user context
 func AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Get the Authorization header
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header is required"})
-			c.Abort()
-			return
+		if authenticate(c) {
+			// Continue to the next handler
+			c.Next()
 		}
+	}
+}
 
-		// Check if the header has the correct format
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization header format"})
-			c.Abort()
+// OptionalAuthMiddleware lets anonymous requests through, and authenticates
+// requests that carry an Authorization header just like AuthMiddleware.
+// A header with an invalid token is still rejected, so a client whose login
+// expired finds out instead of silently seeing less.
+func OptionalAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			c.Next()
 			return
 		}
+		if authenticate(c) {
+			c.Next()
+		}
+	}
+}
+
+// authenticate validates the bearer token of the request and sets the user
+// context. On failure it responds with 401, aborts and returns false.
+func authenticate(c *gin.Context) bool {
+	// Get the Authorization header
+	authHeader := c.GetHeader("Authorization")
+	if authHeader == "" {
+		metrics.TokenValidationFailuresTotal.WithLabelValues("missing_header").Inc()
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header is required"})
+		c.Abort()
+		return false
+	}
 
-		// Extract the token
-		tokenString := parts[1]
+	// Check if the header has the correct format
+	parts := strings.Split(authHeader, " ")
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		metrics.TokenValidationFailuresTotal.WithLabelValues("malformed_header").Inc()
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization header format"})
+		c.Abort()
+		return false
+	}
+
+	// Extract the token
+	tokenString := parts[1]
 
-		// Validate the token
-		claims, err := utils.ValidateToken(tokenString)
-		if err != nil {
+	// Validate the token
+	_, span := tracing.Start(c.Request.Context(), "jwt.Validate")
+	claims, err := utils.ValidateToken(tokenString)
+	tracing.End(span, err)
+	if err != nil {
+		metrics.TokenValidationFailuresTotal.WithLabelValues(tokenFailureReason(err)).Inc()
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		c.Abort()
+		return false
+	}
+
+	// Load the account, so deleted users are locked out and role changes apply
+	// immediately instead of when the token expires
+	user, err := services.AuthenticatedUser(c.Request.Context(), claims.UserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			metrics.TokenValidationFailuresTotal.WithLabelValues("unknown_user").Inc()
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
-			c.Abort()
-			return
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to authenticate"})
 		}
+		c.Abort()
+		return false
+	}
+
+	// Set the user details in the context
+	c.Set("user_id", user.ID)
+	c.Set("username", user.Username)
+	c.Set("role", user.Role)
 
-		// Set the username in the context
-		c.Set("username", claims.Username)
+	// Make the caller known to the service layer as well
+	info := requestctx.FromContext(c.Request.Context())
+	info.UserID = user.ID
+	info.Username = user.Username
+	info.Role = user.Role
+	c.Request = c.Request.WithContext(requestctx.WithInfo(c.Request.Context(), info))
+	return true
+}
 
-		// Continue to the next handler
-		c.Next()
+// tokenFailureReason classifies a token validation error for the metrics
+func tokenFailureReason(err error) string {
+	var validationErr *jwt.ValidationError
+	if errors.As(err, &validationErr) {
+		switch {
+		case validationErr.Errors&jwt.ValidationErrorExpired != 0:
+			return "expired"
+		case validationErr.Errors&jwt.ValidationErrorSignatureInvalid != 0:
+			return "invalid_signature"
+		case validationErr.Errors&jwt.ValidationErrorMalformed != 0:
+			return "malformed_token"
+		}
 	}
+	return "invalid_token"
 }
@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"bytes"
+	"go-gin-auth-api-starter-kit/config"
+	"go-gin-auth-api-starter-kit/pkg/logger"
+	"io"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxLoggedBody caps how much of a request body is logged
+const maxLoggedBody = 4 << 10
+
+// Logger writes one structured access log line per request.
+// Headers are included at debug level, and JSON request bodies when LOG_REQUEST_BODY
+// is enabled; credentials are redacted from both.
+func Logger() gin.HandlerFunc {
+	logBodies := config.GetEnvBool("LOG_REQUEST_BODY", false)
+
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		var body []byte
+		if logBodies && c.Request.Body != nil && strings.HasPrefix(c.ContentType(), "application/json") {
+			body, _ = io.ReadAll(io.LimitReader(c.Request.Body, maxLoggedBody+1))
+			// Put the consumed bytes back so the handler still sees the full body
+			c.Request.Body = readCloser{io.MultiReader(bytes.NewReader(body), c.Request.Body), c.Request.Body}
+		}
+
+		c.Next()
+
+		ctx := c.Request.Context()
+		status := c.Writer.Status()
+		attrs := []any{
+			slog.String("method", c.Request.Method),
+			slog.String("path", c.Request.URL.Path),
+			slog.Int("status", status),
+			slog.Duration("latency", time.Since(start)),
+			slog.String("ip", c.ClientIP()),
+			slog.Int("size", c.Writer.Size()),
+			slog.String("user_agent", c.Request.UserAgent()),
+		}
+
+		if slog.Default().Enabled(ctx, slog.LevelDebug) {
+			attrs = append(attrs, slog.Any("headers", logger.RedactHeaders(c.Request.Header)))
+		}
+		if len(body) > 0 && len(body) <= maxLoggedBody {
+			attrs = append(attrs, slog.String("body", logger.RedactJSON(body)))
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, slog.String("errors", c.Errors.String()))
+		}
+
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+		slog.Log(ctx, level, "http request", attrs...)
+	}
+}
+
+// Recovery turns panics into a 500 response and logs them with the request attributes
+func Recovery() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, err any) {
+		slog.ErrorContext(c.Request.Context(), "panic recovered", slog.Any("error", err), slog.String("stack", string(debug.Stack())))
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+	})
+}
+
+// readCloser pairs a replacement reader with the original body's Close
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"go-gin-auth-api-starter-kit/config"
+	"go-gin-auth-api-starter-kit/pkg/metrics"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Metrics records a request counter and latency histogram for every request.
+// Requests that match no route are grouped under "unmatched" to keep label cardinality bounded.
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		status := strconv.Itoa(c.Writer.Status())
+
+		metrics.HTTPRequestsTotal.WithLabelValues(c.Request.Method, route, status).Inc()
+		metrics.HTTPRequestDuration.WithLabelValues(c.Request.Method, route, status).Observe(time.Since(start).Seconds())
+	}
+}
+
+// MetricsAccess restricts the /metrics endpoint. A scraper is let through when its IP is
+// within METRICS_ALLOWED_CIDRS (loopback only by default) or, if METRICS_TOKEN is set,
+// when it presents that token as a bearer token. The IP is only taken from
+// X-Forwarded-For when the request came through one of the router's trusted proxies.
+func MetricsAccess() gin.HandlerFunc {
+	token := config.GetEnv("METRICS_TOKEN", "")
+
+	var allowed []*net.IPNet
+	for _, cidr := range config.GetEnvList("METRICS_ALLOWED_CIDRS", []string{"127.0.0.0/8", "::1/128"}) {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			slog.Warn("ignoring invalid METRICS_ALLOWED_CIDRS entry", slog.String("cidr", cidr))
+			continue
+		}
+		allowed = append(allowed, network)
+	}
+
+	return func(c *gin.Context) {
+		if token != "" {
+			presented := c.GetHeader("Authorization")
+			if subtle.ConstantTimeCompare([]byte(presented), []byte("Bearer "+token)) == 1 {
+				c.Next()
+				return
+			}
+		}
+
+		if ip := net.ParseIP(c.ClientIP()); ip != nil {
+			for _, network := range allowed {
+				if network.Contains(ip) {
+					c.Next()
+					return
+				}
+			}
+		}
+
+		c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+		c.Abort()
+	}
+}
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMetricsAccessIgnoresForwardedForFromUntrustedClients(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("METRICS_TOKEN", "")
+	t.Setenv("METRICS_ALLOWED_CIDRS", "127.0.0.0/8")
+
+	tests := []struct {
+		name           string
+		trustedProxies []string
+		remoteAddr     string
+		forwardedFor   string
+		want           int
+	}{
+		{"loopback client", nil, "127.0.0.1:4000", "", http.StatusOK},
+		{"remote client", nil, "203.0.113.5:4000", "", http.StatusForbidden},
+		{"spoofed forwarded for", nil, "203.0.113.5:4000", "127.0.0.1", http.StatusForbidden},
+		{"forwarded by a trusted proxy", []string{"203.0.113.0/24"}, "203.0.113.5:4000", "127.0.0.1", http.StatusOK},
+		{"remote client behind a trusted proxy", []string{"203.0.113.0/24"}, "203.0.113.5:4000", "198.51.100.7", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			if err := router.SetTrustedProxies(tt.trustedProxies); err != nil {
+				t.Fatal(err)
+			}
+			router.GET("/metrics", MetricsAccess(), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.forwardedFor != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwardedFor)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
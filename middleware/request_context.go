@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"go-gin-auth-api-starter-kit/pkg/requestctx"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestContext attaches request metadata (client IP, user agent, request ID,
+// route template) to the request's context.Context so the service layer and the
+// logger can read it. It must run after RequestID.
+func RequestContext() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		info := &requestctx.Info{
+			RequestID: c.GetHeader(RequestIDHeader),
+			IP:        c.ClientIP(),
+			UserAgent: c.Request.UserAgent(),
+			Route:     c.FullPath(),
+		}
+		c.Request = c.Request.WithContext(requestctx.WithInfo(c.Request.Context(), info))
+
+		c.Next()
+	}
+}
@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// Attachment is a file uploaded to a post. Its content is kept in the file
+// storage under StorageKey, which is derived from the SHA-256 of the content,
+// so attachments with identical content share one stored object.
+type Attachment struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	PostID uint `gorm:"not null;index" json:"post_id"`
+	Post   Post `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+	// UserID is the uploader; it is cleared when their account is purged
+	UserID *uint `gorm:"index" json:"user_id"`
+	User   *User `gorm:"constraint:OnDelete:SET NULL" json:"-"`
+
+	Filename string `gorm:"size:255" json:"filename"`
+	// ContentType is sniffed from the content, never taken from the client
+	ContentType string `gorm:"size:100" json:"content_type"`
+	Size        int64  `json:"size"`
+	SHA256      string `gorm:"size:64;index" json:"sha256"`
+	StorageKey  string `gorm:"size:255" json:"-"`
+
+	// Width and Height are set for images
+	Width  *int `json:"width,omitempty"`
+	Height *int `json:"height,omitempty"`
+}
+
+// IsImage reports whether the attachment is an image browsers can display inline
+func (a Attachment) IsImage() bool {
+	switch a.ContentType {
+	case "image/jpeg", "image/png", "image/gif", "image/webp":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package models
+
+import "time"
+
+// Audit event names. They are stored verbatim, so existing values must never be renamed.
+const (
+	AuditLoginSucceeded    = "auth.login.succeeded"
+	AuditLoginFailed       = "auth.login.failed"
+	AuditRegisterSucceeded = "auth.register.succeeded"
+	AuditRegisterFailed    = "auth.register.failed"
+
+	AuditPostCreated = "post.created"
+	AuditPostUpdated = "post.updated"
+	AuditPostDeleted = "post.deleted"
+
+	AuditPostPublished   = "post.published"
+	AuditPostScheduled   = "post.scheduled"
+	AuditPostUnpublished = "post.unpublished"
+	AuditPostArchived    = "post.archived"
+
+	AuditPostRevisionRestored = "post.revision.restored"
+
+	AuditPostShared           = "post.shared"
+	AuditPostUnshared         = "post.unshared"
+	AuditPostShareLinkCreated = "post.share_link.created"
+
+	AuditAttachmentUploaded = "attachment.uploaded"
+	AuditAttachmentDeleted  = "attachment.deleted"
+
+	AuditPostRestored = "post.restored"
+	AuditPostPurged   = "post.purged"
+	AuditUserDeleted  = "user.deleted"
+	AuditUserRestored = "user.restored"
+	AuditUserPurged   = "user.purged"
+
+	AuditUserAvatarUpdated = "user.avatar.updated"
+	AuditUserAvatarRemoved = "user.avatar.removed"
+
+	AuditCategoryCreated = "category.created"
+	AuditPostsRetagged   = "admin.posts.retagged"
+
+	AuditCommentCreated = "comment.created"
+	AuditCommentUpdated = "comment.updated"
+	AuditCommentDeleted = "comment.deleted"
+
+	AuditAuditExported = "admin.audit.exported"
+	AuditAuditPruned   = "system.audit.pruned"
+
+	AuditWebhookCreated     = "admin.webhook.created"
+	AuditWebhookUpdated     = "admin.webhook.updated"
+	AuditWebhookDeleted     = "admin.webhook.deleted"
+	AuditWebhookRedelivered = "admin.webhook.redelivered"
+	AuditWebhookDisabled    = "system.webhook.disabled"
+
+	AuditJobRetried = "admin.job.retried"
+)
+
+// AuditLog is an append-only record of a security-relevant or content event.
+// Rows are only ever inserted, and removed in bulk by the retention job.
+type AuditLog struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `gorm:"not null;index" json:"created_at"`
+
+	// Event is one of the Audit* constants above
+	Event string `gorm:"size:64;not null;index" json:"event"`
+
+	// Actor is the user who performed the action; nil for anonymous or system events
+	ActorID       *uint  `gorm:"index" json:"actor_id"`
+	ActorUsername string `gorm:"size:255" json:"actor_username"`
+
+	// Target is the entity the action was performed on, e.g. ("post", "42")
+	TargetType string `gorm:"size:64;index:idx_audit_logs_target" json:"target_type"`
+	TargetID   string `gorm:"size:64;index:idx_audit_logs_target" json:"target_id"`
+
+	IP        string `gorm:"size:64" json:"ip"`
+	UserAgent string `gorm:"size:512" json:"user_agent"`
+	RequestID string `gorm:"size:64;index" json:"request_id"`
+
+	// Metadata holds event specific details, such as the reason for a failed login
+	Metadata JSONMap `json:"metadata"`
+}
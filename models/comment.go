@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Comment is a reader's response to a post. Comments can reply to other
+// comments, forming threads up to a configurable depth.
+type Comment struct {
+	gorm.Model
+
+	PostID uint `gorm:"not null;index" json:"post_id"`
+	Post   Post `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+
+	// UserID is the author of the comment; nil once the author's account has been purged
+	UserID *uint `gorm:"index" json:"user_id"`
+	User   *User `gorm:"constraint:OnDelete:SET NULL" json:"-"`
+
+	// ParentID is the comment being replied to; nil for top-level comments
+	ParentID *uint `gorm:"index" json:"parent_id"`
+	// RootID is the top-level comment of the thread; nil for top-level comments
+	// It lets a whole thread be loaded with a single query
+	RootID *uint `gorm:"index" json:"root_id"`
+	// Depth is 0 for top-level comments, 1 for replies to them, and so on
+	Depth int `gorm:"not null;default:0" json:"depth"`
+
+	Body string `gorm:"type:text;not null" json:"body"`
+
+	// EditedAt is set when the author changes the body
+	EditedAt *time.Time `json:"edited_at"`
+}
+
+// IsAuthoredBy reports whether userID wrote the comment
+func (c Comment) IsAuthoredBy(userID uint) bool {
+	return c.UserID != nil && *c.UserID == userID
+}
+
+// DeletedCommentPlaceholder replaces the body of soft-deleted comments,
+// which stay in their thread so that replies keep their context
+const DeletedCommentPlaceholder = "[deleted]"
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// Follow puts the posts of one user (the followee) into the home feed of
+// another (the follower)
+type Follow struct {
+	FollowerID uint      `gorm:"primaryKey" json:"follower_id"`
+	Follower   User      `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+	FolloweeID uint      `gorm:"primaryKey;index" json:"followee_id"`
+	Followee   User      `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// FeedItem is a published post copied into a follower's home feed (fan-out
+// on write). Posts of authors with very many followers are not copied and
+// are read from the posts table instead; see Post.FannedOut.
+type FeedItem struct {
+	UserID uint `gorm:"primaryKey;index:idx_feed_items_user_published,priority:1" json:"user_id"`
+	User   User `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+	PostID uint `gorm:"primaryKey;index" json:"post_id"`
+	Post   Post `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+	// AuthorID lets unfollowing remove the author's posts from the feed
+	AuthorID    uint      `gorm:"index" json:"author_id"`
+	PublishedAt time.Time `gorm:"index:idx_feed_items_user_published,priority:2" json:"published_at"`
+}
@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// Job statuses
+const (
+	// JobPending jobs wait for their RunAt time and a free worker
+	JobPending = "pending"
+	// JobRunning jobs are held by a worker until LockedUntil
+	JobRunning = "running"
+	// JobSucceeded jobs are done
+	JobSucceeded = "succeeded"
+	// JobDead jobs failed MaxAttempts times and wait for an admin to retry them
+	JobDead = "dead"
+)
+
+// Job is a unit of background work in the database-backed queue. Workers
+// claim due jobs with SKIP LOCKED, highest priority first.
+type Job struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// Type selects the handler, e.g. trash.purge
+	Type string `gorm:"size:64;not null;index" json:"type"`
+	// Payload is the handler's input encoded as JSON
+	Payload JSONMap `json:"payload"`
+	// Priority orders due jobs; higher runs first
+	Priority int `gorm:"not null;default:0;index:idx_jobs_due,priority:2,sort:desc" json:"priority"`
+	// UniqueKey, when set, keeps the same job from being queued twice, e.g.
+	// by every worker firing the same cron schedule
+	UniqueKey *string `gorm:"size:191;uniqueIndex" json:"unique_key"`
+
+	// Status is one of the Job* constants
+	Status string `gorm:"size:16;not null;index:idx_jobs_due,priority:1" json:"status"`
+	// RunAt is when the job is due (again)
+	RunAt       time.Time `gorm:"not null;index:idx_jobs_due,priority:3" json:"run_at"`
+	Attempts    int       `gorm:"not null;default:0" json:"attempts"`
+	MaxAttempts int       `gorm:"not null" json:"max_attempts"`
+	// LockedUntil is when a running job is given up on and can be claimed again
+	LockedUntil *time.Time `json:"locked_until"`
+	// LockedBy identifies the worker running the job
+	LockedBy string `gorm:"size:128;not null;default:''" json:"locked_by"`
+	// LastError is why the last attempt failed
+	LastError  string     `gorm:"type:text;not null;default:''" json:"last_error"`
+	FinishedAt *time.Time `gorm:"index" json:"finished_at"`
+}
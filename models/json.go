@@ -0,0 +1,75 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+)
+
+// JSONMap is a free-form JSON object stored in a jsonb column
+type JSONMap map[string]any
+
+// Value implements driver.Valuer so GORM can write the map as JSON
+func (m JSONMap) Value() (driver.Value, error) {
+	if m == nil {
+		return "{}", nil
+	}
+	data, err := json.Marshal(m)
+	return string(data), err
+}
+
+// Scan implements sql.Scanner so GORM can read the JSON back into the map
+func (m *JSONMap) Scan(value any) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*m = JSONMap{}
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return errors.New("unsupported type for JSONMap")
+	}
+	return json.Unmarshal(data, m)
+}
+
+// GormDataType tells GORM which column type to use for JSONMap fields
+func (JSONMap) GormDataType() string {
+	return "jsonb"
+}
+
+// StringList is a list of strings stored as a JSON array in a jsonb column
+type StringList []string
+
+// Value implements driver.Valuer so GORM can write the list as JSON
+func (l StringList) Value() (driver.Value, error) {
+	if l == nil {
+		return "[]", nil
+	}
+	data, err := json.Marshal(l)
+	return string(data), err
+}
+
+// Scan implements sql.Scanner so GORM can read the JSON back into the list
+func (l *StringList) Scan(value any) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*l = StringList{}
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return errors.New("unsupported type for StringList")
+	}
+	return json.Unmarshal(data, l)
+}
+
+// GormDataType tells GORM which column type to use for StringList fields
+func (StringList) GormDataType() string {
+	return "jsonb"
+}
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// Mention records that a post's content mentions a user with @username.
+// Mentions are kept in step with the content whenever it changes.
+type Mention struct {
+	PostID    uint      `gorm:"primaryKey" json:"post_id"`
+	Post      Post      `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+	UserID    uint      `gorm:"primaryKey;index" json:"user_id"`
+	User      User      `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,52 @@
+package models
+
+import "time"
+
+// Notification types, one per thing a user can be told about
+const (
+	NotificationComment  = "comment"  // Someone commented on your post
+	NotificationReply    = "reply"    // Someone replied to your comment
+	NotificationReaction = "reaction" // Someone reacted to your post
+	NotificationMention  = "mention"  // Someone mentioned you in a post
+)
+
+// NotificationTypes lists every notification type, in the order they are shown in preferences
+var NotificationTypes = []string{NotificationComment, NotificationReply, NotificationReaction, NotificationMention}
+
+// Notification tells a user that someone else did something involving them
+type Notification struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// UserID is the user being notified
+	UserID uint `gorm:"not null;index:idx_notifications_user_read,priority:1" json:"user_id"`
+	User   User `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+
+	// Type is one of the Notification* constants
+	Type string `gorm:"size:32;not null" json:"type"`
+
+	// ActorID is who caused the notification; nil once their account is purged
+	ActorID *uint `gorm:"index" json:"actor_id"`
+	Actor   *User `gorm:"foreignKey:ActorID;constraint:OnDelete:SET NULL" json:"-"`
+
+	// PostID and CommentID are what the notification is about, when it is about them
+	PostID    *uint    `gorm:"index" json:"post_id"`
+	Post      *Post    `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+	CommentID *uint    `gorm:"index" json:"comment_id"`
+	Comment   *Comment `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+
+	// Data holds details that depend on the type, such as the emoji of a reaction
+	Data JSONMap `json:"data"`
+
+	// ReadAt is nil while the notification is unread
+	ReadAt *time.Time `gorm:"index:idx_notifications_user_read,priority:2" json:"read_at"`
+}
+
+// NotificationPreference turns one type of notification on or off for a
+// user. Types without a row are on.
+type NotificationPreference struct {
+	UserID  uint   `gorm:"primaryKey" json:"user_id"`
+	User    User   `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+	Type    string `gorm:"primaryKey;size:32" json:"type"`
+	Enabled bool   `gorm:"not null" json:"enabled"`
+}
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// OutboxEvent is a domain event, written in the same transaction as the change
+// it describes so that it exists exactly when the change was committed. The
+// outbox dispatcher hands it to every subscribed handler, retrying until they
+// have all succeeded.
+type OutboxEvent struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// Type names the event, e.g. post.created
+	Type string `gorm:"size:64;not null;index" json:"type"`
+	// AggregateType and AggregateID identify the entity that changed
+	AggregateType string `gorm:"size:32;not null;index:idx_outbox_events_aggregate,priority:1" json:"aggregate_type"`
+	AggregateID   uint   `gorm:"not null;index:idx_outbox_events_aggregate,priority:2" json:"aggregate_id"`
+	// Payload is the event encoded as JSON
+	Payload JSONMap `json:"payload"`
+
+	// DispatchedAt is set once every handler has processed the event
+	DispatchedAt *time.Time `gorm:"index:idx_outbox_events_pending,priority:1" json:"dispatched_at"`
+	// NextAttemptAt is when the dispatcher picks up the event (again)
+	NextAttemptAt time.Time `gorm:"not null;index:idx_outbox_events_pending,priority:2" json:"next_attempt_at"`
+	Attempts      int       `gorm:"not null;default:0" json:"attempts"`
+	// LastError is why the last attempt failed
+	LastError string `gorm:"type:text;not null;default:''" json:"last_error"`
+}
+
+// ProcessedEvent records that a handler processed an outbox event. It is
+// written in the handler's transaction, so an event that is dispatched again
+// skips the handlers that already ran.
+type ProcessedEvent struct {
+	EventID     uint        `gorm:"primaryKey;autoIncrement:false" json:"event_id"`
+	Event       OutboxEvent `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+	Handler     string      `gorm:"primaryKey;size:64" json:"handler"`
+	ProcessedAt time.Time   `gorm:"not null" json:"processed_at"`
+}
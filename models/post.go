@@ -1,10 +1,94 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Post statuses. Only published posts are visible to users other than the author.
+const (
+	PostStatusDraft     = "draft"
+	PostStatusScheduled = "scheduled"
+	PostStatusPublished = "published"
+	PostStatusArchived  = "archived"
+)
+
+// Post content formats. They decide how Content is rendered to HTML.
+const (
+	PostFormatPlain    = "plain"
+	PostFormatMarkdown = "markdown"
+)
+
+// Post visibility levels. They decide who may read a published post.
+const (
+	// PostVisibilityPublic posts are readable by anyone, even without an account
+	PostVisibilityPublic = "public"
+	// PostVisibilityInternal posts are readable by every signed-in user
+	PostVisibilityInternal = "internal"
+	// PostVisibilityPrivate posts are readable by the author and the users it is shared with
+	PostVisibilityPrivate = "private"
+	// PostVisibilityUnlisted posts are readable by anyone who knows their ID,
+	// but are left out of listings
+	PostVisibilityUnlisted = "unlisted"
+)
 
 type Post struct {
 	gorm.Model
 
-	Title   string `gorm:"size:255" json:"title"`
+	// UserID is the author; nil for posts created before authors were recorded
+	UserID *uint `gorm:"index;index:idx_posts_feed_pending,priority:1,where:fanned_out = false" json:"user_id"`
+	Author *User `gorm:"foreignKey:UserID;constraint:OnDelete:SET NULL" json:"-"`
+
+	Title string `gorm:"size:255" json:"title"`
+	// Slug addresses the post in URLs. It is generated from the title and
+	// changes with it; earlier slugs are kept as PostSlugAlias redirects.
+	Slug    string `gorm:"size:255;uniqueIndex" json:"slug"`
 	Content string `json:"content"`
+
+	// ContentFormat is one of the PostFormat* constants
+	ContentFormat string `gorm:"size:16;not null;default:plain" json:"content_format"`
+	// ContentHTML is Content rendered and sanitized, refreshed whenever Content changes
+	ContentHTML string `gorm:"type:text;not null;default:''" json:"-"`
+	// Excerpt is the start of the rendered text
+	Excerpt string `gorm:"type:text;not null;default:''" json:"excerpt"`
+	// ReadingTime is the estimated reading time in minutes
+	ReadingTime int `gorm:"not null;default:0" json:"reading_time"`
+
+	// Status is one of the PostStatus* constants. Rows that predate the
+	// workflow default to published so they stay visible.
+	Status string `gorm:"size:16;not null;default:published;index" json:"status"`
+	// PublishedAt is when the post was first published
+	PublishedAt *time.Time `gorm:"index:idx_posts_feed_pending,priority:2" json:"published_at"`
+	// ScheduledAt is when a scheduled post will be published by the scheduler
+	ScheduledAt *time.Time `gorm:"index" json:"scheduled_at"`
+
+	// Visibility is one of the PostVisibility* constants
+	Visibility string `gorm:"size:16;not null;default:internal;index" json:"visibility"`
+
+	// CategoryID places the post in the category tree; nil when uncategorized
+	CategoryID *uint     `gorm:"index" json:"category_id"`
+	Category   *Category `gorm:"constraint:OnDelete:SET NULL" json:"category,omitempty"`
+
+	Tags []Tag `gorm:"many2many:post_tags;constraint:OnDelete:CASCADE" json:"tags"`
+
+	// Version is incremented on every change and exposed as the ETag.
+	// Updates only succeed when the version is still the one the client read.
+	Version int `gorm:"not null;default:1" json:"version"`
+
+	// FannedOut is set once the post has been copied into its followers'
+	// home feeds. Feeds read the posts that aren't (from large accounts, or
+	// from before feeds existed) straight from this table, through the
+	// partial idx_posts_feed_pending index.
+	FannedOut bool `gorm:"not null;default:false" json:"-"`
+
+	// Reactions and Bookmarked are how the post looks to the user reading it.
+	// They are not columns; services fill them in with LoadPostEngagement.
+	Reactions  []ReactionSummary `gorm:"-" json:"-"`
+	Bookmarked bool              `gorm:"-" json:"-"`
+}
+
+// IsAuthoredBy reports whether userID wrote the post
+func (p Post) IsAuthoredBy(userID uint) bool {
+	return p.UserID != nil && *p.UserID == userID
 }
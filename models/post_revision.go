@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// PostRevision is an immutable snapshot of a post's title and content, written
+// every time the post is created, updated or restored. Revisions are numbered
+// per post starting at 1 and are never changed, only pruned oldest first.
+type PostRevision struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	PostID uint `gorm:"not null;uniqueIndex:idx_post_revisions_number" json:"post_id"`
+	Post   Post `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+	Number int  `gorm:"not null;uniqueIndex:idx_post_revisions_number" json:"number"`
+
+	Title   string `gorm:"size:255" json:"title"`
+	Content string `json:"content"`
+
+	// EditorID is the user who made the change; nil for system changes
+	EditorID *uint `gorm:"index" json:"editor_id"`
+	Editor   *User `gorm:"constraint:OnDelete:SET NULL" json:"-"`
+
+	// RestoredFrom is the number of the revision this one restored, if any
+	RestoredFrom *int `json:"restored_from"`
+}
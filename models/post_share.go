@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// PostShare grants a user read access to a private post
+type PostShare struct {
+	PostID    uint      `gorm:"primaryKey" json:"post_id"`
+	Post      Post      `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+	UserID    uint      `gorm:"primaryKey;index" json:"user_id"`
+	User      User      `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+}
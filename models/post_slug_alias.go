@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// PostSlugAlias is a slug a post used to have. Requests for it are
+// redirected to the post's current slug.
+type PostSlugAlias struct {
+	Slug      string    `gorm:"primaryKey;size:255" json:"slug"`
+	PostID    uint      `gorm:"not null;index" json:"post_id"`
+	Post      Post      `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// PostReaction is one user's reaction to a post with one emoji. A user can
+// react to a post with several emoji, but with each one only once.
+type PostReaction struct {
+	PostID    uint      `gorm:"primaryKey" json:"post_id"`
+	Post      Post      `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+	UserID    uint      `gorm:"primaryKey;index" json:"user_id"`
+	User      User      `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+	Emoji     string    `gorm:"primaryKey;size:32" json:"emoji"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+}
+
+// PostReactionCount is how many reactions with one emoji a post has. It is
+// updated in the same transaction as every reaction added or removed, so
+// counts can be read without counting the reactions themselves.
+type PostReactionCount struct {
+	PostID uint   `gorm:"primaryKey" json:"post_id"`
+	Post   Post   `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+	Emoji  string `gorm:"primaryKey;size:32" json:"emoji"`
+	Count  int    `gorm:"not null;default:0" json:"count"`
+}
+
+// ReactionSummary is the number of reactions with one emoji on a post, as
+// seen by one viewer. It is not stored.
+type ReactionSummary struct {
+	Emoji string
+	Count int
+	// Reacted reports whether the viewer is one of the users who reacted
+	Reacted bool
+}
+
+// Bookmark saves a post to a user's reading list
+type Bookmark struct {
+	UserID    uint      `gorm:"primaryKey" json:"user_id"`
+	User      User      `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+	PostID    uint      `gorm:"primaryKey;index" json:"post_id"`
+	Post      Post      `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+}
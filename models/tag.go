@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// Tag is a free-form label attached to posts. Tags are created the first time
+// they are used and are identified by their slug.
+type Tag struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// Name is the tag as it was first written, e.g. "Go Modules"
+	Name string `gorm:"size:64;not null" json:"name"`
+	// Slug is the normalized form used for lookups, e.g. "go-modules"
+	Slug string `gorm:"size:64;not null;uniqueIndex" json:"slug"`
+
+	Posts []Post `gorm:"many2many:post_tags;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// Tag sources
+const (
+	// TagSourceManual tags were chosen by the author or an admin
+	TagSourceManual = "manual"
+	// TagSourceHashtag tags come from #hashtags in the content and are
+	// replaced whenever the content changes
+	TagSourceHashtag = "hashtag"
+)
+
+// PostTag attaches a tag to a post, remembering where the tag came from.
+// It is the post_tags join table of Post.Tags.
+type PostTag struct {
+	PostID uint `gorm:"primaryKey;autoIncrement:false"`
+	TagID  uint `gorm:"primaryKey;autoIncrement:false"`
+	// Source is one of the TagSource* constants
+	Source string `gorm:"size:16;not null;default:manual"`
+}
+
+// Category groups posts in a tree, e.g. Programming > Go > Web
+type Category struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	Name string `gorm:"size:128;not null" json:"name"`
+	Slug string `gorm:"size:128;not null;uniqueIndex" json:"slug"`
+
+	// ParentID is nil for top-level categories
+	ParentID *uint     `gorm:"index" json:"parent_id"`
+	Parent   *Category `gorm:"constraint:OnDelete:RESTRICT" json:"-"`
+}
@@ -4,6 +4,12 @@ package models
 // Import GORM for database operations
 import "gorm.io/gorm"
 
+// Roles a user can have
+const (
+	RoleUser  = "user"  // Regular account (the default)
+	RoleAdmin = "admin" // Can use the /admin endpoints
+)
+
 // User represents a person who can use our application
 // It includes basic information like username, email, and password
 type User struct {
@@ -22,10 +28,27 @@ type User struct {
 	// Email is the user's email address
 	// It must be unique (no two users can have the same email)
 	// It cannot be empty
-	Email    string `gorm:"unique;not null" json:"email"`
+	Email string `gorm:"unique;not null" json:"email"`
 
 	// Password is the user's secret password
 	// It cannot be empty
 	// Note: In a real application, this should be hashed before storing
 	Password string `gorm:"not null" json:"password"`
+
+	// Role controls access to administrative endpoints
+	// New accounts always start as RoleUser
+	Role string `gorm:"size:20;not null;default:user" json:"role"`
+
+	// AvatarKey is where the resized copies of the user's avatar are stored,
+	// e.g. "avatars/12/9f86d081884c7d65"; empty when they have not uploaded one
+	AvatarKey string `gorm:"size:100;not null;default:''" json:"-"`
+
+	// PendingAvatarKey is the latest upload while it is resized in the
+	// background; it becomes the AvatarKey once every size is stored
+	PendingAvatarKey string `gorm:"size:100;not null;default:''" json:"-"`
+
+	// FollowersCount and FollowingCount are updated together with the
+	// follows they count, so profiles don't need to count them
+	FollowersCount int `gorm:"not null;default:0" json:"followers_count"`
+	FollowingCount int `gorm:"not null;default:0" json:"following_count"`
 }
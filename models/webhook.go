@@ -0,0 +1,111 @@
+package models
+
+import (
+	"slices"
+	"time"
+)
+
+// Webhook events. A webhook subscribes to some of them, or to all with WebhookAllEvents.
+const (
+	WebhookUserRegistered = "user.registered"
+	WebhookUserDeleted    = "user.deleted"
+	WebhookUserRestored   = "user.restored"
+	WebhookUserPurged     = "user.purged"
+
+	WebhookPostCreated = "post.created"
+	// WebhookPostUpdated is sent for changes to the content and for status
+	// changes other than publishing
+	WebhookPostUpdated   = "post.updated"
+	WebhookPostPublished = "post.published"
+	WebhookPostDeleted   = "post.deleted"
+	WebhookPostRestored  = "post.restored"
+	WebhookPostPurged    = "post.purged"
+
+	WebhookCommentCreated = "comment.created"
+	WebhookCommentUpdated = "comment.updated"
+	WebhookCommentDeleted = "comment.deleted"
+
+	// WebhookAllEvents subscribes a webhook to every event, including ones added later
+	WebhookAllEvents = "*"
+)
+
+// WebhookEvents lists every event a webhook can subscribe to
+var WebhookEvents = []string{
+	WebhookUserRegistered, WebhookUserDeleted, WebhookUserRestored, WebhookUserPurged,
+	WebhookPostCreated, WebhookPostUpdated, WebhookPostPublished, WebhookPostDeleted, WebhookPostRestored, WebhookPostPurged,
+	WebhookCommentCreated, WebhookCommentUpdated, WebhookCommentDeleted,
+}
+
+// Webhook delivery statuses
+const (
+	// WebhookDeliveryPending deliveries are waiting for their next attempt
+	WebhookDeliveryPending = "pending"
+	// WebhookDeliverySucceeded deliveries got a 2xx response
+	WebhookDeliverySucceeded = "succeeded"
+	// WebhookDeliveryFailed deliveries ran out of attempts, or their webhook was disabled
+	WebhookDeliveryFailed = "failed"
+)
+
+// Webhook is an admin-managed subscription that POSTs events to a URL
+type Webhook struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	URL         string `gorm:"size:2048;not null" json:"url"`
+	Description string `gorm:"size:255;not null;default:''" json:"description"`
+	// Secret signs every delivery; it is only shown when the webhook is created
+	Secret string `gorm:"size:128;not null" json:"-"`
+	// Events are the events the webhook subscribes to, e.g. post.created or *
+	Events StringList `gorm:"not null" json:"events"`
+
+	// Active webhooks receive events. Webhooks are disabled automatically
+	// when too many deliveries in a row fail.
+	Active bool `gorm:"not null" json:"active"`
+	// ConsecutiveFailures counts the deliveries that failed for good since
+	// the last one that succeeded
+	ConsecutiveFailures int        `gorm:"not null;default:0" json:"consecutive_failures"`
+	DisabledAt          *time.Time `json:"disabled_at"`
+
+	CreatedByID *uint `gorm:"index" json:"created_by_id"`
+	CreatedBy   *User `gorm:"constraint:OnDelete:SET NULL" json:"-"`
+}
+
+// Subscribes reports whether the webhook wants event
+func (w Webhook) Subscribes(event string) bool {
+	return slices.Contains(w.Events, event) || slices.Contains(w.Events, WebhookAllEvents)
+}
+
+// WebhookDelivery is one event sent to one webhook, retried until it
+// succeeds or runs out of attempts. It doubles as the delivery log.
+type WebhookDelivery struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	WebhookID uint    `gorm:"not null;index" json:"webhook_id"`
+	Webhook   Webhook `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+
+	// EventID identifies the event; it is the same for every webhook and for
+	// redeliveries, so receivers can ignore events they already handled
+	EventID string `gorm:"size:32;not null;index" json:"event_id"`
+	Event   string `gorm:"size:64;not null" json:"event"`
+	// Payload is the JSON body that is sent
+	Payload JSONMap `json:"payload"`
+
+	// Status is one of the WebhookDelivery* constants
+	Status   string `gorm:"size:16;not null;index:idx_webhook_deliveries_due,priority:1" json:"status"`
+	Attempts int    `gorm:"not null;default:0" json:"attempts"`
+	// NextAttemptAt is when a pending delivery is tried (again)
+	NextAttemptAt *time.Time `gorm:"index:idx_webhook_deliveries_due,priority:2" json:"next_attempt_at"`
+	LastAttemptAt *time.Time `json:"last_attempt_at"`
+
+	// ResponseStatus, ResponseBody and Error describe the last attempt
+	ResponseStatus *int   `json:"response_status"`
+	ResponseBody   string `gorm:"type:text;not null;default:''" json:"response_body"`
+	Error          string `gorm:"type:text;not null;default:''" json:"error"`
+	DurationMs     int64  `gorm:"not null;default:0" json:"duration_ms"`
+
+	// RedeliveryOf is the delivery this one repeats, when it was redelivered by hand
+	RedeliveryOf *uint `json:"redelivery_of"`
+}
@@ -0,0 +1,183 @@
+// Package cron parses cron expressions and computes when they fire next.
+//
+// Expressions have the five standard fields, in the time zone of the times
+// passed to Next:
+//
+//	minute hour day-of-month month day-of-week
+//
+// Fields accept *, numbers, ranges (1-5), lists (1,15) and steps (*/15, 0-30/10).
+// Day of week runs from 0 (Sunday) to 6; 7 is Sunday too. When both day fields
+// are restricted, a day matching either of them fires, as in Vixie cron.
+//
+// The descriptors @yearly (@annually), @monthly, @weekly, @daily (@midnight)
+// and @hourly are supported, as is "@every <duration>", which fires at the
+// multiples of the duration since the Unix epoch, so every process agrees on
+// the times.
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule tells when a cron expression fires
+type Schedule interface {
+	// Next returns the first time after t the schedule fires
+	Next(t time.Time) time.Time
+}
+
+// descriptors are the shorthands for common expressions
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// Parse parses a cron expression
+func Parse(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if rest, ok := strings.CutPrefix(spec, "@every "); ok {
+		interval, err := time.ParseDuration(strings.TrimSpace(rest))
+		if err != nil || interval < time.Second {
+			return nil, fmt.Errorf("cron: invalid interval in %q", spec)
+		}
+		return every(interval), nil
+	}
+	if expanded, ok := descriptors[spec]; ok {
+		spec = expanded
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron: %q must have 5 fields", spec)
+	}
+	var s fieldSchedule
+	var err error
+	if s.minute, err = parseField(fields[0], 0, 59); err != nil {
+		return nil, err
+	}
+	if s.hour, err = parseField(fields[1], 0, 23); err != nil {
+		return nil, err
+	}
+	if s.dom, err = parseField(fields[2], 1, 31); err != nil {
+		return nil, err
+	}
+	if s.month, err = parseField(fields[3], 1, 12); err != nil {
+		return nil, err
+	}
+	if s.dow, err = parseField(fields[4], 0, 7); err != nil {
+		return nil, err
+	}
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1 << 0
+	}
+	s.domStar, s.dowStar = fields[2] == "*", fields[4] == "*"
+	return s, nil
+}
+
+// parseField turns a field into a bit set of the values it matches
+func parseField(field string, low, high int) (uint64, error) {
+	var bits uint64
+	for part := range strings.SplitSeq(field, ",") {
+		valueRange, stepText, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepText); err != nil || step < 1 {
+				return 0, fmt.Errorf("cron: invalid step in %q", field)
+			}
+		}
+
+		start, end := low, high
+		if valueRange != "*" {
+			startText, endText, isRange := strings.Cut(valueRange, "-")
+			var err error
+			if start, err = strconv.Atoi(startText); err != nil {
+				return 0, fmt.Errorf("cron: invalid value in %q", field)
+			}
+			end = start
+			if isRange {
+				if end, err = strconv.Atoi(endText); err != nil {
+					return 0, fmt.Errorf("cron: invalid value in %q", field)
+				}
+			} else if hasStep {
+				end = high
+			}
+		}
+		if start < low || end > high || start > end {
+			return 0, fmt.Errorf("cron: %q is out of range %d-%d", field, low, high)
+		}
+
+		for value := start; value <= end; value += step {
+			bits |= 1 << value
+		}
+	}
+	return bits, nil
+}
+
+// fieldSchedule is a parsed five-field expression
+type fieldSchedule struct {
+	minute, hour, dom, month, dow uint64
+	domStar, dowStar              bool
+}
+
+// maxSearch bounds the search for the next time, so expressions that can
+// never fire, such as 0 0 30 2 *, don't loop forever
+const maxSearch = 5 * 366 * 24 * time.Hour
+
+// Next implements Schedule. It returns the zero time if the expression does
+// not fire within five years.
+func (s fieldSchedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.Add(maxSearch)
+	for t.Before(limit) {
+		switch {
+		case s.month&(1<<int(t.Month())) == 0:
+			t = advance(t, time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location()))
+		case !s.dayMatches(t):
+			t = advance(t, time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location()))
+		case s.hour&(1<<t.Hour()) == 0:
+			t = advance(t, time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location()))
+		case s.minute&(1<<t.Minute()) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// advance returns next, or the minute after t when next is not after t. A
+// wall clock time skipped by a daylight saving change, such as 02:00 on the
+// day clocks spring forward, can come out of time.Date earlier than t.
+func advance(t, next time.Time) time.Time {
+	if next.After(t) {
+		return next
+	}
+	return t.Add(time.Minute)
+}
+
+// dayMatches reports whether the day of t matches the day fields
+func (s fieldSchedule) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<t.Day()) != 0
+	dow := s.dow&(1<<int(t.Weekday())) != 0
+	if s.domStar || s.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// every is an "@every <duration>" schedule
+type every time.Duration
+
+// Next implements Schedule
+func (e every) Next(t time.Time) time.Time {
+	interval := time.Duration(e)
+	return time.Unix(0, 0).Add(t.Sub(time.Unix(0, 0)).Truncate(interval) + interval).In(t.Location())
+}
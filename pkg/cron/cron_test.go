@@ -0,0 +1,110 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseErrors(t *testing.T) {
+	tests := []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"a * * * *",
+		"1-x * * * *",
+		"@reboot",
+		"@every soon",
+		"@every 500ms",
+	}
+	for _, spec := range tests {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("Parse(%q) succeeded, want an error", spec)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	// A Thursday
+	from := time.Date(2026, 1, 15, 10, 30, 45, 0, time.UTC)
+	at := func(year int, month time.Month, day, hour, minute, second int) time.Time {
+		return time.Date(year, month, day, hour, minute, second, 0, time.UTC)
+	}
+
+	tests := []struct {
+		spec string
+		want time.Time
+	}{
+		{"* * * * *", at(2026, 1, 15, 10, 31, 0)},
+		{"*/15 * * * *", at(2026, 1, 15, 10, 45, 0)},
+		{"0 * * * *", at(2026, 1, 15, 11, 0, 0)},
+		{"30 10 * * *", at(2026, 1, 16, 10, 30, 0)},
+		{"0-30/10 12 * * *", at(2026, 1, 15, 12, 0, 0)},
+		{"5/20 * * * *", at(2026, 1, 15, 10, 45, 0)},
+		{"0 9 * * 1-5", at(2026, 1, 16, 9, 0, 0)},
+		{"0 0 * * 0", at(2026, 1, 18, 0, 0, 0)},
+		{"0 0 * * 7", at(2026, 1, 18, 0, 0, 0)},
+		{"0 0 1,15 * *", at(2026, 2, 1, 0, 0, 0)},
+		{"0 0 31 * *", at(2026, 1, 31, 0, 0, 0)},
+		{"0 0 31 2-4 *", at(2026, 3, 31, 0, 0, 0)},
+		{"0 0 29 2 *", at(2028, 2, 29, 0, 0, 0)},
+		// Both day fields restricted: either one matching is enough
+		{"0 0 13 * 5", at(2026, 1, 16, 0, 0, 0)},
+		// One day field restricted: both must match
+		{"0 0 13 * *", at(2026, 2, 13, 0, 0, 0)},
+		{"0 0 30 2 *", time.Time{}},
+		{"@hourly", at(2026, 1, 15, 11, 0, 0)},
+		{"@daily", at(2026, 1, 16, 0, 0, 0)},
+		{"@weekly", at(2026, 1, 18, 0, 0, 0)},
+		{"@monthly", at(2026, 2, 1, 0, 0, 0)},
+		{"@yearly", at(2027, 1, 1, 0, 0, 0)},
+		{"@every 30s", at(2026, 1, 15, 10, 31, 0)},
+		{"@every 1h", at(2026, 1, 15, 11, 0, 0)},
+		{"@every 90m", at(2026, 1, 15, 12, 0, 0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			schedule, err := Parse(tt.spec)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.spec, err)
+			}
+			if got := schedule.Next(from); !got.Equal(tt.want) {
+				t.Errorf("Next(%v) = %v, want %v", from, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextInLocation(t *testing.T) {
+	location, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+	schedule, err := Parse("30 2 * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		from time.Time
+		want time.Time
+	}{
+		{"fields are local time", time.Date(2026, 1, 15, 12, 0, 0, 0, location), time.Date(2026, 1, 16, 2, 30, 0, 0, location)},
+		// 02:30 doesn't exist on the day clocks spring forward
+		{"skipped hour", time.Date(2026, 3, 7, 12, 0, 0, 0, location), time.Date(2026, 3, 9, 2, 30, 0, 0, location)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := schedule.Next(tt.from); !got.Equal(tt.want) {
+				t.Errorf("Next(%v) = %v, want %v", tt.from, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"go-gin-auth-api-starter-kit/config"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// GormLogger sends GORM query logs to slog, so they carry the same request
+// attributes as the rest of the request's log lines
+type GormLogger struct {
+	// SlowThreshold marks queries slower than this as warnings
+	SlowThreshold time.Duration
+	// Level is the minimum GORM level that gets logged
+	Level gormlogger.LogLevel
+	// Params keeps bind values in the logged SQL. They hold password hashes,
+	// emails and tokens, so this is meant for local debugging only.
+	Params bool
+}
+
+// NewGormLogger creates a GORM logger. Every query is logged at debug level,
+// slow queries (DB_SLOW_QUERY_THRESHOLD, default 200ms) at warn level and failures at error level.
+// Bind values are replaced with [REDACTED] in the logged SQL unless LOG_SQL_PARAMS is true.
+func NewGormLogger() *GormLogger {
+	return &GormLogger{
+		SlowThreshold: config.GetEnvDuration("DB_SLOW_QUERY_THRESHOLD", 200*time.Millisecond),
+		Level:         gormlogger.Info,
+		Params:        config.GetEnvBool("LOG_SQL_PARAMS", false),
+	}
+}
+
+// ParamsFilter implements gorm.ParamsFilter. GORM interpolates what it returns
+// into the SQL handed to Trace, so every value is swapped for Redacted; keeping
+// the count means each placeholder is still filled in.
+func (l *GormLogger) ParamsFilter(ctx context.Context, sql string, params ...any) (string, []any) {
+	if l.Params {
+		return sql, params
+	}
+	redacted := make([]any, len(params))
+	for i := range redacted {
+		redacted[i] = Redacted
+	}
+	return sql, redacted
+}
+
+// LogMode implements gormlogger.Interface
+func (l *GormLogger) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
+	clone := *l
+	clone.Level = level
+	return &clone
+}
+
+// Info implements gormlogger.Interface
+func (l *GormLogger) Info(ctx context.Context, msg string, args ...any) {
+	if l.Level >= gormlogger.Info {
+		slog.InfoContext(ctx, msg, slog.Any("args", args))
+	}
+}
+
+// Warn implements gormlogger.Interface
+func (l *GormLogger) Warn(ctx context.Context, msg string, args ...any) {
+	if l.Level >= gormlogger.Warn {
+		slog.WarnContext(ctx, msg, slog.Any("args", args))
+	}
+}
+
+// Error implements gormlogger.Interface
+func (l *GormLogger) Error(ctx context.Context, msg string, args ...any) {
+	if l.Level >= gormlogger.Error {
+		slog.ErrorContext(ctx, msg, slog.Any("args", args))
+	}
+}
+
+// Trace implements gormlogger.Interface and is called after every query
+func (l *GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	if l.Level <= gormlogger.Silent {
+		return
+	}
+
+	elapsed := time.Since(begin)
+	sql, rows := fc()
+	attrs := []any{
+		slog.String("sql", sql),
+		slog.Int64("rows", rows),
+		slog.Duration("duration", elapsed),
+	}
+
+	switch {
+	// A missing record is an expected outcome (e.g. 404s), not a database failure
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound) && l.Level >= gormlogger.Error:
+		slog.ErrorContext(ctx, "database query failed", append(attrs, slog.String("error", err.Error()))...)
+	case l.SlowThreshold > 0 && elapsed > l.SlowThreshold && l.Level >= gormlogger.Warn:
+		slog.WarnContext(ctx, "slow database query", attrs...)
+	case l.Level >= gormlogger.Info:
+		slog.DebugContext(ctx, "database query", attrs...)
+	}
+}
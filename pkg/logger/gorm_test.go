@@ -0,0 +1,36 @@
+package logger
+
+import (
+	"regexp"
+	"testing"
+
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// GormLogger must keep satisfying the interface GORM looks for, or bind values
+// would silently start showing up in the logs again
+var _ gorm.ParamsFilter = (*GormLogger)(nil)
+
+func TestGormLoggerParamsFilter(t *testing.T) {
+	const sql = "SELECT * FROM users WHERE email = $1 AND id = $2"
+	tests := []struct {
+		name   string
+		params bool
+		want   string
+	}{
+		{"values redacted by default", false, "SELECT * FROM users WHERE email = '[REDACTED]' AND id = '[REDACTED]'"},
+		{"values kept when enabled", true, "SELECT * FROM users WHERE email = 'a@example.com' AND id = 7"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := &GormLogger{Params: tt.params}
+			filtered, params := l.ParamsFilter(t.Context(), sql, "a@example.com", 7)
+			// This is how the postgres dialector interpolates the values for the log
+			got := gormlogger.ExplainSQL(filtered, regexp.MustCompile(`\$(\d+)`), `'`, params...)
+			if got != tt.want {
+				t.Errorf("logged SQL = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
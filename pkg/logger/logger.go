@@ -0,0 +1,95 @@
+// Package logger configures the application wide log/slog logger.
+//
+// Every log line written with a context (slog.InfoContext and friends) is
+// enriched with the request ID, user ID and route template of the request
+// that produced it, and with the current trace and span IDs, so lines can be
+// correlated across the HTTP, service and database layers.
+package logger
+
+import (
+	"context"
+	"go-gin-auth-api-starter-kit/config"
+	"go-gin-auth-api-starter-kit/pkg/requestctx"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Init installs the default slog logger based on LOG_LEVEL (debug, info, warn, error)
+// and LOG_FORMAT (json or text). It also redirects the standard log package to slog.
+func Init() {
+	slog.SetDefault(New(os.Stdout))
+	// Anything still written through the standard log package ends up as an info line
+	log.SetFlags(0)
+}
+
+// New creates a logger that writes to w using the configured level and format
+func New(w io.Writer) *slog.Logger {
+	options := &slog.HandlerOptions{Level: Level()}
+
+	var handler slog.Handler
+	if strings.EqualFold(config.GetEnv("LOG_FORMAT", "json"), "text") {
+		handler = slog.NewTextHandler(w, options)
+	} else {
+		handler = slog.NewJSONHandler(w, options)
+	}
+
+	return slog.New(contextHandler{handler})
+}
+
+// Level returns the configured minimum log level
+func Level() slog.Level {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(config.GetEnv("LOG_LEVEL", "info"))); err != nil {
+		return slog.LevelInfo
+	}
+	return level
+}
+
+// Fatal logs msg at error level and exits the process
+func Fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
+// contextHandler adds request attributes from the context to every record
+type contextHandler struct {
+	slog.Handler
+}
+
+// Handle implements slog.Handler
+func (h contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if ctx != nil {
+		info := requestctx.FromContext(ctx)
+		if info.RequestID != "" {
+			record.AddAttrs(slog.String("request_id", info.RequestID))
+		}
+		if info.UserID != 0 {
+			record.AddAttrs(slog.Uint64("user_id", uint64(info.UserID)))
+		}
+		if info.Route != "" {
+			record.AddAttrs(slog.String("route", info.Route))
+		}
+		if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+			record.AddAttrs(
+				slog.String("trace_id", spanContext.TraceID().String()),
+				slog.String("span_id", spanContext.SpanID().String()),
+			)
+		}
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+// WithAttrs implements slog.Handler
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+// WithGroup implements slog.Handler
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
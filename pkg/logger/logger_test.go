@@ -0,0 +1,53 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"go-gin-auth-api-starter-kit/pkg/requestctx"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestLogsCarryRequestAndTraceIDs(t *testing.T) {
+	t.Setenv("LOG_FORMAT", "json")
+	var out bytes.Buffer
+	log := New(&out)
+
+	spanContext := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+		SpanID:     trace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+		TraceFlags: trace.FlagsSampled,
+	})
+	ctx := requestctx.WithInfo(context.Background(), &requestctx.Info{RequestID: "req-1"})
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want map[string]any
+	}{
+		{"without a span", ctx, map[string]any{"request_id": "req-1", "trace_id": nil, "span_id": nil}},
+		{"in a span", trace.ContextWithSpanContext(ctx, spanContext), map[string]any{
+			"request_id": "req-1",
+			"trace_id":   "4bf92f3577b34da6a3ce929d0e0e4736",
+			"span_id":    "00f067aa0ba902b7",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out.Reset()
+			log.InfoContext(tt.ctx, "hello")
+
+			var line map[string]any
+			if err := json.Unmarshal(out.Bytes(), &line); err != nil {
+				t.Fatalf("log line %q: %v", out.String(), err)
+			}
+			for key, want := range tt.want {
+				if got := line[key]; got != want {
+					t.Errorf("%s = %v, want %v", key, got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package logger
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// Redacted replaces secret values in logs
+const Redacted = "[REDACTED]"
+
+// sensitiveHeaders are never logged verbatim
+var sensitiveHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"Set-Cookie":          true,
+	"X-Api-Key":           true,
+	"X-Auth-Token":        true,
+}
+
+// sensitiveKeyParts mark JSON keys whose values must be hidden (matched case-insensitively)
+var sensitiveKeyParts = []string{"password", "token", "secret", "authorization", "api_key", "apikey"}
+
+// RedactHeaders returns a loggable copy of headers with credentials replaced
+func RedactHeaders(headers http.Header) map[string]string {
+	redacted := make(map[string]string, len(headers))
+	for name, values := range headers {
+		if sensitiveHeaders[http.CanonicalHeaderKey(name)] {
+			redacted[name] = Redacted
+			continue
+		}
+		redacted[name] = strings.Join(values, ", ")
+	}
+	return redacted
+}
+
+// RedactJSON returns body with the values of password and token like keys replaced.
+// Bodies that are not valid JSON are dropped entirely, since they cannot be inspected.
+func RedactJSON(body []byte) string {
+	var value any
+	if err := json.Unmarshal(body, &value); err != nil {
+		return "[unparseable body omitted]"
+	}
+
+	redacted, err := json.Marshal(redactValue(value))
+	if err != nil {
+		return "[unparseable body omitted]"
+	}
+	return string(redacted)
+}
+
+// redactValue walks decoded JSON and hides sensitive object members
+func redactValue(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, member := range v {
+			if isSensitiveKey(key) {
+				v[key] = Redacted
+			} else {
+				v[key] = redactValue(member)
+			}
+		}
+		return v
+	case []any:
+		for i, item := range v {
+			v[i] = redactValue(item)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// isSensitiveKey reports whether a JSON key looks like it holds a credential
+func isSensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, part := range sensitiveKeyParts {
+		if strings.Contains(key, part) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+package logger
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestRedactHeaders(t *testing.T) {
+	headers := http.Header{
+		"Authorization":   {"Bearer abc"},
+		"Cookie":          {"session=1"},
+		"X-Api-Key":       {"k"},
+		"Content-Type":    {"application/json"},
+		"Accept-Language": {"en", "de"},
+	}
+	// Header names that were not canonicalized are still caught
+	headers["x-auth-token"] = []string{"t"}
+
+	want := map[string]string{
+		"Authorization":   Redacted,
+		"Cookie":          Redacted,
+		"X-Api-Key":       Redacted,
+		"x-auth-token":    Redacted,
+		"Content-Type":    "application/json",
+		"Accept-Language": "en, de",
+	}
+	got := RedactHeaders(headers)
+	if len(got) != len(want) {
+		t.Fatalf("got %d headers, want %d: %v", len(got), len(want), got)
+	}
+	for name, value := range want {
+		if got[name] != value {
+			t.Errorf("%s = %q, want %q", name, got[name], value)
+		}
+	}
+}
+
+func TestRedactJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"password", `{"email":"a@example.com","password":"hunter2"}`, `{"email":"a@example.com","password":"[REDACTED]"}`},
+		{"key case and affixes", `{"NewPassword":"x","refresh_token":"y","clientSecret":"z","API_KEY":"k"}`,
+			`{"API_KEY":"[REDACTED]","NewPassword":"[REDACTED]","clientSecret":"[REDACTED]","refresh_token":"[REDACTED]"}`},
+		{"nested objects", `{"user":{"name":"a","password":"p"}}`, `{"user":{"name":"a","password":"[REDACTED]"}}`},
+		{"objects in arrays", `[{"token":"t"},{"title":"kept"}]`, `[{"token":"[REDACTED]"},{"title":"kept"}]`},
+		{"whole sensitive subtree", `{"secrets":{"a":1}}`, `{"secrets":"[REDACTED]"}`},
+		{"values are not keys", `{"title":"my password"}`, `{"title":"my password"}`},
+		{"scalar", `"password"`, `"password"`},
+		{"not json", `password=hunter2`, `[unparseable body omitted]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RedactJSON([]byte(tt.body)); got != tt.want {
+				t.Errorf("RedactJSON(%s) = %s, want %s", tt.body, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+// Package metrics defines the Prometheus metrics exposed on /metrics
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Registry holds every application metric. A dedicated registry (instead of the
+// global default one) keeps third-party libraries from adding metrics implicitly.
+var Registry = prometheus.NewRegistry()
+
+var (
+	// HTTPRequestsTotal counts finished HTTP requests
+	HTTPRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "Total number of HTTP requests by method, route template and status code.",
+	}, []string{"method", "route", "status"})
+
+	// HTTPRequestDuration observes how long HTTP requests take
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "HTTP request latency by method, route template and status code.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// LoginAttemptsTotal counts login attempts by result (success, failure) and failure reason
+	LoginAttemptsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "auth_login_attempts_total",
+		Help: "Login attempts by result and failure reason.",
+	}, []string{"result", "reason"})
+
+	// RegistrationsTotal counts account registrations by result (success, failure)
+	RegistrationsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "auth_registrations_total",
+		Help: "Account registrations by result.",
+	}, []string{"result"})
+
+	// TokenValidationFailuresTotal counts requests rejected by the auth middleware
+	TokenValidationFailuresTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "auth_token_validation_failures_total",
+		Help: "Requests rejected by the authentication middleware, by reason.",
+	}, []string{"reason"})
+
+	// JobsProcessedTotal counts background job attempts by job type and outcome
+	// (succeeded, retried, dead, released)
+	JobsProcessedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "jobs_processed_total",
+		Help: "Background job attempts by job type and outcome.",
+	}, []string{"type", "outcome"})
+
+	// JobDuration observes how long background job attempts take
+	JobDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "job_duration_seconds",
+		Help:    "Background job attempt duration by job type.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"type"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequestsTotal,
+		HTTPRequestDuration,
+		LoginAttemptsTotal,
+		RegistrationsTotal,
+		TokenValidationFailuresTotal,
+		JobsProcessedTotal,
+		JobDuration,
+	)
+}
+
+// RegisterDB exposes connection pool statistics (open, in use, idle, wait counts) for db
+func RegisterDB(db *sql.DB, name string) error {
+	return Registry.Register(collectors.NewDBStatsCollector(db, name))
+}
+
+// Handler serves the registry in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
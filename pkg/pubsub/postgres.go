@@ -0,0 +1,86 @@
+package pubsub
+
+import (
+	"context"
+	"encoding/json"
+	"go-gin-auth-api-starter-kit/config"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// postgresChannel is the LISTEN/NOTIFY channel every topic shares
+const postgresChannel = "pubsub"
+
+// envelope carries a message and its topic through NOTIFY
+type envelope struct {
+	Topic   string          `json:"topic"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// NewPostgres returns a broker that publishes with NOTIFY and delivers what
+// its own LISTEN connection (opened with dsn) receives, including its own
+// messages. It keeps reconnecting until ctx is cancelled. Payloads must stay
+// under the 8000 byte NOTIFY limit, so messages should carry IDs rather than records.
+func NewPostgres(ctx context.Context, dsn string) *Broker {
+	b := NewMemory()
+	b.send = func(ctx context.Context, topic string, payload []byte) error {
+		data, err := json.Marshal(envelope{Topic: topic, Payload: payload})
+		if err != nil {
+			return err
+		}
+		return config.DB.WithContext(ctx).Exec("SELECT pg_notify(?, ?)", postgresChannel, string(data)).Error
+	}
+	go b.listen(ctx, dsn)
+	return b
+}
+
+// listen relays notifications to local subscribers, reconnecting with a
+// growing delay when the connection is lost
+func (b *Broker) listen(ctx context.Context, dsn string) {
+	delay := time.Second
+	for {
+		started := time.Now()
+		err := b.listenOnce(ctx, dsn)
+		if ctx.Err() != nil {
+			return
+		}
+		if time.Since(started) > time.Minute {
+			delay = time.Second
+		}
+		slog.ErrorContext(ctx, "pubsub listener disconnected", slog.Any("error", err), slog.Duration("retry_in", delay))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, 30*time.Second)
+	}
+}
+
+// listenOnce holds one LISTEN connection until it fails or ctx is cancelled
+func (b *Broker) listenOnce(ctx context.Context, dsn string) error {
+	conn, err := pgx.Connect(ctx, dsn)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+postgresChannel); err != nil {
+		return err
+	}
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		var message envelope
+		if err := json.Unmarshal([]byte(notification.Payload), &message); err != nil {
+			slog.WarnContext(ctx, "ignoring malformed pubsub message", slog.Any("error", err))
+			continue
+		}
+		b.deliver(message.Topic, message.Payload)
+	}
+}
@@ -0,0 +1,129 @@
+// Package pubsub delivers small JSON messages to the subscribers of a topic,
+// e.g. to push notifications to open event streams.
+//
+// The backend is chosen with PUBSUB_DRIVER:
+//   - "postgres": (default) Postgres LISTEN/NOTIFY, so a message published on
+//     one replica reaches subscribers on every replica
+//   - "memory":   in-process only, for a single replica
+//
+// Delivery is best effort: messages published while a replica reconnects, or
+// sent to a subscriber that isn't keeping up, are dropped. Subscribers should
+// be able to catch up from the database.
+package pubsub
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go-gin-auth-api-starter-kit/config"
+	"sync"
+)
+
+// subscriberBuffer is how many messages may wait for a subscriber before new
+// ones are dropped
+const subscriberBuffer = 16
+
+// Broker hands published messages to the subscribers of their topic
+type Broker struct {
+	mu     sync.Mutex
+	subs   map[string]map[chan []byte]struct{}
+	closed bool
+	// send publishes a message; local delivery is the default
+	send func(ctx context.Context, topic string, payload []byte) error
+}
+
+// Default is the broker used by the application. It is set by Init.
+var Default = NewMemory()
+
+// Init creates the broker selected by PUBSUB_DRIVER and makes it the Default.
+// The postgres broker listens until ctx is cancelled.
+func Init(ctx context.Context) error {
+	switch driver := config.GetEnv("PUBSUB_DRIVER", "postgres"); driver {
+	case "memory":
+		Default = NewMemory()
+	case "postgres":
+		Default = NewPostgres(ctx, config.DSN())
+	default:
+		return fmt.Errorf("unknown PUBSUB_DRIVER %q", driver)
+	}
+	return nil
+}
+
+// NewMemory returns a broker that only delivers within this process
+func NewMemory() *Broker {
+	b := &Broker{subs: make(map[string]map[chan []byte]struct{})}
+	b.send = func(_ context.Context, topic string, payload []byte) error {
+		b.deliver(topic, payload)
+		return nil
+	}
+	return b
+}
+
+// Publish sends message, marshaled as JSON, to the subscribers of topic
+func (b *Broker) Publish(ctx context.Context, topic string, message any) error {
+	payload, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	return b.send(ctx, topic, payload)
+}
+
+// Subscribe returns a channel receiving the JSON messages published to topic,
+// and a function that ends the subscription. The channel is closed when the
+// subscription ends or the broker is closed.
+func (b *Broker) Subscribe(topic string) (<-chan []byte, func()) {
+	ch := make(chan []byte, subscriberBuffer)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		close(ch)
+		return ch, func() {}
+	}
+	if b.subs[topic] == nil {
+		b.subs[topic] = make(map[chan []byte]struct{})
+	}
+	b.subs[topic][ch] = struct{}{}
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			if _, ok := b.subs[topic][ch]; ok {
+				delete(b.subs[topic], ch)
+				if len(b.subs[topic]) == 0 {
+					delete(b.subs, topic)
+				}
+				close(ch)
+			}
+		})
+	}
+}
+
+// Close ends every subscription, so long-lived streams finish when the
+// server shuts down. Later subscriptions are closed right away.
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for topic, subs := range b.subs {
+		for ch := range subs {
+			close(ch)
+		}
+		delete(b.subs, topic)
+	}
+}
+
+// deliver hands payload to the local subscribers of topic, dropping it for
+// subscribers whose buffer is full
+func (b *Broker) deliver(topic string, payload []byte) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subs[topic] {
+		select {
+		case ch <- payload:
+		default:
+		}
+	}
+}
@@ -0,0 +1,36 @@
+// Package requestctx carries per-request metadata (who is calling, from where)
+// through context.Context so that services can use it without depending on Gin.
+package requestctx
+
+import "context"
+
+type contextKey struct{}
+
+// Info describes the request currently being served
+type Info struct {
+	RequestID string
+	IP        string
+	UserAgent string
+
+	// Route is the matched route template, e.g. /api/v1/posts/:id
+	Route string
+
+	// Set by the auth middleware once the caller has been authenticated
+	UserID   uint
+	Username string
+	Role     string
+}
+
+// WithInfo returns a copy of ctx that carries info
+func WithInfo(ctx context.Context, info *Info) context.Context {
+	return context.WithValue(ctx, contextKey{}, info)
+}
+
+// FromContext returns the request info stored in ctx, or an empty Info when there is none
+// (for example in background jobs)
+func FromContext(ctx context.Context) *Info {
+	if info, ok := ctx.Value(contextKey{}).(*Info); ok && info != nil {
+		return info
+	}
+	return &Info{}
+}
@@ -4,7 +4,7 @@ import (
 	"go-gin-auth-api-starter-kit/config"
 	"go-gin-auth-api-starter-kit/models"
 	"go-gin-auth-api-starter-kit/utils"
-	"log"
+	"log/slog"
 )
 
 // SeedUsers creates initial users if they don't exist
@@ -17,7 +17,7 @@ func SeedUsers() error {
 
 	// If users exist, don't seed
 	if count > 0 {
-		log.Println("Users already exist, skipping seeding")
+		slog.Info("Users already exist, skipping seeding")
 		return nil
 	}
 
@@ -27,16 +27,19 @@ func SeedUsers() error {
 			Username: "admin",
 			Email:    "admin@example.com",
 			Password: utils.HashPasswordOrPanic("admin123"),
+			Role:     models.RoleAdmin,
 		},
 		{
 			Username: "user1",
 			Email:    "user1@example.com",
 			Password: utils.HashPasswordOrPanic("user123"),
+			Role:     models.RoleUser,
 		},
 		{
 			Username: "user2",
 			Email:    "user2@example.com",
 			Password: utils.HashPasswordOrPanic("user123"),
+			Role:     models.RoleUser,
 		},
 	}
 
@@ -45,10 +48,10 @@ func SeedUsers() error {
 		if err := config.DB.Create(&user).Error; err != nil {
 			return err
 		}
-		log.Printf("Seeded user: %s", user.Username)
+		slog.Info("Seeded user", slog.String("username", user.Username))
 	}
 
-	log.Println("Successfully seeded users")
+	slog.Info("Successfully seeded users")
 	return nil
 }
 
@@ -0,0 +1,116 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// S3Config configures the connection to an S3-compatible service
+type S3Config struct {
+	// Endpoint is the host (and port) of the service, e.g. "localhost:9000" for MinIO
+	Endpoint  string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	Region    string
+	UseSSL    bool
+}
+
+// S3 stores objects in a bucket of an S3-compatible service
+type S3 struct {
+	client *minio.Client
+	bucket string
+}
+
+// NewS3 connects to the service and creates the bucket if it doesn't exist yet
+func NewS3(ctx context.Context, cfg S3Config) (*S3, error) {
+	if cfg.Bucket == "" {
+		return nil, errors.New("S3_BUCKET is required for the s3 storage driver")
+	}
+
+	client, err := minio.New(cfg.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(cfg.AccessKey, cfg.SecretKey, ""),
+		Secure: cfg.UseSSL,
+		Region: cfg.Region,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("creating S3 client: %w", err)
+	}
+
+	exists, err := client.BucketExists(ctx, cfg.Bucket)
+	if err != nil {
+		return nil, fmt.Errorf("checking S3 bucket %q: %w", cfg.Bucket, err)
+	}
+	if !exists {
+		if err := client.MakeBucket(ctx, cfg.Bucket, minio.MakeBucketOptions{Region: cfg.Region}); err != nil {
+			return nil, fmt.Errorf("creating S3 bucket %q: %w", cfg.Bucket, err)
+		}
+	}
+
+	return &S3{client: client, bucket: cfg.Bucket}, nil
+}
+
+// Put uploads the object, in parts when it is large or of unknown size
+func (s *S3) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	_, err := s.client.PutObject(ctx, s.bucket, key, r, size, minio.PutObjectOptions{ContentType: contentType})
+	return err
+}
+
+// Get opens the object stored under key
+func (s *S3) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	object, err := s.client.GetObject(ctx, s.bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, notFound(err)
+	}
+	// GetObject is lazy; stat the object so a missing key fails here and not on the first read
+	if _, err := object.Stat(); err != nil {
+		object.Close()
+		return nil, notFound(err)
+	}
+	return object, nil
+}
+
+// Exists reports whether an object is stored under key
+func (s *S3) Exists(ctx context.Context, key string) (bool, error) {
+	_, err := s.client.StatObject(ctx, s.bucket, key, minio.StatObjectOptions{})
+	if err == nil {
+		return true, nil
+	}
+	if err = notFound(err); errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	return false, err
+}
+
+// Delete removes the object stored under key
+func (s *S3) Delete(ctx context.Context, key string) error {
+	return s.client.RemoveObject(ctx, s.bucket, key, minio.RemoveObjectOptions{})
+}
+
+// PresignGet returns a presigned GET URL for the object
+func (s *S3) PresignGet(ctx context.Context, key, disposition string, ttl time.Duration) (string, error) {
+	params := url.Values{}
+	if disposition != "" {
+		params.Set("response-content-disposition", disposition)
+	}
+	u, err := s.client.PresignedGetObject(ctx, s.bucket, key, ttl, params)
+	if err != nil {
+		return "", err
+	}
+	return u.String(), nil
+}
+
+// notFound turns the service's "no such key" error into ErrNotFound
+func notFound(err error) error {
+	if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+		return ErrNotFound
+	}
+	return err
+}
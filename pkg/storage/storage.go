@@ -0,0 +1,70 @@
+// Package storage keeps uploaded files in a pluggable blob store.
+//
+// The backend is chosen with STORAGE_DRIVER:
+//   - "local": (default) files under STORAGE_LOCAL_PATH (default ./uploads)
+//   - "s3":    an S3-compatible bucket such as AWS S3 or MinIO, configured with
+//     S3_ENDPOINT, S3_BUCKET, S3_ACCESS_KEY, S3_SECRET_KEY, S3_REGION and S3_USE_SSL
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-gin-auth-api-starter-kit/config"
+	"io"
+	"time"
+)
+
+// ErrNotFound is returned when no object is stored under a key
+var ErrNotFound = errors.New("object not found")
+
+// Storage stores opaque objects under slash separated keys such as "ab/cd/abcd..."
+type Storage interface {
+	// Put stores the contents of r under key, replacing any existing object.
+	// size is the number of bytes r will yield, or -1 when it is unknown.
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get opens the object stored under key. The caller must close it.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Exists reports whether an object is stored under key
+	Exists(ctx context.Context, key string) (bool, error)
+	// Delete removes the object stored under key. Deleting a missing object is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// Presigner is implemented by backends that can hand out their own expiring
+// download URLs, so downloads don't have to pass through the API
+type Presigner interface {
+	// PresignGet returns a URL that downloads key until ttl has passed. The
+	// response carries disposition as its Content-Disposition header.
+	PresignGet(ctx context.Context, key, disposition string, ttl time.Duration) (string, error)
+}
+
+// Default is the storage used for uploads. It is set by Init.
+var Default Storage
+
+// Init opens the backend selected by STORAGE_DRIVER and makes it the Default
+func Init(ctx context.Context) error {
+	var store Storage
+	var err error
+	switch driver := config.GetEnv("STORAGE_DRIVER", "local"); driver {
+	case "local":
+		store, err = NewLocal(config.GetEnv("STORAGE_LOCAL_PATH", "./uploads"))
+	case "s3":
+		store, err = NewS3(ctx, S3Config{
+			Endpoint:  config.GetEnv("S3_ENDPOINT", "s3.amazonaws.com"),
+			Bucket:    config.GetEnv("S3_BUCKET", ""),
+			AccessKey: config.GetEnv("S3_ACCESS_KEY", ""),
+			SecretKey: config.GetEnv("S3_SECRET_KEY", ""),
+			Region:    config.GetEnv("S3_REGION", ""),
+			UseSSL:    config.GetEnvBool("S3_USE_SSL", true),
+		})
+	default:
+		return fmt.Errorf("unknown STORAGE_DRIVER %q (expected local or s3)", driver)
+	}
+	if err != nil {
+		return err
+	}
+
+	Default = store
+	return nil
+}
@@ -0,0 +1,162 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestLocal runs the storage contract against a directory
+func TestLocal(t *testing.T) {
+	store, err := NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	testStorage(t, store)
+}
+
+// TestLocalRejectsKeysOutsideTheRoot checks that keys can't address files
+// outside the storage directory
+func TestLocalRejectsKeysOutsideTheRoot(t *testing.T) {
+	store, err := NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"../escape", "a/../../escape", "/etc/passwd", ""} {
+		if err := store.Put(context.Background(), key, strings.NewReader("x"), 1, "text/plain"); err == nil {
+			t.Errorf("Put(%q) succeeded, want an error", key)
+		}
+	}
+}
+
+// TestS3 runs the storage contract against an S3-compatible service such as
+// MinIO. It is skipped unless TEST_S3_ENDPOINT is set:
+//
+//	docker run -p 9000:9000 minio/minio server /data
+//	TEST_S3_ENDPOINT=localhost:9000 TEST_S3_ACCESS_KEY=minioadmin TEST_S3_SECRET_KEY=minioadmin go test ./pkg/storage
+func TestS3(t *testing.T) {
+	endpoint := os.Getenv("TEST_S3_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("TEST_S3_ENDPOINT is not set")
+	}
+	bucket := os.Getenv("TEST_S3_BUCKET")
+	if bucket == "" {
+		bucket = "storage-test"
+	}
+
+	store, err := NewS3(context.Background(), S3Config{
+		Endpoint:  endpoint,
+		Bucket:    bucket,
+		AccessKey: os.Getenv("TEST_S3_ACCESS_KEY"),
+		SecretKey: os.Getenv("TEST_S3_SECRET_KEY"),
+		Region:    os.Getenv("TEST_S3_REGION"),
+		UseSSL:    os.Getenv("TEST_S3_USE_SSL") == "true",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testStorage(t, store)
+
+	t.Run("presign", func(t *testing.T) {
+		ctx := context.Background()
+		key := "contract/" + t.Name()
+		if err := store.Put(ctx, key, strings.NewReader("presigned"), -1, "text/plain"); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { store.Delete(ctx, key) })
+
+		url, err := store.PresignGet(ctx, key, `attachment; filename="a.txt"`, time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(url, "response-content-disposition=") {
+			t.Errorf("presigned URL %q does not set the disposition", url)
+		}
+	})
+}
+
+// testStorage checks the behaviour every Storage must have
+func testStorage(t *testing.T, store Storage) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		key  string
+		data []byte
+		size int64
+	}{
+		{"known size", "contract/ab/cd/known", []byte("hello, world"), 12},
+		{"unknown size", "contract/ab/cd/unknown", []byte("streamed without a length"), -1},
+		{"empty", "contract/empty", []byte{}, 0},
+		{"binary", "contract/binary", []byte{0, 1, 2, 0xff, 0xfe}, 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Cleanup(func() { store.Delete(ctx, tt.key) })
+
+			if err := store.Put(ctx, tt.key, bytes.NewReader(tt.data), tt.size, "application/octet-stream"); err != nil {
+				t.Fatalf("Put: %v", err)
+			}
+			if ok, err := store.Exists(ctx, tt.key); err != nil || !ok {
+				t.Fatalf("Exists after Put = %v, %v; want true", ok, err)
+			}
+			if got := readObject(t, store, tt.key); !bytes.Equal(got, tt.data) {
+				t.Errorf("Get = %q, want %q", got, tt.data)
+			}
+
+			if err := store.Delete(ctx, tt.key); err != nil {
+				t.Fatalf("Delete: %v", err)
+			}
+			if ok, err := store.Exists(ctx, tt.key); err != nil || ok {
+				t.Errorf("Exists after Delete = %v, %v; want false", ok, err)
+			}
+		})
+	}
+
+	t.Run("put replaces", func(t *testing.T) {
+		key := "contract/replaced"
+		t.Cleanup(func() { store.Delete(ctx, key) })
+
+		for _, data := range []string{"first version", "second"} {
+			if err := store.Put(ctx, key, strings.NewReader(data), int64(len(data)), "text/plain"); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if got := readObject(t, store, key); string(got) != "second" {
+			t.Errorf("Get = %q, want the second version", got)
+		}
+	})
+
+	t.Run("missing object", func(t *testing.T) {
+		key := "contract/missing"
+		if _, err := store.Get(ctx, key); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Get = %v, want ErrNotFound", err)
+		}
+		if ok, err := store.Exists(ctx, key); err != nil || ok {
+			t.Errorf("Exists = %v, %v; want false", ok, err)
+		}
+		if err := store.Delete(ctx, key); err != nil {
+			t.Errorf("Delete = %v, want nil", err)
+		}
+	})
+}
+
+// readObject returns the content of the object stored under key
+func readObject(t *testing.T, store Storage, key string) []byte {
+	t.Helper()
+	body, err := store.Get(context.Background(), key)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	defer body.Close()
+	data, err := io.ReadAll(body)
+	if err != nil {
+		t.Fatalf("reading: %v", err)
+	}
+	return data
+}
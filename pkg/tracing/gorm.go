@@ -0,0 +1,89 @@
+package tracing
+
+import (
+	"errors"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// spanKey is where the running span is kept on the *gorm.DB statement
+const spanKey = "tracing:span"
+
+// GormPlugin creates a client span for every GORM operation, as a child of
+// the span in the statement's context (see repositories' db(ctx))
+type GormPlugin struct{}
+
+// Name implements gorm.Plugin
+func (GormPlugin) Name() string {
+	return "tracing"
+}
+
+// Initialize implements gorm.Plugin by wrapping each callback chain
+func (GormPlugin) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	hooks := []struct {
+		operation string
+		before    func(string, func(*gorm.DB)) error
+		after     func(string, func(*gorm.DB)) error
+	}{
+		{"create", callbacks.Create().Before("gorm:create").Register, callbacks.Create().After("gorm:create").Register},
+		{"query", callbacks.Query().Before("gorm:query").Register, callbacks.Query().After("gorm:query").Register},
+		{"update", callbacks.Update().Before("gorm:update").Register, callbacks.Update().After("gorm:update").Register},
+		{"delete", callbacks.Delete().Before("gorm:delete").Register, callbacks.Delete().After("gorm:delete").Register},
+		{"row", callbacks.Row().Before("gorm:row").Register, callbacks.Row().After("gorm:row").Register},
+		{"raw", callbacks.Raw().Before("gorm:raw").Register, callbacks.Raw().After("gorm:raw").Register},
+	}
+
+	for _, hook := range hooks {
+		if err := hook.before("tracing:before_"+hook.operation, startSpan(hook.operation)); err != nil {
+			return err
+		}
+		if err := hook.after("tracing:after_"+hook.operation, endSpan); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// startSpan returns a callback that opens a span for operation
+func startSpan(operation string) func(*gorm.DB) {
+	return func(tx *gorm.DB) {
+		ctx, span := Start(tx.Statement.Context, "gorm."+operation,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				semconv.DBSystemPostgreSQL,
+				semconv.DBOperationName(operation),
+			),
+		)
+		tx.Statement.Context = ctx
+		tx.InstanceSet(spanKey, span)
+	}
+}
+
+// endSpan closes the span opened by startSpan, attaching the SQL and the outcome
+func endSpan(tx *gorm.DB) {
+	value, ok := tx.InstanceGet(spanKey)
+	if !ok {
+		return
+	}
+	span, ok := value.(trace.Span)
+	if !ok {
+		return
+	}
+
+	span.SetAttributes(
+		semconv.DBQueryText(tx.Statement.SQL.String()),
+		semconv.DBCollectionName(tx.Statement.Table),
+		attribute.Int64("db.rows_affected", tx.Statement.RowsAffected),
+	)
+
+	// Not finding a record is a normal result, not a failed query
+	err := tx.Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		err = nil
+	}
+	End(span, err)
+}
@@ -0,0 +1,93 @@
+// Package tracing sets up OpenTelemetry tracing and provides helpers for
+// creating spans in the service layer.
+//
+// The exporter is chosen with OTEL_TRACES_EXPORTER:
+//   - "otlp":   send spans over OTLP/HTTP; configured with the standard
+//     OTEL_EXPORTER_OTLP_* variables (endpoint, headers, insecure, ...)
+//   - "stdout": pretty-print spans to stdout, handy for local development
+//   - "none":   (default) create no spans, but still propagate trace context
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"go-gin-auth-api-starter-kit/config"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName identifies spans created by this application's own code
+const instrumentationName = "go-gin-auth-api-starter-kit"
+
+// ServiceName returns the service name reported with every span
+func ServiceName() string {
+	return config.GetEnv("OTEL_SERVICE_NAME", config.GetEnv("APP_NAME", "go-gin-auth-api-starter-kit"))
+}
+
+// Init installs the global tracer provider and the W3C trace context propagator.
+// The returned function flushes pending spans and must be called on shutdown.
+func Init(ctx context.Context) (func(context.Context) error, error) {
+	// Always accept and forward traceparent/baggage headers, even when not exporting
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch kind := config.GetEnv("OTEL_TRACES_EXPORTER", "none"); kind {
+	case "none":
+		return func(context.Context) error { return nil }, nil
+	case "otlp":
+		exporter, err = otlptracehttp.New(ctx)
+	case "stdout":
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout), stdouttrace.WithPrettyPrint())
+	default:
+		return nil, fmt.Errorf("unknown OTEL_TRACES_EXPORTER %q (expected otlp, stdout or none)", kind)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+		resource.WithHost(),
+		resource.WithAttributes(semconv.ServiceName(ServiceName())),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// The sampler honours OTEL_TRACES_SAMPLER and OTEL_TRACES_SAMPLER_ARG
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+// Start begins a span named name as a child of the span in ctx
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, opts...)
+}
+
+// End finishes span, marking it as failed when err is not nil
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
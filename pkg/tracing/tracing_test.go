@@ -0,0 +1,103 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// keepGlobals restores the global tracer provider and propagator after the test
+func keepGlobals(t *testing.T) {
+	t.Helper()
+	provider, propagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	t.Cleanup(func() {
+		otel.SetTracerProvider(provider)
+		otel.SetTextMapPropagator(propagator)
+	})
+}
+
+func TestInitSelectsTheExporter(t *testing.T) {
+	tests := []struct {
+		exporter  string
+		exporting bool
+		wantErr   bool
+	}{
+		{"", false, false},
+		{"none", false, false},
+		{"stdout", true, false},
+		{"otlp", true, false},
+		{"zipkin", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.exporter, func(t *testing.T) {
+			keepGlobals(t)
+			otel.SetTracerProvider(noop.NewTracerProvider())
+			t.Setenv("OTEL_TRACES_EXPORTER", tt.exporter)
+			// Nothing listens here; the exporter only connects when it has spans to send
+			t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://127.0.0.1:1")
+
+			shutdown, err := Init(context.Background())
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("Init accepted an unknown exporter")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer shutdown(context.Background())
+
+			_, exporting := otel.GetTracerProvider().(*sdktrace.TracerProvider)
+			if exporting != tt.exporting {
+				t.Errorf("SDK tracer provider installed = %v, want %v", exporting, tt.exporting)
+			}
+
+			// Trace context is propagated even when nothing is exported
+			parent := trace.NewSpanContext(trace.SpanContextConfig{
+				TraceID:    trace.TraceID{1},
+				SpanID:     trace.SpanID{2},
+				TraceFlags: trace.FlagsSampled,
+			})
+			header := http.Header{}
+			otel.GetTextMapPropagator().Inject(trace.ContextWithSpanContext(context.Background(), parent), propagation.HeaderCarrier(header))
+			if header.Get("traceparent") == "" {
+				t.Error("traceparent is not propagated")
+			}
+		})
+	}
+}
+
+func TestEndRecordsErrors(t *testing.T) {
+	keepGlobals(t)
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	ctx, parent := Start(context.Background(), "parent")
+	_, failed := Start(ctx, "failed")
+	End(failed, errors.New("boom"))
+	End(parent, nil)
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("%d spans ended, want 2", len(spans))
+	}
+	if spans[0].Name() != "failed" || spans[0].Status().Code != codes.Error || spans[0].Status().Description != "boom" {
+		t.Errorf("failed span: %s, status %+v", spans[0].Name(), spans[0].Status())
+	}
+	if spans[0].Parent().SpanID() != spans[1].SpanContext().SpanID() {
+		t.Error("child span is not a child of the span in ctx")
+	}
+	if spans[1].Status().Code != codes.Unset {
+		t.Errorf("successful span status = %+v, want unset", spans[1].Status())
+	}
+}
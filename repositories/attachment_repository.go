@@ -0,0 +1,69 @@
+package repositories
+
+import (
+	"context"
+	"go-gin-auth-api-starter-kit/models"
+)
+
+// LockAttachmentContent serializes storing and releasing the object under
+// storageKey until the end of the transaction, so an upload can't reuse an
+// object that a concurrent delete is about to remove. It must be called
+// inside a transaction.
+func LockAttachmentContent(ctx context.Context, storageKey string) error {
+	return db(ctx).Exec("SELECT pg_advisory_xact_lock(hashtext(?))", "attachment:"+storageKey).Error
+}
+
+// CreateAttachment stores a new attachment record
+func CreateAttachment(ctx context.Context, attachment *models.Attachment) error {
+	return db(ctx).Omit("Post", "User").Create(attachment).Error
+}
+
+// FindPostAttachmentByHash finds an attachment of the post with the given content hash
+func FindPostAttachmentByHash(ctx context.Context, postID uint, sha256 string) (models.Attachment, error) {
+	var attachment models.Attachment
+	err := db(ctx).Preload("User").
+		Where("post_id = ? AND sha256 = ?", postID, sha256).
+		Order("id ASC").
+		First(&attachment).Error
+	return attachment, err
+}
+
+// ListPostAttachments returns the attachments of a post, oldest first
+func ListPostAttachments(ctx context.Context, postID uint) ([]models.Attachment, error) {
+	var attachments []models.Attachment
+	err := db(ctx).Preload("User").Where("post_id = ?", postID).Order("id ASC").Find(&attachments).Error
+	return attachments, err
+}
+
+// GetPostAttachment finds an attachment of a post
+func GetPostAttachment(ctx context.Context, postID, id uint) (models.Attachment, error) {
+	var attachment models.Attachment
+	err := db(ctx).Preload("User").Where("post_id = ?", postID).First(&attachment, id).Error
+	return attachment, err
+}
+
+// GetAttachmentByID finds an attachment regardless of its post
+func GetAttachmentByID(ctx context.Context, id uint) (models.Attachment, error) {
+	var attachment models.Attachment
+	err := db(ctx).First(&attachment, id).Error
+	return attachment, err
+}
+
+// DeleteAttachment removes an attachment record. The stored object is left alone.
+func DeleteAttachment(ctx context.Context, id uint) error {
+	return db(ctx).Delete(&models.Attachment{}, id).Error
+}
+
+// CountAttachmentsByStorageKey counts the attachments whose content is stored under storageKey
+func CountAttachmentsByStorageKey(ctx context.Context, storageKey string) (int64, error) {
+	var count int64
+	err := db(ctx).Model(&models.Attachment{}).Where("storage_key = ?", storageKey).Count(&count).Error
+	return count, err
+}
+
+// PostAttachmentStorageKeys returns the distinct storage keys used by a post's attachments
+func PostAttachmentStorageKeys(ctx context.Context, postID uint) ([]string, error) {
+	var keys []string
+	err := db(ctx).Model(&models.Attachment{}).Where("post_id = ?", postID).Distinct().Pluck("storage_key", &keys).Error
+	return keys, err
+}
@@ -0,0 +1,94 @@
+package repositories
+
+import (
+	"context"
+	"go-gin-auth-api-starter-kit/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AuditLogFilter narrows down an audit log query. Zero values are ignored.
+type AuditLogFilter struct {
+	Events     []string
+	ActorID    *uint
+	TargetType string
+	TargetID   string
+	IP         string
+	RequestID  string
+	From       *time.Time
+	To         *time.Time
+}
+
+// CreateAuditLog appends an entry to the audit log
+func CreateAuditLog(ctx context.Context, entry models.AuditLog) error {
+	return db(ctx).Create(&entry).Error
+}
+
+// ListAuditLogs returns one page of audit entries matching filter, newest first,
+// together with the total number of matching entries
+func ListAuditLogs(ctx context.Context, filter AuditLogFilter, page, perPage int) ([]models.AuditLog, int64, error) {
+	var total int64
+	if err := auditQuery(ctx, filter).Model(&models.AuditLog{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var entries []models.AuditLog
+	err := auditQuery(ctx, filter).
+		Order("created_at DESC, id DESC").
+		Offset((page - 1) * perPage).
+		Limit(perPage).
+		Find(&entries).Error
+	return entries, total, err
+}
+
+// EachAuditLog streams every audit entry matching filter, oldest first, in batches
+// so that large exports do not have to be held in memory
+func EachAuditLog(ctx context.Context, filter AuditLogFilter, fn func(models.AuditLog) error) error {
+	var batch []models.AuditLog
+	result := auditQuery(ctx, filter).Order("id ASC").FindInBatches(&batch, 500, func(tx *gorm.DB, _ int) error {
+		for _, entry := range batch {
+			if err := fn(entry); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return result.Error
+}
+
+// DeleteAuditLogsBefore removes entries older than cutoff and returns how many were removed
+func DeleteAuditLogsBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	result := db(ctx).Where("created_at < ?", cutoff).Delete(&models.AuditLog{})
+	return result.RowsAffected, result.Error
+}
+
+// auditQuery builds the WHERE clause shared by listing and exporting
+func auditQuery(ctx context.Context, filter AuditLogFilter) *gorm.DB {
+	query := db(ctx).Model(&models.AuditLog{})
+	if len(filter.Events) > 0 {
+		query = query.Where("event IN ?", filter.Events)
+	}
+	if filter.ActorID != nil {
+		query = query.Where("actor_id = ?", *filter.ActorID)
+	}
+	if filter.TargetType != "" {
+		query = query.Where("target_type = ?", filter.TargetType)
+	}
+	if filter.TargetID != "" {
+		query = query.Where("target_id = ?", filter.TargetID)
+	}
+	if filter.IP != "" {
+		query = query.Where("ip = ?", filter.IP)
+	}
+	if filter.RequestID != "" {
+		query = query.Where("request_id = ?", filter.RequestID)
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at < ?", *filter.To)
+	}
+	return query
+}
@@ -0,0 +1,52 @@
+package repositories
+
+import (
+	"context"
+	"go-gin-auth-api-starter-kit/models"
+
+	"gorm.io/gorm/clause"
+)
+
+// AddBookmark saves a post to a user's bookmarks; saving it again changes nothing
+func AddBookmark(ctx context.Context, userID, postID uint) error {
+	return db(ctx).Clauses(clause.OnConflict{DoNothing: true}).
+		Omit("User", "Post").
+		Create(&models.Bookmark{UserID: userID, PostID: postID}).Error
+}
+
+// RemoveBookmark removes a post from a user's bookmarks
+func RemoveBookmark(ctx context.Context, userID, postID uint) error {
+	return db(ctx).Where("user_id = ? AND post_id = ?", userID, postID).Delete(&models.Bookmark{}).Error
+}
+
+// BookmarkedPostIDs returns which of the given posts a user has bookmarked
+func BookmarkedPostIDs(ctx context.Context, userID uint, postIDs []uint) ([]uint, error) {
+	var ids []uint
+	err := db(ctx).Model(&models.Bookmark{}).
+		Where("user_id = ? AND post_id IN ?", userID, postIDs).
+		Pluck("post_id", &ids).Error
+	return ids, err
+}
+
+// ListBookmarkedPosts returns a page of the posts viewer has bookmarked,
+// most recently bookmarked first. Bookmarked posts the viewer can no longer
+// read are left out.
+func ListBookmarkedPosts(ctx context.Context, viewer Viewer, page, perPage int) ([]models.Post, int64, error) {
+	query := db(ctx).Model(&models.Post{}).
+		Scopes(visibleTo(viewer)).
+		Joins("JOIN bookmarks ON bookmarks.post_id = posts.id AND bookmarks.user_id = ?", viewer.ID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var posts []models.Post
+	err := query.Select("posts.*").
+		Preload("Author").Preload("Tags").Preload("Category").
+		Order("bookmarks.created_at DESC, posts.id DESC").
+		Offset((page - 1) * perPage).
+		Limit(perPage).
+		Find(&posts).Error
+	return posts, total, err
+}
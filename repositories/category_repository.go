@@ -0,0 +1,46 @@
+package repositories
+
+import (
+	"context"
+	"go-gin-auth-api-starter-kit/models"
+)
+
+// CreateCategory saves a new category
+func CreateCategory(ctx context.Context, category models.Category) (models.Category, error) {
+	err := db(ctx).Create(&category).Error
+	return category, err
+}
+
+// GetCategoryByID finds a category by its ID
+func GetCategoryByID(ctx context.Context, id uint) (models.Category, error) {
+	var category models.Category
+	err := db(ctx).First(&category, id).Error
+	return category, err
+}
+
+// GetCategoryBySlug finds a category by its slug
+func GetCategoryBySlug(ctx context.Context, slug string) (models.Category, error) {
+	var category models.Category
+	err := db(ctx).Where("slug = ?", slug).First(&category).Error
+	return category, err
+}
+
+// ListCategories returns every category ordered by name
+func ListCategories(ctx context.Context) ([]models.Category, error) {
+	var categories []models.Category
+	err := db(ctx).Order("name ASC").Find(&categories).Error
+	return categories, err
+}
+
+// CategoryWithDescendants returns the ID of a category and of every category below it
+func CategoryWithDescendants(ctx context.Context, id uint) ([]uint, error) {
+	var ids []uint
+	err := db(ctx).Raw(`
+		WITH RECURSIVE tree AS (
+			SELECT id FROM categories WHERE id = ?
+			UNION
+			SELECT categories.id FROM categories JOIN tree ON categories.parent_id = tree.id
+		)
+		SELECT id FROM tree`, id).Scan(&ids).Error
+	return ids, err
+}
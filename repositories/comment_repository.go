@@ -0,0 +1,90 @@
+package repositories
+
+import (
+	"context"
+	"go-gin-auth-api-starter-kit/models"
+	"time"
+)
+
+// CreateComment saves a new comment
+func CreateComment(ctx context.Context, comment models.Comment) (models.Comment, error) {
+	if err := db(ctx).Create(&comment).Error; err != nil {
+		return models.Comment{}, err
+	}
+	return GetComment(ctx, comment.PostID, comment.ID)
+}
+
+// GetComment finds a comment (that has not been deleted) on a post
+func GetComment(ctx context.Context, postID, commentID uint) (models.Comment, error) {
+	var comment models.Comment
+	err := db(ctx).Preload("User").Where("post_id = ?", postID).First(&comment, commentID).Error
+	return comment, err
+}
+
+// GetCommentThread returns a comment and all of its replies, including deleted ones so the
+// thread can be shown with placeholders. It returns gorm.ErrRecordNotFound if the
+// comment itself does not exist or has been deleted.
+func GetCommentThread(ctx context.Context, postID, commentID uint) ([]models.Comment, error) {
+	comment, err := GetComment(ctx, postID, commentID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Replies always share the thread's root, so load the root's thread and
+	// let the caller pick the subtree below the requested comment
+	rootID := comment.ID
+	if comment.RootID != nil {
+		rootID = *comment.RootID
+	}
+	return threadComments(ctx, postID, []uint{rootID})
+}
+
+// ListCommentThreads returns one page of top-level comments on a post together with
+// all their replies, oldest first, plus the total number of top-level comments
+func ListCommentThreads(ctx context.Context, postID uint, page, perPage int) ([]models.Comment, int64, error) {
+	roots := db(ctx).Unscoped().Model(&models.Comment{}).Where("post_id = ? AND parent_id IS NULL", postID)
+
+	var total int64
+	if err := roots.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var rootIDs []uint
+	err := roots.Order("created_at ASC, id ASC").
+		Offset((page-1)*perPage).
+		Limit(perPage).
+		Pluck("id", &rootIDs).Error
+	if err != nil || len(rootIDs) == 0 {
+		return nil, total, err
+	}
+
+	comments, err := threadComments(ctx, postID, rootIDs)
+	return comments, total, err
+}
+
+// threadComments loads the given top-level comments and every reply in their threads
+func threadComments(ctx context.Context, postID uint, rootIDs []uint) ([]models.Comment, error) {
+	var comments []models.Comment
+	err := db(ctx).Unscoped().
+		Preload("User").
+		Where("post_id = ?", postID).
+		Where("id IN ? OR root_id IN ?", rootIDs, rootIDs).
+		Order("created_at ASC, id ASC").
+		Find(&comments).Error
+	return comments, err
+}
+
+// UpdateCommentBody changes the body of a comment and marks it as edited
+func UpdateCommentBody(ctx context.Context, comment models.Comment, body string) (models.Comment, error) {
+	now := time.Now()
+	err := db(ctx).Model(&comment).Updates(map[string]any{"body": body, "edited_at": now}).Error
+	if err != nil {
+		return models.Comment{}, err
+	}
+	return GetComment(ctx, comment.PostID, comment.ID)
+}
+
+// DeleteComment soft-deletes a comment. Its replies are kept.
+func DeleteComment(ctx context.Context, comment models.Comment) error {
+	return db(ctx).Delete(&comment).Error
+}
@@ -0,0 +1,56 @@
+package repositories
+
+import (
+	"context"
+	"go-gin-auth-api-starter-kit/config"
+
+	"gorm.io/gorm"
+)
+
+type txKey struct{}
+
+// afterCommitKey holds the functions AfterCommit deferred in a transaction
+type afterCommitKey struct{}
+
+// db returns the connection to use for ctx: the surrounding transaction when
+// called inside Transaction, otherwise the shared connection. Either way it is
+// bound to ctx, so query logs carry the request ID and cancelled requests stop
+// their queries.
+func db(ctx context.Context) *gorm.DB {
+	if tx, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return tx.WithContext(ctx)
+	}
+	return config.DB.WithContext(ctx)
+}
+
+// Transaction runs fn inside a database transaction. Every repository function
+// called with the ctx passed to fn takes part in it. If ctx is already inside a
+// transaction, fn simply joins it.
+func Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return fn(ctx)
+	}
+
+	var afterCommit []func()
+	ctx = context.WithValue(ctx, afterCommitKey{}, &afterCommit)
+	err := config.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(context.WithValue(ctx, txKey{}, tx))
+	})
+	if err != nil {
+		return err
+	}
+	for _, f := range afterCommit {
+		f()
+	}
+	return nil
+}
+
+// AfterCommit runs f once the transaction ctx is in has been committed, and
+// not at all if it is rolled back. Outside a transaction f runs right away.
+func AfterCommit(ctx context.Context, f func()) {
+	if afterCommit, ok := ctx.Value(afterCommitKey{}).(*[]func()); ok {
+		*afterCommit = append(*afterCommit, f)
+		return
+	}
+	f()
+}
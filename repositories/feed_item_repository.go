@@ -0,0 +1,75 @@
+package repositories
+
+import (
+	"context"
+	"go-gin-auth-api-starter-kit/models"
+	"time"
+)
+
+// FeedEntry is a post in a home feed. Feeds are ordered by PublishedAt and
+// then PostID, newest first, so an entry also works as a cursor.
+type FeedEntry struct {
+	PostID      uint
+	PublishedAt time.Time
+}
+
+// FanOutPost copies a published post into the feeds of everyone following
+// its author and marks it as fanned out
+func FanOutPost(ctx context.Context, postID, authorID uint) error {
+	return Transaction(ctx, func(ctx context.Context) error {
+		if err := lockFeedAuthor(ctx, authorID); err != nil {
+			return err
+		}
+		err := db(ctx).Exec(`
+			INSERT INTO feed_items (user_id, post_id, author_id, published_at)
+			SELECT follows.follower_id, posts.id, posts.user_id, posts.published_at
+			FROM posts JOIN follows ON follows.followee_id = posts.user_id
+			WHERE posts.id = ? AND posts.user_id = ? AND posts.published_at IS NOT NULL
+			ON CONFLICT DO NOTHING`,
+			postID, authorID).Error
+		if err != nil {
+			return err
+		}
+		return db(ctx).Model(&models.Post{}).Where("id = ?", postID).UpdateColumn("fanned_out", true).Error
+	})
+}
+
+// ListHomeFeed returns up to limit entries of userID's home feed that come
+// after the entry before (from the start when nil). Fanned out posts come
+// from the user's feed items; the others are read from the posts of the
+// authors they follow. Entries are published posts, but whether the user may
+// still read them is left to the caller.
+func ListHomeFeed(ctx context.Context, userID uint, before *FeedEntry, limit int) ([]FeedEntry, error) {
+	args := map[string]any{
+		"user":      userID,
+		"published": models.PostStatusPublished,
+		"limit":     limit,
+	}
+	itemsAfter, postsAfter := "", ""
+	if before != nil {
+		itemsAfter = "AND (feed_items.published_at, feed_items.post_id) < (@at, @id)"
+		postsAfter = "AND (posts.published_at, posts.id) < (@at, @id)"
+		args["at"] = before.PublishedAt
+		args["id"] = before.PostID
+	}
+
+	var entries []FeedEntry
+	err := db(ctx).Raw(`
+		SELECT post_id, published_at FROM (
+			(SELECT feed_items.post_id, feed_items.published_at
+			FROM feed_items JOIN posts ON posts.id = feed_items.post_id
+			WHERE feed_items.user_id = @user AND posts.status = @published AND posts.deleted_at IS NULL `+itemsAfter+`
+			ORDER BY feed_items.published_at DESC, feed_items.post_id DESC
+			LIMIT @limit)
+			UNION
+			(SELECT posts.id, posts.published_at
+			FROM posts JOIN follows ON follows.followee_id = posts.user_id AND follows.follower_id = @user
+			WHERE posts.fanned_out = false AND posts.published_at IS NOT NULL
+				AND posts.status = @published AND posts.deleted_at IS NULL `+postsAfter+`
+			ORDER BY posts.published_at DESC, posts.id DESC
+			LIMIT @limit)
+		) AS entries
+		ORDER BY published_at DESC, post_id DESC
+		LIMIT @limit`, args).Scan(&entries).Error
+	return entries, err
+}
@@ -0,0 +1,127 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"go-gin-auth-api-starter-kit/models"
+
+	"gorm.io/gorm"
+)
+
+// lockFeedAuthor serializes fanning out an author's posts with people
+// following them until the end of the transaction. Without it a new follower
+// could miss a post that is being copied into feeds at the same time.
+func lockFeedAuthor(ctx context.Context, authorID uint) error {
+	return db(ctx).Exec("SELECT pg_advisory_xact_lock(hashtext(?))", fmt.Sprintf("feed:%d", authorID)).Error
+}
+
+// Follow makes followerID follow followeeID and copies up to backfill of the
+// followee's latest fanned out posts into the follower's feed. Following
+// someone twice changes nothing and reports false.
+func Follow(ctx context.Context, followerID, followeeID uint, backfill int) (bool, error) {
+	followed := false
+	err := Transaction(ctx, func(ctx context.Context) error {
+		if err := lockFeedAuthor(ctx, followeeID); err != nil {
+			return err
+		}
+		result := db(ctx).Exec(
+			"INSERT INTO follows (follower_id, followee_id, created_at) VALUES (?, ?, NOW()) ON CONFLICT DO NOTHING",
+			followerID, followeeID)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		followed = true
+		if err := adjustFollowCounts(ctx, followerID, followeeID, 1); err != nil {
+			return err
+		}
+
+		return db(ctx).Exec(`
+			INSERT INTO feed_items (user_id, post_id, author_id, published_at)
+			SELECT ?, id, user_id, published_at FROM posts
+			WHERE user_id = ? AND fanned_out AND status = ? AND published_at IS NOT NULL AND deleted_at IS NULL
+			ORDER BY published_at DESC
+			LIMIT ?
+			ON CONFLICT DO NOTHING`,
+			followerID, followeeID, models.PostStatusPublished, backfill).Error
+	})
+	return followed, err
+}
+
+// Unfollow stops followerID from following followeeID and removes the
+// followee's posts from the follower's feed. It reports false when there was
+// nothing to undo.
+func Unfollow(ctx context.Context, followerID, followeeID uint) (bool, error) {
+	unfollowed := false
+	err := Transaction(ctx, func(ctx context.Context) error {
+		result := db(ctx).Where("follower_id = ? AND followee_id = ?", followerID, followeeID).Delete(&models.Follow{})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		unfollowed = true
+		if err := adjustFollowCounts(ctx, followerID, followeeID, -1); err != nil {
+			return err
+		}
+		return db(ctx).Where("user_id = ? AND author_id = ?", followerID, followeeID).Delete(&models.FeedItem{}).Error
+	})
+	return unfollowed, err
+}
+
+// adjustFollowCounts moves the counters of both sides of a follow by delta
+func adjustFollowCounts(ctx context.Context, followerID, followeeID uint, delta int) error {
+	err := db(ctx).Unscoped().Model(&models.User{}).Where("id = ?", followeeID).
+		UpdateColumn("followers_count", gorm.Expr("followers_count + ?", delta)).Error
+	if err != nil {
+		return err
+	}
+	return db(ctx).Unscoped().Model(&models.User{}).Where("id = ?", followerID).
+		UpdateColumn("following_count", gorm.Expr("following_count + ?", delta)).Error
+}
+
+// ListFollowers returns a page of the users following userID, most recent first
+func ListFollowers(ctx context.Context, userID uint, page, perPage int) ([]models.User, int64, error) {
+	return listFollowUsers(ctx, "follows.follower_id = users.id AND follows.followee_id = ?", userID, page, perPage)
+}
+
+// ListFollowing returns a page of the users userID follows, most recent first
+func ListFollowing(ctx context.Context, userID uint, page, perPage int) ([]models.User, int64, error) {
+	return listFollowUsers(ctx, "follows.followee_id = users.id AND follows.follower_id = ?", userID, page, perPage)
+}
+
+// listFollowUsers pages through the users on one side of userID's follows
+func listFollowUsers(ctx context.Context, join string, userID uint, page, perPage int) ([]models.User, int64, error) {
+	query := db(ctx).Model(&models.User{}).Joins("JOIN follows ON "+join, userID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var users []models.User
+	err := query.Select("users.*").
+		Order("follows.created_at DESC, users.id DESC").
+		Offset((page - 1) * perPage).
+		Limit(perPage).
+		Find(&users).Error
+	return users, total, err
+}
+
+// deleteUserFollows removes every follow from and to a user, with the
+// counters of the users on the other side and the feed items they caused
+func deleteUserFollows(ctx context.Context, userID uint) error {
+	err := db(ctx).Unscoped().Model(&models.User{}).
+		Where("id IN (SELECT followee_id FROM follows WHERE follower_id = ?)", userID).
+		UpdateColumn("followers_count", gorm.Expr("followers_count - 1")).Error
+	if err != nil {
+		return err
+	}
+	err = db(ctx).Unscoped().Model(&models.User{}).
+		Where("id IN (SELECT follower_id FROM follows WHERE followee_id = ?)", userID).
+		UpdateColumn("following_count", gorm.Expr("following_count - 1")).Error
+	if err != nil {
+		return err
+	}
+	if err := db(ctx).Where("follower_id = ? OR followee_id = ?", userID, userID).Delete(&models.Follow{}).Error; err != nil {
+		return err
+	}
+	return db(ctx).Where("user_id = ? OR author_id = ?", userID, userID).Delete(&models.FeedItem{}).Error
+}
@@ -0,0 +1,144 @@
+package repositories
+
+import (
+	"context"
+	"go-gin-auth-api-starter-kit/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CreateJob queues a job. A job whose UniqueKey is already taken is not
+// queued again; CreateJob then reports false.
+func CreateJob(ctx context.Context, job *models.Job) (bool, error) {
+	result := db(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "unique_key"}},
+		DoNothing: true,
+	}).Create(job)
+	return result.RowsAffected > 0, result.Error
+}
+
+// JobFilter narrows down ListJobs; empty fields match everything
+type JobFilter struct {
+	Status string
+	Type   string
+}
+
+// ListJobs returns a page of jobs matching filter, newest first
+func ListJobs(ctx context.Context, filter JobFilter, page, perPage int) ([]models.Job, int64, error) {
+	query := db(ctx).Model(&models.Job{})
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.Type != "" {
+		query = query.Where("type = ?", filter.Type)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var jobs []models.Job
+	err := query.Order("id DESC").
+		Offset((page - 1) * perPage).
+		Limit(perPage).
+		Find(&jobs).Error
+	return jobs, total, err
+}
+
+// GetJob finds a job by ID
+func GetJob(ctx context.Context, id uint) (models.Job, error) {
+	var job models.Job
+	err := db(ctx).First(&job, id).Error
+	return job, err
+}
+
+// ClaimDueJobs takes up to limit due jobs for worker, highest priority first,
+// marks them running until now+lease and counts the attempt. Running jobs
+// whose lease ran out belonged to a worker that died, and are claimed again
+// if they have attempts left; those that used their last attempt are moved
+// to the dead-letter state in the same statement.
+func ClaimDueJobs(ctx context.Context, worker string, now time.Time, lease time.Duration, limit int) ([]models.Job, error) {
+	var ids []uint
+	err := db(ctx).Raw(`
+		WITH exhausted AS (
+			UPDATE jobs SET status = ?, finished_at = ?, locked_until = NULL, locked_by = '', last_error = ?, updated_at = ?
+			WHERE status = ? AND locked_until < ? AND attempts >= max_attempts
+		)
+		UPDATE jobs SET status = ?, attempts = attempts + 1, locked_until = ?, locked_by = ?, updated_at = ?
+		WHERE id IN (
+			SELECT id FROM jobs
+			WHERE (status = ? AND run_at <= ?) OR (status = ? AND locked_until < ? AND attempts < max_attempts)
+			ORDER BY priority DESC, run_at, id
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id`,
+		models.JobDead, now, "lease expired on the last attempt", now,
+		models.JobRunning, now,
+		models.JobRunning, now.Add(lease), worker, now,
+		models.JobPending, now, models.JobRunning, now,
+		limit,
+	).Scan(&ids).Error
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+
+	var jobs []models.Job
+	err = db(ctx).Where("id IN ?", ids).Order("priority DESC, run_at, id").Find(&jobs).Error
+	return jobs, err
+}
+
+// finishJob updates a job the worker still holds
+func finishJob(ctx context.Context, job models.Job, worker string, updates map[string]any) error {
+	updates["locked_until"] = nil
+	updates["locked_by"] = ""
+	return db(ctx).Model(&models.Job{}).
+		Where("id = ? AND status = ? AND locked_by = ?", job.ID, models.JobRunning, worker).
+		Updates(updates).Error
+}
+
+// CompleteJob marks a job the worker holds as succeeded
+func CompleteJob(ctx context.Context, job models.Job, worker string, now time.Time) error {
+	return finishJob(ctx, job, worker, map[string]any{"status": models.JobSucceeded, "finished_at": now, "last_error": ""})
+}
+
+// RetryJob puts a job the worker holds back in the queue, due at runAt
+func RetryJob(ctx context.Context, job models.Job, worker string, lastError string, runAt time.Time) error {
+	return finishJob(ctx, job, worker, map[string]any{"status": models.JobPending, "run_at": runAt, "last_error": lastError})
+}
+
+// KillJob moves a job the worker holds to the dead-letter state
+func KillJob(ctx context.Context, job models.Job, worker string, lastError string, now time.Time) error {
+	return finishJob(ctx, job, worker, map[string]any{"status": models.JobDead, "finished_at": now, "last_error": lastError})
+}
+
+// ReleaseJob gives a job the worker holds back without counting the attempt,
+// for when the worker shuts down in the middle of it
+func ReleaseJob(ctx context.Context, job models.Job, worker string) error {
+	return finishJob(ctx, job, worker, map[string]any{"status": models.JobPending, "attempts": gorm.Expr("attempts - 1")})
+}
+
+// ResurrectJob puts a dead job back in the queue with a fresh set of attempts.
+// It returns gorm.ErrRecordNotFound when there is no dead job with that ID.
+func ResurrectJob(ctx context.Context, id uint, now time.Time) error {
+	result := db(ctx).Model(&models.Job{}).
+		Where("id = ? AND status = ?", id, models.JobDead).
+		Updates(map[string]any{"status": models.JobPending, "attempts": 0, "run_at": now, "finished_at": nil})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// PruneSucceededJobs deletes jobs that succeeded before cutoff and returns
+// how many it removed
+func PruneSucceededJobs(ctx context.Context, cutoff time.Time) (int64, error) {
+	result := db(ctx).Where("status = ? AND finished_at < ?", models.JobSucceeded, cutoff).Delete(&models.Job{})
+	return result.RowsAffected, result.Error
+}
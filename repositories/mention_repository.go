@@ -0,0 +1,80 @@
+package repositories
+
+import (
+	"context"
+	"go-gin-auth-api-starter-kit/models"
+)
+
+// FindUsersByUsernames returns the users with the given usernames. Usernames
+// nobody has are left out.
+func FindUsersByUsernames(ctx context.Context, usernames []string) ([]models.User, error) {
+	if len(usernames) == 0 {
+		return nil, nil
+	}
+	var users []models.User
+	err := db(ctx).Where("username IN ?", usernames).Find(&users).Error
+	return users, err
+}
+
+// ReplacePostMentions sets the users a post mentions to exactly userIDs.
+// Mentions that stay keep their original time.
+func ReplacePostMentions(ctx context.Context, postID uint, userIDs []uint) error {
+	return Transaction(ctx, func(ctx context.Context) error {
+		removed := db(ctx).Where("post_id = ?", postID)
+		if len(userIDs) > 0 {
+			removed = removed.Where("user_id NOT IN ?", userIDs)
+		}
+		if err := removed.Delete(&models.Mention{}).Error; err != nil {
+			return err
+		}
+		for _, userID := range userIDs {
+			err := db(ctx).Exec(
+				"INSERT INTO mentions (post_id, user_id, created_at) VALUES (?, ?, NOW()) ON CONFLICT DO NOTHING",
+				postID, userID).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ListPostMentions returns the IDs of the users a post mentions
+func ListPostMentions(ctx context.Context, postID uint) ([]uint, error) {
+	var userIDs []uint
+	err := db(ctx).Model(&models.Mention{}).Where("post_id = ?", postID).Order("user_id").Pluck("user_id", &userIDs).Error
+	return userIDs, err
+}
+
+// ListMentioningPosts returns a page of the posts the viewer may read that
+// mention a user, newest first
+func ListMentioningPosts(ctx context.Context, viewer Viewer, userID uint, page, perPage int) ([]models.Post, int64, error) {
+	query := db(ctx).Model(&models.Post{}).
+		Scopes(listableBy(viewer)).
+		Joins("JOIN mentions ON mentions.post_id = posts.id AND mentions.user_id = ?", userID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var posts []models.Post
+	err := query.Select("posts.*").
+		Preload("Author").Preload("Tags").Preload("Category").
+		Order("posts.published_at DESC NULLS LAST, posts.id DESC").
+		Offset((page - 1) * perPage).
+		Limit(perPage).
+		Find(&posts).Error
+	return posts, total, err
+}
+
+// MentionNotified reports whether a user was already notified of being
+// mentioned in a post, so editing the post doesn't notify them again
+func MentionNotified(ctx context.Context, userID, postID uint) (bool, error) {
+	var count int64
+	err := db(ctx).Model(&models.Notification{}).
+		Where("user_id = ? AND post_id = ? AND type = ?", userID, postID, models.NotificationMention).
+		Limit(1).
+		Count(&count).Error
+	return count > 0, err
+}
@@ -0,0 +1,126 @@
+package repositories
+
+import (
+	"context"
+	"go-gin-auth-api-starter-kit/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CreateNotification stores a new notification
+func CreateNotification(ctx context.Context, notification *models.Notification) error {
+	return db(ctx).Omit(clause.Associations).Create(notification).Error
+}
+
+// notificationsOf starts a query for a user's notifications with what
+// responses show about them
+func notificationsOf(ctx context.Context, userID uint) *gorm.DB {
+	return db(ctx).Model(&models.Notification{}).Preload("Actor").Preload("Post").Where("notifications.user_id = ?", userID)
+}
+
+// ListNotifications returns a page of a user's notifications, newest first
+func ListNotifications(ctx context.Context, userID uint, unreadOnly bool, page, perPage int) ([]models.Notification, int64, error) {
+	query := notificationsOf(ctx, userID)
+	if unreadOnly {
+		query = query.Where("notifications.read_at IS NULL")
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var notifications []models.Notification
+	err := query.Order("notifications.id DESC").
+		Offset((page - 1) * perPage).
+		Limit(perPage).
+		Find(&notifications).Error
+	return notifications, total, err
+}
+
+// ListNotificationsAfter returns up to limit of a user's notifications with
+// an ID above afterID, oldest first
+func ListNotificationsAfter(ctx context.Context, userID, afterID uint, limit int) ([]models.Notification, error) {
+	var notifications []models.Notification
+	err := notificationsOf(ctx, userID).
+		Where("notifications.id > ?", afterID).
+		Order("notifications.id ASC").
+		Limit(limit).
+		Find(&notifications).Error
+	return notifications, err
+}
+
+// GetNotification finds one of a user's notifications
+func GetNotification(ctx context.Context, userID, id uint) (models.Notification, error) {
+	var notification models.Notification
+	err := notificationsOf(ctx, userID).First(&notification, id).Error
+	return notification, err
+}
+
+// CountUnreadNotifications counts a user's unread notifications
+func CountUnreadNotifications(ctx context.Context, userID uint) (int64, error) {
+	var count int64
+	err := db(ctx).Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", userID).Count(&count).Error
+	return count, err
+}
+
+// MarkNotificationRead marks one of a user's notifications as read. Marking
+// it again keeps the time it was first read.
+func MarkNotificationRead(ctx context.Context, userID, id uint, now time.Time) error {
+	result := db(ctx).Model(&models.Notification{}).
+		Where("id = ? AND user_id = ?", id, userID).
+		UpdateColumn("read_at", gorm.Expr("COALESCE(read_at, ?)", now))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// MarkAllNotificationsRead marks every unread notification of a user as read
+// and returns how many there were
+func MarkAllNotificationsRead(ctx context.Context, userID uint, now time.Time) (int64, error) {
+	result := db(ctx).Model(&models.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		UpdateColumn("read_at", now)
+	return result.RowsAffected, result.Error
+}
+
+// ReactionNotified reports whether a user was already notified of a
+// reaction, so taking it back and reacting again doesn't notify twice
+func ReactionNotified(ctx context.Context, userID, actorID, postID uint, emoji string) (bool, error) {
+	var count int64
+	err := db(ctx).Model(&models.Notification{}).
+		Where("user_id = ? AND actor_id = ? AND post_id = ? AND type = ? AND data->>'emoji' = ?",
+			userID, actorID, postID, models.NotificationReaction, emoji).
+		Limit(1).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// ListNotificationPreferences returns the notification types a user has
+// turned on or off
+func ListNotificationPreferences(ctx context.Context, userID uint) ([]models.NotificationPreference, error) {
+	var preferences []models.NotificationPreference
+	err := db(ctx).Where("user_id = ?", userID).Find(&preferences).Error
+	return preferences, err
+}
+
+// SetNotificationPreferences turns notification types on or off for a user
+func SetNotificationPreferences(ctx context.Context, userID uint, enabled map[string]bool) error {
+	if len(enabled) == 0 {
+		return nil
+	}
+	preferences := make([]models.NotificationPreference, 0, len(enabled))
+	for notificationType, on := range enabled {
+		preferences = append(preferences, models.NotificationPreference{UserID: userID, Type: notificationType, Enabled: on})
+	}
+	return db(ctx).Omit("User").Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "type"}},
+		DoUpdates: clause.AssignmentColumns([]string{"enabled"}),
+	}).Create(&preferences).Error
+}
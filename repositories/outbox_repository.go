@@ -0,0 +1,78 @@
+package repositories
+
+import (
+	"context"
+	"go-gin-auth-api-starter-kit/models"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+// CreateOutboxEvent stores a domain event. Call it with the ctx of the
+// transaction that makes the change the event describes.
+func CreateOutboxEvent(ctx context.Context, event *models.OutboxEvent) error {
+	return db(ctx).Create(event).Error
+}
+
+// ClaimDueOutboxEvents takes up to limit undispatched events whose next
+// attempt is due, oldest first, and moves their next attempt lease into the
+// future. Other dispatchers skip the claimed events, and a dispatcher that
+// dies mid-event only delays it until the lease runs out. An event is only
+// claimed once every earlier event of its entity was dispatched, so handlers
+// see the events of an entity in order even when one of them is retried.
+func ClaimDueOutboxEvents(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.OutboxEvent, error) {
+	var ids []uint
+	err := db(ctx).Raw(`
+		UPDATE outbox_events SET next_attempt_at = ?
+		WHERE id IN (
+			SELECT id FROM outbox_events AS event
+			WHERE dispatched_at IS NULL AND next_attempt_at <= ?
+			AND NOT EXISTS (
+				SELECT 1 FROM outbox_events AS earlier
+				WHERE earlier.aggregate_type = event.aggregate_type AND earlier.aggregate_id = event.aggregate_id
+				AND earlier.dispatched_at IS NULL AND earlier.id < event.id
+			)
+			ORDER BY id
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id`,
+		now.Add(lease), now, limit,
+	).Scan(&ids).Error
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+
+	var events []models.OutboxEvent
+	err = db(ctx).Where("id IN ?", ids).Order("id").Find(&events).Error
+	return events, err
+}
+
+// MarkEventProcessed records that handler processed an event. It reports
+// false when the handler already had, in which case it must not run again.
+// Inside a transaction, a concurrent attempt waits for this one to finish.
+func MarkEventProcessed(ctx context.Context, eventID uint, handler string, now time.Time) (bool, error) {
+	result := db(ctx).Omit("Event").Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.ProcessedEvent{EventID: eventID, Handler: handler, ProcessedAt: now})
+	return result.RowsAffected > 0, result.Error
+}
+
+// MarkOutboxEventDispatched records that every handler processed an event
+func MarkOutboxEventDispatched(ctx context.Context, id uint, attempts int, now time.Time) error {
+	return db(ctx).Model(&models.OutboxEvent{}).Where("id = ?", id).
+		Updates(map[string]any{"dispatched_at": now, "attempts": attempts, "last_error": ""}).Error
+}
+
+// RecordOutboxFailure records a failed attempt to dispatch an event and when
+// to try again
+func RecordOutboxFailure(ctx context.Context, id uint, attempts int, lastError string, next time.Time) error {
+	return db(ctx).Model(&models.OutboxEvent{}).Where("id = ?", id).
+		Updates(map[string]any{"attempts": attempts, "last_error": lastError, "next_attempt_at": next}).Error
+}
+
+// PruneOutboxEvents deletes events dispatched before cutoff, with their
+// processed records, and returns how many events it removed
+func PruneOutboxEvents(ctx context.Context, cutoff time.Time) (int64, error) {
+	result := db(ctx).Where("dispatched_at < ?", cutoff).Delete(&models.OutboxEvent{})
+	return result.RowsAffected, result.Error
+}
@@ -1,51 +1,457 @@
 package repositories
 
 import (
-	"go-gin-auth-api-starter-kit/config"
+	"context"
+	"errors"
 	"go-gin-auth-api-starter-kit/models"
+	"time"
+
+	"gorm.io/gorm"
 )
 
-// CreatePost saves a new post to the database
-func CreatePost(post models.Post) (models.Post, error) {
-	err := config.DB.Create(&post).Error
+// ErrVersionConflict is returned when a post no longer has the version the
+// caller expected, because someone else changed it in the meantime
+var ErrVersionConflict = errors.New("post version conflict")
+
+// postSchedulerLock is the Postgres advisory lock key that makes sure only
+// one replica publishes scheduled posts at a time
+const postSchedulerLock int64 = 7_301_842_001
+
+// Viewer is the user whose permissions decide which posts a query may return
+type Viewer struct {
+	// ID is zero for anonymous viewers
+	ID uint
+	// Admin viewers can see every post
+	Admin bool
+	// SharedPostID is the post a verified share link grants access to, if any
+	SharedPostID uint
+}
+
+// visibleTo limits a post query to the posts viewer is allowed to read:
+// their own posts in any status, and published posts whose visibility lets
+// them in. Unlisted posts are readable by anyone who knows their ID.
+func visibleTo(viewer Viewer) func(*gorm.DB) *gorm.DB {
+	return readableBy(viewer, true)
+}
+
+// listableBy is like visibleTo, but leaves out other users' unlisted posts
+func listableBy(viewer Viewer) func(*gorm.DB) *gorm.DB {
+	return readableBy(viewer, false)
+}
+
+// readableBy builds the visibility rules shared by visibleTo and listableBy
+func readableBy(viewer Viewer, includeUnlisted bool) func(*gorm.DB) *gorm.DB {
+	return func(query *gorm.DB) *gorm.DB {
+		if viewer.Admin {
+			return query
+		}
+
+		visibilities := []string{models.PostVisibilityPublic}
+		if includeUnlisted {
+			visibilities = append(visibilities, models.PostVisibilityUnlisted)
+		}
+		if viewer.ID != 0 {
+			visibilities = append(visibilities, models.PostVisibilityInternal)
+		}
+
+		readable := db(query.Statement.Context).Where("posts.visibility IN ?", visibilities)
+		if viewer.ID != 0 {
+			readable = readable.Or("posts.visibility = ? AND EXISTS (SELECT 1 FROM post_shares WHERE post_shares.post_id = posts.id AND post_shares.user_id = ?)",
+				models.PostVisibilityPrivate, viewer.ID)
+		}
+
+		allowed := db(query.Statement.Context).Where("posts.status = ?", models.PostStatusPublished).Where(readable)
+		if viewer.ID != 0 {
+			allowed = allowed.Or("posts.user_id = ?", viewer.ID)
+		}
+		if viewer.SharedPostID != 0 {
+			allowed = allowed.Or("posts.id = ?", viewer.SharedPostID)
+		}
+		return query.Where(allowed)
+	}
+}
+
+// PostFilter narrows down ListPosts. Zero values are ignored, except Viewer,
+// which always applies.
+type PostFilter struct {
+	Viewer Viewer
+	// Statuses keeps posts in any of these statuses
+	Statuses []string
+	// TagSlugs keeps posts that have at least one of these tags
+	TagSlugs []string
+	// CategoryIDs keeps posts in any of these categories
+	CategoryIDs []uint
+	// AuthorID keeps posts written by this user
+	AuthorID uint
+}
+
+// CreatePost saves a new post to the database and links its tags: post.Tags
+// chosen by the author and hashtags from its content
+func CreatePost(ctx context.Context, post models.Post, hashtags []models.Tag) (models.Post, error) {
+	err := Transaction(ctx, func(ctx context.Context) error {
+		// Tags are created beforehand, so only the post_tags rows need inserting
+		if err := db(ctx).Omit("Tags", "Category").Create(&post).Error; err != nil {
+			return err
+		}
+		return ReplacePostTags(ctx, post.ID, post.Tags, hashtags)
+	})
+	if err != nil {
+		return models.Post{}, err
+	}
+	return GetPostByID(ctx, post.ID)
+}
+
+// ListPosts returns the posts matching filter
+func ListPosts(ctx context.Context, filter PostFilter) ([]models.Post, error) {
+	var posts []models.Post
+	err := filteredPosts(ctx, filter).Find(&posts).Error
+	return posts, err
+}
+
+// ListLatestPosts returns up to limit posts matching filter, most recently published first
+func ListLatestPosts(ctx context.Context, filter PostFilter, limit int) ([]models.Post, error) {
+	var posts []models.Post
+	err := filteredPosts(ctx, filter).
+		Order("posts.published_at DESC NULLS LAST, posts.id DESC").
+		Limit(limit).
+		Find(&posts).Error
+	return posts, err
+}
+
+// PostsLastModified returns when any post last changed, counting deletions.
+// Trashed posts are included so that removing a post also moves the time forward.
+func PostsLastModified(ctx context.Context) (time.Time, error) {
+	var lastModified *time.Time
+	err := db(ctx).Unscoped().Model(&models.Post{}).
+		Select("MAX(GREATEST(updated_at, deleted_at))").
+		Scan(&lastModified).Error
+	if err != nil || lastModified == nil {
+		return time.Time{}, err
+	}
+	return *lastModified, nil
+}
+
+// filteredPosts builds the query shared by ListPosts and ListLatestPosts
+func filteredPosts(ctx context.Context, filter PostFilter) *gorm.DB {
+	query := db(ctx).Scopes(listableBy(filter.Viewer)).Preload("Author").Preload("Tags").Preload("Category")
+
+	if len(filter.Statuses) > 0 {
+		query = query.Where("posts.status IN ?", filter.Statuses)
+	}
+
+	if len(filter.TagSlugs) > 0 {
+		query = query.Where("posts.id IN (?)",
+			db(ctx).Table("post_tags").
+				Select("post_tags.post_id").
+				Joins("JOIN tags ON tags.id = post_tags.tag_id").
+				Where("tags.slug IN ?", filter.TagSlugs))
+	}
+	if len(filter.CategoryIDs) > 0 {
+		query = query.Where("posts.category_id IN ?", filter.CategoryIDs)
+	}
+	if filter.AuthorID != 0 {
+		query = query.Where("posts.user_id = ?", filter.AuthorID)
+	}
+	return query
+}
+
+// GetPostByID finds a post by its ID, regardless of who may read it
+func GetPostByID(ctx context.Context, id uint) (models.Post, error) {
+	var post models.Post
+	err := db(ctx).Preload("Author").Preload("Tags").Preload("Category").First(&post, id).Error
+	return post, err
+}
+
+// GetVisiblePostByID finds a post by its ID if viewer may read it.
+// Posts the viewer may not see are reported as gorm.ErrRecordNotFound.
+func GetVisiblePostByID(ctx context.Context, viewer Viewer, id uint) (models.Post, error) {
+	var post models.Post
+	err := db(ctx).Scopes(visibleTo(viewer)).Preload("Author").Preload("Tags").Preload("Category").First(&post, id).Error
 	return post, err
 }
 
-// GetPostByID finds a post by its ID
-func GetPostByID(id uint) (models.Post, error) {
+// ListPostsByIDs returns the posts with the given IDs that viewer may list,
+// in no particular order
+func ListPostsByIDs(ctx context.Context, viewer Viewer, ids []uint) ([]models.Post, error) {
+	var posts []models.Post
+	err := db(ctx).Scopes(listableBy(viewer)).Preload("Author").Preload("Tags").Preload("Category").
+		Where("posts.id IN ?", ids).Find(&posts).Error
+	return posts, err
+}
+
+// GetVisiblePostBySlug finds a post by its current slug if viewer may read it
+func GetVisiblePostBySlug(ctx context.Context, viewer Viewer, slug string) (models.Post, error) {
 	var post models.Post
-	err := config.DB.First(&post, id).Error
+	err := db(ctx).Scopes(visibleTo(viewer)).Preload("Author").Preload("Tags").Preload("Category").
+		Where("posts.slug = ?", slug).First(&post).Error
 	return post, err
 }
 
 // Delete post
-func DeletePost(id uint) error {
+// It fails with ErrVersionConflict unless the post is still at version
+func DeletePost(ctx context.Context, id uint, version int) error {
 	// First check if post exists
-	_, err := GetPostByID(id)
+	_, err := GetPostByID(ctx, id)
 	if err != nil {
 		return err
 	}
 
-	// If post exists, proceed with deletion
-	err = config.DB.Delete(&models.Post{}, id).Error
-	return err
+	// If post exists, soft-delete it together with its comments
+	// Both get the same deletion time, so the comments can be told apart from
+	// ones that had already been deleted individually
+	return Transaction(ctx, func(ctx context.Context) error {
+		now := time.Now()
+		result := db(ctx).Model(&models.Post{}).
+			Where("id = ? AND version = ?", id, version).
+			UpdateColumns(map[string]any{"deleted_at": now, "version": gorm.Expr("version + 1")})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrVersionConflict
+		}
+		return db(ctx).Model(&models.Comment{}).Where("post_id = ?", id).UpdateColumn("deleted_at", now).Error
+	})
 }
 
-// UpdatePost updates an existing post
-func UpdatePost(id uint, post models.Post) (models.Post, error) {
+// UpdatePost replaces the title, slug, content (with its format and rendering),
+// category, visibility and tags of a post: post.Tags chosen by the author and
+// hashtags from its content.
+// Empty values are written too, so clients can clear a field.
+// The update is a compare-and-swap on version: it fails with ErrVersionConflict
+// unless the post is still at version, and moves it to the next version.
+func UpdatePost(ctx context.Context, id uint, version int, post models.Post, hashtags []models.Tag) (models.Post, error) {
 	// First check if post exists
-	_, err := GetPostByID(id)
+	_, err := GetPostByID(ctx, id)
 	if err != nil {
 		return models.Post{}, err
 	}
 
-	// Update the post
-	err = config.DB.Model(&models.Post{}).Where("id = ?", id).Updates(post).Error
+	err = Transaction(ctx, func(ctx context.Context) error {
+		// Update the post
+		post.Version = version + 1
+		result := db(ctx).Model(&models.Post{}).
+			Where("id = ? AND version = ?", id, version).
+			Select("Title", "Slug", "Content", "ContentFormat", "ContentHTML", "Excerpt", "ReadingTime", "CategoryID", "Visibility", "Version").
+			Updates(post)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrVersionConflict
+		}
+
+		return ReplacePostTags(ctx, id, post.Tags, hashtags)
+	})
 	if err != nil {
 		return models.Post{}, err
 	}
 
 	// Fetch the updated post
-	updatedPost, err := GetPostByID(id)
+	updatedPost, err := GetPostByID(ctx, id)
 	return updatedPost, err
 }
+
+// SetPostContent overwrites the title, slug and content of a post with those
+// of post, together with the content rendering, including empty values.
+// It fails with ErrVersionConflict unless the post is still at version.
+func SetPostContent(ctx context.Context, id uint, version int, post models.Post) error {
+	result := db(ctx).Model(&models.Post{}).
+		Where("id = ? AND version = ?", id, version).
+		Updates(map[string]any{
+			"title":        post.Title,
+			"slug":         post.Slug,
+			"content":      post.Content,
+			"content_html": post.ContentHTML,
+			"excerpt":      post.Excerpt,
+			"reading_time": post.ReadingTime,
+			"version":      version + 1,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrVersionConflict
+	}
+	return nil
+}
+
+// ListUnrenderedPosts returns up to limit posts with an ID above afterID whose
+// content has never been rendered, including trashed posts
+func ListUnrenderedPosts(ctx context.Context, afterID uint, limit int) ([]models.Post, error) {
+	var posts []models.Post
+	err := db(ctx).Unscoped().
+		Where("id > ? AND content_html = '' AND content <> ''", afterID).
+		Order("id ASC").
+		Limit(limit).
+		Find(&posts).Error
+	return posts, err
+}
+
+// SetPostRendering stores the rendering of a post's content. It is a cache
+// of the content, so neither the version nor updated_at change.
+func SetPostRendering(ctx context.Context, post models.Post) error {
+	return db(ctx).Unscoped().Model(&models.Post{}).
+		Where("id = ?", post.ID).
+		UpdateColumns(map[string]any{
+			"content_html": post.ContentHTML,
+			"excerpt":      post.Excerpt,
+			"reading_time": post.ReadingTime,
+		}).Error
+}
+
+// SetPostStatus moves a post from status from to the status in post, together
+// with its PublishedAt and ScheduledAt. It returns false when the post is no
+// longer in status from, e.g. because the scheduler published it meanwhile.
+func SetPostStatus(ctx context.Context, id uint, from string, post models.Post) (bool, error) {
+	result := db(ctx).Model(&models.Post{}).
+		Where("id = ? AND status = ?", id, from).
+		Updates(map[string]any{
+			"status":       post.Status,
+			"published_at": post.PublishedAt,
+			"scheduled_at": post.ScheduledAt,
+			"version":      gorm.Expr("version + 1"),
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+// PublishDuePosts publishes every scheduled post whose time has come and returns
+// them, with only their ID and UserID set. It holds an advisory lock for the
+// duration of the transaction, so when several replicas run the scheduler only
+// one of them does the work.
+func PublishDuePosts(ctx context.Context, now time.Time) ([]models.Post, error) {
+	var posts []models.Post
+	err := Transaction(ctx, func(ctx context.Context) error {
+		var locked bool
+		if err := db(ctx).Raw("SELECT pg_try_advisory_xact_lock(?)", postSchedulerLock).Scan(&locked).Error; err != nil {
+			return err
+		}
+		if !locked {
+			return nil
+		}
+
+		return db(ctx).Raw(`
+			UPDATE posts
+			SET status = ?, published_at = COALESCE(published_at, scheduled_at), scheduled_at = NULL,
+				version = version + 1, updated_at = ?
+			WHERE status = ? AND scheduled_at <= ? AND deleted_at IS NULL
+			RETURNING id, user_id`,
+			models.PostStatusPublished, now, models.PostStatusScheduled, now,
+		).Scan(&posts).Error
+	})
+	return posts, err
+}
+
+// BumpPostVersions moves the posts to their next version, for changes such as
+// bulk retagging that don't go through UpdatePost
+func BumpPostVersions(ctx context.Context, ids []uint) error {
+	return db(ctx).Model(&models.Post{}).
+		Where("id IN ?", ids).
+		Updates(map[string]any{"version": gorm.Expr("version + 1")}).Error
+}
+
+// ExistingPostIDs returns which of ids belong to posts that exist
+func ExistingPostIDs(ctx context.Context, ids []uint) ([]uint, error) {
+	var existing []uint
+	err := db(ctx).Model(&models.Post{}).Where("id IN ?", ids).Pluck("id", &existing).Error
+	return existing, err
+}
+
+// ListTrashedPosts returns a page of soft-deleted posts, most recently deleted
+// first. Viewers other than admins only see their own posts.
+func ListTrashedPosts(ctx context.Context, viewer Viewer, page, perPage int) ([]models.Post, int64, error) {
+	query := db(ctx).Unscoped().Model(&models.Post{}).Where("posts.deleted_at IS NOT NULL")
+	if !viewer.Admin {
+		query = query.Where("posts.user_id = ?", viewer.ID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var posts []models.Post
+	err := query.Preload("Author").Preload("Tags").Preload("Category").
+		Order("posts.deleted_at DESC, posts.id DESC").
+		Offset((page - 1) * perPage).
+		Limit(perPage).
+		Find(&posts).Error
+	return posts, total, err
+}
+
+// GetTrashedPost finds a soft-deleted post by its ID
+func GetTrashedPost(ctx context.Context, id uint) (models.Post, error) {
+	var post models.Post
+	err := db(ctx).Unscoped().Where("deleted_at IS NOT NULL").First(&post, id).Error
+	return post, err
+}
+
+// RestorePost brings a soft-deleted post back together with the comments that
+// were deleted along with it (those with the very same deletion time)
+func RestorePost(ctx context.Context, post models.Post) error {
+	return Transaction(ctx, func(ctx context.Context) error {
+		err := db(ctx).Unscoped().Model(&models.Post{}).
+			Where("id = ?", post.ID).
+			UpdateColumns(map[string]any{"deleted_at": nil, "updated_at": time.Now(), "version": gorm.Expr("version + 1")}).Error
+		if err != nil {
+			return err
+		}
+		return db(ctx).Unscoped().Model(&models.Comment{}).
+			Where("post_id = ? AND deleted_at = ?", post.ID, post.DeletedAt.Time).
+			UpdateColumn("deleted_at", nil).Error
+	})
+}
+
+// PurgePost permanently deletes a post (soft-deleted or not) with its
+// comments, revisions, shares, slug aliases, attachment records and tag links
+func PurgePost(ctx context.Context, id uint) error {
+	return Transaction(ctx, func(ctx context.Context) error {
+		if err := db(ctx).Exec("DELETE FROM post_tags WHERE post_id = ?", id).Error; err != nil {
+			return err
+		}
+		if err := db(ctx).Where("post_id = ?", id).Delete(&models.PostShare{}).Error; err != nil {
+			return err
+		}
+		if err := db(ctx).Where("post_id = ?", id).Delete(&models.PostSlugAlias{}).Error; err != nil {
+			return err
+		}
+		if err := db(ctx).Where("post_id = ?", id).Delete(&models.Attachment{}).Error; err != nil {
+			return err
+		}
+		if err := db(ctx).Where("post_id = ?", id).Delete(&models.FeedItem{}).Error; err != nil {
+			return err
+		}
+		if err := deletePostReactions(ctx, id); err != nil {
+			return err
+		}
+		if err := db(ctx).Where("post_id = ?", id).Delete(&models.Bookmark{}).Error; err != nil {
+			return err
+		}
+		if err := db(ctx).Where("post_id = ?", id).Delete(&models.Mention{}).Error; err != nil {
+			return err
+		}
+		if err := db(ctx).Unscoped().Where("post_id = ?", id).Delete(&models.Comment{}).Error; err != nil {
+			return err
+		}
+		if err := db(ctx).Where("post_id = ?", id).Delete(&models.PostRevision{}).Error; err != nil {
+			return err
+		}
+		result := db(ctx).Unscoped().Delete(&models.Post{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+// TrashedPostIDsBefore returns the IDs of posts soft-deleted before cutoff
+func TrashedPostIDsBefore(ctx context.Context, cutoff time.Time) ([]uint, error) {
+	var ids []uint
+	err := db(ctx).Unscoped().Model(&models.Post{}).
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).
+		Pluck("id", &ids).Error
+	return ids, err
+}
@@ -0,0 +1,28 @@
+package repositories
+
+import (
+	"context"
+	"go-gin-auth-api-starter-kit/models"
+
+	"gorm.io/gorm/clause"
+)
+
+// AddPostShare gives a user read access to a private post; sharing twice is a no-op
+func AddPostShare(ctx context.Context, postID, userID uint) error {
+	share := models.PostShare{PostID: postID, UserID: userID}
+	return db(ctx).Omit("Post", "User").Clauses(clause.OnConflict{DoNothing: true}).Create(&share).Error
+}
+
+// RemovePostShare takes a user's access to a private post away.
+// It reports whether the post was shared with the user.
+func RemovePostShare(ctx context.Context, postID, userID uint) (bool, error) {
+	result := db(ctx).Where("post_id = ? AND user_id = ?", postID, userID).Delete(&models.PostShare{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// ListPostShares returns the users a post is shared with, oldest share first
+func ListPostShares(ctx context.Context, postID uint) ([]models.PostShare, error) {
+	var shares []models.PostShare
+	err := db(ctx).Preload("User").Where("post_id = ?", postID).Order("created_at ASC").Find(&shares).Error
+	return shares, err
+}
@@ -0,0 +1,105 @@
+package repositories
+
+import (
+	"context"
+	"go-gin-auth-api-starter-kit/models"
+	"time"
+)
+
+// AddReaction records a user's reaction to a post and counts it. Reacting
+// twice with the same emoji changes nothing and reports false.
+func AddReaction(ctx context.Context, postID, userID uint, emoji string) (bool, error) {
+	added := false
+	err := Transaction(ctx, func(ctx context.Context) error {
+		result := db(ctx).Exec(
+			"INSERT INTO post_reactions (post_id, user_id, emoji, created_at) VALUES (?, ?, ?, ?) ON CONFLICT DO NOTHING",
+			postID, userID, emoji, time.Now())
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		added = true
+		// The upsert locks the counter row, so concurrent reactions are counted one after another
+		return db(ctx).Exec(
+			"INSERT INTO post_reaction_counts (post_id, emoji, count) VALUES (?, ?, 1) "+
+				"ON CONFLICT (post_id, emoji) DO UPDATE SET count = post_reaction_counts.count + 1",
+			postID, emoji).Error
+	})
+	return added, err
+}
+
+// RemoveReaction takes back a user's reaction to a post and uncounts it.
+// It reports false when there was no such reaction.
+func RemoveReaction(ctx context.Context, postID, userID uint, emoji string) (bool, error) {
+	removed := false
+	err := Transaction(ctx, func(ctx context.Context) error {
+		result := db(ctx).Where("post_id = ? AND user_id = ? AND emoji = ?", postID, userID, emoji).Delete(&models.PostReaction{})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		removed = true
+		return db(ctx).Exec(
+			"UPDATE post_reaction_counts SET count = count - 1 WHERE post_id = ? AND emoji = ?",
+			postID, emoji).Error
+	})
+	return removed, err
+}
+
+// ListReactionCounts returns the non-zero reaction counts of the given posts
+func ListReactionCounts(ctx context.Context, postIDs []uint) ([]models.PostReactionCount, error) {
+	var counts []models.PostReactionCount
+	err := db(ctx).Where("post_id IN ? AND count > 0", postIDs).Order("post_id ASC, emoji ASC").Find(&counts).Error
+	return counts, err
+}
+
+// ListUserReactions returns a user's reactions to the given posts
+func ListUserReactions(ctx context.Context, userID uint, postIDs []uint) ([]models.PostReaction, error) {
+	var reactions []models.PostReaction
+	err := db(ctx).Where("user_id = ? AND post_id IN ?", userID, postIDs).Find(&reactions).Error
+	return reactions, err
+}
+
+// ListTopReactedPosts returns up to limit posts matching filter with the most
+// reactions, most reacted first. With a non-zero since only reactions from
+// then on count; otherwise all of them do.
+func ListTopReactedPosts(ctx context.Context, filter PostFilter, since time.Time, limit int) ([]models.Post, error) {
+	totals := db(ctx).Model(&models.PostReactionCount{}).
+		Select("post_id, SUM(count) AS total").
+		Group("post_id")
+	if !since.IsZero() {
+		totals = db(ctx).Model(&models.PostReaction{}).
+			Select("post_id, COUNT(*) AS total").
+			Where("created_at >= ?", since).
+			Group("post_id")
+	}
+
+	var posts []models.Post
+	err := filteredPosts(ctx, filter).
+		Select("posts.*").
+		Joins("JOIN (?) AS reaction_totals ON reaction_totals.post_id = posts.id", totals).
+		Where("reaction_totals.total > 0").
+		Order("reaction_totals.total DESC, posts.id DESC").
+		Limit(limit).
+		Find(&posts).Error
+	return posts, err
+}
+
+// deleteUserReactions removes every reaction of a user and uncounts them
+func deleteUserReactions(ctx context.Context, userID uint) error {
+	err := db(ctx).Exec(
+		"UPDATE post_reaction_counts SET count = post_reaction_counts.count - r.n "+
+			"FROM (SELECT post_id, emoji, COUNT(*) AS n FROM post_reactions WHERE user_id = ? GROUP BY post_id, emoji) AS r "+
+			"WHERE post_reaction_counts.post_id = r.post_id AND post_reaction_counts.emoji = r.emoji",
+		userID).Error
+	if err != nil {
+		return err
+	}
+	return db(ctx).Where("user_id = ?", userID).Delete(&models.PostReaction{}).Error
+}
+
+// deletePostReactions removes every reaction to a post along with its counts
+func deletePostReactions(ctx context.Context, postID uint) error {
+	if err := db(ctx).Where("post_id = ?", postID).Delete(&models.PostReaction{}).Error; err != nil {
+		return err
+	}
+	return db(ctx).Where("post_id = ?", postID).Delete(&models.PostReactionCount{}).Error
+}
@@ -0,0 +1,59 @@
+package repositories
+
+import (
+	"context"
+	"go-gin-auth-api-starter-kit/models"
+)
+
+// CreatePostRevision saves the next revision of a post. It must run in the
+// same transaction that changed the post: the row lock taken by that change
+// keeps concurrent updates from picking the same revision number.
+func CreatePostRevision(ctx context.Context, revision models.PostRevision) (models.PostRevision, error) {
+	err := db(ctx).Model(&models.PostRevision{}).
+		Where("post_id = ?", revision.PostID).
+		Select("COALESCE(MAX(number), 0) + 1").
+		Scan(&revision.Number).Error
+	if err != nil {
+		return models.PostRevision{}, err
+	}
+
+	err = db(ctx).Omit("Post", "Editor").Create(&revision).Error
+	return revision, err
+}
+
+// ListPostRevisions returns a page of a post's revisions, newest first
+func ListPostRevisions(ctx context.Context, postID uint, page, perPage int) ([]models.PostRevision, int64, error) {
+	query := db(ctx).Model(&models.PostRevision{}).Where("post_id = ?", postID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var revisions []models.PostRevision
+	err := query.Preload("Editor").
+		Order("number DESC").
+		Offset((page - 1) * perPage).
+		Limit(perPage).
+		Find(&revisions).Error
+	return revisions, total, err
+}
+
+// GetPostRevision finds a revision of a post by its number
+func GetPostRevision(ctx context.Context, postID uint, number int) (models.PostRevision, error) {
+	var revision models.PostRevision
+	err := db(ctx).Preload("Editor").
+		Where("post_id = ? AND number = ?", postID, number).
+		First(&revision).Error
+	return revision, err
+}
+
+// PrunePostRevisions keeps only the newest keep revisions of a post
+func PrunePostRevisions(ctx context.Context, postID uint, keep int) (int64, error) {
+	result := db(ctx).Exec(`
+		DELETE FROM post_revisions
+		WHERE post_id = ? AND number <= (SELECT MAX(number) FROM post_revisions WHERE post_id = ?) - ?`,
+		postID, postID, keep,
+	)
+	return result.RowsAffected, result.Error
+}
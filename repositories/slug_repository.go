@@ -0,0 +1,66 @@
+package repositories
+
+import (
+	"context"
+	"go-gin-auth-api-starter-kit/models"
+)
+
+// LockSlug serializes slug assignment for slugs built from base until the
+// end of the transaction, so two posts with the same title can't both take
+// the same free slug. It must be called inside a transaction.
+func LockSlug(ctx context.Context, base string) error {
+	return db(ctx).Exec("SELECT pg_advisory_xact_lock(hashtext(?))", "post-slug:"+base).Error
+}
+
+// TakenSlugs returns the slugs and slug aliases of posts other than
+// exceptPostID that are base itself or start with base followed by a dash.
+// Trashed posts keep their slugs, so they are included.
+func TakenSlugs(ctx context.Context, base string, exceptPostID uint) ([]string, error) {
+	// Slugs only contain a-z, 0-9 and dashes, so base needs no LIKE escaping
+	pattern := base + "-%"
+
+	var slugs []string
+	err := db(ctx).Raw(`
+		SELECT slug FROM posts WHERE id <> ? AND (slug = ? OR slug LIKE ?)
+		UNION
+		SELECT slug FROM post_slug_aliases WHERE post_id <> ? AND (slug = ? OR slug LIKE ?)`,
+		exceptPostID, base, pattern, exceptPostID, base, pattern,
+	).Scan(&slugs).Error
+	return slugs, err
+}
+
+// AddSlugAlias keeps slug as a redirect to the post
+func AddSlugAlias(ctx context.Context, postID uint, slug string) error {
+	alias := models.PostSlugAlias{Slug: slug, PostID: postID}
+	return db(ctx).Omit("Post").Create(&alias).Error
+}
+
+// RemoveSlugAlias drops an alias of the post, e.g. when the post takes the slug back
+func RemoveSlugAlias(ctx context.Context, postID uint, slug string) error {
+	return db(ctx).Where("post_id = ? AND slug = ?", postID, slug).Delete(&models.PostSlugAlias{}).Error
+}
+
+// GetSlugAlias finds the alias with the given slug
+func GetSlugAlias(ctx context.Context, slug string) (models.PostSlugAlias, error) {
+	var alias models.PostSlugAlias
+	err := db(ctx).Where("slug = ?", slug).First(&alias).Error
+	return alias, err
+}
+
+// ListPostsWithoutSlug returns up to limit posts with an ID above afterID that
+// have no slug yet, including trashed posts
+func ListPostsWithoutSlug(ctx context.Context, afterID uint, limit int) ([]models.Post, error) {
+	var posts []models.Post
+	err := db(ctx).Unscoped().
+		Where("id > ? AND (slug IS NULL OR slug = '')", afterID).
+		Order("id ASC").
+		Limit(limit).
+		Find(&posts).Error
+	return posts, err
+}
+
+// SetPostSlug stores a slug for a post that has none. Like the content
+// rendering, it is derived data, so neither the version nor updated_at change.
+func SetPostSlug(ctx context.Context, id uint, slug string) error {
+	return db(ctx).Unscoped().Model(&models.Post{}).Where("id = ?", id).UpdateColumn("slug", slug).Error
+}
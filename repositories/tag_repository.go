@@ -0,0 +1,127 @@
+package repositories
+
+import (
+	"context"
+	"go-gin-auth-api-starter-kit/models"
+
+	"gorm.io/gorm/clause"
+)
+
+// TagWithCount is a tag together with the number of posts using it
+type TagWithCount struct {
+	models.Tag
+	PostCount int64
+}
+
+// FindOrCreateTags returns the tags for the given (already normalized) slugs,
+// creating the missing ones. names supplies the display name for new tags.
+func FindOrCreateTags(ctx context.Context, slugs []string, names map[string]string) ([]models.Tag, error) {
+	if len(slugs) == 0 {
+		return []models.Tag{}, nil
+	}
+
+	newTags := make([]models.Tag, 0, len(slugs))
+	for _, slug := range slugs {
+		newTags = append(newTags, models.Tag{Name: names[slug], Slug: slug})
+	}
+
+	// Concurrent requests may create the same tag, so let the unique index decide
+	if err := db(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&newTags).Error; err != nil {
+		return nil, err
+	}
+
+	var tags []models.Tag
+	err := db(ctx).Where("slug IN ?", slugs).Order("slug").Find(&tags).Error
+	return tags, err
+}
+
+// ListTagsWithCounts returns every tag with the number of posts using it that
+// viewer can list, most used first. Other users' drafts and posts the viewer
+// can't read are not counted, so the counts match what ListPosts returns.
+func ListTagsWithCounts(ctx context.Context, viewer Viewer) ([]TagWithCount, error) {
+	listed := db(ctx).Model(&models.Post{}).Select("posts.id").Scopes(listableBy(viewer))
+
+	var tags []TagWithCount
+	err := db(ctx).Model(&models.Tag{}).
+		Select("tags.*, COUNT(listed.id) AS post_count").
+		Joins("LEFT JOIN post_tags ON post_tags.tag_id = tags.id").
+		Joins("LEFT JOIN (?) AS listed ON listed.id = post_tags.post_id", listed).
+		Group("tags.id").
+		Order("post_count DESC, tags.slug ASC").
+		Scan(&tags).Error
+	return tags, err
+}
+
+// ReplacePostTags sets the tags of a post to exactly manual, the ones its
+// author chose, and hashtags, the ones from its content. A tag in both counts
+// as chosen, so it stays when the hashtag is edited out.
+func ReplacePostTags(ctx context.Context, postID uint, manual, hashtags []models.Tag) error {
+	if err := db(ctx).Where("post_id = ?", postID).Delete(&models.PostTag{}).Error; err != nil {
+		return err
+	}
+	if err := AddTagsToPosts(ctx, []uint{postID}, manual); err != nil {
+		return err
+	}
+	return insertPostTags(ctx, []uint{postID}, hashtags, models.TagSourceHashtag)
+}
+
+// ReplacePostHashtags sets the tags a post has from its content to exactly
+// tags, leaving the ones its author chose alone
+func ReplacePostHashtags(ctx context.Context, postID uint, tags []models.Tag) error {
+	err := db(ctx).Where("post_id = ? AND source = ?", postID, models.TagSourceHashtag).Delete(&models.PostTag{}).Error
+	if err != nil {
+		return err
+	}
+	return insertPostTags(ctx, []uint{postID}, tags, models.TagSourceHashtag)
+}
+
+// ListPostTags returns the tags of a post that came from source, by slug
+func ListPostTags(ctx context.Context, postID uint, source string) ([]models.Tag, error) {
+	var tags []models.Tag
+	err := db(ctx).Joins("JOIN post_tags ON post_tags.tag_id = tags.id").
+		Where("post_tags.post_id = ? AND post_tags.source = ?", postID, source).
+		Order("tags.slug").
+		Find(&tags).Error
+	return tags, err
+}
+
+// AddTagsToPosts attaches tags to each of the posts as chosen tags. Tags a
+// post already has from a hashtag become chosen, so they stay when the
+// hashtag goes.
+func AddTagsToPosts(ctx context.Context, postIDs []uint, tags []models.Tag) error {
+	return insertPostTags(ctx, postIDs, tags, models.TagSourceManual)
+}
+
+// insertPostTags attaches tags from source to each of the posts. Chosen tags
+// take over existing links; hashtags never replace a chosen tag.
+func insertPostTags(ctx context.Context, postIDs []uint, tags []models.Tag, source string) error {
+	var rows []models.PostTag
+	for _, postID := range postIDs {
+		for _, tag := range tags {
+			rows = append(rows, models.PostTag{PostID: postID, TagID: tag.ID, Source: source})
+		}
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+
+	conflict := clause.OnConflict{Columns: []clause.Column{{Name: "post_id"}, {Name: "tag_id"}}, DoNothing: true}
+	if source == models.TagSourceManual {
+		conflict = clause.OnConflict{
+			Columns:   []clause.Column{{Name: "post_id"}, {Name: "tag_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"source"}),
+		}
+	}
+	return db(ctx).Clauses(conflict).Create(&rows).Error
+}
+
+// RemoveTagsFromPosts detaches tags from each of the posts
+func RemoveTagsFromPosts(ctx context.Context, postIDs []uint, tagSlugs []string) error {
+	if len(postIDs) == 0 || len(tagSlugs) == 0 {
+		return nil
+	}
+	return db(ctx).Exec(
+		"DELETE FROM post_tags WHERE post_id IN ? AND tag_id IN (SELECT id FROM tags WHERE slug IN ?)",
+		postIDs, tagSlugs,
+	).Error
+}
@@ -3,27 +3,219 @@ package repositories
 
 // Import necessary packages
 import (
-	"go-gin-auth-api-starter-kit/config" // Database configuration
+	"context"                            // Request context
+	"errors"                             // For checking the not found error
 	"go-gin-auth-api-starter-kit/models" // User model
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // CreateUser saves a new user to the database
 // user: The user information to save
 // Returns: The saved user and any error that occurred
-func CreateUser(user models.User) (models.User, error) {
+func CreateUser(ctx context.Context, user models.User) (models.User, error) {
 	// Use GORM to create a new record in the users table
-	err := config.DB.Create(&user).Error
+	err := db(ctx).Create(&user).Error
 	return user, err
 }
 
 // GetUserByEmail finds a user by their email address
 // email: The email address to search for
 // Returns: The found user and any error that occurred
-func GetUserByEmail(email string) (models.User, error) {
+func GetUserByEmail(ctx context.Context, email string) (models.User, error) {
 	// Create a variable to hold the user
 	var user models.User
 
 	// Use GORM to find the first user with matching email
-	err := config.DB.Where("email = ?", email).First(&user).Error
+	err := db(ctx).Where("email = ?", email).First(&user).Error
 	return user, err
 }
+
+// ListUsers returns every user
+func ListUsers(ctx context.Context) ([]models.User, error) {
+	var users []models.User
+	err := db(ctx).Find(&users).Error
+	return users, err
+}
+
+// GetUserByUsername finds a user by their username
+func GetUserByUsername(ctx context.Context, username string) (models.User, error) {
+	var user models.User
+	err := db(ctx).Where("username = ?", username).First(&user).Error
+	return user, err
+}
+
+// GetUserByID finds a user by their ID
+func GetUserByID(ctx context.Context, id uint) (models.User, error) {
+	var user models.User
+	err := db(ctx).First(&user, id).Error
+	return user, err
+}
+
+// SetUserAvatar stores where a user's avatar is kept ("" removes it) and
+// returns where the previous one was, so its files can be deleted. An upload
+// that is still being resized is dropped.
+func SetUserAvatar(ctx context.Context, id uint, key string) (string, error) {
+	var previous string
+	err := Transaction(ctx, func(ctx context.Context) error {
+		var user models.User
+		if err := db(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "avatar_key").First(&user, id).Error; err != nil {
+			return err
+		}
+		previous = user.AvatarKey
+		return db(ctx).Model(&user).Updates(map[string]any{"avatar_key": key, "pending_avatar_key": ""}).Error
+	})
+	return previous, err
+}
+
+// SetUserPendingAvatar records key as the user's latest avatar upload, to be
+// made their avatar by PromoteUserAvatar once it is resized
+func SetUserPendingAvatar(ctx context.Context, id uint, key string) error {
+	result := db(ctx).Model(&models.User{}).Where("id = ?", id).Update("pending_avatar_key", key)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// PromoteUserAvatar makes key the user's avatar if it is still their latest
+// upload, including for users in the trash. It returns the avatar and pending
+// upload the user had before, and whether key was promoted; a purged user
+// comes back as a zero User.
+func PromoteUserAvatar(ctx context.Context, id uint, key string) (models.User, bool, error) {
+	var user models.User
+	var promoted bool
+	err := Transaction(ctx, func(ctx context.Context) error {
+		err := db(ctx).Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id", "avatar_key", "pending_avatar_key").First(&user, id).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil || user.PendingAvatarKey != key {
+			return err
+		}
+		promoted = true
+		return db(ctx).Unscoped().Model(&models.User{}).Where("id = ?", id).
+			Updates(map[string]any{"avatar_key": key, "pending_avatar_key": ""}).Error
+	})
+	return user, promoted, err
+}
+
+// GetUserAvatarKey returns where a user's avatar is kept, including for users in the trash
+func GetUserAvatarKey(ctx context.Context, id uint) (string, error) {
+	var keys []string
+	err := db(ctx).Unscoped().Model(&models.User{}).Where("id = ?", id).Pluck("avatar_key", &keys).Error
+	if err != nil || len(keys) == 0 {
+		return "", err
+	}
+	return keys[0], nil
+}
+
+// DeleteUser soft-deletes a user, which stops them from logging in
+func DeleteUser(ctx context.Context, id uint) error {
+	result := db(ctx).Delete(&models.User{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// ListTrashedUsers returns a page of soft-deleted users, most recently deleted first
+func ListTrashedUsers(ctx context.Context, page, perPage int) ([]models.User, int64, error) {
+	query := db(ctx).Unscoped().Model(&models.User{}).Where("deleted_at IS NOT NULL")
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var users []models.User
+	err := query.Order("deleted_at DESC, id DESC").
+		Offset((page - 1) * perPage).
+		Limit(perPage).
+		Find(&users).Error
+	return users, total, err
+}
+
+// RestoreUser brings a soft-deleted user back
+func RestoreUser(ctx context.Context, id uint) error {
+	result := db(ctx).Unscoped().Model(&models.User{}).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		UpdateColumn("deleted_at", nil)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// PurgeUser permanently deletes a user (soft-deleted or not). Their posts are
+// kept without an author, and their comments become "[deleted]" placeholders
+// without an author so that threads stay intact. Their follows, reactions,
+// bookmarks and mentions are taken back.
+func PurgeUser(ctx context.Context, id uint) error {
+	return Transaction(ctx, func(ctx context.Context) error {
+		err := db(ctx).Unscoped().Model(&models.Comment{}).
+			Where("user_id = ?", id).
+			UpdateColumns(map[string]any{
+				"user_id":    nil,
+				"body":       "",
+				"deleted_at": gorm.Expr("COALESCE(deleted_at, ?)", time.Now()),
+			}).Error
+		if err != nil {
+			return err
+		}
+		if err := db(ctx).Unscoped().Model(&models.Post{}).Where("user_id = ?", id).UpdateColumn("user_id", nil).Error; err != nil {
+			return err
+		}
+		if err := db(ctx).Model(&models.PostRevision{}).Where("editor_id = ?", id).UpdateColumn("editor_id", nil).Error; err != nil {
+			return err
+		}
+		if err := db(ctx).Where("user_id = ?", id).Delete(&models.PostShare{}).Error; err != nil {
+			return err
+		}
+		if err := db(ctx).Model(&models.Attachment{}).Where("user_id = ?", id).UpdateColumn("user_id", nil).Error; err != nil {
+			return err
+		}
+		if err := deleteUserFollows(ctx, id); err != nil {
+			return err
+		}
+		if err := deleteUserReactions(ctx, id); err != nil {
+			return err
+		}
+		if err := db(ctx).Where("user_id = ?", id).Delete(&models.Bookmark{}).Error; err != nil {
+			return err
+		}
+		if err := db(ctx).Where("user_id = ?", id).Delete(&models.Mention{}).Error; err != nil {
+			return err
+		}
+
+		result := db(ctx).Unscoped().Delete(&models.User{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+// TrashedUserIDsBefore returns the IDs of users soft-deleted before cutoff
+func TrashedUserIDsBefore(ctx context.Context, cutoff time.Time) ([]uint, error) {
+	var ids []uint
+	err := db(ctx).Unscoped().Model(&models.User{}).
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).
+		Pluck("id", &ids).Error
+	return ids, err
+}
@@ -0,0 +1,155 @@
+package repositories
+
+import (
+	"context"
+	"go-gin-auth-api-starter-kit/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// CreateWebhook saves a new webhook
+func CreateWebhook(ctx context.Context, webhook *models.Webhook) error {
+	return db(ctx).Omit("CreatedBy").Create(webhook).Error
+}
+
+// ListWebhooks returns every webhook, oldest first
+func ListWebhooks(ctx context.Context) ([]models.Webhook, error) {
+	var webhooks []models.Webhook
+	err := db(ctx).Order("id").Find(&webhooks).Error
+	return webhooks, err
+}
+
+// ListActiveWebhooks returns the webhooks that receive events
+func ListActiveWebhooks(ctx context.Context) ([]models.Webhook, error) {
+	var webhooks []models.Webhook
+	err := db(ctx).Where("active").Order("id").Find(&webhooks).Error
+	return webhooks, err
+}
+
+// GetWebhook finds a webhook by its ID
+func GetWebhook(ctx context.Context, id uint) (models.Webhook, error) {
+	var webhook models.Webhook
+	err := db(ctx).First(&webhook, id).Error
+	return webhook, err
+}
+
+// UpdateWebhook saves the editable fields and the state of a webhook
+func UpdateWebhook(ctx context.Context, webhook models.Webhook) error {
+	return db(ctx).Model(&webhook).
+		Select("URL", "Description", "Secret", "Events", "Active", "ConsecutiveFailures", "DisabledAt").
+		Updates(&webhook).Error
+}
+
+// DeleteWebhook deletes a webhook together with its delivery log
+func DeleteWebhook(ctx context.Context, id uint) error {
+	return Transaction(ctx, func(ctx context.Context) error {
+		if err := db(ctx).Where("webhook_id = ?", id).Delete(&models.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		result := db(ctx).Delete(&models.Webhook{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+// CreateWebhookDeliveries queues deliveries
+func CreateWebhookDeliveries(ctx context.Context, deliveries []models.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return db(ctx).Omit("Webhook").Create(&deliveries).Error
+}
+
+// ListWebhookDeliveries returns a page of a webhook's delivery log, newest
+// first, optionally only the deliveries in status
+func ListWebhookDeliveries(ctx context.Context, webhookID uint, status string, page, perPage int) ([]models.WebhookDelivery, int64, error) {
+	query := db(ctx).Model(&models.WebhookDelivery{}).Where("webhook_id = ?", webhookID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var deliveries []models.WebhookDelivery
+	err := query.Order("id DESC").Offset((page - 1) * perPage).Limit(perPage).Find(&deliveries).Error
+	return deliveries, total, err
+}
+
+// GetWebhookDelivery finds one of a webhook's deliveries
+func GetWebhookDelivery(ctx context.Context, webhookID, id uint) (models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	err := db(ctx).Where("webhook_id = ?", webhookID).First(&delivery, id).Error
+	return delivery, err
+}
+
+// ClaimDueWebhookDeliveries takes up to limit pending deliveries whose next
+// attempt is due, with their webhooks, and moves their next attempt lease
+// into the future. Other workers skip the claimed deliveries, and a worker
+// that dies mid-delivery only delays it until the lease runs out.
+func ClaimDueWebhookDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.WebhookDelivery, error) {
+	var ids []uint
+	err := db(ctx).Raw(`
+		UPDATE webhook_deliveries SET next_attempt_at = ?, updated_at = ?
+		WHERE id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE status = ? AND next_attempt_at <= ?
+			ORDER BY next_attempt_at
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id`,
+		now.Add(lease), now, models.WebhookDeliveryPending, now, limit,
+	).Scan(&ids).Error
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+
+	var deliveries []models.WebhookDelivery
+	err = db(ctx).Preload("Webhook").Where("id IN ?", ids).Order("id").Find(&deliveries).Error
+	return deliveries, err
+}
+
+// SaveWebhookAttempt records the outcome of an attempt on a delivery
+func SaveWebhookAttempt(ctx context.Context, delivery models.WebhookDelivery) error {
+	return db(ctx).Model(&delivery).
+		Select("Status", "Attempts", "NextAttemptAt", "LastAttemptAt", "ResponseStatus", "ResponseBody", "Error", "DurationMs").
+		Updates(&delivery).Error
+}
+
+// ResetWebhookFailures clears a webhook's count of failed deliveries after a success
+func ResetWebhookFailures(ctx context.Context, id uint) error {
+	return db(ctx).Model(&models.Webhook{}).
+		Where("id = ? AND consecutive_failures <> 0", id).
+		UpdateColumn("consecutive_failures", 0).Error
+}
+
+// RecordWebhookFailure counts a delivery that failed for good against a
+// webhook, and disables the webhook once disableAfter deliveries in a row
+// have failed. It reports whether this failure disabled the webhook.
+func RecordWebhookFailure(ctx context.Context, id uint, disableAfter int, now time.Time) (bool, error) {
+	var disabled []uint
+	err := Transaction(ctx, func(ctx context.Context) error {
+		err := db(ctx).Model(&models.Webhook{}).
+			Where("id = ?", id).
+			UpdateColumn("consecutive_failures", gorm.Expr("consecutive_failures + 1")).Error
+		if err != nil || disableAfter <= 0 {
+			return err
+		}
+		return db(ctx).Raw(`
+			UPDATE webhooks SET active = false, disabled_at = ?, updated_at = ?
+			WHERE id = ? AND active AND consecutive_failures >= ?
+			RETURNING id`,
+			now, now, id, disableAfter,
+		).Scan(&disabled).Error
+	})
+	return len(disabled) > 0, err
+}
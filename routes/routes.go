@@ -3,8 +3,11 @@ package routes
 
 // Import necessary packages
 import (
+	"go-gin-auth-api-starter-kit/config"      // For feature flags
 	"go-gin-auth-api-starter-kit/controllers" // Our route handlers
+	"go-gin-auth-api-starter-kit/docs"        // OpenAPI specification
 	"go-gin-auth-api-starter-kit/middleware"  // Our middleware
+	"go-gin-auth-api-starter-kit/pkg/metrics" // Prometheus metrics
 
 	"github.com/gin-gonic/gin" // Web framework
 )
@@ -12,6 +15,32 @@ import (
 // SetupRoutes configures all the URLs our application will respond to
 // router: The Gin engine that will handle all web requests
 func SetupRoutes(router *gin.Engine) {
+	// Create a simple test route
+	// When someone visits the homepage ("/"), we send a welcome message
+	router.GET("/", func(c *gin.Context) {
+		c.JSON(200, gin.H{
+			"message": "Go Gin Auth! Server Working AIR Hot Reload!",
+		})
+	})
+
+	// Prometheus scrape endpoint, restricted by METRICS_ALLOWED_CIDRS / METRICS_TOKEN
+	if config.GetEnvBool("METRICS_ENABLED", true) {
+		router.GET("/metrics", middleware.MetricsAccess(), gin.WrapH(metrics.Handler()))
+	}
+
+	// API documentation: the OpenAPI document and a Swagger UI that renders it
+	router.GET("/openapi.json", docs.ServeSpec)
+	router.GET("/docs", docs.ServeUI)
+	router.GET("/docs/assets/:file", docs.ServeAsset)
+
+	// Public feeds of the latest public posts
+	feedRoutes := router.Group("/feeds")
+	{
+		feedRoutes.GET("/posts.rss", controllers.PostsRSS)
+		feedRoutes.GET("/posts.atom", controllers.PostsAtom)
+		feedRoutes.GET("/posts.json", controllers.PostsJSONFeed)
+	}
+
 	// Create a versioned API group
 	v1 := router.Group("/api/v1")
 	{
@@ -19,15 +48,123 @@ func SetupRoutes(router *gin.Engine) {
 		v1.POST("/login", controllers.Login)
 		v1.GET("/dashboard", middleware.AuthMiddleware(), controllers.Dashboard)
 		v1.GET("/users", middleware.AuthMiddleware(), controllers.ListUsers)
+		v1.GET("/users/:id/avatar", controllers.GetAvatar)
+		v1.PUT("/me/avatar", middleware.AuthMiddleware(), controllers.UploadAvatar)
+		v1.DELETE("/me/avatar", middleware.AuthMiddleware(), controllers.DeleteAvatar)
+		v1.GET("/me/bookmarks", middleware.AuthMiddleware(), controllers.ListBookmarks)
+		v1.GET("/feed", middleware.AuthMiddleware(), controllers.GetHomeFeed)
+		v1.GET("/users/:id/followers", middleware.AuthMiddleware(), controllers.ListFollowers)
+		v1.GET("/users/:id/following", middleware.AuthMiddleware(), controllers.ListFollowing)
+		v1.PUT("/users/:id/follow", middleware.AuthMiddleware(), controllers.FollowUser)
+		v1.DELETE("/users/:id/follow", middleware.AuthMiddleware(), controllers.UnfollowUser)
+		// Posts mentioning a user; :id is the username here
+		v1.GET("/users/:id/mentions", middleware.OptionalAuthMiddleware(), controllers.ListUserMentions)
+		v1.GET("/tags", middleware.AuthMiddleware(), controllers.ListTags)
+		v1.GET("/categories", middleware.AuthMiddleware(), controllers.ListCategories)
 
-		// Group all post routes and apply AuthMiddleware once
+		// Notifications inbox, including a live server-sent event stream
+		notificationRoutes := v1.Group("/notifications", middleware.AuthMiddleware())
+		{
+			notificationRoutes.GET("", controllers.ListNotifications)
+			notificationRoutes.GET("/unread-count", controllers.GetUnreadNotificationCount)
+			notificationRoutes.GET("/stream", controllers.StreamNotifications)
+			notificationRoutes.POST("/read-all", controllers.MarkAllNotificationsRead)
+			notificationRoutes.POST("/:id/read", controllers.MarkNotificationRead)
+		}
+		v1.GET("/me/notification-preferences", middleware.AuthMiddleware(), controllers.GetNotificationPreferences)
+		v1.PUT("/me/notification-preferences", middleware.AuthMiddleware(), controllers.UpdateNotificationPreferences)
+
+		// Share links carry their own signed token instead of a login
+		v1.GET("/shared/posts/:token", controllers.GetSharedPost)
+
+		// Attachment downloads are authorized by the signed URL as well
+		v1.GET("/files/:token", controllers.DownloadAttachment)
+
+		// Reading posts works without a login too; anonymous visitors only see public posts
+		publicPostRoutes := v1.Group("/posts", middleware.OptionalAuthMiddleware())
+		{
+			publicPostRoutes.GET("", controllers.ListPosts)
+			publicPostRoutes.GET("/by-slug/:slug", controllers.GetPostBySlug)
+			publicPostRoutes.GET("/top-reacted", controllers.TopReactedPosts)
+			publicPostRoutes.GET("/:id", controllers.GetPost)
+			publicPostRoutes.GET("/:id/comments", controllers.ListComments)
+			publicPostRoutes.GET("/:id/comments/:comment_id", controllers.GetComment)
+			publicPostRoutes.GET("/:id/attachments", controllers.ListPostAttachments)
+			publicPostRoutes.GET("/:id/attachments/:attachment_id", controllers.GetPostAttachment)
+			publicPostRoutes.GET("/:id/reactions", controllers.ListPostReactions)
+		}
+
+		// Group all other post routes and apply AuthMiddleware once
 		postRoutes := v1.Group("/posts", middleware.AuthMiddleware())
 		{
-			postRoutes.GET("", controllers.ListPosts)
 			postRoutes.POST("", controllers.CreatePost)
-			postRoutes.GET("/:id", controllers.GetPost)
+			postRoutes.GET("/trash", controllers.ListPostTrash)
 			postRoutes.PUT("/:id", controllers.UpdatePost)
+			postRoutes.PATCH("/:id", controllers.PatchPost)
 			postRoutes.DELETE("/:id", controllers.DeletePost)
+
+			// Draft/published workflow
+			postRoutes.POST("/:id/publish", controllers.PublishPost)
+			postRoutes.POST("/:id/schedule", controllers.SchedulePost)
+			postRoutes.POST("/:id/unpublish", controllers.UnpublishPost)
+			postRoutes.POST("/:id/archive", controllers.ArchivePost)
+			postRoutes.POST("/:id/restore", controllers.RestorePost)
+
+			// Revision history
+			postRoutes.GET("/:id/revisions", controllers.ListPostRevisions)
+			postRoutes.GET("/:id/revisions/diff", controllers.DiffPostRevisions)
+			postRoutes.GET("/:id/revisions/:revision", controllers.GetPostRevision)
+			postRoutes.POST("/:id/revisions/:revision/restore", controllers.RestorePostRevision)
+
+			// Sharing private posts with users, and share links for anyone
+			postRoutes.GET("/:id/shares", controllers.ListPostShares)
+			postRoutes.POST("/:id/shares", controllers.SharePost)
+			postRoutes.DELETE("/:id/shares/:user_id", controllers.UnsharePost)
+			postRoutes.POST("/:id/share-links", controllers.CreateShareLink)
+
+			// Comments on a post
+			postRoutes.POST("/:id/comments", controllers.CreateComment)
+			postRoutes.PUT("/:id/comments/:comment_id", controllers.UpdateComment)
+			postRoutes.DELETE("/:id/comments/:comment_id", controllers.DeleteComment)
+
+			// File attachments
+			postRoutes.POST("/:id/attachments", controllers.UploadAttachment)
+			postRoutes.DELETE("/:id/attachments/:attachment_id", controllers.DeleteAttachment)
+
+			// Reactions and bookmarks
+			postRoutes.PUT("/:id/reactions/:emoji", controllers.AddReaction)
+			postRoutes.DELETE("/:id/reactions/:emoji", controllers.RemoveReaction)
+			postRoutes.PUT("/:id/bookmark", controllers.BookmarkPost)
+			postRoutes.DELETE("/:id/bookmark", controllers.UnbookmarkPost)
+		}
+
+		// Administrative routes: authenticated and restricted to admins
+		adminRoutes := v1.Group("/admin", middleware.AuthMiddleware(), middleware.AdminMiddleware())
+		{
+			adminRoutes.GET("/audit-logs", controllers.ListAuditLogs)
+			adminRoutes.GET("/audit-logs/export", controllers.ExportAuditLogs)
+			adminRoutes.POST("/categories", controllers.CreateCategory)
+			adminRoutes.POST("/posts/retag", controllers.RetagPosts)
+			adminRoutes.DELETE("/posts/:id/purge", controllers.PurgePost)
+
+			// User trash
+			adminRoutes.DELETE("/users/:id", controllers.DeleteUser)
+			adminRoutes.GET("/users/trash", controllers.ListUserTrash)
+			adminRoutes.POST("/users/:id/restore", controllers.RestoreUser)
+			adminRoutes.DELETE("/users/:id/purge", controllers.PurgeUser)
+
+			// Webhooks
+			adminRoutes.GET("/webhooks", controllers.ListWebhooks)
+			adminRoutes.POST("/webhooks", controllers.CreateWebhook)
+			adminRoutes.GET("/webhooks/:id", controllers.GetWebhook)
+			adminRoutes.PUT("/webhooks/:id", controllers.UpdateWebhook)
+			adminRoutes.DELETE("/webhooks/:id", controllers.DeleteWebhook)
+			adminRoutes.GET("/webhooks/:id/deliveries", controllers.ListWebhookDeliveries)
+			adminRoutes.POST("/webhooks/:id/deliveries/:delivery_id/redeliver", controllers.RedeliverWebhook)
+
+			// Background jobs
+			adminRoutes.GET("/jobs", controllers.ListJobs)
+			adminRoutes.POST("/jobs/:id/retry", controllers.RetryJob)
 		}
 	}
 }
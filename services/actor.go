@@ -0,0 +1,23 @@
+package services
+
+import (
+	"go-gin-auth-api-starter-kit/models"
+	"go-gin-auth-api-starter-kit/repositories"
+)
+
+// Actor is the authenticated user performing an action,
+// used by services that check ownership or permissions
+type Actor struct {
+	ID   uint
+	Role string
+}
+
+// IsAdmin reports whether the actor has the admin role
+func (a Actor) IsAdmin() bool {
+	return a.Role == models.RoleAdmin
+}
+
+// viewer describes the actor to repositories that filter by read access
+func (a Actor) viewer() repositories.Viewer {
+	return repositories.Viewer{ID: a.ID, Admin: a.IsAdmin()}
+}
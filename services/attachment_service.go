@@ -0,0 +1,415 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go-gin-auth-api-starter-kit/config"
+	"go-gin-auth-api-starter-kit/models"
+	"go-gin-auth-api-starter-kit/pkg/storage"
+	"go-gin-auth-api-starter-kit/pkg/tracing"
+	"go-gin-auth-api-starter-kit/repositories"
+	"go-gin-auth-api-starter-kit/utils"
+	"image"
+	_ "image/gif"  // Register GIF for image dimensions
+	_ "image/jpeg" // Register JPEG for image dimensions
+	_ "image/png"  // Register PNG for image dimensions
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// attachmentURLPurpose scopes download tokens so they can't be reused as other signed tokens
+const attachmentURLPurpose = "attachment"
+
+var (
+	// ErrEmptyAttachment is returned when an uploaded file has no content
+	ErrEmptyAttachment = errors.New("file is empty")
+	// ErrAttachmentTooLarge is returned when an upload exceeds ATTACHMENT_MAX_SIZE
+	ErrAttachmentTooLarge = errors.New("file is too large")
+	// ErrAttachmentTypeNotAllowed is returned when the sniffed type is not in ATTACHMENT_ALLOWED_TYPES
+	ErrAttachmentTypeNotAllowed = errors.New("file type is not allowed")
+)
+
+// AttachmentMaxSize is the largest file that can be uploaded, in bytes
+func AttachmentMaxSize() int64 {
+	return int64(config.GetEnvInt("ATTACHMENT_MAX_SIZE", 10<<20))
+}
+
+// AttachmentAllowedTypes are the content types that may be uploaded
+func AttachmentAllowedTypes() []string {
+	return config.GetEnvList("ATTACHMENT_ALLOWED_TYPES", []string{
+		"image/jpeg", "image/png", "image/gif", "image/webp", "application/pdf", "text/plain",
+	})
+}
+
+// AttachmentURLTTL is how long a signed download URL stays valid
+func AttachmentURLTTL() time.Duration {
+	return config.GetEnvDuration("ATTACHMENT_URL_TTL", 15*time.Minute)
+}
+
+// AttachmentUpload is a file received for upload
+type AttachmentUpload struct {
+	// Filename is the name the client gave the file
+	Filename string
+	// Content must yield Size bytes and be seekable, since it is read more than once
+	Content io.ReadSeeker
+	Size    int64
+}
+
+// UploadAttachment stores a file and attaches it to a post (author or admin only).
+// The content type is sniffed from the content. Uploading content the post
+// already has returns the existing attachment and false; identical content
+// attached to other posts is stored only once.
+func UploadAttachment(ctx context.Context, actor Actor, postID uint, upload AttachmentUpload) (_ models.Attachment, created bool, err error) {
+	ctx, span := tracing.Start(ctx, "services.UploadAttachment")
+	defer func() { tracing.End(span, err) }()
+
+	if upload.Size <= 0 {
+		return models.Attachment{}, false, ErrEmptyAttachment
+	}
+	if upload.Size > AttachmentMaxSize() {
+		return models.Attachment{}, false, ErrAttachmentTooLarge
+	}
+	if _, err := editablePost(ctx, actor, postID); err != nil {
+		return models.Attachment{}, false, err
+	}
+
+	contentType, err := sniffContentType(upload.Content)
+	if err != nil {
+		return models.Attachment{}, false, err
+	}
+	if !slices.Contains(AttachmentAllowedTypes(), contentType) {
+		return models.Attachment{}, false, ErrAttachmentTypeNotAllowed
+	}
+
+	hash, err := contentHash(upload.Content, upload.Size)
+	if err != nil {
+		return models.Attachment{}, false, err
+	}
+
+	attachment := models.Attachment{
+		PostID:      postID,
+		UserID:      &actor.ID,
+		Filename:    attachmentFilename(upload.Filename),
+		ContentType: contentType,
+		Size:        upload.Size,
+		SHA256:      hash,
+		StorageKey:  attachmentStorageKey(hash),
+	}
+	if attachment.IsImage() {
+		if err := imageDimensions(upload.Content, &attachment); err != nil {
+			return models.Attachment{}, false, err
+		}
+	}
+
+	// Store the content before the transaction, so a slow upload doesn't hold
+	// the transaction open. If no attachment ends up using it, it is released again.
+	uploaded, err := storeAttachmentContent(ctx, attachment.StorageKey, upload, contentType)
+	if err != nil {
+		return models.Attachment{}, false, err
+	}
+
+	err = repositories.Transaction(ctx, func(ctx context.Context) error {
+		if err := repositories.LockAttachmentContent(ctx, attachment.StorageKey); err != nil {
+			return err
+		}
+
+		existing, err := repositories.FindPostAttachmentByHash(ctx, postID, hash)
+		if err == nil {
+			attachment = existing
+			return nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		// A release that ran between the upload and the lock may have removed
+		// the object again; with the lock held it can't happen anymore
+		if _, err := storeAttachmentContent(ctx, attachment.StorageKey, upload, contentType); err != nil {
+			return err
+		}
+
+		created = true
+		return repositories.CreateAttachment(ctx, &attachment)
+	})
+	if uploaded && (err != nil || !created) {
+		releaseAttachmentContent(ctx, []string{attachment.StorageKey})
+	}
+	if err != nil {
+		return models.Attachment{}, false, err
+	}
+	if !created {
+		return attachment, false, nil
+	}
+
+	auditTarget(ctx, models.AuditAttachmentUploaded, "post", postID, models.JSONMap{
+		"attachment_id": attachment.ID,
+		"filename":      attachment.Filename,
+		"content_type":  attachment.ContentType,
+		"size":          attachment.Size,
+		"sha256":        attachment.SHA256,
+	})
+	attachment, err = repositories.GetPostAttachment(ctx, postID, attachment.ID)
+	return attachment, true, err
+}
+
+// ListPostAttachments returns the attachments of a post the actor may read
+func ListPostAttachments(ctx context.Context, actor Actor, postID uint) (_ []models.Attachment, err error) {
+	ctx, span := tracing.Start(ctx, "services.ListPostAttachments")
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := repositories.GetVisiblePostByID(ctx, actor.viewer(), postID); err != nil {
+		return nil, err
+	}
+	return repositories.ListPostAttachments(ctx, postID)
+}
+
+// GetPostAttachment returns an attachment of a post the actor may read
+func GetPostAttachment(ctx context.Context, actor Actor, postID, id uint) (_ models.Attachment, err error) {
+	ctx, span := tracing.Start(ctx, "services.GetPostAttachment")
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := repositories.GetVisiblePostByID(ctx, actor.viewer(), postID); err != nil {
+		return models.Attachment{}, err
+	}
+	return repositories.GetPostAttachment(ctx, postID, id)
+}
+
+// DeleteAttachment removes an attachment from a post (author or admin only).
+// The stored content is removed once no attachment uses it anymore.
+func DeleteAttachment(ctx context.Context, actor Actor, postID, id uint) (err error) {
+	ctx, span := tracing.Start(ctx, "services.DeleteAttachment")
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := editablePost(ctx, actor, postID); err != nil {
+		return err
+	}
+	attachment, err := repositories.GetPostAttachment(ctx, postID, id)
+	if err != nil {
+		return err
+	}
+
+	err = repositories.Transaction(ctx, func(txCtx context.Context) error {
+		if err := repositories.DeleteAttachment(txCtx, id); err != nil {
+			return err
+		}
+		// The object is only removed once the row is gone for good, so a
+		// rollback can't leave an attachment without its content
+		repositories.AfterCommit(txCtx, func() {
+			releaseAttachmentContent(ctx, []string{attachment.StorageKey})
+		})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	auditTarget(ctx, models.AuditAttachmentDeleted, "post", postID, models.JSONMap{
+		"attachment_id": id,
+		"filename":      attachment.Filename,
+	})
+	return nil
+}
+
+// storeAttachmentContent puts the upload under storageKey unless an object is
+// already stored there, and reports whether it did. The object may be released
+// again by anyone not holding LockAttachmentContent on storageKey.
+func storeAttachmentContent(ctx context.Context, storageKey string, upload AttachmentUpload, contentType string) (bool, error) {
+	stored, err := storage.Default.Exists(ctx, storageKey)
+	if err != nil || stored {
+		return false, err
+	}
+	if _, err := upload.Content.Seek(0, io.SeekStart); err != nil {
+		return false, err
+	}
+	if err := storage.Default.Put(ctx, storageKey, upload.Content, upload.Size, contentType); err != nil {
+		return false, fmt.Errorf("storing attachment: %w", err)
+	}
+	return true, nil
+}
+
+// deleteUnusedContent removes the object under storageKey when no attachment
+// uses it. It must run in a transaction holding LockAttachmentContent that
+// changes nothing else, so there is nothing a rollback could leave pointing
+// at the removed object.
+func deleteUnusedContent(ctx context.Context, storageKey string) error {
+	count, err := repositories.CountAttachmentsByStorageKey(ctx, storageKey)
+	if err != nil || count > 0 {
+		return err
+	}
+	if err := storage.Default.Delete(ctx, storageKey); err != nil {
+		return fmt.Errorf("deleting attachment content: %w", err)
+	}
+	return nil
+}
+
+// releaseAttachmentContent removes the objects under storageKeys that are no
+// longer used, e.g. after their attachments were deleted or an upload failed.
+// Each object is checked in its own transaction, after the change that
+// released it was committed. Failures only leave an unused object behind, so
+// they are logged.
+func releaseAttachmentContent(ctx context.Context, storageKeys []string) {
+	for _, key := range storageKeys {
+		err := repositories.Transaction(ctx, func(ctx context.Context) error {
+			if err := repositories.LockAttachmentContent(ctx, key); err != nil {
+				return err
+			}
+			return deleteUnusedContent(ctx, key)
+		})
+		if err != nil {
+			slog.ErrorContext(ctx, "releasing attachment content failed", slog.String("storage_key", key), slog.Any("error", err))
+		}
+	}
+}
+
+// AttachmentURL signs a token that downloads the attachment until it expires
+func AttachmentURL(attachment models.Attachment) (token string, expires time.Time) {
+	expires = time.Now().Add(AttachmentURLTTL()).Truncate(time.Second)
+	return utils.SignToken(attachmentURLPurpose, strconv.FormatUint(uint64(attachment.ID), 10), expires), expires
+}
+
+// AttachmentDownload is how the content of an attachment is served
+type AttachmentDownload struct {
+	Attachment models.Attachment
+	// Disposition is the Content-Disposition to serve the content with
+	Disposition string
+	// RedirectURL is set when the storage serves the download itself
+	RedirectURL string
+	// Body is the content otherwise; the caller must close it
+	Body io.ReadCloser
+}
+
+// OpenAttachment resolves a download token made by AttachmentURL.
+// Invalid and expired tokens fail with the errors from utils.VerifyToken.
+func OpenAttachment(ctx context.Context, token string) (_ AttachmentDownload, err error) {
+	ctx, span := tracing.Start(ctx, "services.OpenAttachment")
+	defer func() { tracing.End(span, err) }()
+
+	payload, _, err := utils.VerifyToken(attachmentURLPurpose, token)
+	if err != nil {
+		return AttachmentDownload{}, err
+	}
+	id, err := strconv.ParseUint(payload, 10, 64)
+	if err != nil {
+		return AttachmentDownload{}, utils.ErrInvalidSignedToken
+	}
+
+	attachment, err := repositories.GetAttachmentByID(ctx, uint(id))
+	if err != nil {
+		return AttachmentDownload{}, err
+	}
+	download := AttachmentDownload{Attachment: attachment, Disposition: attachmentDisposition(attachment)}
+
+	if presigner, ok := storage.Default.(storage.Presigner); ok && config.GetEnvBool("STORAGE_REDIRECT_DOWNLOADS", false) {
+		// The client follows the redirect right away, so the presigned URL can be short-lived
+		download.RedirectURL, err = presigner.PresignGet(ctx, attachment.StorageKey, download.Disposition, time.Minute)
+		return download, err
+	}
+
+	download.Body, err = storage.Default.Get(ctx, attachment.StorageKey)
+	if errors.Is(err, storage.ErrNotFound) {
+		return AttachmentDownload{}, gorm.ErrRecordNotFound
+	}
+	return download, err
+}
+
+// attachmentDisposition shows images in the browser and downloads everything else
+func attachmentDisposition(attachment models.Attachment) string {
+	disposition := "attachment"
+	if attachment.IsImage() {
+		disposition = "inline"
+	}
+	return mime.FormatMediaType(disposition, map[string]string{"filename": attachment.Filename})
+}
+
+// attachmentStorageKey is where content with the given SHA-256 is stored,
+// fanned out over directories so none of them grows too large
+func attachmentStorageKey(hash string) string {
+	return "attachments/" + hash[:2] + "/" + hash[2:4] + "/" + hash
+}
+
+// attachmentFilename keeps the base name of an uploaded file without control
+// characters, cut to fit the column
+func attachmentFilename(name string) string {
+	name = path.Base(strings.ReplaceAll(name, `\`, "/"))
+	name = strings.TrimSpace(strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, name))
+	if name == "" || name == "." || name == ".." || name == "/" {
+		return "file"
+	}
+	for len(name) > 255 {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+	return name
+}
+
+// sniffContentType detects the media type of content from its first bytes,
+// without parameters such as the charset
+func sniffContentType(content io.ReadSeeker) (string, error) {
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	head := make([]byte, 512)
+	n, err := io.ReadFull(content, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return "", err
+	}
+
+	mediaType, _, err := mime.ParseMediaType(http.DetectContentType(head[:n]))
+	if err != nil {
+		return "application/octet-stream", nil
+	}
+	return mediaType, nil
+}
+
+// contentHash returns the hex SHA-256 of content, checking that it is size bytes long
+func contentHash(content io.ReadSeeker, size int64) (string, error) {
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	hash := sha256.New()
+	n, err := io.Copy(hash, io.LimitReader(content, size+1))
+	if err != nil {
+		return "", err
+	}
+	if n != size {
+		return "", fmt.Errorf("file is %d bytes, expected %d", n, size)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// imageDimensions records the width and height of an image attachment.
+// Formats the standard library can't decode, such as WebP, are left without.
+func imageDimensions(content io.ReadSeeker, attachment *models.Attachment) error {
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	dimensions, _, err := image.DecodeConfig(content)
+	if err != nil {
+		if errors.Is(err, image.ErrFormat) {
+			return nil
+		}
+		// The content looks like an image but doesn't decode as one
+		return ErrAttachmentTypeNotAllowed
+	}
+	attachment.Width, attachment.Height = &dimensions.Width, &dimensions.Height
+	return nil
+}
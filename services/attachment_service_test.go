@@ -0,0 +1,140 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"go-gin-auth-api-starter-kit/config"
+	"go-gin-auth-api-starter-kit/models"
+	"go-gin-auth-api-starter-kit/pkg/storage"
+	"strings"
+	"testing"
+)
+
+func TestSniffContentType(t *testing.T) {
+	tests := []struct {
+		name    string
+		content []byte
+		want    string
+	}{
+		{"png", []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"), "image/png"},
+		{"jpeg", []byte("\xff\xd8\xff\xe0\x00\x10JFIF\x00"), "image/jpeg"},
+		{"gif", []byte("GIF89a\x01\x00\x01\x00"), "image/gif"},
+		{"pdf", []byte("%PDF-1.7\n"), "application/pdf"},
+		{"plain text without charset", []byte("just some notes\n"), "text/plain"},
+		{"html is not text/plain", []byte("<html><script>alert(1)</script></html>"), "text/html"},
+		{"binary", []byte{0, 1, 2, 3, 0xff}, "application/octet-stream"},
+		// Only the first 512 bytes are looked at
+		{"long text", []byte(strings.Repeat("a", 2000)), "text/plain"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := sniffContentType(bytes.NewReader(tt.content))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("sniffContentType = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAttachmentFilename(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"plain", "report.pdf", "report.pdf"},
+		{"unix path", "/home/alice/report.pdf", "report.pdf"},
+		{"windows path", `C:\Users\alice\report.pdf`, "report.pdf"},
+		{"control characters", "re\x00po\nrt.pdf", "report.pdf"},
+		{"empty", "", "file"},
+		{"dot dot", "..", "file"},
+		{"too long", strings.Repeat("é", 200), strings.Repeat("é", 127)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := attachmentFilename(tt.in); got != tt.want {
+				t.Errorf("attachmentFilename(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUploadAttachmentStoresIdenticalContentOnce(t *testing.T) {
+	openTestDB(t, &models.User{}, &models.Category{}, &models.Tag{}, &models.Post{}, &models.PostTag{}, &models.Attachment{}, &models.AuditLog{})
+	store := useTestStorage(t)
+	ctx := context.Background()
+
+	admin := models.User{Username: "admin", Email: "admin@example.com", Password: "x", Role: models.RoleAdmin}
+	if err := config.DB.Create(&admin).Error; err != nil {
+		t.Fatal(err)
+	}
+	actor := Actor{ID: admin.ID, Role: admin.Role}
+	first := models.Post{UserID: &admin.ID, Title: "First", Slug: "first"}
+	second := models.Post{UserID: &admin.ID, Title: "Second", Slug: "second"}
+	if err := config.DB.Create(&first).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := config.DB.Create(&second).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	content := []byte("the same notes, uploaded three times\n")
+	upload := func(postID uint, filename string) (models.Attachment, bool) {
+		t.Helper()
+		attachment, created, err := UploadAttachment(ctx, actor, postID, AttachmentUpload{
+			Filename: filename,
+			Content:  bytes.NewReader(content),
+			Size:     int64(len(content)),
+		})
+		if err != nil {
+			t.Fatalf("UploadAttachment: %v", err)
+		}
+		return attachment, created
+	}
+
+	original, created := upload(first.ID, "notes.txt")
+	if !created {
+		t.Fatal("first upload was not created")
+	}
+	again, created := upload(first.ID, "copy.txt")
+	if created || again.ID != original.ID {
+		t.Errorf("reupload to the same post: created = %v, id = %d; want the existing attachment %d", created, again.ID, original.ID)
+	}
+	shared, created := upload(second.ID, "notes.txt")
+	if !created || shared.ID == original.ID {
+		t.Fatalf("upload to another post: created = %v, id = %d; want a new attachment", created, shared.ID)
+	}
+	if shared.StorageKey != original.StorageKey {
+		t.Errorf("storage keys differ: %q and %q", shared.StorageKey, original.StorageKey)
+	}
+
+	// The object stays while any attachment uses it
+	if err := DeleteAttachment(ctx, actor, first.ID, original.ID); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := store.Exists(ctx, original.StorageKey); err != nil || !ok {
+		t.Fatalf("content removed while still attached to another post (exists = %v, %v)", ok, err)
+	}
+	if err := DeleteAttachment(ctx, actor, second.ID, shared.ID); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := store.Exists(ctx, original.StorageKey); err != nil || ok {
+		t.Errorf("content kept after its last attachment was deleted (exists = %v, %v)", ok, err)
+	}
+}
+
+// useTestStorage makes storage.Default a Local storage in a temporary directory
+func useTestStorage(t *testing.T) storage.Storage {
+	t.Helper()
+	store, err := storage.NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := storage.Default
+	storage.Default = store
+	t.Cleanup(func() { storage.Default = previous })
+	return store
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"context"
+	"go-gin-auth-api-starter-kit/config"
+	"go-gin-auth-api-starter-kit/models"
+	"go-gin-auth-api-starter-kit/pkg/requestctx"
+	"go-gin-auth-api-starter-kit/pkg/tracing"
+	"go-gin-auth-api-starter-kit/repositories"
+	"log/slog"
+	"time"
+)
+
+// RecordAudit appends an event to the audit log. The actor, IP address, user agent
+// and request ID are taken from the request info in ctx unless entry already sets them.
+// A failure to write the audit log is logged but never fails the calling operation.
+func RecordAudit(ctx context.Context, entry models.AuditLog) {
+	info := requestctx.FromContext(ctx)
+
+	if entry.ActorID == nil && info.UserID != 0 {
+		actorID := info.UserID
+		entry.ActorID = &actorID
+		entry.ActorUsername = info.Username
+	}
+	if entry.IP == "" {
+		entry.IP = info.IP
+	}
+	if entry.UserAgent == "" {
+		entry.UserAgent = info.UserAgent
+	}
+	if entry.RequestID == "" {
+		entry.RequestID = info.RequestID
+	}
+	if entry.Metadata == nil {
+		entry.Metadata = models.JSONMap{}
+	}
+
+	if err := repositories.CreateAuditLog(ctx, entry); err != nil {
+		slog.ErrorContext(ctx, "failed to write audit log entry", slog.String("event", entry.Event), slog.Any("error", err))
+	}
+}
+
+// auditTarget is a small helper for the common "event on an entity" case
+func auditTarget(ctx context.Context, event, targetType string, targetID uint, metadata models.JSONMap) {
+	RecordAudit(ctx, models.AuditLog{
+		Event:      event,
+		TargetType: targetType,
+		TargetID:   uintToString(targetID),
+		Metadata:   metadata,
+	})
+}
+
+// ListAuditLogs returns a page of audit entries for the admin API
+func ListAuditLogs(ctx context.Context, filter repositories.AuditLogFilter, page, perPage int) (_ []models.AuditLog, _ int64, err error) {
+	ctx, span := tracing.Start(ctx, "services.ListAuditLogs")
+	defer func() { tracing.End(span, err) }()
+
+	return repositories.ListAuditLogs(ctx, filter, page, perPage)
+}
+
+// ExportAuditLogs streams all matching audit entries to fn and records the export itself
+func ExportAuditLogs(ctx context.Context, filter repositories.AuditLogFilter, format string, fn func(models.AuditLog) error) (err error) {
+	ctx, span := tracing.Start(ctx, "services.ExportAuditLogs")
+	defer func() { tracing.End(span, err) }()
+
+	RecordAudit(ctx, models.AuditLog{
+		Event:    models.AuditAuditExported,
+		Metadata: models.JSONMap{"format": format, "filter": filter},
+	})
+	return repositories.EachAuditLog(ctx, filter, fn)
+}
+
+// AuditRetention returns how long audit entries are kept; zero means forever
+func AuditRetention() time.Duration {
+	return time.Duration(config.GetEnvInt("AUDIT_RETENTION_DAYS", 365)) * 24 * time.Hour
+}
+
+// PruneAuditLogs deletes entries that are older than the configured retention period
+func PruneAuditLogs(ctx context.Context) (_ int64, err error) {
+	ctx, span := tracing.Start(ctx, "services.PruneAuditLogs")
+	defer func() { tracing.End(span, err) }()
+
+	retention := AuditRetention()
+	if retention <= 0 {
+		return 0, nil
+	}
+
+	cutoff := time.Now().Add(-retention)
+	removed, err := repositories.DeleteAuditLogsBefore(ctx, cutoff)
+	if err != nil {
+		return 0, err
+	}
+
+	if removed > 0 {
+		RecordAudit(ctx, models.AuditLog{
+			Event:    models.AuditAuditPruned,
+			Metadata: models.JSONMap{"removed": removed, "cutoff": cutoff},
+		})
+	}
+	return removed, nil
+}
+
+func init() {
+	RegisterJob(JobAuditPrune, func(ctx context.Context, _ struct{}) error {
+		removed, err := PruneAuditLogs(ctx)
+		if removed > 0 {
+			slog.InfoContext(ctx, "pruned audit log entries", slog.Int64("removed", removed))
+		}
+		return err
+	})
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"context"
+	"go-gin-auth-api-starter-kit/config"
+	"go-gin-auth-api-starter-kit/models"
+	"go-gin-auth-api-starter-kit/repositories"
+	"testing"
+	"time"
+)
+
+func TestAuditRetention(t *testing.T) {
+	tests := []struct {
+		env  string
+		want time.Duration
+	}{
+		{"", 365 * 24 * time.Hour},
+		{"30", 30 * 24 * time.Hour},
+		{"0", 0},
+	}
+	for _, tt := range tests {
+		t.Setenv("AUDIT_RETENTION_DAYS", tt.env)
+		if got := AuditRetention(); got != tt.want {
+			t.Errorf("AUDIT_RETENTION_DAYS=%q: AuditRetention() = %v, want %v", tt.env, got, tt.want)
+		}
+	}
+}
+
+func TestPruneAuditLogs(t *testing.T) {
+	openTestDB(t, &models.AuditLog{})
+	ctx := context.Background()
+	t.Setenv("AUDIT_RETENTION_DAYS", "30")
+
+	entries := []models.AuditLog{
+		{Event: models.AuditLoginSucceeded, CreatedAt: time.Now().Add(-40 * 24 * time.Hour), Metadata: models.JSONMap{}},
+		{Event: models.AuditLoginFailed, CreatedAt: time.Now().Add(-20 * 24 * time.Hour), Metadata: models.JSONMap{}},
+	}
+	if err := config.DB.Create(&entries).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := PruneAuditLogs(ctx)
+	if err != nil || removed != 1 {
+		t.Fatalf("PruneAuditLogs() = %d, %v; want the entry past the retention removed", removed, err)
+	}
+	var left []models.AuditLog
+	if err := config.DB.Order("id").Find(&left).Error; err != nil {
+		t.Fatal(err)
+	}
+	// The recent entry stays, and the pruning is recorded
+	if len(left) != 2 || left[0].ID != entries[1].ID || left[1].Event != models.AuditAuditPruned {
+		t.Fatalf("audit log after pruning = %+v", left)
+	}
+
+	// With no retention, nothing is ever removed
+	t.Setenv("AUDIT_RETENTION_DAYS", "0")
+	if err := config.DB.Model(&models.AuditLog{}).Where("1 = 1").Update("created_at", time.Now().Add(-10*365*24*time.Hour)).Error; err != nil {
+		t.Fatal(err)
+	}
+	if removed, err := PruneAuditLogs(ctx); err != nil || removed != 0 {
+		t.Errorf("PruneAuditLogs() without retention = %d, %v; want nothing removed", removed, err)
+	}
+}
+
+func TestExportAuditLogs(t *testing.T) {
+	openTestDB(t, &models.AuditLog{})
+	ctx := context.Background()
+
+	for _, event := range []string{models.AuditLoginSucceeded, models.AuditPostCreated, models.AuditLoginFailed, models.AuditLoginSucceeded} {
+		RecordAudit(ctx, models.AuditLog{Event: event})
+	}
+
+	filter := repositories.AuditLogFilter{Events: []string{models.AuditLoginSucceeded, models.AuditLoginFailed}}
+	var exported []string
+	err := ExportAuditLogs(ctx, filter, "csv", func(entry models.AuditLog) error {
+		exported = append(exported, entry.Event)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{models.AuditLoginSucceeded, models.AuditLoginFailed, models.AuditLoginSucceeded}
+	if len(exported) != len(want) {
+		t.Fatalf("exported %v, want %v", exported, want)
+	}
+	for i := range want {
+		if exported[i] != want[i] {
+			t.Fatalf("exported %v, want %v oldest first", exported, want)
+		}
+	}
+
+	// The export is audited too
+	var export models.AuditLog
+	if err := config.DB.Where("event = ?", models.AuditAuditExported).First(&export).Error; err != nil {
+		t.Fatalf("no %s entry: %v", models.AuditAuditExported, err)
+	}
+	if export.Metadata["format"] != "csv" {
+		t.Errorf("export entry metadata = %v, want the format", export.Metadata)
+	}
+}
@@ -3,17 +3,34 @@ package services
 
 // Import necessary packages
 import (
+	"context"                                  // For request scoped values
+	"errors"                                   // For error values
 	"go-gin-auth-api-starter-kit/models"       // Our data models
+	"go-gin-auth-api-starter-kit/pkg/metrics"  // For login and registration counters
+	"go-gin-auth-api-starter-kit/pkg/tracing"  // For tracing spans
 	"go-gin-auth-api-starter-kit/repositories" // For database operations
 	"go-gin-auth-api-starter-kit/utils"        // For helper functions
+
+	"gorm.io/gorm" // For the record not found error
 )
 
+// ErrInvalidCredentials is returned by Login when the email or password is wrong
+// The same error is used for both cases so callers cannot tell which one it was
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
 // Register creates a new user account
+// ctx: The request context (used for the audit log)
 // user: The user information to register
 // Returns: The created user and any error that occurred
-func Register(user models.User) (models.User, error) {
+func Register(ctx context.Context, user models.User) (_ models.User, err error) {
+	ctx, span := tracing.Start(ctx, "services.Register")
+	defer func() { tracing.End(span, err) }()
+
 	// Hash the user's password for security
+	// bcrypt is deliberately slow, so it gets its own span
+	_, hashSpan := tracing.Start(ctx, "bcrypt.Hash")
 	hashedPassword, err := utils.HashPassword(user.Password)
+	tracing.End(hashSpan, err)
 	if err != nil {
 		// If hashing fails, return an empty user and the error
 		return models.User{}, err
@@ -22,28 +39,114 @@ func Register(user models.User) (models.User, error) {
 	// Replace the plain password with the hashed one
 	user.Password = hashedPassword
 
-	// Save the user to the database
-	return repositories.CreateUser(user)
+	// Never let a client choose its own role
+	user.Role = models.RoleUser
+
+	// Save the user to the database, together with the event announcing them
+	var createdUser models.User
+	err = repositories.Transaction(ctx, func(ctx context.Context) error {
+		createdUser, err = repositories.CreateUser(ctx, user)
+		if err != nil {
+			return err
+		}
+		return publishEvent(ctx, UserRegistered{UserID: createdUser.ID, Username: createdUser.Username, Email: createdUser.Email})
+	})
+	if err != nil {
+		metrics.RegistrationsTotal.WithLabelValues("failure").Inc()
+		RecordAudit(ctx, models.AuditLog{
+			Event:    models.AuditRegisterFailed,
+			Metadata: models.JSONMap{"username": user.Username, "email": user.Email},
+		})
+		return models.User{}, err
+	}
+
+	// Record the new account, with the new user as the actor
+	metrics.RegistrationsTotal.WithLabelValues("success").Inc()
+	RecordAudit(ctx, models.AuditLog{
+		Event:         models.AuditRegisterSucceeded,
+		ActorID:       &createdUser.ID,
+		ActorUsername: createdUser.Username,
+		TargetType:    "user",
+		TargetID:      uintToString(createdUser.ID),
+	})
+	return createdUser, nil
 }
 
 // Login authenticates a user and generates a JWT token
+// ctx: The request context (used for the audit log)
 // email: The user's email address
 // password: The user's password
 // Returns: A JWT token and any error that occurred
-func Login(email, password string) (string, error) {
+func Login(ctx context.Context, email, password string) (_ string, err error) {
+	ctx, span := tracing.Start(ctx, "services.Login")
+	defer func() { tracing.End(span, err) }()
+
 	// Find the user by their email
-	user, err := repositories.GetUserByEmail(email)
+	user, err := repositories.GetUserByEmail(ctx, email)
 	if err != nil {
-		// If user not found, return empty token and error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// If user not found, record the failure and hide which part was wrong
+			auditLoginFailed(ctx, email, "unknown_email", nil)
+			return "", ErrInvalidCredentials
+		}
+		metrics.LoginAttemptsTotal.WithLabelValues("failure", "error").Inc()
 		return "", err
 	}
 
 	// Check if the provided password matches the stored hash
-	if !utils.CheckPasswordHash(password, user.Password) {
+	_, compareSpan := tracing.Start(ctx, "bcrypt.Compare")
+	passwordMatches := utils.CheckPasswordHash(password, user.Password)
+	compareSpan.End()
+	if !passwordMatches {
 		// If password doesn't match, return empty token and error
-		return "", err
+		auditLoginFailed(ctx, email, "wrong_password", &user)
+		return "", ErrInvalidCredentials
 	}
 
 	// Generate a JWT token for the authenticated user
-	return utils.GenerateJWT(user.Username)
+	token, err := utils.GenerateJWT(user.ID, user.Username, user.Role)
+	if err != nil {
+		metrics.LoginAttemptsTotal.WithLabelValues("failure", "error").Inc()
+		return "", err
+	}
+
+	metrics.LoginAttemptsTotal.WithLabelValues("success", "").Inc()
+
+	RecordAudit(ctx, models.AuditLog{
+		Event:         models.AuditLoginSucceeded,
+		ActorID:       &user.ID,
+		ActorUsername: user.Username,
+		TargetType:    "user",
+		TargetID:      uintToString(user.ID),
+	})
+	return token, nil
+}
+
+// AuthenticatedUser loads the account a valid token was issued to
+// ctx: The request context
+// id: The user ID from the token
+// Returns: The user, or gorm.ErrRecordNotFound when the account was deleted since
+// The role in the token is not trusted: it may have changed since the token was issued
+func AuthenticatedUser(ctx context.Context, id uint) (_ models.User, err error) {
+	ctx, span := tracing.Start(ctx, "services.AuthenticatedUser")
+	defer func() { tracing.End(span, err) }()
+
+	// Soft-deleted users are not found, so their tokens stop working at once
+	return repositories.GetUserByID(ctx, id)
+}
+
+// auditLoginFailed records a failed login attempt in the audit log and the metrics
+// user is nil when no account matches the email address
+func auditLoginFailed(ctx context.Context, email, reason string, user *models.User) {
+	metrics.LoginAttemptsTotal.WithLabelValues("failure", reason).Inc()
+
+	entry := models.AuditLog{
+		Event:    models.AuditLoginFailed,
+		Metadata: models.JSONMap{"email": email, "reason": reason},
+	}
+	if user != nil {
+		entry.TargetType = "user"
+		entry.TargetID = uintToString(user.ID)
+	}
+	RecordAudit(ctx, entry)
 }
@@ -0,0 +1,37 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"go-gin-auth-api-starter-kit/config"
+	"go-gin-auth-api-starter-kit/models"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func TestAuthenticatedUserUsesTheCurrentAccount(t *testing.T) {
+	openTestDB(t, &models.User{})
+	ctx := context.Background()
+
+	user := models.User{Username: "alice", Email: "alice@example.com", Password: "x", Role: models.RoleUser}
+	if err := config.DB.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// A token issued while alice was an admin must not keep the role
+	loaded, err := AuthenticatedUser(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Role != models.RoleUser {
+		t.Errorf("role = %q, want %q", loaded.Role, models.RoleUser)
+	}
+
+	if err := config.DB.Delete(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := AuthenticatedUser(ctx, user.ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("deleted user: err = %v, want gorm.ErrRecordNotFound", err)
+	}
+}
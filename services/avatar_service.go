@@ -0,0 +1,276 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go-gin-auth-api-starter-kit/config"
+	"go-gin-auth-api-starter-kit/models"
+	"go-gin-auth-api-starter-kit/pkg/storage"
+	"go-gin-auth-api-starter-kit/pkg/tracing"
+	"go-gin-auth-api-starter-kit/repositories"
+	"go-gin-auth-api-starter-kit/utils"
+	"image/png"
+	"io"
+	"log/slog"
+	"path"
+	"slices"
+	"strconv"
+	"time"
+)
+
+var (
+	// ErrAvatarTooLarge is returned when an avatar upload exceeds AVATAR_MAX_SIZE
+	ErrAvatarTooLarge = errors.New("avatar file is too large")
+	// ErrInvalidAvatarSize is returned when an avatar is requested in a size that is not generated
+	ErrInvalidAvatarSize = errors.New("avatar size is not available")
+)
+
+// AvatarSizes are the edge lengths, in pixels, avatars are generated in, smallest first
+func AvatarSizes() []int {
+	var sizes []int
+	for _, value := range config.GetEnvList("AVATAR_SIZES", []string{"64", "128", "512"}) {
+		if size, err := strconv.Atoi(value); err == nil && size > 0 && size <= 2048 {
+			sizes = append(sizes, size)
+		}
+	}
+	if len(sizes) == 0 {
+		sizes = []int{64, 128, 512}
+	}
+	slices.Sort(sizes)
+	return slices.Compact(sizes)
+}
+
+// AvatarMaxSize is the largest avatar file that can be uploaded, in bytes
+func AvatarMaxSize() int64 {
+	return int64(config.GetEnvInt("AVATAR_MAX_SIZE", 5<<20))
+}
+
+// AvatarMaxPixels is the largest image, in pixels, that is decoded for an avatar
+func AvatarMaxPixels() int {
+	return config.GetEnvInt("AVATAR_MAX_PIXELS", 40_000_000)
+}
+
+// avatarResizeJob is the payload of a JobAvatarResize job
+type avatarResizeJob struct {
+	UserID uint `json:"user_id"`
+	// Key is where the resized copies go, and the user's AvatarKey afterwards
+	Key string `json:"key"`
+	// Upload is where the uploaded file waits to be resized
+	Upload string `json:"upload"`
+}
+
+func init() {
+	RegisterJob(JobAvatarResize, resizeAvatar)
+}
+
+// SetAvatar replaces the actor's avatar with an uploaded JPEG, PNG or WebP
+// image. Only the image header is checked here; the upload is stored as is
+// and a JobAvatarResize job crops it to a square around its center and
+// stores it in every AvatarSizes size as a JPEG without metadata. The actor
+// keeps their current avatar until then.
+func SetAvatar(ctx context.Context, actor Actor, content io.Reader) (_ models.User, err error) {
+	ctx, span := tracing.Start(ctx, "services.SetAvatar")
+	defer func() { tracing.End(span, err) }()
+
+	data, err := io.ReadAll(io.LimitReader(content, AvatarMaxSize()+1))
+	if err != nil {
+		return models.User{}, err
+	}
+	if int64(len(data)) > AvatarMaxSize() {
+		return models.User{}, ErrAvatarTooLarge
+	}
+	if _, err := utils.CheckImage(data, AvatarMaxPixels()); err != nil {
+		return models.User{}, err
+	}
+
+	// Each upload gets its own key, so avatar URLs change with the picture and can be cached forever
+	hash := sha256.Sum256(data)
+	job := avatarResizeJob{
+		UserID: actor.ID,
+		Key:    fmt.Sprintf("avatars/%d/%s", actor.ID, hex.EncodeToString(hash[:8])),
+	}
+	// The same picture can be uploaded again while it is being resized, so
+	// every upload waits under a name of its own
+	job.Upload = fmt.Sprintf("avatars/%d/uploads/%s", actor.ID, strconv.FormatInt(time.Now().UnixNano(), 36))
+	if err := storage.Default.Put(ctx, job.Upload, bytes.NewReader(data), int64(len(data)), "application/octet-stream"); err != nil {
+		return models.User{}, fmt.Errorf("storing avatar: %w", err)
+	}
+
+	err = repositories.Transaction(ctx, func(ctx context.Context) error {
+		if err := repositories.SetUserPendingAvatar(ctx, actor.ID, job.Key); err != nil {
+			return err
+		}
+		_, err := EnqueueJob(ctx, JobAvatarResize, job, JobOptions{Priority: JobPriorityHigh})
+		return err
+	})
+	if err != nil {
+		deleteAvatarUpload(ctx, job.Upload)
+		return models.User{}, err
+	}
+
+	auditTarget(ctx, models.AuditUserAvatarUpdated, "user", actor.ID, nil)
+	return repositories.GetUserByID(ctx, actor.ID)
+}
+
+// resizeAvatar runs a JobAvatarResize job: it stores every size of the
+// upload and makes it the user's avatar, unless they uploaded another picture
+// or removed their avatar in the meantime
+func resizeAvatar(ctx context.Context, job avatarResizeJob) (err error) {
+	ctx, span := tracing.Start(ctx, "services.resizeAvatar")
+	defer func() { tracing.End(span, err) }()
+
+	body, err := storage.Default.Get(ctx, job.Upload)
+	if errors.Is(err, storage.ErrNotFound) {
+		// An earlier attempt finished but failed to report it
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	data, err := io.ReadAll(body)
+	body.Close()
+	if err != nil {
+		return err
+	}
+
+	img, orientation, err := utils.DecodeImage(data, AvatarMaxPixels())
+	if err != nil {
+		// The header looked fine but the image is broken; trying again won't help
+		slog.WarnContext(ctx, "dropping avatar upload that does not decode", slog.Uint64("user_id", uint64(job.UserID)), slog.Any("error", err))
+		deleteAvatarUpload(ctx, job.Upload)
+		return nil
+	}
+
+	area := utils.CenterSquare(img)
+	for _, size := range AvatarSizes() {
+		var buf bytes.Buffer
+		if err := utils.EncodeJPEG(&buf, utils.ResizeSquare(img, area, size, orientation)); err != nil {
+			return err
+		}
+		if err := storage.Default.Put(ctx, avatarObjectKey(job.Key, size), &buf, int64(buf.Len()), "image/jpeg"); err != nil {
+			return fmt.Errorf("storing avatar: %w", err)
+		}
+	}
+
+	before, promoted, err := repositories.PromoteUserAvatar(ctx, job.UserID, job.Key)
+	if err != nil {
+		return err
+	}
+	switch {
+	case promoted && before.AvatarKey != job.Key:
+		deleteAvatarFiles(ctx, before.AvatarKey)
+	case !promoted && before.AvatarKey != job.Key && before.PendingAvatarKey != job.Key:
+		// Superseded, and the same picture isn't in use or on its way either
+		deleteAvatarFiles(ctx, job.Key)
+	}
+	deleteAvatarUpload(ctx, job.Upload)
+	return nil
+}
+
+// RemoveAvatar deletes the actor's avatar; they get their identicon back
+func RemoveAvatar(ctx context.Context, actor Actor) (_ models.User, err error) {
+	ctx, span := tracing.Start(ctx, "services.RemoveAvatar")
+	defer func() { tracing.End(span, err) }()
+
+	previous, err := repositories.SetUserAvatar(ctx, actor.ID, "")
+	if err != nil {
+		return models.User{}, err
+	}
+	if previous != "" {
+		deleteAvatarFiles(ctx, previous)
+		auditTarget(ctx, models.AuditUserAvatarRemoved, "user", actor.ID, nil)
+	}
+	return repositories.GetUserByID(ctx, actor.ID)
+}
+
+// AvatarImage is an avatar ready to be served
+type AvatarImage struct {
+	ContentType string
+	// Version identifies the uploaded picture; it is empty for identicons
+	Version string
+	// Body is the image; the caller must close it
+	Body io.ReadCloser
+}
+
+// GetAvatar returns a user's avatar in one of the AvatarSizes, or their
+// identicon when they have not uploaded one
+func GetAvatar(ctx context.Context, userID uint, size int) (_ AvatarImage, err error) {
+	ctx, span := tracing.Start(ctx, "services.GetAvatar")
+	defer func() { tracing.End(span, err) }()
+
+	if !slices.Contains(AvatarSizes(), size) {
+		return AvatarImage{}, ErrInvalidAvatarSize
+	}
+	user, err := repositories.GetUserByID(ctx, userID)
+	if err != nil {
+		return AvatarImage{}, err
+	}
+
+	if user.AvatarKey != "" {
+		body, err := storage.Default.Get(ctx, avatarObjectKey(user.AvatarKey, size))
+		if err == nil {
+			return AvatarImage{ContentType: "image/jpeg", Version: path.Base(user.AvatarKey), Body: body}, nil
+		}
+		// Sizes added to AVATAR_SIZES after the upload don't exist; fall back to the identicon
+		if !errors.Is(err, storage.ErrNotFound) {
+			return AvatarImage{}, err
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, utils.Identicon(identiconSeed(user), size)); err != nil {
+		return AvatarImage{}, err
+	}
+	return AvatarImage{ContentType: "image/png", Body: io.NopCloser(&buf)}, nil
+}
+
+// AvatarURLs returns the URL of a user's avatar for each of the AvatarSizes,
+// keyed by size. Uploaded avatars carry their version, so a new upload gets new URLs.
+func AvatarURLs(user models.User) map[string]string {
+	base := "/api/v1/users/" + uintToString(user.ID) + "/avatar?size="
+	version := ""
+	if user.AvatarKey != "" {
+		version = "&v=" + path.Base(user.AvatarKey)
+	}
+
+	urls := make(map[string]string)
+	for _, size := range AvatarSizes() {
+		urls[strconv.Itoa(size)] = base + strconv.Itoa(size) + version
+	}
+	return urls
+}
+
+// identiconSeed keeps a user's identicon the same for as long as the account exists
+func identiconSeed(user models.User) string {
+	return "user:" + uintToString(user.ID)
+}
+
+// avatarObjectKey is where one size of an avatar is stored
+func avatarObjectKey(key string, size int) string {
+	return key + "/" + strconv.Itoa(size) + ".jpg"
+}
+
+// deleteAvatarUpload removes an upload that has been resized or dropped.
+// Failures only leave an unused file behind, so they are logged.
+func deleteAvatarUpload(ctx context.Context, upload string) {
+	if err := storage.Default.Delete(ctx, upload); err != nil {
+		slog.ErrorContext(ctx, "deleting avatar upload failed", slog.String("upload", upload), slog.Any("error", err))
+	}
+}
+
+// deleteAvatarFiles removes every size of an avatar that is no longer used.
+// Failures only leave unused files behind, so they are logged.
+func deleteAvatarFiles(ctx context.Context, key string) {
+	if key == "" {
+		return
+	}
+	for _, size := range AvatarSizes() {
+		if err := storage.Default.Delete(ctx, avatarObjectKey(key, size)); err != nil {
+			slog.ErrorContext(ctx, "deleting avatar failed", slog.String("key", key), slog.Int("size", size), slog.Any("error", err))
+		}
+	}
+}
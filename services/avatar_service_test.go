@@ -0,0 +1,120 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"go-gin-auth-api-starter-kit/config"
+	"go-gin-auth-api-starter-kit/models"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+func TestAvatarUploadsAreResizedByAJob(t *testing.T) {
+	openTestDB(t, &models.User{}, &models.Job{}, &models.AuditLog{})
+	store := useTestStorage(t)
+	t.Setenv("AVATAR_SIZES", "16,32")
+	ctx := context.Background()
+
+	user := models.User{Username: "alice", Email: "alice@example.com", Password: "x"}
+	if err := config.DB.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	actor := Actor{ID: user.ID, Role: models.RoleUser}
+
+	// The upload doesn't change the avatar until its job has run
+	updated, err := SetAvatar(ctx, actor, bytes.NewReader(testPNG(t, 40, 20, color.RGBA{R: 255, A: 255})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.AvatarKey != "" || updated.PendingAvatarKey == "" {
+		t.Fatalf("after upload: avatar %q, pending %q; want no avatar and a pending one", updated.AvatarKey, updated.PendingAvatarKey)
+	}
+	first := runAvatarJobs(t)[0]
+
+	current := loadUser(t, user.ID)
+	if current.AvatarKey != first.Key || current.PendingAvatarKey != "" {
+		t.Fatalf("after the job: avatar %q, pending %q; want %q and none", current.AvatarKey, current.PendingAvatarKey, first.Key)
+	}
+	for _, size := range []int{16, 32} {
+		if ok, _ := store.Exists(ctx, avatarObjectKey(first.Key, size)); !ok {
+			t.Errorf("size %d of the avatar was not stored", size)
+		}
+	}
+	if ok, _ := store.Exists(ctx, first.Upload); ok {
+		t.Error("the upload was kept after resizing")
+	}
+
+	// Of two uploads waiting at once, the later one wins whichever job runs first
+	if _, err := SetAvatar(ctx, actor, bytes.NewReader(testPNG(t, 20, 20, color.RGBA{G: 255, A: 255}))); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SetAvatar(ctx, actor, bytes.NewReader(testPNG(t, 20, 20, color.RGBA{B: 255, A: 255}))); err != nil {
+		t.Fatal(err)
+	}
+	jobs := runAvatarJobs(t)
+	superseded, latest := jobs[0], jobs[1]
+
+	current = loadUser(t, user.ID)
+	if current.AvatarKey != latest.Key {
+		t.Errorf("avatar = %q, want the latest upload %q", current.AvatarKey, latest.Key)
+	}
+	if ok, _ := store.Exists(ctx, avatarObjectKey(superseded.Key, 16)); ok {
+		t.Error("the superseded upload was kept")
+	}
+	if ok, _ := store.Exists(ctx, avatarObjectKey(first.Key, 16)); ok {
+		t.Error("the replaced avatar was kept")
+	}
+}
+
+// runAvatarJobs runs the queued avatar jobs in the order they were queued,
+// deletes them and returns their payloads
+func runAvatarJobs(t *testing.T) []avatarResizeJob {
+	t.Helper()
+	var jobs []models.Job
+	if err := config.DB.Where("type = ?", JobAvatarResize).Order("id").Find(&jobs).Error; err != nil {
+		t.Fatal(err)
+	}
+	var payloads []avatarResizeJob
+	for _, job := range jobs {
+		var payload avatarResizeJob
+		if err := fromJSONMap(job.Payload, &payload); err != nil {
+			t.Fatal(err)
+		}
+		if err := jobHandlers[job.Type](context.Background(), job.Payload); err != nil {
+			t.Fatalf("running job %d: %v", job.ID, err)
+		}
+		payloads = append(payloads, payload)
+	}
+	if err := config.DB.Where("type = ?", JobAvatarResize).Delete(&models.Job{}).Error; err != nil {
+		t.Fatal(err)
+	}
+	return payloads
+}
+
+// loadUser reads a user straight from the database
+func loadUser(t *testing.T, id uint) models.User {
+	t.Helper()
+	var user models.User
+	if err := config.DB.First(&user, id).Error; err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+// testPNG encodes a single-coloured PNG
+func testPNG(t *testing.T, width, height int, fill color.Color) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := range height {
+		for x := range width {
+			img.Set(x, y, fill)
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,38 @@
+package services
+
+import (
+	"context"
+	"go-gin-auth-api-starter-kit/models"
+	"go-gin-auth-api-starter-kit/pkg/tracing"
+	"go-gin-auth-api-starter-kit/repositories"
+)
+
+// BookmarkPost saves a post the actor may read to their bookmarks.
+// Bookmarking a post twice is not an error.
+func BookmarkPost(ctx context.Context, actor Actor, postID uint) (err error) {
+	ctx, span := tracing.Start(ctx, "services.BookmarkPost")
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := repositories.GetVisiblePostByID(ctx, actor.viewer(), postID); err != nil {
+		return err
+	}
+	return repositories.AddBookmark(ctx, actor.ID, postID)
+}
+
+// UnbookmarkPost removes a post from the actor's bookmarks. This works for
+// posts the actor can no longer read, so they can tidy up their list.
+func UnbookmarkPost(ctx context.Context, actor Actor, postID uint) (err error) {
+	ctx, span := tracing.Start(ctx, "services.UnbookmarkPost")
+	defer func() { tracing.End(span, err) }()
+
+	return repositories.RemoveBookmark(ctx, actor.ID, postID)
+}
+
+// ListBookmarks returns a page of the posts the actor bookmarked, most
+// recently bookmarked first, leaving out posts they can no longer read
+func ListBookmarks(ctx context.Context, actor Actor, page, perPage int) (_ []models.Post, _ int64, err error) {
+	ctx, span := tracing.Start(ctx, "services.ListBookmarks")
+	defer func() { tracing.End(span, err) }()
+
+	return repositories.ListBookmarkedPosts(ctx, actor.viewer(), page, perPage)
+}
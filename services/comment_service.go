@@ -0,0 +1,245 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"go-gin-auth-api-starter-kit/config"
+	"go-gin-auth-api-starter-kit/models"
+	"go-gin-auth-api-starter-kit/pkg/tracing"
+	"go-gin-auth-api-starter-kit/repositories"
+	"go-gin-auth-api-starter-kit/utils"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrEmptyComment is returned when a comment body is blank
+	ErrEmptyComment = errors.New("comment body must not be empty")
+	// ErrParentCommentNotFound is returned when replying to a comment that does not exist on the post
+	ErrParentCommentNotFound = errors.New("parent comment not found")
+	// ErrCommentTooDeep is returned when a reply would exceed COMMENT_MAX_DEPTH
+	ErrCommentTooDeep = errors.New("maximum reply depth reached")
+	// ErrNotCommentAuthor is returned when someone other than the author edits a comment
+	ErrNotCommentAuthor = errors.New("only the author can change this comment")
+	// ErrCommentEditWindowClosed is returned when the author edits a comment after COMMENT_EDIT_WINDOW
+	ErrCommentEditWindowClosed = errors.New("comment can no longer be edited")
+)
+
+// CommentMaxDepth is the deepest reply level allowed (top-level comments have depth 0)
+func CommentMaxDepth() int {
+	return config.GetEnvInt("COMMENT_MAX_DEPTH", 5)
+}
+
+// CommentEditWindow is how long after posting the author may still edit a comment
+func CommentEditWindow() time.Duration {
+	return config.GetEnvDuration("COMMENT_EDIT_WINDOW", 15*time.Minute)
+}
+
+// ListCommentThreads returns a page of top-level comments on a post with all their replies
+func ListCommentThreads(ctx context.Context, actor Actor, postID uint, page, perPage int) (_ []models.Comment, _ int64, err error) {
+	ctx, span := tracing.Start(ctx, "services.ListCommentThreads")
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := repositories.GetVisiblePostByID(ctx, actor.viewer(), postID); err != nil {
+		return nil, 0, err
+	}
+	return repositories.ListCommentThreads(ctx, postID, page, perPage)
+}
+
+// GetCommentThread returns a comment together with the whole thread it belongs to
+func GetCommentThread(ctx context.Context, actor Actor, postID, commentID uint) (_ []models.Comment, err error) {
+	ctx, span := tracing.Start(ctx, "services.GetCommentThread")
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := repositories.GetVisiblePostByID(ctx, actor.viewer(), postID); err != nil {
+		return nil, err
+	}
+
+	return repositories.GetCommentThread(ctx, postID, commentID)
+}
+
+// CreateComment adds a comment to a post, optionally as a reply to parentID
+func CreateComment(ctx context.Context, actor Actor, postID uint, parentID *uint, body string) (_ models.Comment, err error) {
+	ctx, span := tracing.Start(ctx, "services.CreateComment")
+	defer func() { tracing.End(span, err) }()
+
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return models.Comment{}, ErrEmptyComment
+	}
+
+	if _, err := repositories.GetVisiblePostByID(ctx, actor.viewer(), postID); err != nil {
+		return models.Comment{}, err
+	}
+
+	comment := models.Comment{PostID: postID, UserID: &actor.ID, Body: body}
+
+	if parentID != nil {
+		parent, err := repositories.GetComment(ctx, postID, *parentID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return models.Comment{}, ErrParentCommentNotFound
+			}
+			return models.Comment{}, err
+		}
+		if parent.Depth+1 > CommentMaxDepth() {
+			return models.Comment{}, ErrCommentTooDeep
+		}
+
+		rootID := parent.ID
+		if parent.RootID != nil {
+			rootID = *parent.RootID
+		}
+		comment.ParentID = &parent.ID
+		comment.RootID = &rootID
+		comment.Depth = parent.Depth + 1
+	}
+
+	var createdComment models.Comment
+	err = repositories.Transaction(ctx, func(ctx context.Context) error {
+		createdComment, err = repositories.CreateComment(ctx, comment)
+		if err != nil {
+			return err
+		}
+		return publishEvent(ctx, CommentCreated{CommentID: createdComment.ID, PostID: postID, ParentID: createdComment.ParentID, AuthorID: actor.ID})
+	})
+	if err != nil {
+		return models.Comment{}, err
+	}
+
+	auditTarget(ctx, models.AuditCommentCreated, "comment", createdComment.ID, models.JSONMap{"post_id": postID})
+	return createdComment, nil
+}
+
+// commentNotificationExcerpt is how much of a comment its notifications quote
+const commentNotificationExcerpt = 140
+
+func init() {
+	Subscribe("notifications", notifyComment)
+}
+
+// notifyComment tells the author of the comment being replied to, and the
+// author of the post, about a new comment. Someone who is both only hears
+// about the reply.
+func notifyComment(ctx context.Context, event CommentCreated) error {
+	comment, err := repositories.GetComment(ctx, event.PostID, event.CommentID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// Deleted since, maybe together with the post
+		return nil
+	} else if err != nil {
+		return err
+	}
+	post, err := repositories.GetPostByID(ctx, event.PostID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	notification := models.Notification{
+		ActorID:   &event.AuthorID,
+		PostID:    &post.ID,
+		CommentID: &comment.ID,
+		Data:      models.JSONMap{"excerpt": utils.Excerpt(comment.Body, commentNotificationExcerpt)},
+	}
+
+	var repliedTo uint
+	if event.ParentID != nil {
+		parent, err := repositories.GetComment(ctx, event.PostID, *event.ParentID)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if err == nil && parent.UserID != nil {
+			repliedTo = *parent.UserID
+			reply := notification
+			reply.UserID, reply.Type = repliedTo, models.NotificationReply
+			if err := deliverNotification(ctx, reply); err != nil {
+				return err
+			}
+		}
+	}
+	if post.UserID != nil && *post.UserID != repliedTo {
+		notification.UserID, notification.Type = *post.UserID, models.NotificationComment
+		return deliverNotification(ctx, notification)
+	}
+	return nil
+}
+
+// UpdateComment lets the author change a comment's body within the edit window
+func UpdateComment(ctx context.Context, actor Actor, postID, commentID uint, body string) (_ models.Comment, err error) {
+	ctx, span := tracing.Start(ctx, "services.UpdateComment")
+	defer func() { tracing.End(span, err) }()
+
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return models.Comment{}, ErrEmptyComment
+	}
+
+	comment, err := repositories.GetComment(ctx, postID, commentID)
+	if err != nil {
+		return models.Comment{}, err
+	}
+	if !comment.IsAuthoredBy(actor.ID) {
+		return models.Comment{}, ErrNotCommentAuthor
+	}
+	if time.Since(comment.CreatedAt) > CommentEditWindow() {
+		return models.Comment{}, ErrCommentEditWindowClosed
+	}
+
+	var updatedComment models.Comment
+	err = repositories.Transaction(ctx, func(ctx context.Context) error {
+		updatedComment, err = repositories.UpdateCommentBody(ctx, comment, body)
+		if err != nil {
+			return err
+		}
+		return publishEvent(ctx, CommentUpdated{CommentID: comment.ID, PostID: postID, AuthorID: actor.ID})
+	})
+	if err != nil {
+		return models.Comment{}, err
+	}
+
+	auditTarget(ctx, models.AuditCommentUpdated, "comment", comment.ID, models.JSONMap{"post_id": postID})
+	return updatedComment, nil
+}
+
+// DeleteComment soft-deletes a comment. Authors can delete their own comments and
+// admins can delete any comment as a moderation action.
+func DeleteComment(ctx context.Context, actor Actor, postID, commentID uint) (err error) {
+	ctx, span := tracing.Start(ctx, "services.DeleteComment")
+	defer func() { tracing.End(span, err) }()
+
+	comment, err := repositories.GetComment(ctx, postID, commentID)
+	if err != nil {
+		return err
+	}
+
+	moderated := !comment.IsAuthoredBy(actor.ID)
+	if moderated && !actor.IsAdmin() {
+		return ErrNotCommentAuthor
+	}
+
+	err = repositories.Transaction(ctx, func(ctx context.Context) error {
+		if err := repositories.DeleteComment(ctx, comment); err != nil {
+			return err
+		}
+		return publishEvent(ctx, CommentDeleted{
+			CommentID: comment.ID,
+			PostID:    postID,
+			AuthorID:  comment.UserID,
+			ActorID:   actor.ID,
+			Moderated: moderated,
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	auditTarget(ctx, models.AuditCommentDeleted, "comment", comment.ID, models.JSONMap{
+		"post_id":   postID,
+		"author_id": comment.UserID,
+		"moderated": moderated,
+	})
+	return nil
+}
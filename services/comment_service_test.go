@@ -0,0 +1,160 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"go-gin-auth-api-starter-kit/config"
+	"go-gin-auth-api-starter-kit/models"
+	"testing"
+	"time"
+)
+
+// commentTestPost creates two users and a published internal post by the first
+func commentTestPost(t *testing.T) (Actor, Actor, models.Post) {
+	t.Helper()
+	openTestDB(t, &models.User{}, &models.Tag{}, &models.Category{}, &models.Post{}, &models.PostTag{}, &models.PostShare{},
+		&models.Comment{}, &models.OutboxEvent{}, &models.AuditLog{})
+
+	users := []models.User{
+		{Username: "alice", Email: "alice@example.com", Password: "x"},
+		{Username: "bob", Email: "bob@example.com", Password: "x"},
+	}
+	if err := config.DB.Create(&users).Error; err != nil {
+		t.Fatal(err)
+	}
+	post := models.Post{Title: "Hello", Slug: "hello", UserID: &users[0].ID,
+		Status: models.PostStatusPublished, Visibility: models.PostVisibilityInternal}
+	if err := config.DB.Create(&post).Error; err != nil {
+		t.Fatal(err)
+	}
+	return Actor{ID: users[0].ID, Role: models.RoleUser}, Actor{ID: users[1].ID, Role: models.RoleUser}, post
+}
+
+func TestCreateCommentLimitsReplyDepth(t *testing.T) {
+	_, reader, post := commentTestPost(t)
+	ctx := context.Background()
+	t.Setenv("COMMENT_MAX_DEPTH", "2")
+
+	root, err := CreateComment(ctx, reader, post.ID, nil, "root")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parent := root
+	for depth := 1; depth <= 2; depth++ {
+		reply, err := CreateComment(ctx, reader, post.ID, &parent.ID, "reply")
+		if err != nil {
+			t.Fatalf("reply at depth %d: %v", depth, err)
+		}
+		if reply.Depth != depth || reply.RootID == nil || *reply.RootID != root.ID {
+			t.Fatalf("reply at depth %d = %+v, want depth %d in thread %d", depth, reply, depth, root.ID)
+		}
+		parent = reply
+	}
+
+	if _, err := CreateComment(ctx, reader, post.ID, &parent.ID, "too deep"); !errors.Is(err, ErrCommentTooDeep) {
+		t.Errorf("reply below the maximum depth: err = %v, want ErrCommentTooDeep", err)
+	}
+	missing := parent.ID + 100
+	if _, err := CreateComment(ctx, reader, post.ID, &missing, "orphan"); !errors.Is(err, ErrParentCommentNotFound) {
+		t.Errorf("reply to a missing comment: err = %v, want ErrParentCommentNotFound", err)
+	}
+}
+
+func TestUpdateCommentWithinTheEditWindow(t *testing.T) {
+	author, reader, post := commentTestPost(t)
+	ctx := context.Background()
+	t.Setenv("COMMENT_EDIT_WINDOW", "1m")
+
+	comment, err := CreateComment(ctx, reader, post.ID, nil, "first")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := UpdateComment(ctx, author, post.ID, comment.ID, "not mine"); !errors.Is(err, ErrNotCommentAuthor) {
+		t.Errorf("edit by someone else: err = %v, want ErrNotCommentAuthor", err)
+	}
+	edited, err := UpdateComment(ctx, reader, post.ID, comment.ID, "second")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if edited.Body != "second" || edited.EditedAt == nil {
+		t.Errorf("edited comment = %+v, want the new body and an edit time", edited)
+	}
+
+	if err := config.DB.Model(&comment).UpdateColumn("created_at", time.Now().Add(-2*time.Minute)).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := UpdateComment(ctx, reader, post.ID, comment.ID, "third"); !errors.Is(err, ErrCommentEditWindowClosed) {
+		t.Errorf("edit after the window: err = %v, want ErrCommentEditWindowClosed", err)
+	}
+}
+
+func TestDeletedCommentsStayInTheirThread(t *testing.T) {
+	author, reader, post := commentTestPost(t)
+	ctx := context.Background()
+
+	root, err := CreateComment(ctx, reader, post.ID, nil, "root")
+	if err != nil {
+		t.Fatal(err)
+	}
+	reply, err := CreateComment(ctx, author, post.ID, &root.ID, "reply")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := DeleteComment(ctx, reader, post.ID, root.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	comments, total, err := ListCommentThreads(ctx, reader, post.ID, 1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 1 || len(comments) != 2 || comments[0].ID != root.ID || comments[1].ID != reply.ID {
+		t.Fatalf("threads = %d, %+v; want the deleted root with its reply", total, comments)
+	}
+	if !comments[0].DeletedAt.Valid || comments[1].DeletedAt.Valid {
+		t.Errorf("only the root should be deleted: %+v", comments)
+	}
+
+	// A deleted comment can't be replied to or opened
+	if _, err := CreateComment(ctx, reader, post.ID, &root.ID, "late"); !errors.Is(err, ErrParentCommentNotFound) {
+		t.Errorf("reply to a deleted comment: err = %v, want ErrParentCommentNotFound", err)
+	}
+}
+
+func TestRestorePostRestoresOnlyTheCommentsDeletedWithIt(t *testing.T) {
+	author, reader, post := commentTestPost(t)
+	ctx := context.Background()
+
+	kept, err := CreateComment(ctx, reader, post.ID, nil, "kept")
+	if err != nil {
+		t.Fatal(err)
+	}
+	removed, err := CreateComment(ctx, reader, post.ID, nil, "removed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := DeleteComment(ctx, reader, post.ID, removed.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := DeletePost(ctx, author, post.ID, IfMatch{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RestorePost(ctx, author, post.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	var comments []models.Comment
+	if err := config.DB.Unscoped().Where("post_id = ?", post.ID).Order("id").Find(&comments).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(comments) != 2 || comments[0].ID != kept.ID || comments[1].ID != removed.ID {
+		t.Fatalf("comments = %+v", comments)
+	}
+	if comments[0].DeletedAt.Valid {
+		t.Error("the comment deleted with the post was not restored")
+	}
+	if !comments[1].DeletedAt.Valid {
+		t.Error("the comment deleted before the post was restored too")
+	}
+}
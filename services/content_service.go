@@ -0,0 +1,105 @@
+package services
+
+import (
+	"context"
+	"go-gin-auth-api-starter-kit/config"
+	"go-gin-auth-api-starter-kit/models"
+	"go-gin-auth-api-starter-kit/pkg/tracing"
+	"go-gin-auth-api-starter-kit/repositories"
+	"go-gin-auth-api-starter-kit/utils"
+	"log/slog"
+)
+
+// PostExcerptLength is the maximum length of a post excerpt in characters
+func PostExcerptLength() int {
+	return config.GetEnvInt("POST_EXCERPT_LENGTH", 200)
+}
+
+// ReadingWordsPerMinute is the reading speed used for reading time estimates
+func ReadingWordsPerMinute() int {
+	return config.GetEnvInt("POST_READING_WPM", 200)
+}
+
+// renderPost renders the content of post in its format, links its @mentions
+// and #hashtags, and derives the excerpt and reading time from the rendered
+// text. It returns what the content refers to, for the caller to record.
+func renderPost(ctx context.Context, post *models.Post) (postReferences, error) {
+	switch post.ContentFormat {
+	case models.PostFormatMarkdown:
+		rendered, err := utils.RenderMarkdown(post.Content)
+		if err != nil {
+			return postReferences{}, err
+		}
+		post.ContentHTML = rendered
+	default:
+		post.ContentFormat = models.PostFormatPlain
+		post.ContentHTML = utils.RenderPlainText(post.Content)
+	}
+
+	references, err := linkReferences(ctx, post)
+	if err != nil {
+		return postReferences{}, err
+	}
+
+	text := utils.HTMLToText(post.ContentHTML)
+	post.Excerpt = utils.Excerpt(text, PostExcerptLength())
+	post.ReadingTime = utils.ReadingTime(text, ReadingWordsPerMinute())
+	return references, nil
+}
+
+// recordReferences saves the users a post mentions and replaces the tags it
+// has from hashtags with those of its new content. The tags its author chose
+// are left alone.
+func recordReferences(ctx context.Context, postID uint, references postReferences) error {
+	if err := repositories.ReplacePostMentions(ctx, postID, references.mentionedIDs()); err != nil {
+		return err
+	}
+	tags, err := ResolveTags(ctx, references.Hashtags)
+	if err != nil {
+		return err
+	}
+	return repositories.ReplacePostHashtags(ctx, postID, tags)
+}
+
+// RenderMissingPostContent renders the posts saved before content was
+// rendered on write, recording their mentions and hashtags. It runs once at
+// startup.
+func RenderMissingPostContent(ctx context.Context) (err error) {
+	ctx, span := tracing.Start(ctx, "services.RenderMissingPostContent")
+	defer func() { tracing.End(span, err) }()
+
+	const batchSize = 100
+	var afterID uint
+	rendered := 0
+	for {
+		posts, err := repositories.ListUnrenderedPosts(ctx, afterID, batchSize)
+		if err != nil {
+			return err
+		}
+		for _, post := range posts {
+			afterID = post.ID
+			references, err := renderPost(ctx, &post)
+			if err != nil {
+				return err
+			}
+			err = repositories.Transaction(ctx, func(ctx context.Context) error {
+				if err := repositories.SetPostRendering(ctx, post); err != nil {
+					return err
+				}
+				return recordReferences(ctx, post.ID, references)
+			})
+			if err != nil {
+				return err
+			}
+			rendered++
+		}
+		if len(posts) < batchSize {
+			break
+		}
+	}
+
+	if rendered > 0 {
+		slog.InfoContext(ctx, "rendered post content", slog.Int("posts", rendered))
+	}
+	return nil
+}
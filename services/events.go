@@ -0,0 +1,265 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"go-gin-auth-api-starter-kit/models"
+)
+
+// Domain event types, as stored in the outbox
+const (
+	EventUserRegistered = "user.registered"
+	EventUserDeleted    = "user.deleted"
+	EventUserRestored   = "user.restored"
+	EventUserPurged     = "user.purged"
+
+	EventPostCreated       = "post.created"
+	EventPostUpdated       = "post.updated"
+	EventPostPublished     = "post.published"
+	EventPostStatusChanged = "post.status_changed"
+	EventPostDeleted       = "post.deleted"
+	EventPostRestored      = "post.restored"
+	EventPostPurged        = "post.purged"
+
+	EventCommentCreated = "comment.created"
+	EventCommentUpdated = "comment.updated"
+	EventCommentDeleted = "comment.deleted"
+)
+
+// Reasons for purging a post or user, as carried by PostPurged and UserPurged
+const (
+	// PurgeReasonAdmin is an admin purging by hand
+	PurgeReasonAdmin = "admin"
+	// PurgeReasonRetention is the trash purge job, after TRASH_RETENTION_DAYS
+	PurgeReasonRetention = "retention"
+)
+
+// DomainEvent is something that happened to an entity. Services publish
+// events with publishEvent in the transaction that makes the change, and
+// handlers registered with Subscribe receive them once it is committed.
+type DomainEvent interface {
+	// EventType names the event, e.g. post.created
+	EventType() string
+	// aggregate identifies the entity that changed
+	aggregate() (string, uint)
+}
+
+// UserRegistered is published when someone signs up
+type UserRegistered struct {
+	UserID   uint   `json:"user_id"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
+}
+
+// EventType implements DomainEvent
+func (UserRegistered) EventType() string { return EventUserRegistered }
+
+func (e UserRegistered) aggregate() (string, uint) { return "user", e.UserID }
+
+// PostCreated is published when a post is created, in whatever status
+type PostCreated struct {
+	PostID     uint   `json:"post_id"`
+	AuthorID   *uint  `json:"author_id"`
+	Title      string `json:"title"`
+	Status     string `json:"status"`
+	Visibility string `json:"visibility"`
+}
+
+// EventType implements DomainEvent
+func (PostCreated) EventType() string { return EventPostCreated }
+
+func (e PostCreated) aggregate() (string, uint) { return "post", e.PostID }
+
+// PostUpdated is published when the fields of a post are replaced or
+// patched, or an old revision is restored
+type PostUpdated struct {
+	PostID     uint   `json:"post_id"`
+	AuthorID   *uint  `json:"author_id"`
+	ActorID    uint   `json:"actor_id"`
+	Version    int    `json:"version"`
+	Title      string `json:"title"`
+	Status     string `json:"status"`
+	Visibility string `json:"visibility"`
+	// RestoredRevision is the number of the revision that was restored, if any
+	RestoredRevision *int `json:"restored_revision,omitempty"`
+}
+
+// EventType implements DomainEvent
+func (PostUpdated) EventType() string { return EventPostUpdated }
+
+func (e PostUpdated) aggregate() (string, uint) { return "post", e.PostID }
+
+// PostPublished is published when a post is published, by its author or an
+// admin, or by the scheduler when its time comes
+type PostPublished struct {
+	PostID   uint  `json:"post_id"`
+	AuthorID *uint `json:"author_id"`
+	// ActorID is zero when the scheduler published the post
+	ActorID uint   `json:"actor_id"`
+	From    string `json:"from"`
+}
+
+// EventType implements DomainEvent
+func (PostPublished) EventType() string { return EventPostPublished }
+
+func (e PostPublished) aggregate() (string, uint) { return "post", e.PostID }
+
+// PostStatusChanged is published when a post is scheduled, unpublished or
+// archived. Publishing is a PostPublished instead.
+type PostStatusChanged struct {
+	PostID   uint   `json:"post_id"`
+	AuthorID *uint  `json:"author_id"`
+	ActorID  uint   `json:"actor_id"`
+	From     string `json:"from"`
+	To       string `json:"to"`
+}
+
+// EventType implements DomainEvent
+func (PostStatusChanged) EventType() string { return EventPostStatusChanged }
+
+func (e PostStatusChanged) aggregate() (string, uint) { return "post", e.PostID }
+
+// PostDeleted is published when a post is moved to the trash
+type PostDeleted struct {
+	PostID   uint  `json:"post_id"`
+	AuthorID *uint `json:"author_id"`
+	ActorID  uint  `json:"actor_id"`
+}
+
+// EventType implements DomainEvent
+func (PostDeleted) EventType() string { return EventPostDeleted }
+
+func (e PostDeleted) aggregate() (string, uint) { return "post", e.PostID }
+
+// PostRestored is published when a post is taken out of the trash
+type PostRestored struct {
+	PostID   uint  `json:"post_id"`
+	AuthorID *uint `json:"author_id"`
+	ActorID  uint  `json:"actor_id"`
+}
+
+// EventType implements DomainEvent
+func (PostRestored) EventType() string { return EventPostRestored }
+
+func (e PostRestored) aggregate() (string, uint) { return "post", e.PostID }
+
+// PostPurged is published when a post is deleted for good
+type PostPurged struct {
+	PostID uint `json:"post_id"`
+	// Reason is one of the PurgeReason* constants
+	Reason string `json:"reason"`
+}
+
+// EventType implements DomainEvent
+func (PostPurged) EventType() string { return EventPostPurged }
+
+func (e PostPurged) aggregate() (string, uint) { return "post", e.PostID }
+
+// UserDeleted is published when an admin moves a user to the trash
+type UserDeleted struct {
+	UserID  uint `json:"user_id"`
+	ActorID uint `json:"actor_id"`
+}
+
+// EventType implements DomainEvent
+func (UserDeleted) EventType() string { return EventUserDeleted }
+
+func (e UserDeleted) aggregate() (string, uint) { return "user", e.UserID }
+
+// UserRestored is published when an admin takes a user out of the trash
+type UserRestored struct {
+	UserID uint `json:"user_id"`
+}
+
+// EventType implements DomainEvent
+func (UserRestored) EventType() string { return EventUserRestored }
+
+func (e UserRestored) aggregate() (string, uint) { return "user", e.UserID }
+
+// UserPurged is published when a user is deleted for good
+type UserPurged struct {
+	UserID uint `json:"user_id"`
+	// Reason is one of the PurgeReason* constants
+	Reason string `json:"reason"`
+}
+
+// EventType implements DomainEvent
+func (UserPurged) EventType() string { return EventUserPurged }
+
+func (e UserPurged) aggregate() (string, uint) { return "user", e.UserID }
+
+// CommentCreated is published when someone comments on a post or replies to a comment
+type CommentCreated struct {
+	CommentID uint  `json:"comment_id"`
+	PostID    uint  `json:"post_id"`
+	ParentID  *uint `json:"parent_id"`
+	AuthorID  uint  `json:"author_id"`
+}
+
+// EventType implements DomainEvent
+func (CommentCreated) EventType() string { return EventCommentCreated }
+
+func (e CommentCreated) aggregate() (string, uint) { return "comment", e.CommentID }
+
+// CommentUpdated is published when the author edits a comment
+type CommentUpdated struct {
+	CommentID uint `json:"comment_id"`
+	PostID    uint `json:"post_id"`
+	AuthorID  uint `json:"author_id"`
+}
+
+// EventType implements DomainEvent
+func (CommentUpdated) EventType() string { return EventCommentUpdated }
+
+func (e CommentUpdated) aggregate() (string, uint) { return "comment", e.CommentID }
+
+// CommentDeleted is published when a comment is deleted by its author, or by
+// an admin as a moderation action
+type CommentDeleted struct {
+	CommentID uint  `json:"comment_id"`
+	PostID    uint  `json:"post_id"`
+	AuthorID  *uint `json:"author_id"`
+	ActorID   uint  `json:"actor_id"`
+	Moderated bool  `json:"moderated"`
+}
+
+// EventType implements DomainEvent
+func (CommentDeleted) EventType() string { return EventCommentDeleted }
+
+func (e CommentDeleted) aggregate() (string, uint) { return "comment", e.CommentID }
+
+// eventHandler is a handler registered with Subscribe
+type eventHandler struct {
+	name   string
+	handle func(ctx context.Context, payload models.JSONMap) error
+}
+
+// eventHandlers maps event types to their handlers. It is only written while
+// the program starts, by Subscribe.
+var eventHandlers = map[string][]eventHandler{}
+
+// Subscribe registers handle for events of type E under name, which must be
+// unique for the event type: it is what records which handlers processed an
+// event. Delivery is at least once, but a handler runs in a transaction
+// together with that record, so its database changes happen exactly once.
+// Subscribe is meant to be called from init functions.
+func Subscribe[E DomainEvent](name string, handle func(ctx context.Context, event E) error) {
+	var zero E
+	eventType := zero.EventType()
+	for _, handler := range eventHandlers[eventType] {
+		if handler.name == name {
+			panic(fmt.Sprintf("services: handler %q subscribed to %s twice", name, eventType))
+		}
+	}
+
+	eventHandlers[eventType] = append(eventHandlers[eventType], eventHandler{
+		name: name,
+		handle: func(ctx context.Context, payload models.JSONMap) error {
+			var event E
+			if err := fromJSONMap(payload, &event); err != nil {
+				return err
+			}
+			return handle(ctx, event)
+		},
+	})
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-gin-auth-api-starter-kit/config"
+	"go-gin-auth-api-starter-kit/models"
+	"go-gin-auth-api-starter-kit/pkg/tracing"
+	"go-gin-auth-api-starter-kit/repositories"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// FeedSize is the number of posts in the RSS, Atom and JSON feeds
+func FeedSize() int {
+	return config.GetEnvInt("FEED_SIZE", 20)
+}
+
+// FeedTitle is the title of the feeds
+func FeedTitle() string {
+	return config.GetEnv("FEED_TITLE", config.GetEnv("APP_NAME", "Posts"))
+}
+
+// FeedBaseURL is the public URL of the service used for links in the feeds,
+// e.g. https://blog.example.com. When empty the URL of the request is used.
+func FeedBaseURL() string {
+	return strings.TrimRight(config.GetEnv("FEED_BASE_URL", ""), "/")
+}
+
+// CheckFeedBaseURL fails when FEED_BASE_URL is not an absolute http(s) URL.
+// It may only be left empty outside release mode: the request's Host header
+// is chosen by the client, and a cached feed would hand its links to everyone.
+func CheckFeedBaseURL(release bool) error {
+	base := FeedBaseURL()
+	if base == "" {
+		if release {
+			return errors.New("FEED_BASE_URL is required in release mode")
+		}
+		return nil
+	}
+	u, err := url.Parse(base)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("FEED_BASE_URL %q is not an absolute http(s) URL", base)
+	}
+	return nil
+}
+
+// ListFeedPosts returns the latest published public posts, newest first.
+// Only query.Tags and query.Author apply. Feeds are public, so they never
+// include posts that need a login to read.
+func ListFeedPosts(ctx context.Context, query PostQuery) (_ []models.Post, err error) {
+	ctx, span := tracing.Start(ctx, "services.ListFeedPosts")
+	defer func() { tracing.End(span, err) }()
+
+	filter, ok, err := postFilter(ctx, repositories.Viewer{}, PostQuery{Tags: query.Tags, Author: query.Author})
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return []models.Post{}, nil
+	}
+	filter.Statuses = []string{models.PostStatusPublished}
+	return repositories.ListLatestPosts(ctx, filter, FeedSize())
+}
+
+// FeedLastModified returns when the feeds last changed. It covers every post,
+// so it may move forward without a particular feed changing. Purging a post
+// straight away, without trashing it first, is the one change it can miss.
+func FeedLastModified(ctx context.Context) (_ time.Time, err error) {
+	ctx, span := tracing.Start(ctx, "services.FeedLastModified")
+	defer func() { tracing.End(span, err) }()
+
+	return repositories.PostsLastModified(ctx)
+}
@@ -0,0 +1,29 @@
+package services
+
+import "testing"
+
+func TestCheckFeedBaseURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		base    string
+		release bool
+		wantErr bool
+	}{
+		{"unset in debug mode", "", false, false},
+		{"unset in release mode", "", true, true},
+		{"https", "https://blog.example.com", true, false},
+		{"trailing slash", "https://blog.example.com/", true, false},
+		{"with a path", "http://example.com/blog", true, false},
+		{"no scheme", "blog.example.com", true, true},
+		{"other scheme", "ftp://blog.example.com", false, true},
+		{"no host", "https://", true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("FEED_BASE_URL", tt.base)
+			if err := CheckFeedBaseURL(tt.release); (err != nil) != tt.wantErr {
+				t.Errorf("CheckFeedBaseURL(%v) with %q = %v, want error: %v", tt.release, tt.base, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"go-gin-auth-api-starter-kit/models"
+	"go-gin-auth-api-starter-kit/pkg/tracing"
+	"go-gin-auth-api-starter-kit/repositories"
+)
+
+// ErrCannotFollowSelf is returned when users try to follow themselves
+var ErrCannotFollowSelf = errors.New("you cannot follow yourself")
+
+// FollowUser makes the actor follow a user, bringing that user's latest posts
+// into the actor's home feed. Following someone twice is not an error. It
+// returns the followed user with their new counts.
+func FollowUser(ctx context.Context, actor Actor, userID uint) (_ models.User, err error) {
+	ctx, span := tracing.Start(ctx, "services.FollowUser")
+	defer func() { tracing.End(span, err) }()
+
+	if userID == actor.ID {
+		return models.User{}, ErrCannotFollowSelf
+	}
+	if _, err := repositories.GetUserByID(ctx, userID); err != nil {
+		return models.User{}, err
+	}
+	if _, err := repositories.Follow(ctx, actor.ID, userID, HomeFeedBackfillPosts()); err != nil {
+		return models.User{}, err
+	}
+	return repositories.GetUserByID(ctx, userID)
+}
+
+// UnfollowUser stops the actor from following a user and removes that user's
+// posts from the actor's home feed. Unfollowing someone the actor doesn't
+// follow is not an error. It returns the user with their new counts.
+func UnfollowUser(ctx context.Context, actor Actor, userID uint) (_ models.User, err error) {
+	ctx, span := tracing.Start(ctx, "services.UnfollowUser")
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := repositories.GetUserByID(ctx, userID); err != nil {
+		return models.User{}, err
+	}
+	if _, err := repositories.Unfollow(ctx, actor.ID, userID); err != nil {
+		return models.User{}, err
+	}
+	return repositories.GetUserByID(ctx, userID)
+}
+
+// ListFollowers returns a page of the users following a user, most recent first
+func ListFollowers(ctx context.Context, userID uint, page, perPage int) (_ []models.User, _ int64, err error) {
+	ctx, span := tracing.Start(ctx, "services.ListFollowers")
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := repositories.GetUserByID(ctx, userID); err != nil {
+		return nil, 0, err
+	}
+	return repositories.ListFollowers(ctx, userID, page, perPage)
+}
+
+// ListFollowing returns a page of the users a user follows, most recent first
+func ListFollowing(ctx context.Context, userID uint, page, perPage int) (_ []models.User, _ int64, err error) {
+	ctx, span := tracing.Start(ctx, "services.ListFollowing")
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := repositories.GetUserByID(ctx, userID); err != nil {
+		return nil, 0, err
+	}
+	return repositories.ListFollowing(ctx, userID, page, perPage)
+}
@@ -0,0 +1,33 @@
+package services
+
+import (
+	"encoding/json"
+	"go-gin-auth-api-starter-kit/models"
+	"strconv"
+)
+
+// uintToString formats an ID for use in string fields such as audit targets
+func uintToString(id uint) string {
+	return strconv.FormatUint(uint64(id), 10)
+}
+
+// toJSONMap encodes v as a JSON object, for storing typed values such as
+// event and job payloads in jsonb columns
+func toJSONMap(v any) (models.JSONMap, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var m models.JSONMap
+	err = json.Unmarshal(data, &m)
+	return m, err
+}
+
+// fromJSONMap decodes a JSON object stored by toJSONMap into v
+func fromJSONMap(m models.JSONMap, v any) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
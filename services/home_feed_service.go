@@ -0,0 +1,123 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"go-gin-auth-api-starter-kit/config"
+	"go-gin-auth-api-starter-kit/models"
+	"go-gin-auth-api-starter-kit/pkg/tracing"
+	"go-gin-auth-api-starter-kit/repositories"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrInvalidFeedCursor is returned for home feed cursors that were not issued by HomeFeed
+var ErrInvalidFeedCursor = errors.New("invalid feed cursor")
+
+// HomeFeedFanoutMaxFollowers is the number of followers up to which an
+// author's new posts are copied into every follower's feed. Posts of authors
+// with more followers are read from the posts table when feeds are loaded.
+func HomeFeedFanoutMaxFollowers() int {
+	return config.GetEnvInt("HOME_FEED_FANOUT_MAX_FOLLOWERS", 10_000)
+}
+
+// HomeFeedBackfillPosts is how many of an author's latest posts are copied
+// into a new follower's feed
+func HomeFeedBackfillPosts() int {
+	return config.GetEnvInt("HOME_FEED_BACKFILL_POSTS", 50)
+}
+
+// HomeFeed returns up to limit published posts by the users the actor
+// follows, newest first, starting after cursor ("" for the first page). The
+// returned cursor fetches the next page; it is empty on the last one.
+func HomeFeed(ctx context.Context, actor Actor, cursor string, limit int) (_ []models.Post, next string, err error) {
+	ctx, span := tracing.Start(ctx, "services.HomeFeed")
+	defer func() { tracing.End(span, err) }()
+
+	var before *repositories.FeedEntry
+	if cursor != "" {
+		entry, err := parseFeedCursor(cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		before = &entry
+	}
+
+	entries, err := repositories.ListHomeFeed(ctx, actor.ID, before, limit)
+	if err != nil || len(entries) == 0 {
+		return []models.Post{}, "", err
+	}
+	ids := make([]uint, 0, len(entries))
+	for _, entry := range entries {
+		ids = append(ids, entry.PostID)
+	}
+	found, err := repositories.ListPostsByIDs(ctx, actor.viewer(), ids)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Posts the actor can no longer read drop out, so a page may come up short
+	byID := make(map[uint]models.Post, len(found))
+	for _, post := range found {
+		byID[post.ID] = post
+	}
+	posts := make([]models.Post, 0, len(found))
+	for _, entry := range entries {
+		if post, ok := byID[entry.PostID]; ok {
+			posts = append(posts, post)
+		}
+	}
+	if len(entries) == limit {
+		next = feedCursor(entries[len(entries)-1])
+	}
+	return posts, next, nil
+}
+
+// feedCursor encodes the position of a feed entry
+func feedCursor(entry repositories.FeedEntry) string {
+	return base64.RawURLEncoding.EncodeToString(fmt.Appendf(nil, "%d:%d", entry.PublishedAt.UnixMicro(), entry.PostID))
+}
+
+// parseFeedCursor decodes a cursor made by feedCursor
+func parseFeedCursor(cursor string) (repositories.FeedEntry, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return repositories.FeedEntry{}, ErrInvalidFeedCursor
+	}
+	var micros int64
+	var id uint
+	if n, err := fmt.Sscanf(string(raw), "%d:%d", &micros, &id); err != nil || n != 2 || id == 0 {
+		return repositories.FeedEntry{}, ErrInvalidFeedCursor
+	}
+	return repositories.FeedEntry{PostID: id, PublishedAt: time.UnixMicro(micros)}, nil
+}
+
+func init() {
+	Subscribe("home_feed", func(ctx context.Context, event PostCreated) error {
+		return fanOutPost(ctx, event.PostID)
+	})
+	Subscribe("home_feed", func(ctx context.Context, event PostPublished) error {
+		return fanOutPost(ctx, event.PostID)
+	})
+}
+
+// fanOutPost copies a newly published post into the feeds of its author's
+// followers, unless the author has more than HomeFeedFanoutMaxFollowers.
+// Posts that aren't fanned out are still found by HomeFeed, just less cheaply.
+func fanOutPost(ctx context.Context, postID uint) error {
+	post, err := repositories.GetPostByID(ctx, postID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// Deleted since
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if post.Status != models.PostStatusPublished || post.Author == nil || post.FannedOut ||
+		post.Author.FollowersCount > HomeFeedFanoutMaxFollowers() {
+		return nil
+	}
+	return repositories.FanOutPost(ctx, post.ID, post.Author.ID)
+}
@@ -0,0 +1,136 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"go-gin-auth-api-starter-kit/config"
+	"go-gin-auth-api-starter-kit/models"
+	"go-gin-auth-api-starter-kit/repositories"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFeedCursor(t *testing.T) {
+	entry := repositories.FeedEntry{PostID: 42, PublishedAt: time.Date(2025, 3, 1, 12, 30, 0, 123456000, time.UTC)}
+	got, err := parseFeedCursor(feedCursor(entry))
+	if err != nil || got.PostID != entry.PostID || !got.PublishedAt.Equal(entry.PublishedAt) {
+		t.Fatalf("round trip of %+v = %+v, %v", entry, got, err)
+	}
+
+	for _, cursor := range []string{
+		"not base64!",
+		base64.RawURLEncoding.EncodeToString([]byte("garbage")),
+		base64.RawURLEncoding.EncodeToString([]byte("1700000000000000")),
+		base64.RawURLEncoding.EncodeToString([]byte("1700000000000000:0")),
+	} {
+		if _, err := parseFeedCursor(cursor); !errors.Is(err, ErrInvalidFeedCursor) {
+			t.Errorf("parseFeedCursor(%q) = %v, want ErrInvalidFeedCursor", cursor, err)
+		}
+	}
+}
+
+func TestHomeFeedMergesFannedOutAndUnfannedPosts(t *testing.T) {
+	openTestDB(t, &models.User{}, &models.Follow{}, &models.Tag{}, &models.Category{}, &models.Post{}, &models.PostTag{},
+		&models.PostShare{}, &models.FeedItem{})
+	ctx := context.Background()
+	// Two followers is too many to fan out to
+	t.Setenv("HOME_FEED_FANOUT_MAX_FOLLOWERS", "1")
+
+	users := []models.User{
+		{Username: "reader", Email: "reader@example.com", Password: "x"},
+		{Username: "other", Email: "other@example.com", Password: "x"},
+		{Username: "normal", Email: "normal@example.com", Password: "x"},
+		{Username: "large", Email: "large@example.com", Password: "x"},
+	}
+	if err := config.DB.Create(&users).Error; err != nil {
+		t.Fatal(err)
+	}
+	reader := Actor{ID: users[0].ID, Role: models.RoleUser}
+	other := Actor{ID: users[1].ID, Role: models.RoleUser}
+	normal, large := users[2], users[3]
+	for _, follow := range []struct {
+		actor  Actor
+		author uint
+	}{{reader, normal.ID}, {reader, large.ID}, {other, large.ID}} {
+		if _, err := FollowUser(ctx, follow.actor, follow.author); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The authors post in turns; pairs of posts share a publication time, so
+	// pages have to break ties by ID
+	start := time.Now().Add(-time.Hour).Truncate(time.Second)
+	var want []uint
+	for i := range 10 {
+		author := normal
+		if i%2 == 1 {
+			author = large
+		}
+		published := start.Add(time.Duration(i/2) * time.Minute)
+		post := models.Post{
+			Title: fmt.Sprintf("Post %d", i), Slug: fmt.Sprintf("post-%d", i), UserID: &author.ID,
+			Status: models.PostStatusPublished, Visibility: models.PostVisibilityInternal, PublishedAt: &published,
+		}
+		if err := config.DB.Create(&post).Error; err != nil {
+			t.Fatal(err)
+		}
+		if err := fanOutPost(ctx, post.ID); err != nil {
+			t.Fatal(err)
+		}
+		want = append([]uint{post.ID}, want...)
+	}
+
+	// Only the normal author's posts were copied into the feed
+	var items []models.FeedItem
+	if err := config.DB.Where("user_id = ?", reader.ID).Find(&items).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 5 {
+		t.Fatalf("reader has %d feed items, want the 5 posts of the normal author", len(items))
+	}
+	for _, item := range items {
+		if item.AuthorID != normal.ID {
+			t.Errorf("post %d of author %d was fanned out", item.PostID, item.AuthorID)
+		}
+	}
+
+	// Paging through the feed returns every post once, newest first
+	var got []uint
+	cursor := ""
+	for page := 0; ; page++ {
+		if page > 10 {
+			t.Fatal("the feed does not end")
+		}
+		posts, next, err := HomeFeed(ctx, reader, cursor, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, post := range posts {
+			got = append(got, post.ID)
+		}
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("home feed = %v, want %v", got, want)
+	}
+
+	// Following only the large author, the other user gets its posts read from the posts table
+	posts, next, err := HomeFeed(ctx, other, "", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(posts) != 5 || next != "" {
+		t.Errorf("other user's feed has %d posts and cursor %q, want the 5 posts of the large author", len(posts), next)
+	}
+	for _, post := range posts {
+		if post.UserID == nil || *post.UserID != large.ID {
+			t.Errorf("other user's feed has post %d of another author", post.ID)
+		}
+	}
+}
@@ -0,0 +1,419 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-gin-auth-api-starter-kit/config"
+	"go-gin-auth-api-starter-kit/models"
+	"go-gin-auth-api-starter-kit/pkg/cron"
+	"go-gin-auth-api-starter-kit/pkg/metrics"
+	"go-gin-auth-api-starter-kit/pkg/tracing"
+	"go-gin-auth-api-starter-kit/repositories"
+	"log/slog"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrUnknownJobType is returned when queueing a job no handler is registered for
+	ErrUnknownJobType = errors.New("unknown job type")
+	// ErrJobNotDead is returned when retrying a job that is not in the dead-letter state
+	ErrJobNotDead = errors.New("only dead jobs can be retried")
+)
+
+// Job priorities. Any int works; due jobs with a higher priority run first.
+const (
+	JobPriorityLow    = -10
+	JobPriorityNormal = 0
+	JobPriorityHigh   = 10
+)
+
+// Job types
+const (
+	JobTrashPurge   = "trash.purge"
+	JobAuditPrune   = "audit.prune"
+	JobPrune        = "jobs.prune"
+	JobAvatarResize = "avatar.resize"
+)
+
+// jobMaxRetryDelay caps the backoff between attempts
+const jobMaxRetryDelay = time.Hour
+
+// JobMaxAttempts is how often a job is tried before it is dead, unless it
+// was queued with its own limit
+func JobMaxAttempts() int {
+	return config.GetEnvInt("JOB_MAX_ATTEMPTS", 10)
+}
+
+// JobTimeout is how long a job attempt may run
+func JobTimeout() time.Duration {
+	return config.GetEnvDuration("JOB_TIMEOUT", 5*time.Minute)
+}
+
+// JobRetryBase is the wait before the first retry. It doubles with every
+// further attempt, up to an hour.
+func JobRetryBase() time.Duration {
+	return config.GetEnvDuration("JOB_RETRY_BASE", 10*time.Second)
+}
+
+// JobRetention is how long succeeded jobs are kept; zero keeps them forever
+func JobRetention() time.Duration {
+	return time.Duration(config.GetEnvInt("JOB_RETENTION_DAYS", 7)) * 24 * time.Hour
+}
+
+// JobOptions controls how a job is queued
+type JobOptions struct {
+	// Priority orders due jobs; higher runs first
+	Priority int
+	// RunAt delays the job; the zero time runs it right away
+	RunAt time.Time
+	// MaxAttempts defaults to JOB_MAX_ATTEMPTS
+	MaxAttempts int
+	// UniqueKey, when set, keeps a job with the same key from being queued
+	// again for as long as the first one is kept
+	UniqueKey string
+}
+
+// jobHandlers maps job types to their handlers. It is only written while the
+// program starts, by RegisterJob.
+var jobHandlers = map[string]func(ctx context.Context, payload models.JSONMap) error{}
+
+// RegisterJob registers handle for jobs of jobType, whose payload decodes into
+// P. Jobs are retried when handle returns an error, so it must be safe to run
+// again. RegisterJob is meant to be called from init functions.
+func RegisterJob[P any](jobType string, handle func(ctx context.Context, payload P) error) {
+	if _, ok := jobHandlers[jobType]; ok {
+		panic(fmt.Sprintf("services: job type %s registered twice", jobType))
+	}
+	jobHandlers[jobType] = func(ctx context.Context, payload models.JSONMap) error {
+		var decoded P
+		if err := fromJSONMap(payload, &decoded); err != nil {
+			return err
+		}
+		return handle(ctx, decoded)
+	}
+}
+
+// jobSchedule queues a job whenever a cron expression fires
+type jobSchedule struct {
+	spec     string
+	jobType  string
+	priority int
+	// schedule is spec parsed, set by RunJobWorker
+	schedule cron.Schedule
+}
+
+// jobSchedules are the jobs workers queue on a schedule. The map key names the
+// schedule; it is part of each occurrence's unique key, so every worker can
+// fire the schedule and the job is still only queued once.
+func jobSchedules() map[string]jobSchedule {
+	return map[string]jobSchedule{
+		"trash-purge": {
+			spec:     "@every " + config.GetEnvDuration("TRASH_PURGE_INTERVAL", 24*time.Hour).String(),
+			jobType:  JobTrashPurge,
+			priority: JobPriorityLow,
+		},
+		"audit-prune": {
+			spec:     "@every " + config.GetEnvDuration("AUDIT_PRUNE_INTERVAL", 24*time.Hour).String(),
+			jobType:  JobAuditPrune,
+			priority: JobPriorityLow,
+		},
+		"jobs-prune": {spec: "@hourly", jobType: JobPrune, priority: JobPriorityLow},
+	}
+}
+
+func init() {
+	RegisterJob(JobPrune, func(ctx context.Context, _ struct{}) error {
+		removed, err := PruneJobs(ctx)
+		if removed > 0 {
+			slog.InfoContext(ctx, "pruned succeeded jobs", slog.Int64("removed", removed))
+		}
+		return err
+	})
+}
+
+// jobWake lets the worker of this replica claim new jobs right away instead
+// of at the next poll
+var jobWake = make(chan struct{}, 1)
+
+// wakeJobWorker tells the worker of this replica there is work or room for it
+func wakeJobWorker() {
+	select {
+	case jobWake <- struct{}{}:
+	default:
+	}
+}
+
+// EnqueueJob queues a job of jobType with payload, which is stored as JSON.
+// Called inside a transaction, the job is only queued if the transaction
+// commits. When options.UniqueKey is taken nothing is queued and the returned
+// job has no ID.
+func EnqueueJob(ctx context.Context, jobType string, payload any, options JobOptions) (_ models.Job, err error) {
+	ctx, span := tracing.Start(ctx, "services.EnqueueJob")
+	defer func() { tracing.End(span, err) }()
+
+	if _, ok := jobHandlers[jobType]; !ok {
+		return models.Job{}, ErrUnknownJobType
+	}
+	encoded, err := toJSONMap(payload)
+	if err != nil {
+		return models.Job{}, err
+	}
+
+	job := models.Job{
+		Type:        jobType,
+		Payload:     encoded,
+		Priority:    options.Priority,
+		Status:      models.JobPending,
+		RunAt:       options.RunAt,
+		MaxAttempts: options.MaxAttempts,
+	}
+	if job.RunAt.IsZero() {
+		job.RunAt = time.Now()
+	}
+	if job.MaxAttempts <= 0 {
+		job.MaxAttempts = JobMaxAttempts()
+	}
+	if options.UniqueKey != "" {
+		job.UniqueKey = &options.UniqueKey
+	}
+
+	queued, err := repositories.CreateJob(ctx, &job)
+	if err != nil {
+		return models.Job{}, err
+	}
+	if !queued {
+		return models.Job{}, nil
+	}
+	if !job.RunAt.After(time.Now()) {
+		repositories.AfterCommit(ctx, wakeJobWorker)
+	}
+	return job, nil
+}
+
+// ListJobs returns a page of jobs, newest first (admin only)
+func ListJobs(ctx context.Context, filter repositories.JobFilter, page, perPage int) (_ []models.Job, _ int64, err error) {
+	ctx, span := tracing.Start(ctx, "services.ListJobs")
+	defer func() { tracing.End(span, err) }()
+
+	return repositories.ListJobs(ctx, filter, page, perPage)
+}
+
+// RetryDeadJob puts a dead job back in the queue with a fresh set of
+// attempts (admin only)
+func RetryDeadJob(ctx context.Context, id uint) (_ models.Job, err error) {
+	ctx, span := tracing.Start(ctx, "services.RetryDeadJob")
+	defer func() { tracing.End(span, err) }()
+
+	job, err := repositories.GetJob(ctx, id)
+	if err != nil {
+		return models.Job{}, err
+	}
+	if job.Status != models.JobDead {
+		return models.Job{}, ErrJobNotDead
+	}
+	if err := repositories.ResurrectJob(ctx, id, time.Now()); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// Someone else retried it in the meantime
+			return models.Job{}, ErrJobNotDead
+		}
+		return models.Job{}, err
+	}
+	wakeJobWorker()
+
+	auditTarget(ctx, models.AuditJobRetried, "job", id, models.JSONMap{"type": job.Type, "last_error": job.LastError})
+	return repositories.GetJob(ctx, id)
+}
+
+// PruneJobs deletes jobs that succeeded longer than JOB_RETENTION_DAYS ago
+// and returns how many it removed. Dead jobs are kept until they are retried.
+func PruneJobs(ctx context.Context) (_ int64, err error) {
+	ctx, span := tracing.Start(ctx, "services.PruneJobs")
+	defer func() { tracing.End(span, err) }()
+
+	retention := JobRetention()
+	if retention <= 0 {
+		return 0, nil
+	}
+	return repositories.PruneSucceededJobs(ctx, time.Now().Add(-retention))
+}
+
+// jobWorkerName identifies this process in Job.LockedBy
+func jobWorkerName() string {
+	host, _ := os.Hostname()
+	return host + ":" + strconv.Itoa(os.Getpid())
+}
+
+// RunJobWorker runs due jobs, up to JOB_WORKER_CONCURRENCY at a time, and
+// queues the jobs in jobSchedules when they are due, until ctx is cancelled.
+// It polls every JOB_POLL_INTERVAL, and right away when jobs are queued on
+// this replica. On shutdown it stops claiming jobs and waits up to
+// JOB_SHUTDOWN_TIMEOUT for running ones; jobs still running then are
+// cancelled and handed back to the queue without counting the attempt.
+// Any number of workers can run at once because jobs are claimed with SKIP
+// LOCKED.
+func RunJobWorker(ctx context.Context) {
+	worker := jobWorkerName()
+	concurrency := max(config.GetEnvInt("JOB_WORKER_CONCURRENCY", 4), 1)
+	lease := JobTimeout() + time.Minute
+	ticker := time.NewTicker(config.GetEnvDuration("JOB_POLL_INTERVAL", time.Second))
+	defer ticker.Stop()
+
+	schedules := jobSchedules()
+	for name, schedule := range schedules {
+		parsed, err := cron.Parse(schedule.spec)
+		if err != nil {
+			slog.ErrorContext(ctx, "invalid job schedule", slog.String("schedule", name), slog.Any("error", err))
+			delete(schedules, name)
+			continue
+		}
+		schedule.schedule = parsed
+		schedules[name] = schedule
+	}
+
+	// Running jobs outlive ctx until the shutdown timeout
+	jobCtx, cancelJobs := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelJobs()
+	var running sync.WaitGroup
+	slots := make(chan struct{}, concurrency)
+
+	slog.InfoContext(ctx, "job worker started", slog.String("worker", worker), slog.Int("concurrency", concurrency))
+	checked := time.Now()
+	for ctx.Err() == nil {
+		now := time.Now()
+		queueScheduledJobs(ctx, schedules, checked, now)
+		checked = now
+
+		if free := concurrency - len(slots); free > 0 {
+			jobs, err := repositories.ClaimDueJobs(ctx, worker, now, lease, free)
+			if err != nil && ctx.Err() == nil {
+				slog.ErrorContext(ctx, "claiming jobs failed", slog.Any("error", err))
+			}
+			for _, job := range jobs {
+				slots <- struct{}{}
+				running.Add(1)
+				go func() {
+					defer func() {
+						<-slots
+						running.Done()
+						wakeJobWorker()
+					}()
+					runJob(jobCtx, worker, job)
+				}()
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-ticker.C:
+		case <-jobWake:
+		}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		running.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(config.GetEnvDuration("JOB_SHUTDOWN_TIMEOUT", 30*time.Second)):
+		slog.WarnContext(ctx, "cancelling running jobs", slog.Int("jobs", len(slots)))
+		cancelJobs()
+		<-done
+	}
+	slog.InfoContext(ctx, "job worker stopped", slog.String("worker", worker))
+}
+
+// queueScheduledJobs queues the scheduled jobs that fell due after from and
+// up to to. Occurrences are identified by their schedule and time, so each is
+// queued once however many workers fire it.
+func queueScheduledJobs(ctx context.Context, schedules map[string]jobSchedule, from, to time.Time) {
+	for name, schedule := range schedules {
+		for at := schedule.schedule.Next(from.UTC()); !at.IsZero() && !at.After(to); at = schedule.schedule.Next(at) {
+			_, err := EnqueueJob(ctx, schedule.jobType, struct{}{}, JobOptions{
+				Priority:  schedule.priority,
+				RunAt:     at,
+				UniqueKey: "schedule:" + name + ":" + at.Format(time.RFC3339),
+			})
+			if err != nil && ctx.Err() == nil {
+				slog.ErrorContext(ctx, "queueing scheduled job failed", slog.String("schedule", name), slog.Any("error", err))
+			}
+		}
+	}
+}
+
+// runJob runs one attempt of a job the worker claimed and records the outcome:
+// done, retried with exponential backoff, or dead after its last attempt
+func runJob(ctx context.Context, worker string, job models.Job) {
+	ctx, span := tracing.Start(ctx, "services.runJob", trace.WithAttributes(
+		attribute.String("job.type", job.Type),
+		attribute.Int64("job.id", int64(job.ID)),
+		attribute.Int("job.attempt", job.Attempts),
+	))
+	started := time.Now()
+	err := callJobHandler(ctx, job)
+	tracing.End(span, err)
+	metrics.JobDuration.WithLabelValues(job.Type).Observe(time.Since(started).Seconds())
+
+	// Record the outcome even when the worker is being cancelled
+	cancelled := ctx.Err() != nil
+	ctx = context.WithoutCancel(ctx)
+	logAttrs := []any{slog.Uint64("job_id", uint64(job.ID)), slog.String("type", job.Type), slog.Int("attempt", job.Attempts)}
+
+	var outcome string
+	switch {
+	case err == nil:
+		outcome = "succeeded"
+		err = repositories.CompleteJob(ctx, job, worker, time.Now())
+	case cancelled:
+		outcome = "released"
+		err = repositories.ReleaseJob(ctx, job, worker)
+	case job.Attempts >= job.MaxAttempts:
+		outcome = "dead"
+		slog.ErrorContext(ctx, "job failed for good", append(logAttrs, slog.Any("error", err))...)
+		err = repositories.KillJob(ctx, job, worker, err.Error(), time.Now())
+	default:
+		outcome = "retried"
+		slog.WarnContext(ctx, "job failed", append(logAttrs, slog.Any("error", err))...)
+		err = repositories.RetryJob(ctx, job, worker, err.Error(), time.Now().Add(jobRetryDelay(job.Attempts)))
+	}
+	metrics.JobsProcessedTotal.WithLabelValues(job.Type, outcome).Inc()
+	if err != nil {
+		slog.ErrorContext(ctx, "saving job outcome failed", append(logAttrs, slog.Any("error", err))...)
+	}
+}
+
+// callJobHandler runs the handler of a job with JOB_TIMEOUT, turning panics
+// into errors so one bad job doesn't take the worker down
+func callJobHandler(ctx context.Context, job models.Job) (err error) {
+	handle, ok := jobHandlers[job.Type]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownJobType, job.Type)
+	}
+
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("job panicked: %v", recovered)
+		}
+	}()
+	ctx, cancel := context.WithTimeout(ctx, JobTimeout())
+	defer cancel()
+	return handle(ctx, job.Payload)
+}
+
+// jobRetryDelay is the wait after the given number of failed attempts
+func jobRetryDelay(attempts int) time.Duration {
+	delay := JobRetryBase()
+	for i := 1; i < attempts && delay < jobMaxRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, jobMaxRetryDelay)
+}
@@ -0,0 +1,41 @@
+package services
+
+import (
+	"context"
+	"go-gin-auth-api-starter-kit/config"
+	"go-gin-auth-api-starter-kit/models"
+	"go-gin-auth-api-starter-kit/repositories"
+	"testing"
+	"time"
+)
+
+func TestClaimDueJobsReclaimsExpiredLeases(t *testing.T) {
+	openTestDB(t, &models.Job{})
+	ctx := context.Background()
+	now := time.Now()
+	expired := now.Add(-time.Minute)
+
+	jobs := []models.Job{
+		{Type: "test.job", Status: models.JobRunning, Attempts: 1, MaxAttempts: 3, LockedUntil: &expired, LockedBy: "gone", RunAt: expired},
+		{Type: "test.job", Status: models.JobRunning, Attempts: 3, MaxAttempts: 3, LockedUntil: &expired, LockedBy: "gone", RunAt: expired},
+	}
+	if err := config.DB.Create(&jobs).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	claimed, err := repositories.ClaimDueJobs(ctx, "worker", now, time.Minute, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(claimed) != 1 || claimed[0].ID != jobs[0].ID || claimed[0].Attempts != 2 || claimed[0].LockedBy != "worker" {
+		t.Fatalf("claimed %+v, want only job %d on its second attempt", claimed, jobs[0].ID)
+	}
+
+	exhausted, err := repositories.GetJob(ctx, jobs[1].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exhausted.Status != models.JobDead || exhausted.Attempts != 3 || exhausted.FinishedAt == nil || exhausted.LockedUntil != nil {
+		t.Errorf("job on its last attempt = %+v, want dead with 3 attempts", exhausted)
+	}
+}
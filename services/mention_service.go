@@ -0,0 +1,149 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"go-gin-auth-api-starter-kit/models"
+	"go-gin-auth-api-starter-kit/pkg/tracing"
+	"go-gin-auth-api-starter-kit/repositories"
+	"go-gin-auth-api-starter-kit/utils"
+	"net/url"
+
+	"gorm.io/gorm"
+)
+
+// maxPostMentions caps how many users a single post can mention, and so notify
+const maxPostMentions = 50
+
+// postReferences are the users and tags the content of a post refers to
+type postReferences struct {
+	// Mentioned are the existing users mentioned with @username
+	Mentioned []models.User
+	// Hashtags are the #hashtags that make valid tag names, without the #
+	Hashtags []string
+}
+
+// mentionedIDs returns the IDs of the mentioned users
+func (r postReferences) mentionedIDs() []uint {
+	ids := make([]uint, 0, len(r.Mentioned))
+	for _, user := range r.Mentioned {
+		ids = append(ids, user.ID)
+	}
+	return ids
+}
+
+// linkReferences finds the @mentions and #hashtags in the rendered content of
+// post and turns them into links: mentions of existing users to their posts,
+// hashtags to the posts with that tag. Unknown usernames stay plain text.
+func linkReferences(ctx context.Context, post *models.Post) (postReferences, error) {
+	found := utils.FindReferences(post.ContentHTML)
+	if len(found.Mentions) > maxPostMentions {
+		found.Mentions = found.Mentions[:maxPostMentions]
+	}
+
+	var references postReferences
+	users, err := repositories.FindUsersByUsernames(ctx, found.Mentions)
+	if err != nil {
+		return postReferences{}, err
+	}
+	references.Mentioned = users
+	mentioned := make(map[string]bool, len(users))
+	for _, user := range users {
+		mentioned[user.Username] = true
+	}
+
+	tagSlugs := map[string]string{}
+	for _, hashtag := range found.Hashtags {
+		slug := utils.Slugify(hashtag)
+		if slug == "" || len(slug) > maxTagLength || len(hashtag) > maxTagLength {
+			continue
+		}
+		tagSlugs[hashtag] = slug
+		references.Hashtags = append(references.Hashtags, hashtag)
+	}
+
+	if len(mentioned) == 0 && len(tagSlugs) == 0 {
+		return references, nil
+	}
+	post.ContentHTML = utils.LinkReferences(post.ContentHTML,
+		func(username string) string {
+			if !mentioned[username] {
+				return ""
+			}
+			return "/api/v1/posts?author=" + url.QueryEscape(username)
+		},
+		func(hashtag string) string {
+			slug, ok := tagSlugs[hashtag]
+			if !ok {
+				return ""
+			}
+			return "/api/v1/posts?tag=" + url.QueryEscape(slug)
+		})
+	return references, nil
+}
+
+// ListUserMentions returns a page of the posts the actor may read that
+// mention the user with the given username, newest first
+func ListUserMentions(ctx context.Context, actor Actor, username string, page, perPage int) (_ []models.Post, _ int64, err error) {
+	ctx, span := tracing.Start(ctx, "services.ListUserMentions")
+	defer func() { tracing.End(span, err) }()
+
+	user, err := repositories.GetUserByUsername(ctx, username)
+	if err != nil {
+		return nil, 0, err
+	}
+	return repositories.ListMentioningPosts(ctx, actor.viewer(), user.ID, page, perPage)
+}
+
+func init() {
+	// Mentions are looked for whenever the content or the status of a post changes
+	Subscribe("mentions", func(ctx context.Context, event PostCreated) error {
+		return notifyMentions(ctx, event.PostID)
+	})
+	Subscribe("mentions", func(ctx context.Context, event PostUpdated) error {
+		return notifyMentions(ctx, event.PostID)
+	})
+	Subscribe("mentions", func(ctx context.Context, event PostPublished) error {
+		return notifyMentions(ctx, event.PostID)
+	})
+}
+
+// notifyMentions tells the users a post mentions that they were mentioned,
+// once the post is published and only once per post
+func notifyMentions(ctx context.Context, postID uint) error {
+	post, err := repositories.GetPostByID(ctx, postID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// Deleted since
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if post.Status != models.PostStatusPublished || post.UserID == nil {
+		return nil
+	}
+
+	userIDs, err := repositories.ListPostMentions(ctx, postID)
+	if err != nil {
+		return err
+	}
+	for _, userID := range userIDs {
+		notified, err := repositories.MentionNotified(ctx, userID, postID)
+		if err != nil {
+			return err
+		}
+		if notified {
+			continue
+		}
+		err = deliverNotification(ctx, models.Notification{
+			UserID:  userID,
+			Type:    models.NotificationMention,
+			ActorID: post.UserID,
+			PostID:  &post.ID,
+			Data:    models.JSONMap{"excerpt": post.Excerpt},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
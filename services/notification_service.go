@@ -0,0 +1,235 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go-gin-auth-api-starter-kit/models"
+	"go-gin-auth-api-starter-kit/pkg/pubsub"
+	"go-gin-auth-api-starter-kit/pkg/tracing"
+	"go-gin-auth-api-starter-kit/repositories"
+	"log/slog"
+	"slices"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrUnknownNotificationType is returned for preferences of notification types that don't exist
+var ErrUnknownNotificationType = errors.New("unknown notification type")
+
+// notificationReplayLimit caps how many missed notifications a reconnecting stream is sent
+const notificationReplayLimit = 100
+
+// notificationMessage is published when a notification is created. It only
+// carries IDs; subscribers load the notification itself.
+type notificationMessage struct {
+	ID uint `json:"id"`
+}
+
+// notificationTopic is the pub/sub topic of a user's new notifications
+func notificationTopic(userID uint) string {
+	return "notifications:" + strconv.FormatUint(uint64(userID), 10)
+}
+
+// notify records a notification and pushes it to the recipient's open
+// streams. Notifications are a side effect of the action that caused them, so
+// failures are logged rather than returned.
+func notify(ctx context.Context, notification models.Notification) {
+	if err := deliverNotification(ctx, notification); err != nil {
+		slog.ErrorContext(ctx, "creating notification failed",
+			slog.String("type", notification.Type), slog.Uint64("user_id", uint64(notification.UserID)), slog.Any("error", err))
+	}
+}
+
+// deliverNotification does the work of notify, for event handlers that
+// return failures so the event is retried. Nothing happens when users would
+// be notified of their own doing, when they turned the type off, or when they
+// can't read the post it is about. The notification is pushed once the
+// transaction ctx is in commits.
+func deliverNotification(ctx context.Context, notification models.Notification) error {
+	if notification.ActorID != nil && *notification.ActorID == notification.UserID {
+		return nil
+	}
+	preferences, err := notificationPreferences(ctx, notification.UserID)
+	if err != nil || !preferences[notification.Type] {
+		return err
+	}
+	if notification.PostID != nil {
+		viewer := repositories.Viewer{ID: notification.UserID}
+		if _, err := repositories.GetVisiblePostByID(ctx, viewer, *notification.PostID); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			return err
+		}
+	}
+
+	if err := repositories.CreateNotification(ctx, &notification); err != nil {
+		return err
+	}
+	repositories.AfterCommit(ctx, func() {
+		// Streams that miss the message catch up when they reconnect
+		if err := pubsub.Default.Publish(ctx, notificationTopic(notification.UserID), notificationMessage{ID: notification.ID}); err != nil {
+			slog.ErrorContext(ctx, "pushing notification failed", slog.Uint64("notification_id", uint64(notification.ID)), slog.Any("error", err))
+		}
+	})
+	return nil
+}
+
+// ListNotifications returns a page of the actor's notifications, newest first
+func ListNotifications(ctx context.Context, actor Actor, unreadOnly bool, page, perPage int) (_ []models.Notification, _ int64, err error) {
+	ctx, span := tracing.Start(ctx, "services.ListNotifications")
+	defer func() { tracing.End(span, err) }()
+
+	return repositories.ListNotifications(ctx, actor.ID, unreadOnly, page, perPage)
+}
+
+// CountUnreadNotifications counts the actor's unread notifications
+func CountUnreadNotifications(ctx context.Context, actor Actor) (_ int64, err error) {
+	ctx, span := tracing.Start(ctx, "services.CountUnreadNotifications")
+	defer func() { tracing.End(span, err) }()
+
+	return repositories.CountUnreadNotifications(ctx, actor.ID)
+}
+
+// MarkNotificationRead marks one of the actor's notifications as read
+func MarkNotificationRead(ctx context.Context, actor Actor, id uint) (_ models.Notification, err error) {
+	ctx, span := tracing.Start(ctx, "services.MarkNotificationRead")
+	defer func() { tracing.End(span, err) }()
+
+	if err := repositories.MarkNotificationRead(ctx, actor.ID, id, time.Now()); err != nil {
+		return models.Notification{}, err
+	}
+	return repositories.GetNotification(ctx, actor.ID, id)
+}
+
+// MarkAllNotificationsRead marks all of the actor's notifications as read and
+// returns how many were unread
+func MarkAllNotificationsRead(ctx context.Context, actor Actor) (_ int64, err error) {
+	ctx, span := tracing.Start(ctx, "services.MarkAllNotificationsRead")
+	defer func() { tracing.End(span, err) }()
+
+	return repositories.MarkAllNotificationsRead(ctx, actor.ID, time.Now())
+}
+
+// NotificationPreferences returns which notification types are on for the actor
+func NotificationPreferences(ctx context.Context, actor Actor) (_ map[string]bool, err error) {
+	ctx, span := tracing.Start(ctx, "services.NotificationPreferences")
+	defer func() { tracing.End(span, err) }()
+
+	return notificationPreferences(ctx, actor.ID)
+}
+
+// UpdateNotificationPreferences turns the given notification types on or off
+// for the actor; types that are left out keep their setting. It returns the
+// preferences afterwards.
+func UpdateNotificationPreferences(ctx context.Context, actor Actor, enabled map[string]bool) (_ map[string]bool, err error) {
+	ctx, span := tracing.Start(ctx, "services.UpdateNotificationPreferences")
+	defer func() { tracing.End(span, err) }()
+
+	for notificationType := range enabled {
+		if !slices.Contains(models.NotificationTypes, notificationType) {
+			return nil, ErrUnknownNotificationType
+		}
+	}
+	if err := repositories.SetNotificationPreferences(ctx, actor.ID, enabled); err != nil {
+		return nil, err
+	}
+	return notificationPreferences(ctx, actor.ID)
+}
+
+// notificationPreferences maps every notification type to whether it is on for a user
+func notificationPreferences(ctx context.Context, userID uint) (map[string]bool, error) {
+	stored, err := repositories.ListNotificationPreferences(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	preferences := make(map[string]bool, len(models.NotificationTypes))
+	for _, notificationType := range models.NotificationTypes {
+		preferences[notificationType] = true
+	}
+	for _, preference := range stored {
+		if _, ok := preferences[preference.Type]; ok {
+			preferences[preference.Type] = preference.Enabled
+		}
+	}
+	return preferences, nil
+}
+
+// NotificationStream delivers the actor's notifications as they are created
+type NotificationStream struct {
+	// Notifications receives new notifications. It is closed when the stream
+	// ends, including when the server shuts down.
+	Notifications <-chan models.Notification
+	// Close ends the stream
+	Close func()
+}
+
+// StreamNotifications subscribes to the actor's new notifications until ctx
+// is done or the stream is closed. With a non-zero afterID the notifications
+// created after that one, which a reconnecting client missed, come first.
+func StreamNotifications(ctx context.Context, actor Actor, afterID uint) (NotificationStream, error) {
+	// Subscribe before catching up, so nothing created in between is lost
+	messages, unsubscribe := pubsub.Default.Subscribe(notificationTopic(actor.ID))
+
+	var missed []models.Notification
+	if afterID != 0 {
+		var err error
+		missed, err = repositories.ListNotificationsAfter(ctx, actor.ID, afterID, notificationReplayLimit)
+		if err != nil {
+			unsubscribe()
+			return NotificationStream{}, err
+		}
+	}
+
+	out := make(chan models.Notification)
+	go func() {
+		defer close(out)
+		defer unsubscribe()
+
+		sent := afterID
+		send := func(notification models.Notification) bool {
+			if notification.ID <= sent {
+				return true
+			}
+			select {
+			case out <- notification:
+				sent = notification.ID
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		for _, notification := range missed {
+			if !send(notification) {
+				return
+			}
+		}
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case payload, ok := <-messages:
+				if !ok {
+					return
+				}
+				var message notificationMessage
+				if err := json.Unmarshal(payload, &message); err != nil {
+					continue
+				}
+				notification, err := repositories.GetNotification(ctx, actor.ID, message.ID)
+				if err != nil {
+					slog.WarnContext(ctx, "loading streamed notification failed", slog.Uint64("id", uint64(message.ID)), slog.Any("error", err))
+					continue
+				}
+				if !send(notification) {
+					return
+				}
+			}
+		}
+	}()
+	return NotificationStream{Notifications: out, Close: unsubscribe}, nil
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"context"
+	"go-gin-auth-api-starter-kit/config"
+	"go-gin-auth-api-starter-kit/models"
+	"go-gin-auth-api-starter-kit/pkg/pubsub"
+	"testing"
+	"time"
+)
+
+// receiveNotification waits for the next notification on stream
+func receiveNotification(t *testing.T, stream NotificationStream) models.Notification {
+	t.Helper()
+	select {
+	case notification, ok := <-stream.Notifications:
+		if !ok {
+			t.Fatal("stream closed")
+		}
+		return notification
+	case <-time.After(5 * time.Second):
+		t.Fatal("no notification streamed")
+	}
+	return models.Notification{}
+}
+
+func TestStreamNotificationsReplaysMissedOnes(t *testing.T) {
+	openTestDB(t, &models.User{}, &models.Tag{}, &models.Category{}, &models.Post{}, &models.PostTag{}, &models.Comment{},
+		&models.Notification{})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	reader := models.User{Username: "alice", Email: "alice@example.com", Password: "x"}
+	if err := config.DB.Create(&reader).Error; err != nil {
+		t.Fatal(err)
+	}
+	actor := Actor{ID: reader.ID, Role: models.RoleUser}
+	create := func() models.Notification {
+		t.Helper()
+		notification := models.Notification{UserID: reader.ID, Type: models.NotificationComment, Data: models.JSONMap{}}
+		if err := config.DB.Create(&notification).Error; err != nil {
+			t.Fatal(err)
+		}
+		return notification
+	}
+	seen, first, second := create(), create(), create()
+
+	// Reconnecting after the first notification brings back the two after it
+	stream, err := StreamNotifications(ctx, actor, seen.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+	for _, want := range []uint{first.ID, second.ID} {
+		if got := receiveNotification(t, stream); got.ID != want {
+			t.Fatalf("streamed notification %d, want %d", got.ID, want)
+		}
+	}
+
+	// A live message for a notification already replayed is not sent twice
+	if err := pubsub.Default.Publish(ctx, notificationTopic(reader.ID), notificationMessage{ID: second.ID}); err != nil {
+		t.Fatal(err)
+	}
+	live := create()
+	if err := pubsub.Default.Publish(ctx, notificationTopic(reader.ID), notificationMessage{ID: live.ID}); err != nil {
+		t.Fatal(err)
+	}
+	if got := receiveNotification(t, stream); got.ID != live.ID {
+		t.Fatalf("streamed notification %d, want the live one %d", got.ID, live.ID)
+	}
+}
+
+func TestStreamNotificationsWithoutLastEventIDOnlyStreamsNewOnes(t *testing.T) {
+	openTestDB(t, &models.User{}, &models.Tag{}, &models.Category{}, &models.Post{}, &models.PostTag{}, &models.Comment{},
+		&models.Notification{})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	reader := models.User{Username: "alice", Email: "alice@example.com", Password: "x"}
+	if err := config.DB.Create(&reader).Error; err != nil {
+		t.Fatal(err)
+	}
+	old := models.Notification{UserID: reader.ID, Type: models.NotificationComment, Data: models.JSONMap{}}
+	if err := config.DB.Create(&old).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	stream, err := StreamNotifications(ctx, Actor{ID: reader.ID, Role: models.RoleUser}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+
+	live := models.Notification{UserID: reader.ID, Type: models.NotificationReply, Data: models.JSONMap{}}
+	if err := config.DB.Create(&live).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := pubsub.Default.Publish(ctx, notificationTopic(reader.ID), notificationMessage{ID: live.ID}); err != nil {
+		t.Fatal(err)
+	}
+	if got := receiveNotification(t, stream); got.ID != live.ID {
+		t.Fatalf("streamed notification %d, want only the new one %d", got.ID, live.ID)
+	}
+}
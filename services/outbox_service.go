@@ -0,0 +1,205 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"go-gin-auth-api-starter-kit/config"
+	"go-gin-auth-api-starter-kit/models"
+	"go-gin-auth-api-starter-kit/pkg/tracing"
+	"go-gin-auth-api-starter-kit/repositories"
+	"log/slog"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// outboxBatchSize is how many events a dispatcher claims at once
+	outboxBatchSize = 50
+	// outboxLease is how long a claimed event is left to its dispatcher
+	outboxLease = 5 * time.Minute
+	// outboxRetryBase is the wait after the first failed attempt; it doubles
+	// with every further attempt
+	outboxRetryBase = 5 * time.Second
+	// outboxMaxRetryDelay caps the backoff between attempts
+	outboxMaxRetryDelay = time.Hour
+	// outboxPruneInterval is how often dispatched events are pruned
+	outboxPruneInterval = time.Hour
+)
+
+// OutboxRetention is how long dispatched events are kept; zero keeps them forever
+func OutboxRetention() time.Duration {
+	return time.Duration(config.GetEnvInt("OUTBOX_RETENTION_DAYS", 7)) * 24 * time.Hour
+}
+
+// outboxWake lets the dispatcher pick up events committed on this replica
+// right away instead of at the next poll
+var outboxWake = make(chan struct{}, 1)
+
+// publishEvent writes event to the outbox. Call it with the ctx of the
+// transaction that makes the change, so the event is stored if and only if
+// the change is.
+func publishEvent(ctx context.Context, event DomainEvent) error {
+	payload, err := toJSONMap(event)
+	if err != nil {
+		return err
+	}
+	aggregateType, aggregateID := event.aggregate()
+	err = repositories.CreateOutboxEvent(ctx, &models.OutboxEvent{
+		Type:          event.EventType(),
+		AggregateType: aggregateType,
+		AggregateID:   aggregateID,
+		Payload:       payload,
+		NextAttemptAt: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	repositories.AfterCommit(ctx, wakeOutboxDispatcher)
+	return nil
+}
+
+// wakeOutboxDispatcher tells the dispatcher of this replica there are events
+func wakeOutboxDispatcher() {
+	select {
+	case outboxWake <- struct{}{}:
+	default:
+	}
+}
+
+// DispatchOutbox hands the due events in the outbox to their handlers, a
+// batch at a time, until none are left, and returns how many it dispatched.
+// Events whose handlers fail are retried with exponential backoff, and hold
+// back the later events of their entity until then.
+// StartOutboxDispatcher calls it in the background; tests can call it directly.
+func DispatchOutbox(ctx context.Context) (dispatched int, err error) {
+	ctx, span := tracing.Start(ctx, "services.DispatchOutbox")
+	defer func() { tracing.End(span, err) }()
+
+	for ctx.Err() == nil {
+		events, err := repositories.ClaimDueOutboxEvents(ctx, time.Now(), outboxLease, outboxBatchSize)
+		if err != nil {
+			return dispatched, err
+		}
+
+		// A batch has at most one event per entity; the next ones are claimed
+		// once it is dispatched
+		for _, event := range events {
+			if ctx.Err() != nil {
+				// Shutting down: the lease runs out and the event is dispatched again
+				return dispatched, nil
+			}
+			if dispatchEvent(ctx, event) {
+				dispatched++
+			}
+		}
+
+		if len(events) == 0 {
+			break
+		}
+	}
+	return dispatched, nil
+}
+
+// dispatchEvent runs every handler of an event that hasn't processed it yet,
+// and records the outcome. It reports whether all of them succeeded.
+func dispatchEvent(ctx context.Context, event models.OutboxEvent) bool {
+	var errs []error
+	for _, handler := range eventHandlers[event.Type] {
+		if err := runEventHandler(ctx, handler, event); err != nil {
+			slog.ErrorContext(ctx, "event handler failed",
+				slog.String("event", event.Type), slog.Uint64("event_id", uint64(event.ID)),
+				slog.String("handler", handler.name), slog.Any("error", err))
+			errs = append(errs, err)
+		}
+	}
+
+	attempts := event.Attempts + 1
+	if len(errs) == 0 {
+		if err := repositories.MarkOutboxEventDispatched(ctx, event.ID, attempts, time.Now()); err != nil {
+			slog.ErrorContext(ctx, "marking outbox event dispatched failed", slog.Uint64("event_id", uint64(event.ID)), slog.Any("error", err))
+			return false
+		}
+		return true
+	}
+
+	next := time.Now().Add(outboxRetryDelay(attempts))
+	if err := repositories.RecordOutboxFailure(ctx, event.ID, attempts, errors.Join(errs...).Error(), next); err != nil {
+		slog.ErrorContext(ctx, "recording outbox failure failed", slog.Uint64("event_id", uint64(event.ID)), slog.Any("error", err))
+	}
+	return false
+}
+
+// runEventHandler runs a handler in a transaction that also records that it
+// processed the event. A handler that already processed the event is skipped.
+func runEventHandler(ctx context.Context, handler eventHandler, event models.OutboxEvent) (err error) {
+	ctx, span := tracing.Start(ctx, "services.runEventHandler", trace.WithAttributes(
+		attribute.String("event.type", event.Type),
+		attribute.Int64("event.id", int64(event.ID)),
+		attribute.String("event.handler", handler.name),
+	))
+	defer func() { tracing.End(span, err) }()
+
+	return repositories.Transaction(ctx, func(ctx context.Context) error {
+		first, err := repositories.MarkEventProcessed(ctx, event.ID, handler.name, time.Now())
+		if err != nil || !first {
+			return err
+		}
+		return handler.handle(ctx, event.Payload)
+	})
+}
+
+// outboxRetryDelay is the wait after the given number of failed attempts
+func outboxRetryDelay(attempts int) time.Duration {
+	delay := outboxRetryBase
+	for i := 1; i < attempts && delay < outboxMaxRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, outboxMaxRetryDelay)
+}
+
+// PruneOutbox deletes events that were dispatched longer than
+// OUTBOX_RETENTION_DAYS ago and returns how many it removed
+func PruneOutbox(ctx context.Context) (_ int64, err error) {
+	ctx, span := tracing.Start(ctx, "services.PruneOutbox")
+	defer func() { tracing.End(span, err) }()
+
+	retention := OutboxRetention()
+	if retention <= 0 {
+		return 0, nil
+	}
+	return repositories.PruneOutboxEvents(ctx, time.Now().Add(-retention))
+}
+
+// StartOutboxDispatcher dispatches outbox events every OUTBOX_POLL_INTERVAL,
+// and right away when events are committed on this replica, until ctx is
+// cancelled. Dispatched events are pruned once an hour. It is meant to run in
+// its own goroutine, and can run on every replica at once because events are
+// claimed with SKIP LOCKED.
+func StartOutboxDispatcher(ctx context.Context) {
+	ticker := time.NewTicker(config.GetEnvDuration("OUTBOX_POLL_INTERVAL", 2*time.Second))
+	defer ticker.Stop()
+
+	var pruned time.Time
+	for {
+		if _, err := DispatchOutbox(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			slog.ErrorContext(ctx, "dispatching outbox events failed", slog.Any("error", err))
+		}
+		if time.Since(pruned) >= outboxPruneInterval {
+			pruned = time.Now()
+			if removed, err := PruneOutbox(ctx); err != nil {
+				slog.ErrorContext(ctx, "outbox pruning failed", slog.Any("error", err))
+			} else if removed > 0 {
+				slog.InfoContext(ctx, "pruned outbox events", slog.Int64("removed", removed))
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-outboxWake:
+		}
+	}
+}
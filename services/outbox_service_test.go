@@ -0,0 +1,180 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"go-gin-auth-api-starter-kit/config"
+	"go-gin-auth-api-starter-kit/models"
+	"go-gin-auth-api-starter-kit/repositories"
+	"testing"
+	"time"
+)
+
+// outboxTestEvent is only published by these tests
+type outboxTestEvent struct {
+	ID uint `json:"id"`
+}
+
+func (outboxTestEvent) EventType() string { return "test.outbox" }
+
+func (e outboxTestEvent) aggregate() (string, uint) { return "test", e.ID }
+
+// outboxTestHandled receives the events the test handler runs for; outboxTestFail makes it fail
+var (
+	outboxTestHandled = make(chan uint, 10)
+	outboxTestFail    bool
+)
+
+func init() {
+	Subscribe("test", func(ctx context.Context, event outboxTestEvent) error {
+		if outboxTestFail {
+			return errors.New("handler failed")
+		}
+		outboxTestHandled <- event.ID
+		return nil
+	})
+}
+
+func TestOutboxRetryDelay(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 5 * time.Second},
+		{2, 10 * time.Second},
+		{4, 40 * time.Second},
+		{30, outboxMaxRetryDelay},
+	}
+	for _, tt := range tests {
+		if got := outboxRetryDelay(tt.attempts); got != tt.want {
+			t.Errorf("outboxRetryDelay(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestSubscribeTwicePanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("subscribing the same handler name twice did not panic")
+		}
+	}()
+	Subscribe("test", func(context.Context, outboxTestEvent) error { return nil })
+}
+
+func TestPublishEventIsTransactional(t *testing.T) {
+	openTestDB(t, &models.OutboxEvent{}, &models.ProcessedEvent{})
+	ctx := context.Background()
+
+	// A rolled back change leaves no event behind
+	rollback := errors.New("rollback")
+	err := repositories.Transaction(ctx, func(ctx context.Context) error {
+		if err := publishEvent(ctx, outboxTestEvent{ID: 1}); err != nil {
+			return err
+		}
+		return rollback
+	})
+	if !errors.Is(err, rollback) {
+		t.Fatalf("Transaction() = %v", err)
+	}
+	err = repositories.Transaction(ctx, func(ctx context.Context) error {
+		return publishEvent(ctx, outboxTestEvent{ID: 2})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var events []models.OutboxEvent
+	if err := config.DB.Find(&events).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].Type != "test.outbox" || events[0].AggregateID != 2 {
+		t.Fatalf("outbox = %+v, want only the committed event", events)
+	}
+}
+
+func TestDispatchOutboxRetriesFailedHandlers(t *testing.T) {
+	openTestDB(t, &models.OutboxEvent{}, &models.ProcessedEvent{})
+	ctx := context.Background()
+	t.Cleanup(func() { outboxTestFail = false })
+
+	if err := publishEvent(ctx, outboxTestEvent{ID: 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	outboxTestFail = true
+	if dispatched, err := DispatchOutbox(ctx); err != nil || dispatched != 0 {
+		t.Fatalf("DispatchOutbox() = %d, %v; want nothing dispatched", dispatched, err)
+	}
+	var event models.OutboxEvent
+	if err := config.DB.First(&event).Error; err != nil {
+		t.Fatal(err)
+	}
+	if event.DispatchedAt != nil || event.Attempts != 1 || event.LastError == "" || !event.NextAttemptAt.After(time.Now()) {
+		t.Fatalf("after a failure: %+v, want a retry later", event)
+	}
+
+	outboxTestFail = false
+	if err := config.DB.Model(&event).Update("next_attempt_at", time.Now().Add(-time.Second)).Error; err != nil {
+		t.Fatal(err)
+	}
+	if dispatched, err := DispatchOutbox(ctx); err != nil || dispatched != 1 {
+		t.Fatalf("DispatchOutbox() = %d, %v; want the event dispatched", dispatched, err)
+	}
+	select {
+	case id := <-outboxTestHandled:
+		if id != 3 {
+			t.Fatalf("handler got event %d, want 3", id)
+		}
+	default:
+		t.Fatal("handler did not run")
+	}
+
+	// Dispatched events are not handed out again
+	if dispatched, _ := DispatchOutbox(ctx); dispatched != 0 {
+		t.Fatalf("DispatchOutbox() dispatched %d events again", dispatched)
+	}
+}
+
+func TestDispatchOutboxKeepsEntityOrderAcrossRetries(t *testing.T) {
+	openTestDB(t, &models.OutboxEvent{}, &models.ProcessedEvent{})
+	ctx := context.Background()
+	t.Cleanup(func() { outboxTestFail = false })
+
+	for _, id := range []uint{4, 4, 5} {
+		if err := publishEvent(ctx, outboxTestEvent{ID: id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The first event of entity 4 fails; the second must wait for it
+	outboxTestFail = true
+	if _, err := DispatchOutbox(ctx); err != nil {
+		t.Fatal(err)
+	}
+	var events []models.OutboxEvent
+	if err := config.DB.Order("id").Find(&events).Error; err != nil {
+		t.Fatal(err)
+	}
+	if events[0].Attempts != 1 || events[1].Attempts != 0 || events[2].Attempts != 1 {
+		t.Fatalf("attempts = %d, %d, %d; want the second event of entity 4 held back",
+			events[0].Attempts, events[1].Attempts, events[2].Attempts)
+	}
+
+	outboxTestFail = false
+	if err := config.DB.Model(&models.OutboxEvent{}).Where("dispatched_at IS NULL").
+		Update("next_attempt_at", time.Now().Add(-time.Second)).Error; err != nil {
+		t.Fatal(err)
+	}
+	if dispatched, err := DispatchOutbox(ctx); err != nil || dispatched != 3 {
+		t.Fatalf("DispatchOutbox() = %d, %v; want all three events dispatched", dispatched, err)
+	}
+	if err := config.DB.Order("id").Find(&events).Error; err != nil {
+		t.Fatal(err)
+	}
+	if events[1].DispatchedAt.Before(*events[0].DispatchedAt) {
+		t.Errorf("events of entity 4 dispatched out of order: %v after %v", events[0].DispatchedAt, events[1].DispatchedAt)
+	}
+	for len(outboxTestHandled) > 0 {
+		<-outboxTestHandled
+	}
+}
@@ -1,27 +1,341 @@
 package services
 
 import (
+	"context"
+	"errors"
 	"go-gin-auth-api-starter-kit/models"
+	"go-gin-auth-api-starter-kit/pkg/tracing"
 	"go-gin-auth-api-starter-kit/repositories"
+	"time"
+
+	"gorm.io/gorm"
 )
 
+// ErrNotPostAuthor is returned when someone other than the author (or an admin) changes a post
+var ErrNotPostAuthor = errors.New("only the author can change this post")
+
+// PostQuery holds the optional filters for ListPosts
+type PostQuery struct {
+	// Tags keeps posts that have any of these tags (names or slugs)
+	Tags []string
+	// Category keeps posts in the category with this slug or one of its descendants
+	Category string
+	// Status keeps posts in this status; authors use it to find their drafts
+	Status string
+	// Author keeps posts written by the user with this username
+	Author string
+}
+
 // CreatePost handles business logic for creating a post
-func CreatePost(post models.Post) (models.Post, error) {
-	return repositories.CreatePost(post)
+// tagNames are normalized to slugs, and tags that don't exist yet are created.
+// The post starts as a draft unless post.Status asks for it to be published,
+// and is internal unless post.Visibility says otherwise. The content is
+// rendered to HTML in post.ContentFormat, plain text by default.
+func CreatePost(ctx context.Context, actor Actor, post models.Post, tagNames []string) (_ models.Post, err error) {
+	ctx, span := tracing.Start(ctx, "services.CreatePost")
+	defer func() { tracing.End(span, err) }()
+
+	post.UserID = &actor.ID
+	post.ScheduledAt = nil
+	if post.Visibility == "" {
+		post.Visibility = models.PostVisibilityInternal
+	}
+	references, err := renderPost(ctx, &post)
+	if err != nil {
+		return models.Post{}, err
+	}
+	if post.Status == models.PostStatusPublished {
+		now := time.Now()
+		post.PublishedAt = &now
+	} else {
+		post.Status = models.PostStatusDraft
+		post.PublishedAt = nil
+	}
+
+	if err := checkCategory(ctx, post.CategoryID); err != nil {
+		return models.Post{}, err
+	}
+
+	var createdPost models.Post
+	err = repositories.Transaction(ctx, func(ctx context.Context) error {
+		tags, err := ResolveTags(ctx, tagNames)
+		if err != nil {
+			return err
+		}
+		post.Tags = tags
+		hashtags, err := ResolveTags(ctx, references.Hashtags)
+		if err != nil {
+			return err
+		}
+
+		if err := assignSlug(ctx, &post, nil); err != nil {
+			return err
+		}
+		createdPost, err = repositories.CreatePost(ctx, post, hashtags)
+		if err != nil {
+			return err
+		}
+		if err := repositories.ReplacePostMentions(ctx, createdPost.ID, references.mentionedIDs()); err != nil {
+			return err
+		}
+		if err := recordRevision(ctx, actor, createdPost, nil); err != nil {
+			return err
+		}
+		return publishEvent(ctx, PostCreated{
+			PostID:     createdPost.ID,
+			AuthorID:   createdPost.UserID,
+			Title:      createdPost.Title,
+			Status:     createdPost.Status,
+			Visibility: createdPost.Visibility,
+		})
+	})
+	if err != nil {
+		return models.Post{}, err
+	}
+
+	auditTarget(ctx, models.AuditPostCreated, "post", createdPost.ID, models.JSONMap{"title": createdPost.Title, "status": createdPost.Status})
+	return createdPost, nil
+}
+
+// editablePost returns the post if actor may change it: authors can change their
+// own posts and admins can change any post
+func editablePost(ctx context.Context, actor Actor, id uint) (models.Post, error) {
+	post, err := repositories.GetVisiblePostByID(ctx, actor.viewer(), id)
+	if err != nil {
+		return models.Post{}, err
+	}
+	if !post.IsAuthoredBy(actor.ID) && !actor.IsAdmin() {
+		return models.Post{}, ErrNotPostAuthor
+	}
+	return post, nil
 }
 
 // DeletePost handles business logic for deleting a post
+// ifMatch must match the current version of the post
+func DeletePost(ctx context.Context, actor Actor, id uint, ifMatch IfMatch) (err error) {
+	ctx, span := tracing.Start(ctx, "services.DeletePost")
+	defer func() { tracing.End(span, err) }()
+
+	post, err := editablePost(ctx, actor, id)
+	if err != nil {
+		return err
+	}
+	if err := ifMatch.check(post.Version); err != nil {
+		return err
+	}
+
+	err = repositories.Transaction(ctx, func(ctx context.Context) error {
+		if err := repositories.DeletePost(ctx, id, post.Version); err != nil {
+			return versionConflict(err)
+		}
+		return publishEvent(ctx, PostDeleted{PostID: id, AuthorID: post.UserID, ActorID: actor.ID})
+	})
+	if err != nil {
+		return err
+	}
+
+	auditTarget(ctx, models.AuditPostDeleted, "post", id, nil)
+	return nil
+}
+
+// ListPosts handles business logic for listing posts
+// Users see their own posts and the published posts their visibility lets
+// them read, without other users' unlisted posts; admins see everything
+func ListPosts(ctx context.Context, actor Actor, query PostQuery) (_ []models.Post, err error) {
+	ctx, span := tracing.Start(ctx, "services.ListPosts")
+	defer func() { tracing.End(span, err) }()
+
+	filter, ok, err := postFilter(ctx, actor.viewer(), query)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return []models.Post{}, nil
+	}
+	return repositories.ListPosts(ctx, filter)
+}
 
-func DeletePost(id uint) error {
-	return repositories.DeletePost(id)
+// postFilter turns query into a repository filter for viewer. It returns
+// false when no post can match, e.g. because the author does not exist.
+func postFilter(ctx context.Context, viewer repositories.Viewer, query PostQuery) (repositories.PostFilter, bool, error) {
+	filter := repositories.PostFilter{Viewer: viewer, TagSlugs: NormalizeTagSlugs(query.Tags)}
+	if query.Status != "" {
+		filter.Statuses = []string{query.Status}
+	}
+	if len(query.Tags) > 0 && len(filter.TagSlugs) == 0 {
+		// None of the requested tags can exist
+		return filter, false, nil
+	}
+	if query.Category != "" {
+		categoryIDs, err := categoryFilter(ctx, query.Category)
+		if err != nil {
+			return filter, false, err
+		}
+		filter.CategoryIDs = categoryIDs
+	}
+	if query.Author != "" {
+		author, err := repositories.GetUserByUsername(ctx, query.Author)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return filter, false, nil
+		}
+		if err != nil {
+			return filter, false, err
+		}
+		filter.AuthorID = author.ID
+	}
+	return filter, true, nil
 }
 
 // GetPostByID handles business logic for getting a post by ID
-func GetPostByID(id uint) (models.Post, error) {
-	return repositories.GetPostByID(id)
+// Posts the actor may not read are reported as not found
+func GetPostByID(ctx context.Context, actor Actor, id uint) (_ models.Post, err error) {
+	ctx, span := tracing.Start(ctx, "services.GetPostByID")
+	defer func() { tracing.End(span, err) }()
+
+	return repositories.GetVisiblePostByID(ctx, actor.viewer(), id)
+}
+
+// PostFields are the parts of a post its author edits directly.
+// Updates replace all of them at once.
+type PostFields struct {
+	Title   string
+	Content string
+	// Tags are the tags the author chose. The #hashtags in Content add their
+	// own tags, which come and go with the hashtags.
+	Tags       []string
+	CategoryID *uint
+	// ContentFormat is plain text when empty, as for a new post
+	ContentFormat string
+	// Visibility is internal when empty, as for a new post
+	Visibility string
+}
+
+// postFields returns the editable fields of an existing post whose author
+// chose chosenTags
+func postFields(post models.Post, chosenTags []models.Tag) PostFields {
+	tags := make([]string, 0, len(chosenTags))
+	for _, tag := range chosenTags {
+		tags = append(tags, tag.Name)
+	}
+	return PostFields{
+		Title:         post.Title,
+		Content:       post.Content,
+		ContentFormat: post.ContentFormat,
+		Tags:          tags,
+		CategoryID:    post.CategoryID,
+		Visibility:    post.Visibility,
+	}
 }
 
 // UpdatePost handles business logic for updating a post
-func UpdatePost(id uint, post models.Post) (models.Post, error) {
-	return repositories.UpdatePost(id, post)
+// It is a full replacement: fields left empty are cleared or reset to their
+// defaults, and the chosen tags are set to exactly fields.Tags. ifMatch must match the current version of the post.
+func UpdatePost(ctx context.Context, actor Actor, id uint, fields PostFields, ifMatch IfMatch) (_ models.Post, err error) {
+	ctx, span := tracing.Start(ctx, "services.UpdatePost")
+	defer func() { tracing.End(span, err) }()
+
+	current, err := editablePost(ctx, actor, id)
+	if err != nil {
+		return models.Post{}, err
+	}
+	if err := ifMatch.check(current.Version); err != nil {
+		return models.Post{}, err
+	}
+
+	return replacePost(ctx, actor, current, fields)
+}
+
+// PatchPost applies a partial update. patch receives the current fields of
+// the post and returns the fields to save, or an error to abort. The result is
+// saved against the version patch saw, so a concurrent change makes PatchPost
+// fail with ErrPostVersionMismatch instead of being overwritten.
+func PatchPost(ctx context.Context, actor Actor, id uint, ifMatch IfMatch, patch func(PostFields) (PostFields, error)) (_ models.Post, err error) {
+	ctx, span := tracing.Start(ctx, "services.PatchPost")
+	defer func() { tracing.End(span, err) }()
+
+	current, err := editablePost(ctx, actor, id)
+	if err != nil {
+		return models.Post{}, err
+	}
+	if err := ifMatch.check(current.Version); err != nil {
+		return models.Post{}, err
+	}
+
+	chosenTags, err := repositories.ListPostTags(ctx, id, models.TagSourceManual)
+	if err != nil {
+		return models.Post{}, err
+	}
+	fields, err := patch(postFields(current, chosenTags))
+	if err != nil {
+		return models.Post{}, err
+	}
+	return replacePost(ctx, actor, current, fields)
+}
+
+// replacePost saves fields over current, provided it has not changed since it was read
+func replacePost(ctx context.Context, actor Actor, current models.Post, fields PostFields) (models.Post, error) {
+	if err := checkCategory(ctx, fields.CategoryID); err != nil {
+		return models.Post{}, err
+	}
+
+	post := models.Post{
+		Title:         fields.Title,
+		Content:       fields.Content,
+		ContentFormat: fields.ContentFormat,
+		CategoryID:    fields.CategoryID,
+		Visibility:    fields.Visibility,
+	}
+	if post.Visibility == "" {
+		post.Visibility = models.PostVisibilityInternal
+	}
+	references, err := renderPost(ctx, &post)
+	if err != nil {
+		return models.Post{}, err
+	}
+
+	var updatedPost models.Post
+	err = repositories.Transaction(ctx, func(ctx context.Context) error {
+		tags, err := ResolveTags(ctx, fields.Tags)
+		if err != nil {
+			return err
+		}
+		post.Tags = tags
+		hashtags, err := ResolveTags(ctx, references.Hashtags)
+		if err != nil {
+			return err
+		}
+
+		if err := assignSlug(ctx, &post, &current); err != nil {
+			return err
+		}
+		updatedPost, err = repositories.UpdatePost(ctx, current.ID, current.Version, post, hashtags)
+		if err != nil {
+			return versionConflict(err)
+		}
+		if err := repositories.ReplacePostMentions(ctx, current.ID, references.mentionedIDs()); err != nil {
+			return err
+		}
+		if err := recordRevision(ctx, actor, updatedPost, nil); err != nil {
+			return err
+		}
+		return publishEvent(ctx, PostUpdated{
+			PostID:     updatedPost.ID,
+			AuthorID:   updatedPost.UserID,
+			ActorID:    actor.ID,
+			Version:    updatedPost.Version,
+			Title:      updatedPost.Title,
+			Status:     updatedPost.Status,
+			Visibility: updatedPost.Visibility,
+		})
+	})
+	if err != nil {
+		return models.Post{}, err
+	}
+
+	metadata := models.JSONMap{"title": updatedPost.Title}
+	if updatedPost.Visibility != current.Visibility {
+		metadata["visibility"] = models.JSONMap{"from": current.Visibility, "to": updatedPost.Visibility}
+	}
+	auditTarget(ctx, models.AuditPostUpdated, "post", current.ID, metadata)
+	return updatedPost, nil
 }
@@ -0,0 +1,174 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"go-gin-auth-api-starter-kit/config"
+	"go-gin-auth-api-starter-kit/models"
+	"go-gin-auth-api-starter-kit/pkg/tracing"
+	"go-gin-auth-api-starter-kit/repositories"
+	"log/slog"
+	"slices"
+	"time"
+)
+
+var (
+	// ErrInvalidPostTransition is returned when a post cannot move to the requested status from its current one
+	ErrInvalidPostTransition = errors.New("post cannot move to this status from its current status")
+	// ErrScheduleInPast is returned when scheduling a post for a time that has already passed
+	ErrScheduleInPast = errors.New("publish_at must be in the future")
+)
+
+// Post workflow actions accepted by TransitionPost
+const (
+	PostActionPublish   = "publish"
+	PostActionSchedule  = "schedule"
+	PostActionUnpublish = "unpublish"
+	PostActionArchive   = "archive"
+)
+
+// postTransition describes one workflow action: the statuses it may start
+// from, the status it leads to and the audit event it records
+type postTransition struct {
+	from  []string
+	to    string
+	event string
+}
+
+var postTransitions = map[string]postTransition{
+	PostActionPublish: {
+		from:  []string{models.PostStatusDraft, models.PostStatusScheduled, models.PostStatusArchived},
+		to:    models.PostStatusPublished,
+		event: models.AuditPostPublished,
+	},
+	PostActionSchedule: {
+		from:  []string{models.PostStatusDraft, models.PostStatusScheduled},
+		to:    models.PostStatusScheduled,
+		event: models.AuditPostScheduled,
+	},
+	PostActionUnpublish: {
+		from:  []string{models.PostStatusPublished, models.PostStatusScheduled},
+		to:    models.PostStatusDraft,
+		event: models.AuditPostUnpublished,
+	},
+	PostActionArchive: {
+		from:  []string{models.PostStatusDraft, models.PostStatusScheduled, models.PostStatusPublished},
+		to:    models.PostStatusArchived,
+		event: models.AuditPostArchived,
+	},
+}
+
+// TransitionPost applies a workflow action to a post. publishAt is only used
+// by PostActionSchedule and must be in the future.
+func TransitionPost(ctx context.Context, actor Actor, id uint, action string, publishAt *time.Time) (_ models.Post, err error) {
+	ctx, span := tracing.Start(ctx, "services.TransitionPost")
+	defer func() { tracing.End(span, err) }()
+
+	transition, ok := postTransitions[action]
+	if !ok {
+		return models.Post{}, ErrInvalidPostTransition
+	}
+
+	post, err := editablePost(ctx, actor, id)
+	if err != nil {
+		return models.Post{}, err
+	}
+	if !slices.Contains(transition.from, post.Status) {
+		return models.Post{}, ErrInvalidPostTransition
+	}
+
+	from := post.Status
+	post.Status = transition.to
+	post.ScheduledAt = nil
+	switch action {
+	case PostActionPublish:
+		if post.PublishedAt == nil {
+			now := time.Now()
+			post.PublishedAt = &now
+		}
+	case PostActionSchedule:
+		if publishAt == nil || !publishAt.After(time.Now()) {
+			return models.Post{}, ErrScheduleInPast
+		}
+		post.ScheduledAt = publishAt
+	}
+
+	err = repositories.Transaction(ctx, func(ctx context.Context) error {
+		changed, err := repositories.SetPostStatus(ctx, id, from, post)
+		if err != nil {
+			return err
+		}
+		if !changed {
+			// Someone else (or the scheduler) changed the status in the meantime
+			return ErrInvalidPostTransition
+		}
+		if transition.to == models.PostStatusPublished {
+			return publishEvent(ctx, PostPublished{PostID: id, AuthorID: post.UserID, ActorID: actor.ID, From: from})
+		}
+		return publishEvent(ctx, PostStatusChanged{PostID: id, AuthorID: post.UserID, ActorID: actor.ID, From: from, To: transition.to})
+	})
+	if err != nil {
+		return models.Post{}, err
+	}
+
+	metadata := models.JSONMap{"from": from, "to": transition.to}
+	if post.ScheduledAt != nil {
+		metadata["publish_at"] = post.ScheduledAt
+	}
+	auditTarget(ctx, transition.event, "post", id, metadata)
+	return repositories.GetPostByID(ctx, id)
+}
+
+// PublishScheduledPosts publishes every scheduled post that is due
+func PublishScheduledPosts(ctx context.Context) (_ []uint, err error) {
+	ctx, span := tracing.Start(ctx, "services.PublishScheduledPosts")
+	defer func() { tracing.End(span, err) }()
+
+	var ids []uint
+	err = repositories.Transaction(ctx, func(ctx context.Context) error {
+		posts, err := repositories.PublishDuePosts(ctx, time.Now())
+		if err != nil {
+			return err
+		}
+		for _, post := range posts {
+			if err := publishEvent(ctx, PostPublished{PostID: post.ID, AuthorID: post.UserID, From: models.PostStatusScheduled}); err != nil {
+				return err
+			}
+			ids = append(ids, post.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, id := range ids {
+		auditTarget(ctx, models.AuditPostPublished, "post", id, models.JSONMap{
+			"from": models.PostStatusScheduled, "to": models.PostStatusPublished, "scheduled": true,
+		})
+	}
+	return ids, nil
+}
+
+// StartPostScheduler publishes due scheduled posts every POST_SCHEDULER_INTERVAL
+// until ctx is cancelled. It is meant to run in its own goroutine, and can run
+// on every replica at once because PublishDuePosts coordinates through a DB lock.
+func StartPostScheduler(ctx context.Context) {
+	interval := config.GetEnvDuration("POST_SCHEDULER_INTERVAL", 30*time.Second)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if ids, err := PublishScheduledPosts(ctx); err != nil {
+			slog.ErrorContext(ctx, "publishing scheduled posts failed", slog.Any("error", err))
+		} else if len(ids) > 0 {
+			slog.InfoContext(ctx, "published scheduled posts", slog.Any("post_ids", ids))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
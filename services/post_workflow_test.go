@@ -0,0 +1,130 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-gin-auth-api-starter-kit/config"
+	"go-gin-auth-api-starter-kit/models"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPostTransitions(t *testing.T) {
+	statuses := []string{models.PostStatusDraft, models.PostStatusScheduled, models.PostStatusPublished, models.PostStatusArchived}
+	allowed := map[string][]string{
+		PostActionPublish:   {models.PostStatusDraft, models.PostStatusScheduled, models.PostStatusArchived},
+		PostActionSchedule:  {models.PostStatusDraft, models.PostStatusScheduled},
+		PostActionUnpublish: {models.PostStatusPublished, models.PostStatusScheduled},
+		PostActionArchive:   {models.PostStatusDraft, models.PostStatusScheduled, models.PostStatusPublished},
+	}
+	targets := map[string]string{
+		PostActionPublish:   models.PostStatusPublished,
+		PostActionSchedule:  models.PostStatusScheduled,
+		PostActionUnpublish: models.PostStatusDraft,
+		PostActionArchive:   models.PostStatusArchived,
+	}
+
+	if len(postTransitions) != len(allowed) {
+		t.Errorf("%d workflow actions, want %d", len(postTransitions), len(allowed))
+	}
+	for action, from := range allowed {
+		transition, ok := postTransitions[action]
+		if !ok {
+			t.Errorf("action %q is missing", action)
+			continue
+		}
+		if transition.to != targets[action] {
+			t.Errorf("%s leads to %q, want %q", action, transition.to, targets[action])
+		}
+		if transition.event == "" {
+			t.Errorf("%s records no audit event", action)
+		}
+		for _, status := range statuses {
+			if got, want := slices.Contains(transition.from, status), slices.Contains(from, status); got != want {
+				t.Errorf("%s from %s allowed = %v, want %v", action, status, got, want)
+			}
+		}
+	}
+}
+
+func TestTransitionPostRejectsUnknownActions(t *testing.T) {
+	_, err := TransitionPost(context.Background(), Actor{ID: 1, Role: models.RoleUser}, 1, "delete", nil)
+	if !errors.Is(err, ErrInvalidPostTransition) {
+		t.Errorf("TransitionPost(delete) err = %v, want ErrInvalidPostTransition", err)
+	}
+}
+
+func TestPublishScheduledPostsRunsOnce(t *testing.T) {
+	openTestDB(t, &models.User{}, &models.Tag{}, &models.Category{}, &models.Post{}, &models.PostTag{},
+		&models.OutboxEvent{}, &models.AuditLog{})
+	ctx := context.Background()
+
+	author := models.User{Username: "alice", Email: "alice@example.com", Password: "x"}
+	if err := config.DB.Create(&author).Error; err != nil {
+		t.Fatal(err)
+	}
+	due := time.Now().Add(-time.Minute)
+	later := time.Now().Add(time.Hour)
+	var want []uint
+	for i := range 6 {
+		scheduledAt := due
+		if i == 5 {
+			scheduledAt = later
+		}
+		post := models.Post{Title: fmt.Sprintf("Post %d", i), Slug: fmt.Sprintf("post-%d", i), UserID: &author.ID,
+			Status: models.PostStatusScheduled, ScheduledAt: &scheduledAt}
+		if err := config.DB.Create(&post).Error; err != nil {
+			t.Fatal(err)
+		}
+		if i < 5 {
+			want = append(want, post.ID)
+		}
+	}
+
+	// Two replicas run the scheduler at the same moment
+	var wg sync.WaitGroup
+	results := make([][]uint, 2)
+	errs := make([]error, 2)
+	start := make(chan struct{})
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			results[i], errs[i] = PublishScheduledPosts(ctx)
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	var got []uint
+	for i := range results {
+		if errs[i] != nil {
+			t.Fatal(errs[i])
+		}
+		got = append(got, results[i]...)
+	}
+	slices.Sort(got)
+	if !slices.Equal(got, want) {
+		t.Errorf("published %v, want every due post exactly once: %v", got, want)
+	}
+
+	var events int64
+	if err := config.DB.Model(&models.OutboxEvent{}).Where("type = ?", PostPublished{}.EventType()).Count(&events).Error; err != nil {
+		t.Fatal(err)
+	}
+	if events != int64(len(want)) {
+		t.Errorf("%d post.published events, want %d", events, len(want))
+	}
+
+	var published int64
+	if err := config.DB.Model(&models.Post{}).Where("status = ?", models.PostStatusPublished).Count(&published).Error; err != nil {
+		t.Fatal(err)
+	}
+	if published != int64(len(want)) {
+		t.Errorf("%d posts published, want %d; the one scheduled later must wait", published, len(want))
+	}
+}
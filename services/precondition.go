@@ -0,0 +1,50 @@
+package services
+
+import (
+	"errors"
+	"go-gin-auth-api-starter-kit/config"
+	"go-gin-auth-api-starter-kit/repositories"
+	"slices"
+)
+
+var (
+	// ErrPostVersionMismatch is returned when the client's If-Match does not match the current post version
+	ErrPostVersionMismatch = errors.New("post has been modified since it was read")
+	// ErrPreconditionRequired is returned when If-Match is missing and POST_REQUIRE_IF_MATCH is on
+	ErrPreconditionRequired = errors.New("this request requires an If-Match header")
+)
+
+// IfMatch is the version precondition sent by a client with a change request
+type IfMatch struct {
+	// Present is false when the client sent no precondition at all
+	Present bool
+	// Any matches every version ("If-Match: *")
+	Any bool
+	// Versions are the versions the client accepts
+	Versions []int
+}
+
+// RequireIfMatch reports whether post updates and deletes must carry If-Match
+func RequireIfMatch() bool {
+	return config.GetEnvBool("POST_REQUIRE_IF_MATCH", false)
+}
+
+// check verifies the precondition against the current version of a post
+func (m IfMatch) check(version int) error {
+	switch {
+	case !m.Present && RequireIfMatch():
+		return ErrPreconditionRequired
+	case !m.Present, m.Any, slices.Contains(m.Versions, version):
+		return nil
+	default:
+		return ErrPostVersionMismatch
+	}
+}
+
+// versionConflict translates a failed compare-and-swap into ErrPostVersionMismatch
+func versionConflict(err error) error {
+	if errors.Is(err, repositories.ErrVersionConflict) {
+		return ErrPostVersionMismatch
+	}
+	return err
+}
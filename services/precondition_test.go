@@ -0,0 +1,35 @@
+package services
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestIfMatchCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		ifMatch IfMatch
+		require bool
+		want    error
+	}{
+		{"absent", IfMatch{}, false, nil},
+		{"absent but required", IfMatch{}, true, ErrPreconditionRequired},
+		{"current version", IfMatch{Present: true, Versions: []int{3}}, true, nil},
+		{"one of several", IfMatch{Present: true, Versions: []int{1, 3}}, false, nil},
+		{"stale version", IfMatch{Present: true, Versions: []int{2}}, false, ErrPostVersionMismatch},
+		{"nothing usable", IfMatch{Present: true, Versions: []int{}}, false, ErrPostVersionMismatch},
+		{"any", IfMatch{Present: true, Any: true}, true, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.require {
+				t.Setenv("POST_REQUIRE_IF_MATCH", "true")
+			} else {
+				t.Setenv("POST_REQUIRE_IF_MATCH", "false")
+			}
+			if err := tt.ifMatch.check(3); !errors.Is(err, tt.want) {
+				t.Errorf("check(3) = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
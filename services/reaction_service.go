@@ -0,0 +1,226 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"go-gin-auth-api-starter-kit/config"
+	"go-gin-auth-api-starter-kit/models"
+	"go-gin-auth-api-starter-kit/pkg/tracing"
+	"go-gin-auth-api-starter-kit/repositories"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ErrInvalidReaction is returned for emoji that are not in REACTION_EMOJIS
+var ErrInvalidReaction = errors.New("this reaction is not available")
+
+// ReactionEmojis are the emoji posts can be reacted with, in display order
+func ReactionEmojis() []string {
+	var emojis []string
+	for _, emoji := range config.GetEnvList("REACTION_EMOJIS", []string{"👍", "❤️", "😂", "🎉", "😮", "😢"}) {
+		if utf8.RuneCountInString(emoji) <= 32 && !slices.Contains(emojis, emoji) {
+			emojis = append(emojis, emoji)
+		}
+	}
+	return emojis
+}
+
+// TopReactedLimit is the largest number of posts the top reacted listing returns
+const TopReactedLimit = 100
+
+// variationSelector asks for emoji presentation. Clients add or drop it freely.
+const variationSelector = "\uFE0F"
+
+// reactionEmoji returns the configured form of emoji. Variation selectors
+// are ignored, so "❤" and "❤️" are the same reaction.
+func reactionEmoji(emoji string) (string, error) {
+	key := strings.ReplaceAll(emoji, variationSelector, "")
+	for _, allowed := range ReactionEmojis() {
+		if strings.ReplaceAll(allowed, variationSelector, "") == key {
+			return allowed, nil
+		}
+	}
+	return "", ErrInvalidReaction
+}
+
+// AddReaction reacts to a post the actor may read. Reacting again with the
+// same emoji is not an error. It returns the post's reactions afterwards.
+func AddReaction(ctx context.Context, actor Actor, postID uint, emoji string) (_ []models.ReactionSummary, err error) {
+	ctx, span := tracing.Start(ctx, "services.AddReaction")
+	defer func() { tracing.End(span, err) }()
+
+	emoji, err = reactionEmoji(emoji)
+	if err != nil {
+		return nil, err
+	}
+	post, err := repositories.GetVisiblePostByID(ctx, actor.viewer(), postID)
+	if err != nil {
+		return nil, err
+	}
+	added, err := repositories.AddReaction(ctx, postID, actor.ID, emoji)
+	if err != nil {
+		return nil, err
+	}
+	if added {
+		notifyReaction(ctx, actor, post, emoji)
+	}
+	return postReactions(ctx, actor, postID)
+}
+
+// notifyReaction tells the author of a post about a new reaction to it, once
+// per user and emoji
+func notifyReaction(ctx context.Context, actor Actor, post models.Post, emoji string) {
+	if post.UserID == nil || post.IsAuthoredBy(actor.ID) {
+		return
+	}
+	notified, err := repositories.ReactionNotified(ctx, *post.UserID, actor.ID, post.ID, emoji)
+	if err != nil {
+		slog.ErrorContext(ctx, "checking reaction notifications failed", slog.Uint64("post_id", uint64(post.ID)), slog.Any("error", err))
+		return
+	}
+	if notified {
+		return
+	}
+	notify(ctx, models.Notification{
+		UserID:  *post.UserID,
+		Type:    models.NotificationReaction,
+		ActorID: &actor.ID,
+		PostID:  &post.ID,
+		Data:    models.JSONMap{"emoji": emoji},
+	})
+}
+
+// RemoveReaction takes back the actor's reaction to a post. Removing a
+// reaction that isn't there is not an error. It returns the post's reactions afterwards.
+func RemoveReaction(ctx context.Context, actor Actor, postID uint, emoji string) (_ []models.ReactionSummary, err error) {
+	ctx, span := tracing.Start(ctx, "services.RemoveReaction")
+	defer func() { tracing.End(span, err) }()
+
+	emoji, err = reactionEmoji(emoji)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := repositories.GetVisiblePostByID(ctx, actor.viewer(), postID); err != nil {
+		return nil, err
+	}
+	if _, err := repositories.RemoveReaction(ctx, postID, actor.ID, emoji); err != nil {
+		return nil, err
+	}
+	return postReactions(ctx, actor, postID)
+}
+
+// ListPostReactions returns the reactions to a post the actor may read
+func ListPostReactions(ctx context.Context, actor Actor, postID uint) (_ []models.ReactionSummary, err error) {
+	ctx, span := tracing.Start(ctx, "services.ListPostReactions")
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := repositories.GetVisiblePostByID(ctx, actor.viewer(), postID); err != nil {
+		return nil, err
+	}
+	return postReactions(ctx, actor, postID)
+}
+
+// postReactions summarizes the reactions to one post for the actor
+func postReactions(ctx context.Context, actor Actor, postID uint) ([]models.ReactionSummary, error) {
+	posts := make([]models.Post, 1)
+	posts[0].ID = postID
+	if err := LoadPostEngagement(ctx, actor, posts); err != nil {
+		return nil, err
+	}
+	return posts[0].Reactions, nil
+}
+
+// LoadPostEngagement fills in the reaction counts of posts and, for signed
+// in actors, which reactions are theirs and which posts they bookmarked.
+// Emoji are ordered as in REACTION_EMOJIS; reactions with emoji that have
+// since been removed from it come last.
+func LoadPostEngagement(ctx context.Context, actor Actor, posts []models.Post) (err error) {
+	ctx, span := tracing.Start(ctx, "services.LoadPostEngagement")
+	defer func() { tracing.End(span, err) }()
+
+	if len(posts) == 0 {
+		return nil
+	}
+	ids := make([]uint, 0, len(posts))
+	for _, post := range posts {
+		ids = append(ids, post.ID)
+	}
+
+	counts, err := repositories.ListReactionCounts(ctx, ids)
+	if err != nil {
+		return err
+	}
+	reacted := make(map[uint][]string)
+	bookmarked := make(map[uint]bool)
+	if actor.ID != 0 {
+		reactions, err := repositories.ListUserReactions(ctx, actor.ID, ids)
+		if err != nil {
+			return err
+		}
+		for _, reaction := range reactions {
+			reacted[reaction.PostID] = append(reacted[reaction.PostID], reaction.Emoji)
+		}
+		bookmarkedIDs, err := repositories.BookmarkedPostIDs(ctx, actor.ID, ids)
+		if err != nil {
+			return err
+		}
+		for _, id := range bookmarkedIDs {
+			bookmarked[id] = true
+		}
+	}
+
+	summaries := make(map[uint][]models.ReactionSummary)
+	for _, count := range counts {
+		summaries[count.PostID] = append(summaries[count.PostID], models.ReactionSummary{
+			Emoji:   count.Emoji,
+			Count:   count.Count,
+			Reacted: slices.Contains(reacted[count.PostID], count.Emoji),
+		})
+	}
+
+	order := ReactionEmojis()
+	rank := func(emoji string) int {
+		if i := slices.Index(order, emoji); i >= 0 {
+			return i
+		}
+		return len(order)
+	}
+	for i := range posts {
+		reactions := summaries[posts[i].ID]
+		slices.SortStableFunc(reactions, func(a, b models.ReactionSummary) int {
+			return rank(a.Emoji) - rank(b.Emoji)
+		})
+		if reactions == nil {
+			reactions = []models.ReactionSummary{}
+		}
+		posts[i].Reactions = reactions
+		posts[i].Bookmarked = bookmarked[posts[i].ID]
+	}
+	return nil
+}
+
+// TopReactedPosts returns up to limit published posts the actor may list
+// with the most reactions, most reacted first. With days > 0 only reactions
+// from the last days count; otherwise all of them do.
+func TopReactedPosts(ctx context.Context, actor Actor, query PostQuery, days, limit int) (_ []models.Post, err error) {
+	ctx, span := tracing.Start(ctx, "services.TopReactedPosts")
+	defer func() { tracing.End(span, err) }()
+
+	query.Status = models.PostStatusPublished
+	filter, ok, err := postFilter(ctx, actor.viewer(), query)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return []models.Post{}, nil
+	}
+
+	var since time.Time
+	if days > 0 {
+		since = time.Now().AddDate(0, 0, -days)
+	}
+	return repositories.ListTopReactedPosts(ctx, filter, since, min(max(limit, 1), TopReactedLimit))
+}
@@ -0,0 +1,113 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"go-gin-auth-api-starter-kit/config"
+	"go-gin-auth-api-starter-kit/models"
+	"sync"
+	"testing"
+)
+
+func TestReactionEmoji(t *testing.T) {
+	tests := []struct {
+		name       string
+		configured string
+		emoji      string
+		want       string
+	}{
+		{"exact", "", "👍", "👍"},
+		{"configured with a variation selector", "", "❤\uFE0F", "❤\uFE0F"},
+		{"sent without the variation selector", "", "❤", "❤\uFE0F"},
+		{"sent with an extra variation selector", "", "👍\uFE0F", "👍"},
+		{"configured without the variation selector", "❤,👍", "❤\uFE0F", "❤"},
+		{"several variation selectors", "", "❤\uFE0F\uFE0F", "❤\uFE0F"},
+		{"unknown", "", "🦀", ""},
+		{"only a variation selector", "", "\uFE0F", ""},
+		{"empty", "", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("REACTION_EMOJIS", tt.configured)
+			got, err := reactionEmoji(tt.emoji)
+			if tt.want == "" {
+				if !errors.Is(err, ErrInvalidReaction) {
+					t.Errorf("reactionEmoji(%q) = %q, %v; want ErrInvalidReaction", tt.emoji, got, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("reactionEmoji(%q) = %q, %v; want %q", tt.emoji, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestConcurrentReactionsKeepCountsExact(t *testing.T) {
+	openTestDB(t, &models.User{}, &models.Tag{}, &models.Category{}, &models.Post{}, &models.PostTag{},
+		&models.PostReaction{}, &models.PostReactionCount{}, &models.Notification{}, &models.NotificationPreference{},
+		&models.OutboxEvent{})
+	ctx := context.Background()
+
+	users := make([]models.User, 4)
+	for i := range users {
+		users[i] = models.User{Username: string(rune('a' + i)), Email: string(rune('a'+i)) + "@example.com", Password: "x"}
+	}
+	if err := config.DB.Create(&users).Error; err != nil {
+		t.Fatal(err)
+	}
+	post := models.Post{Title: "Hello", Status: models.PostStatusPublished, Visibility: models.PostVisibilityInternal}
+	if err := config.DB.Create(&post).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// Every user adds and removes the same reaction at once, spelled both ways
+	var wg sync.WaitGroup
+	errs := make(chan error, len(users)*2)
+	for _, user := range users {
+		actor := Actor{ID: user.ID, Role: models.RoleUser}
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for range 20 {
+				if _, err := AddReaction(ctx, actor, post.ID, "❤"); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for range 20 {
+				if _, err := RemoveReaction(ctx, actor, post.ID, "❤\uFE0F"); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+
+	var reactions int64
+	if err := config.DB.Model(&models.PostReaction{}).Where("post_id = ?", post.ID).Count(&reactions).Error; err != nil {
+		t.Fatal(err)
+	}
+	var counts []models.PostReactionCount
+	if err := config.DB.Where("post_id = ?", post.ID).Find(&counts).Error; err != nil {
+		t.Fatal(err)
+	}
+	counted := 0
+	for _, count := range counts {
+		if count.Emoji != "❤\uFE0F" {
+			t.Errorf("counted emoji %q, want only the configured form", count.Emoji)
+		}
+		counted += count.Count
+	}
+	if int64(counted) != reactions {
+		t.Errorf("counter says %d reactions, post_reactions has %d", counted, reactions)
+	}
+}
@@ -0,0 +1,172 @@
+package services
+
+import (
+	"context"
+	"go-gin-auth-api-starter-kit/config"
+	"go-gin-auth-api-starter-kit/models"
+	"go-gin-auth-api-starter-kit/pkg/tracing"
+	"go-gin-auth-api-starter-kit/repositories"
+	"go-gin-auth-api-starter-kit/utils"
+)
+
+// PostRevisionDiff is the line-level difference between two revisions of a post
+type PostRevisionDiff struct {
+	From    models.PostRevision
+	To      models.PostRevision
+	Title   []utils.DiffLine
+	Content []utils.DiffLine
+	// Added and Removed count the changed content lines
+	Added   int
+	Removed int
+}
+
+// PostRevisionLimit is how many revisions are kept per post; zero keeps them all
+func PostRevisionLimit() int {
+	return config.GetEnvInt("POST_REVISION_LIMIT", 50)
+}
+
+// recordRevision snapshots the current title and content of post and prunes
+// revisions beyond PostRevisionLimit. Call it inside the transaction that
+// changed the post.
+func recordRevision(ctx context.Context, actor Actor, post models.Post, restoredFrom *int) error {
+	revision := models.PostRevision{
+		PostID:       post.ID,
+		Title:        post.Title,
+		Content:      post.Content,
+		RestoredFrom: restoredFrom,
+	}
+	if actor.ID != 0 {
+		revision.EditorID = &actor.ID
+	}
+
+	if _, err := repositories.CreatePostRevision(ctx, revision); err != nil {
+		return err
+	}
+
+	if limit := PostRevisionLimit(); limit > 0 {
+		if _, err := repositories.PrunePostRevisions(ctx, post.ID, limit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListPostRevisions returns a page of a post's revisions, newest first.
+// Like editing, the history is only available to the author and admins.
+func ListPostRevisions(ctx context.Context, actor Actor, postID uint, page, perPage int) (_ []models.PostRevision, _ int64, err error) {
+	ctx, span := tracing.Start(ctx, "services.ListPostRevisions")
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := editablePost(ctx, actor, postID); err != nil {
+		return nil, 0, err
+	}
+	return repositories.ListPostRevisions(ctx, postID, page, perPage)
+}
+
+// GetPostRevision returns a single revision of a post
+func GetPostRevision(ctx context.Context, actor Actor, postID uint, number int) (_ models.PostRevision, err error) {
+	ctx, span := tracing.Start(ctx, "services.GetPostRevision")
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := editablePost(ctx, actor, postID); err != nil {
+		return models.PostRevision{}, err
+	}
+	return repositories.GetPostRevision(ctx, postID, number)
+}
+
+// DiffPostRevisions compares revision from with revision to, line by line
+func DiffPostRevisions(ctx context.Context, actor Actor, postID uint, from, to int) (_ PostRevisionDiff, err error) {
+	ctx, span := tracing.Start(ctx, "services.DiffPostRevisions")
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := editablePost(ctx, actor, postID); err != nil {
+		return PostRevisionDiff{}, err
+	}
+
+	fromRevision, err := repositories.GetPostRevision(ctx, postID, from)
+	if err != nil {
+		return PostRevisionDiff{}, err
+	}
+	toRevision, err := repositories.GetPostRevision(ctx, postID, to)
+	if err != nil {
+		return PostRevisionDiff{}, err
+	}
+
+	diff := PostRevisionDiff{
+		From:    fromRevision,
+		To:      toRevision,
+		Title:   utils.DiffLines(fromRevision.Title, toRevision.Title),
+		Content: utils.DiffLines(fromRevision.Content, toRevision.Content),
+	}
+	for _, line := range diff.Content {
+		switch line.Op {
+		case utils.DiffInsert:
+			diff.Added++
+		case utils.DiffDelete:
+			diff.Removed++
+		}
+	}
+	return diff, nil
+}
+
+// RestorePostRevision puts the title and content of an old revision back on
+// the post. The restore is recorded as a new revision, so nothing is lost.
+// ifMatch must match the current version of the post.
+func RestorePostRevision(ctx context.Context, actor Actor, postID uint, number int, ifMatch IfMatch) (_ models.Post, err error) {
+	ctx, span := tracing.Start(ctx, "services.RestorePostRevision")
+	defer func() { tracing.End(span, err) }()
+
+	current, err := editablePost(ctx, actor, postID)
+	if err != nil {
+		return models.Post{}, err
+	}
+	if err := ifMatch.check(current.Version); err != nil {
+		return models.Post{}, err
+	}
+
+	var restoredPost models.Post
+	err = repositories.Transaction(ctx, func(ctx context.Context) error {
+		revision, err := repositories.GetPostRevision(ctx, postID, number)
+		if err != nil {
+			return err
+		}
+
+		// The revision is rendered in the post's current format
+		post := models.Post{Title: revision.Title, Content: revision.Content, ContentFormat: current.ContentFormat}
+		references, err := renderPost(ctx, &post)
+		if err != nil {
+			return err
+		}
+		if err := assignSlug(ctx, &post, &current); err != nil {
+			return err
+		}
+		if err := repositories.SetPostContent(ctx, postID, current.Version, post); err != nil {
+			return versionConflict(err)
+		}
+		if err := recordReferences(ctx, postID, references); err != nil {
+			return err
+		}
+		if restoredPost, err = repositories.GetPostByID(ctx, postID); err != nil {
+			return err
+		}
+		if err := recordRevision(ctx, actor, restoredPost, &number); err != nil {
+			return err
+		}
+		return publishEvent(ctx, PostUpdated{
+			PostID:           restoredPost.ID,
+			AuthorID:         restoredPost.UserID,
+			ActorID:          actor.ID,
+			Version:          restoredPost.Version,
+			Title:            restoredPost.Title,
+			Status:           restoredPost.Status,
+			Visibility:       restoredPost.Visibility,
+			RestoredRevision: &number,
+		})
+	})
+	if err != nil {
+		return models.Post{}, err
+	}
+
+	auditTarget(ctx, models.AuditPostRevisionRestored, "post", postID, models.JSONMap{"revision": number})
+	return restoredPost, nil
+}
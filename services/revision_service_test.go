@@ -0,0 +1,44 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"go-gin-auth-api-starter-kit/config"
+	"go-gin-auth-api-starter-kit/models"
+	"testing"
+)
+
+func TestRestorePostRevisionChecksTheVersion(t *testing.T) {
+	openTestDB(t, &models.User{}, &models.Tag{}, &models.Category{}, &models.Post{}, &models.PostTag{}, &models.PostSlugAlias{},
+		&models.PostRevision{}, &models.Mention{}, &models.OutboxEvent{}, &models.AuditLog{})
+	ctx := context.Background()
+
+	author := models.User{Username: "alice", Email: "alice@example.com", Password: "x"}
+	if err := config.DB.Create(&author).Error; err != nil {
+		t.Fatal(err)
+	}
+	actor := Actor{ID: author.ID, Role: models.RoleUser}
+
+	post, err := CreatePost(ctx, actor, models.Post{Title: "First", Content: "first draft"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale := IfMatch{Present: true, Versions: []int{post.Version}}
+	updated, err := UpdatePost(ctx, actor, post.ID, PostFields{Title: "Second", Content: "second draft"}, stale)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The restore was based on the post before the update
+	if _, err := RestorePostRevision(ctx, actor, post.ID, 1, stale); !errors.Is(err, ErrPostVersionMismatch) {
+		t.Fatalf("restore with a stale If-Match: err = %v, want ErrPostVersionMismatch", err)
+	}
+
+	restored, err := RestorePostRevision(ctx, actor, post.ID, 1, IfMatch{Present: true, Versions: []int{updated.Version}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.Title != "First" || restored.Version != updated.Version+1 {
+		t.Errorf("restored post: title %q, version %d; want %q at version %d", restored.Title, restored.Version, "First", updated.Version+1)
+	}
+}
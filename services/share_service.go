@@ -0,0 +1,134 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"go-gin-auth-api-starter-kit/config"
+	"go-gin-auth-api-starter-kit/models"
+	"go-gin-auth-api-starter-kit/pkg/tracing"
+	"go-gin-auth-api-starter-kit/repositories"
+	"go-gin-auth-api-starter-kit/utils"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// shareLinkPurpose scopes share link tokens so they can't be reused as other signed tokens
+const shareLinkPurpose = "post-share"
+
+var (
+	// ErrShareUserNotFound is returned when sharing a post with a user that does not exist
+	ErrShareUserNotFound = errors.New("user not found")
+	// ErrInvalidShareLinkTTL is returned when a share link would not expire, or expire too late
+	ErrInvalidShareLinkTTL = errors.New("share link lifetime is out of range")
+)
+
+// ShareLinkTTL is how long a share link stays valid when no lifetime is requested
+func ShareLinkTTL() time.Duration {
+	return config.GetEnvDuration("SHARE_LINK_TTL", 7*24*time.Hour)
+}
+
+// ShareLinkMaxTTL is the longest lifetime a share link may be given
+func ShareLinkMaxTTL() time.Duration {
+	return config.GetEnvDuration("SHARE_LINK_MAX_TTL", 30*24*time.Hour)
+}
+
+// ListPostShares returns the users a post is shared with
+func ListPostShares(ctx context.Context, actor Actor, postID uint) (_ []models.PostShare, err error) {
+	ctx, span := tracing.Start(ctx, "services.ListPostShares")
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := editablePost(ctx, actor, postID); err != nil {
+		return nil, err
+	}
+	return repositories.ListPostShares(ctx, postID)
+}
+
+// SharePost lets a user read the post while it is private
+func SharePost(ctx context.Context, actor Actor, postID, userID uint) (err error) {
+	ctx, span := tracing.Start(ctx, "services.SharePost")
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := editablePost(ctx, actor, postID); err != nil {
+		return err
+	}
+	if _, err := repositories.GetUserByID(ctx, userID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrShareUserNotFound
+		}
+		return err
+	}
+
+	if err := repositories.AddPostShare(ctx, postID, userID); err != nil {
+		return err
+	}
+
+	auditTarget(ctx, models.AuditPostShared, "post", postID, models.JSONMap{"user_id": userID})
+	return nil
+}
+
+// UnsharePost takes a user's access to a private post away
+func UnsharePost(ctx context.Context, actor Actor, postID, userID uint) (err error) {
+	ctx, span := tracing.Start(ctx, "services.UnsharePost")
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := editablePost(ctx, actor, postID); err != nil {
+		return err
+	}
+
+	removed, err := repositories.RemovePostShare(ctx, postID, userID)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return gorm.ErrRecordNotFound
+	}
+
+	auditTarget(ctx, models.AuditPostUnshared, "post", postID, models.JSONMap{"user_id": userID})
+	return nil
+}
+
+// CreateShareLink signs a token that lets anyone holding it read the post
+// until it expires, whatever the post's status and visibility. A zero ttl
+// means ShareLinkTTL.
+func CreateShareLink(ctx context.Context, actor Actor, postID uint, ttl time.Duration) (_ string, _ time.Time, err error) {
+	ctx, span := tracing.Start(ctx, "services.CreateShareLink")
+	defer func() { tracing.End(span, err) }()
+
+	if ttl == 0 {
+		ttl = ShareLinkTTL()
+	}
+	if ttl < 0 || ttl > ShareLinkMaxTTL() {
+		return "", time.Time{}, ErrInvalidShareLinkTTL
+	}
+
+	if _, err := editablePost(ctx, actor, postID); err != nil {
+		return "", time.Time{}, err
+	}
+
+	expires := time.Now().Add(ttl).Truncate(time.Second)
+	token := utils.SignToken(shareLinkPurpose, strconv.FormatUint(uint64(postID), 10), expires)
+
+	auditTarget(ctx, models.AuditPostShareLinkCreated, "post", postID, models.JSONMap{"expires_at": expires})
+	return token, expires, nil
+}
+
+// GetSharedPost returns the post a share link token was made for.
+// Invalid and expired tokens fail with the errors from utils.VerifyToken.
+func GetSharedPost(ctx context.Context, token string) (_ models.Post, err error) {
+	ctx, span := tracing.Start(ctx, "services.GetSharedPost")
+	defer func() { tracing.End(span, err) }()
+
+	payload, _, err := utils.VerifyToken(shareLinkPurpose, token)
+	if err != nil {
+		return models.Post{}, err
+	}
+	postID, err := strconv.ParseUint(payload, 10, 64)
+	if err != nil {
+		return models.Post{}, utils.ErrInvalidSignedToken
+	}
+
+	id := uint(postID)
+	return repositories.GetVisiblePostByID(ctx, repositories.Viewer{SharedPostID: id}, id)
+}
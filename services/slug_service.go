@@ -0,0 +1,147 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"go-gin-auth-api-starter-kit/models"
+	"go-gin-auth-api-starter-kit/pkg/tracing"
+	"go-gin-auth-api-starter-kit/repositories"
+	"go-gin-auth-api-starter-kit/utils"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// slugMaxLength limits the part of a slug generated from the title, leaving
+// room for collision suffixes such as "-2"
+const slugMaxLength = 80
+
+// fallbackSlug is used for titles without any letters or digits
+const fallbackSlug = "post"
+
+// assignSlug gives post a unique slug generated from its title. current is
+// the post before the change, or nil for new posts. A post keeps its slug
+// while its title still produces it; otherwise the old slug becomes an alias
+// that redirects to the new one. It must run inside a transaction.
+func assignSlug(ctx context.Context, post *models.Post, current *models.Post) error {
+	base := utils.TransliteratedSlug(post.Title, slugMaxLength)
+	if base == "" {
+		base = fallbackSlug
+	}
+
+	var postID uint
+	if current != nil {
+		postID = current.ID
+		if current.Slug != "" && slugHasBase(current.Slug, base) {
+			post.Slug = current.Slug
+			return nil
+		}
+	}
+
+	if err := repositories.LockSlug(ctx, base); err != nil {
+		return err
+	}
+	taken, err := repositories.TakenSlugs(ctx, base, postID)
+	if err != nil {
+		return err
+	}
+	post.Slug = freeSlug(base, taken)
+
+	if current == nil || current.Slug == "" {
+		return nil
+	}
+	if err := repositories.AddSlugAlias(ctx, current.ID, current.Slug); err != nil {
+		return err
+	}
+	// The post may be taking back one of its earlier slugs
+	return repositories.RemoveSlugAlias(ctx, current.ID, post.Slug)
+}
+
+// slugHasBase reports whether slug is base or base with a collision suffix
+func slugHasBase(slug, base string) bool {
+	if slug == base {
+		return true
+	}
+	suffix, ok := strings.CutPrefix(slug, base+"-")
+	if !ok {
+		return false
+	}
+	n, err := strconv.Atoi(suffix)
+	return err == nil && n >= 2 && strconv.Itoa(n) == suffix
+}
+
+// freeSlug returns base, or base with the smallest suffix from 2 up that is not taken
+func freeSlug(base string, taken []string) string {
+	used := make(map[string]bool, len(taken))
+	for _, slug := range taken {
+		used[slug] = true
+	}
+	slug := base
+	for n := 2; used[slug]; n++ {
+		slug = base + "-" + strconv.Itoa(n)
+	}
+	return slug
+}
+
+// GetPostBySlug finds a post by its slug. For a slug the post used to have,
+// moved is true and the caller should redirect to post.Slug.
+// Posts the actor may not read are reported as not found.
+func GetPostBySlug(ctx context.Context, actor Actor, slug string) (_ models.Post, moved bool, err error) {
+	ctx, span := tracing.Start(ctx, "services.GetPostBySlug")
+	defer func() { tracing.End(span, err) }()
+
+	post, err := repositories.GetVisiblePostBySlug(ctx, actor.viewer(), slug)
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return post, false, err
+	}
+
+	alias, err := repositories.GetSlugAlias(ctx, slug)
+	if err != nil {
+		return models.Post{}, false, err
+	}
+	post, err = repositories.GetVisiblePostByID(ctx, actor.viewer(), alias.PostID)
+	if err != nil {
+		return models.Post{}, false, err
+	}
+	return post, true, nil
+}
+
+// AssignMissingPostSlugs gives a slug to the posts created before posts had
+// slugs. It runs once at startup.
+func AssignMissingPostSlugs(ctx context.Context) (err error) {
+	ctx, span := tracing.Start(ctx, "services.AssignMissingPostSlugs")
+	defer func() { tracing.End(span, err) }()
+
+	const batchSize = 100
+	var afterID uint
+	assigned := 0
+	for {
+		posts, err := repositories.ListPostsWithoutSlug(ctx, afterID, batchSize)
+		if err != nil {
+			return err
+		}
+		for _, post := range posts {
+			afterID = post.ID
+			err := repositories.Transaction(ctx, func(ctx context.Context) error {
+				if err := assignSlug(ctx, &post, nil); err != nil {
+					return err
+				}
+				return repositories.SetPostSlug(ctx, post.ID, post.Slug)
+			})
+			if err != nil {
+				return err
+			}
+			assigned++
+		}
+		if len(posts) < batchSize {
+			break
+		}
+	}
+
+	if assigned > 0 {
+		slog.InfoContext(ctx, "assigned post slugs", slog.Int("posts", assigned))
+	}
+	return nil
+}
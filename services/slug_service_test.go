@@ -0,0 +1,50 @@
+package services
+
+import "testing"
+
+func TestSlugHasBase(t *testing.T) {
+	tests := []struct {
+		slug, base string
+		want       bool
+	}{
+		{"hello", "hello", true},
+		{"hello-2", "hello", true},
+		{"hello-15", "hello", true},
+		{"hello-1", "hello", false},
+		{"hello-0", "hello", false},
+		{"hello-02", "hello", false},
+		{"hello--2", "hello", false},
+		{"hello-world", "hello", false},
+		{"hello-2-3", "hello", false},
+		{"hello2", "hello", false},
+		{"hell", "hello", false},
+		{"hello-world-2", "hello-world", true},
+	}
+	for _, tt := range tests {
+		if got := slugHasBase(tt.slug, tt.base); got != tt.want {
+			t.Errorf("slugHasBase(%q, %q) = %v, want %v", tt.slug, tt.base, got, tt.want)
+		}
+	}
+}
+
+func TestFreeSlug(t *testing.T) {
+	tests := []struct {
+		name  string
+		base  string
+		taken []string
+		want  string
+	}{
+		{"free", "hello", nil, "hello"},
+		{"taken", "hello", []string{"hello"}, "hello-2"},
+		{"first suffixes taken", "hello", []string{"hello", "hello-2", "hello-3"}, "hello-4"},
+		{"gap", "hello", []string{"hello", "hello-3"}, "hello-2"},
+		{"only a suffix taken", "hello", []string{"hello-2"}, "hello"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := freeSlug(tt.base, tt.taken); got != tt.want {
+				t.Errorf("freeSlug(%q, %v) = %q, want %q", tt.base, tt.taken, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"go-gin-auth-api-starter-kit/models"
+	"go-gin-auth-api-starter-kit/pkg/tracing"
+	"go-gin-auth-api-starter-kit/repositories"
+	"go-gin-auth-api-starter-kit/utils"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidTag is returned for tags that are empty once normalized, or too long
+	ErrInvalidTag = errors.New("tags must contain letters or digits and be at most 64 characters")
+	// ErrCategoryNotFound is returned when a post or category refers to a missing category
+	ErrCategoryNotFound = errors.New("category not found")
+	// ErrInvalidCategory is returned for categories without a usable name
+	ErrInvalidCategory = errors.New("category name must contain letters or digits")
+	// ErrCategoryExists is returned when a category with the same slug already exists
+	ErrCategoryExists = errors.New("a category with this slug already exists")
+)
+
+// maxTagLength matches the size of the tags.slug column
+const maxTagLength = 64
+
+// ResolveTags normalizes tag names to slugs, drops duplicates and returns the
+// matching tags, creating the ones that are used for the first time
+func ResolveTags(ctx context.Context, names []string) ([]models.Tag, error) {
+	var slugs []string
+	displayNames := map[string]string{}
+
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		slug := utils.Slugify(name)
+		if slug == "" || len(slug) > maxTagLength || len(name) > maxTagLength {
+			return nil, ErrInvalidTag
+		}
+		if _, seen := displayNames[slug]; !seen {
+			displayNames[slug] = name
+			slugs = append(slugs, slug)
+		}
+	}
+
+	return repositories.FindOrCreateTags(ctx, slugs, displayNames)
+}
+
+// NormalizeTagSlugs turns user supplied tag names into slugs without touching the database
+func NormalizeTagSlugs(names []string) []string {
+	var slugs []string
+	for _, name := range names {
+		if slug := utils.Slugify(name); slug != "" {
+			slugs = append(slugs, slug)
+		}
+	}
+	return slugs
+}
+
+// ListTags returns every tag with the number of posts using it that actor
+// can list
+func ListTags(ctx context.Context, actor Actor) (_ []repositories.TagWithCount, err error) {
+	ctx, span := tracing.Start(ctx, "services.ListTags")
+	defer func() { tracing.End(span, err) }()
+
+	return repositories.ListTagsWithCounts(ctx, actor.viewer())
+}
+
+// RetagPosts adds and removes tags on many posts at once (admin only).
+// It returns the IDs of the posts that were changed.
+func RetagPosts(ctx context.Context, postIDs []uint, add, remove []string) (_ []uint, err error) {
+	ctx, span := tracing.Start(ctx, "services.RetagPosts")
+	defer func() { tracing.End(span, err) }()
+
+	updated := []uint{}
+	err = repositories.Transaction(ctx, func(ctx context.Context) error {
+		existing, err := repositories.ExistingPostIDs(ctx, postIDs)
+		if err != nil || len(existing) == 0 {
+			return err
+		}
+		updated = existing
+
+		if err := repositories.RemoveTagsFromPosts(ctx, existing, NormalizeTagSlugs(remove)); err != nil {
+			return err
+		}
+
+		tags, err := ResolveTags(ctx, add)
+		if err != nil {
+			return err
+		}
+		if err := repositories.AddTagsToPosts(ctx, existing, tags); err != nil {
+			return err
+		}
+		return repositories.BumpPostVersions(ctx, existing)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	RecordAudit(ctx, models.AuditLog{
+		Event:      models.AuditPostsRetagged,
+		TargetType: "post",
+		Metadata:   models.JSONMap{"post_ids": updated, "added": add, "removed": remove},
+	})
+	return updated, nil
+}
+
+// ListCategories returns every category; callers can build the tree from ParentID
+func ListCategories(ctx context.Context) (_ []models.Category, err error) {
+	ctx, span := tracing.Start(ctx, "services.ListCategories")
+	defer func() { tracing.End(span, err) }()
+
+	return repositories.ListCategories(ctx)
+}
+
+// CreateCategory adds a category, optionally below parentID (admin only)
+func CreateCategory(ctx context.Context, name, slug string, parentID *uint) (_ models.Category, err error) {
+	ctx, span := tracing.Start(ctx, "services.CreateCategory")
+	defer func() { tracing.End(span, err) }()
+
+	name = strings.TrimSpace(name)
+	if slug == "" {
+		slug = name
+	}
+	slug = utils.Slugify(slug)
+	if name == "" || slug == "" {
+		return models.Category{}, ErrInvalidCategory
+	}
+
+	if parentID != nil {
+		if _, err := repositories.GetCategoryByID(ctx, *parentID); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return models.Category{}, ErrCategoryNotFound
+			}
+			return models.Category{}, err
+		}
+	}
+
+	if _, err := repositories.GetCategoryBySlug(ctx, slug); err == nil {
+		return models.Category{}, ErrCategoryExists
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.Category{}, err
+	}
+
+	category, err := repositories.CreateCategory(ctx, models.Category{Name: name, Slug: slug, ParentID: parentID})
+	if err != nil {
+		return models.Category{}, err
+	}
+
+	auditTarget(ctx, models.AuditCategoryCreated, "category", category.ID, models.JSONMap{"slug": slug})
+	return category, nil
+}
+
+// categoryFilter resolves a category slug to the IDs of that category and all its descendants
+func categoryFilter(ctx context.Context, slug string) ([]uint, error) {
+	category, err := repositories.GetCategoryBySlug(ctx, utils.Slugify(slug))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCategoryNotFound
+		}
+		return nil, err
+	}
+	return repositories.CategoryWithDescendants(ctx, category.ID)
+}
+
+// checkCategory makes sure a post's category exists
+func checkCategory(ctx context.Context, categoryID *uint) error {
+	if categoryID == nil {
+		return nil
+	}
+	if _, err := repositories.GetCategoryByID(ctx, *categoryID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrCategoryNotFound
+		}
+		return err
+	}
+	return nil
+}
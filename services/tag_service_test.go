@@ -0,0 +1,216 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"go-gin-auth-api-starter-kit/config"
+	"go-gin-auth-api-starter-kit/models"
+	"go-gin-auth-api-starter-kit/repositories"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// tagSlugs returns the slugs of the tags of post, sorted
+func tagSlugs(t *testing.T, post models.Post) []string {
+	t.Helper()
+	var slugs []string
+	err := config.DB.Table("tags").Joins("JOIN post_tags ON post_tags.tag_id = tags.id").
+		Where("post_tags.post_id = ?", post.ID).Order("tags.slug").Pluck("tags.slug", &slugs).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	return slugs
+}
+
+func TestHashtagTagsFollowTheContent(t *testing.T) {
+	openTestDB(t, &models.User{}, &models.Tag{}, &models.Category{}, &models.Post{}, &models.PostTag{}, &models.PostSlugAlias{},
+		&models.PostRevision{}, &models.Mention{}, &models.OutboxEvent{}, &models.AuditLog{})
+	ctx := context.Background()
+
+	author := models.User{Username: "alice", Email: "alice@example.com", Password: "x"}
+	if err := config.DB.Create(&author).Error; err != nil {
+		t.Fatal(err)
+	}
+	actor := Actor{ID: author.ID, Role: models.RoleUser}
+
+	post, err := CreatePost(ctx, actor, models.Post{Title: "Release", Content: "A #golang and #web release"}, []string{"web"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := tagSlugs(t, post), []string{"golang", "web"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("tags after create = %v, want %v", got, want)
+	}
+
+	// Only the chosen tag is in the document a patch applies to
+	var seen []string
+	post, err = PatchPost(ctx, actor, post.ID, IfMatch{}, func(fields PostFields) (PostFields, error) {
+		seen = fields.Tags
+		fields.Content = "A release"
+		return fields, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"web"}; !reflect.DeepEqual(seen, want) {
+		t.Errorf("patch saw tags %v, want %v", seen, want)
+	}
+	// Editing the hashtags out drops #golang; web was also chosen, so it stays
+	if got, want := tagSlugs(t, post), []string{"web"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("tags after editing the hashtags out = %v, want %v", got, want)
+	}
+
+	// Restoring the first revision brings the hashtag back
+	if _, err := RestorePostRevision(ctx, actor, post.ID, 1, IfMatch{}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := tagSlugs(t, post), []string{"golang", "web"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("tags after restoring = %v, want %v", got, want)
+	}
+
+	// Dropping the chosen tag keeps the ones from hashtags
+	if _, err := UpdatePost(ctx, actor, post.ID, PostFields{Title: "Release", Content: "A #golang release"}, IfMatch{}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := tagSlugs(t, post), []string{"golang"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("tags after dropping the chosen tag = %v, want %v", got, want)
+	}
+}
+
+func TestListTagsCountsOnlyListablePosts(t *testing.T) {
+	openTestDB(t, &models.User{}, &models.Tag{}, &models.Category{}, &models.Post{}, &models.PostTag{}, &models.PostShare{},
+		&models.PostSlugAlias{}, &models.PostRevision{}, &models.Mention{}, &models.OutboxEvent{}, &models.AuditLog{})
+	ctx := context.Background()
+
+	users := []models.User{
+		{Username: "alice", Email: "alice@example.com", Password: "x"},
+		{Username: "bob", Email: "bob@example.com", Password: "x"},
+	}
+	if err := config.DB.Create(&users).Error; err != nil {
+		t.Fatal(err)
+	}
+	alice := Actor{ID: users[0].ID, Role: models.RoleUser}
+	bob := Actor{ID: users[1].ID, Role: models.RoleUser}
+
+	for _, post := range []models.Post{
+		{Title: "Public", Status: models.PostStatusPublished, Visibility: models.PostVisibilityPublic},
+		{Title: "Internal", Status: models.PostStatusPublished, Visibility: models.PostVisibilityInternal},
+		{Title: "Private", Status: models.PostStatusPublished, Visibility: models.PostVisibilityPrivate},
+		{Title: "Unlisted", Status: models.PostStatusPublished, Visibility: models.PostVisibilityUnlisted},
+		{Title: "Draft", Visibility: models.PostVisibilityPublic},
+	} {
+		if _, err := CreatePost(ctx, alice, post, []string{"go"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		actor Actor
+		want  int64
+	}{
+		{"author", alice, 5},
+		{"other user", bob, 2},
+		{"admin", Actor{ID: users[1].ID, Role: models.RoleAdmin}, 5},
+	}
+	for _, tt := range tests {
+		tags, err := ListTags(ctx, tt.actor)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(tags) != 1 || tags[0].PostCount != tt.want {
+			t.Errorf("%s: tags = %+v, want go with %d posts", tt.name, tags, tt.want)
+		}
+	}
+}
+
+func TestNormalizeTagSlugs(t *testing.T) {
+	got := NormalizeTagSlugs([]string{"Go Modules", " golang ", "!!!", "", "C++"})
+	if want := []string{"go-modules", "golang", "c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("NormalizeTagSlugs() = %v, want %v", got, want)
+	}
+}
+
+func TestResolveTags(t *testing.T) {
+	openTestDB(t, &models.Tag{})
+	ctx := context.Background()
+
+	tags, err := ResolveTags(ctx, []string{"Go Modules", "go-modules", "  GO   modules ", "Web"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tags) != 2 || tags[0].Slug != "go-modules" || tags[1].Slug != "web" {
+		t.Fatalf("ResolveTags() = %+v, want go-modules and web once each", tags)
+	}
+	// The first spelling names a new tag
+	if tags[0].Name != "Go Modules" {
+		t.Errorf("tag name = %q, want the first spelling", tags[0].Name)
+	}
+
+	again, err := ResolveTags(ctx, []string{"go modules"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(again) != 1 || again[0].ID != tags[0].ID || again[0].Name != "Go Modules" {
+		t.Errorf("resolving an existing tag = %+v, want %+v", again, tags[0])
+	}
+
+	for _, names := range [][]string{{"   "}, {"Go", "!!!"}, {strings.Repeat("a", maxTagLength+1)}} {
+		if _, err := ResolveTags(ctx, names); !errors.Is(err, ErrInvalidTag) {
+			t.Errorf("ResolveTags(%q) err = %v, want ErrInvalidTag", names, err)
+		}
+	}
+
+	var count int64
+	if err := config.DB.Model(&models.Tag{}).Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("%d tags stored, want 2", count)
+	}
+}
+
+func TestCategoryWithDescendants(t *testing.T) {
+	openTestDB(t, &models.Category{}, &models.AuditLog{})
+	ctx := context.Background()
+
+	create := func(name string, parent *models.Category) models.Category {
+		t.Helper()
+		var parentID *uint
+		if parent != nil {
+			parentID = &parent.ID
+		}
+		category, err := CreateCategory(ctx, name, "", parentID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return category
+	}
+	programming := create("Programming", nil)
+	golang := create("Go", &programming)
+	web := create("Web", &golang)
+	rust := create("Rust", &programming)
+	cooking := create("Cooking", nil)
+
+	tests := []struct {
+		root models.Category
+		want []uint
+	}{
+		{programming, []uint{programming.ID, golang.ID, web.ID, rust.ID}},
+		{golang, []uint{golang.ID, web.ID}},
+		{web, []uint{web.ID}},
+		{cooking, []uint{cooking.ID}},
+	}
+	for _, tt := range tests {
+		got, err := repositories.CategoryWithDescendants(ctx, tt.root.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		slices.Sort(got)
+		slices.Sort(tt.want)
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("CategoryWithDescendants(%s) = %v, want %v", tt.root.Name, got, tt.want)
+		}
+	}
+}
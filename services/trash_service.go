@@ -0,0 +1,227 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"go-gin-auth-api-starter-kit/config"
+	"go-gin-auth-api-starter-kit/models"
+	"go-gin-auth-api-starter-kit/pkg/tracing"
+	"go-gin-auth-api-starter-kit/repositories"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrCannotDeleteSelf is returned when an admin tries to delete their own account
+var ErrCannotDeleteSelf = errors.New("you cannot delete your own account")
+
+// TrashRetention returns how long soft-deleted posts and users are kept before
+// they are purged; zero keeps them forever
+func TrashRetention() time.Duration {
+	return time.Duration(config.GetEnvInt("TRASH_RETENTION_DAYS", 30)) * 24 * time.Hour
+}
+
+// ListPostTrash returns a page of the actor's deleted posts (every deleted post for admins)
+func ListPostTrash(ctx context.Context, actor Actor, page, perPage int) (_ []models.Post, _ int64, err error) {
+	ctx, span := tracing.Start(ctx, "services.ListPostTrash")
+	defer func() { tracing.End(span, err) }()
+
+	return repositories.ListTrashedPosts(ctx, actor.viewer(), page, perPage)
+}
+
+// RestorePost takes a post out of the trash, together with the comments that
+// were deleted with it. Authors can restore their own posts and admins any post.
+func RestorePost(ctx context.Context, actor Actor, id uint) (_ models.Post, err error) {
+	ctx, span := tracing.Start(ctx, "services.RestorePost")
+	defer func() { tracing.End(span, err) }()
+
+	post, err := repositories.GetTrashedPost(ctx, id)
+	if err != nil {
+		return models.Post{}, err
+	}
+	if !post.IsAuthoredBy(actor.ID) && !actor.IsAdmin() {
+		// Don't reveal other users' deleted posts
+		return models.Post{}, gorm.ErrRecordNotFound
+	}
+
+	err = repositories.Transaction(ctx, func(ctx context.Context) error {
+		if err := repositories.RestorePost(ctx, post); err != nil {
+			return err
+		}
+		return publishEvent(ctx, PostRestored{PostID: id, AuthorID: post.UserID, ActorID: actor.ID})
+	})
+	if err != nil {
+		return models.Post{}, err
+	}
+
+	auditTarget(ctx, models.AuditPostRestored, "post", id, nil)
+	return repositories.GetPostByID(ctx, id)
+}
+
+// PurgePost permanently deletes a post, whether or not it is in the trash (admin only)
+func PurgePost(ctx context.Context, id uint) (err error) {
+	ctx, span := tracing.Start(ctx, "services.PurgePost")
+	defer func() { tracing.End(span, err) }()
+
+	if err := purgePost(ctx, id, PurgeReasonAdmin); err != nil {
+		return err
+	}
+
+	auditTarget(ctx, models.AuditPostPurged, "post", id, models.JSONMap{"reason": PurgeReasonAdmin})
+	return nil
+}
+
+// purgePost permanently deletes a post, then the stored attachment content
+// that no other post uses
+func purgePost(ctx context.Context, id uint, reason string) error {
+	storageKeys, err := repositories.PostAttachmentStorageKeys(ctx, id)
+	if err != nil {
+		return err
+	}
+	err = repositories.Transaction(ctx, func(ctx context.Context) error {
+		if err := repositories.PurgePost(ctx, id); err != nil {
+			return err
+		}
+		return publishEvent(ctx, PostPurged{PostID: id, Reason: reason})
+	})
+	if err != nil {
+		return err
+	}
+	releaseAttachmentContent(ctx, storageKeys)
+	return nil
+}
+
+// DeleteUser moves a user to the trash (admin only). They can no longer log in.
+func DeleteUser(ctx context.Context, actor Actor, id uint) (err error) {
+	ctx, span := tracing.Start(ctx, "services.DeleteUser")
+	defer func() { tracing.End(span, err) }()
+
+	if id == actor.ID {
+		return ErrCannotDeleteSelf
+	}
+	err = repositories.Transaction(ctx, func(ctx context.Context) error {
+		if err := repositories.DeleteUser(ctx, id); err != nil {
+			return err
+		}
+		return publishEvent(ctx, UserDeleted{UserID: id, ActorID: actor.ID})
+	})
+	if err != nil {
+		return err
+	}
+
+	auditTarget(ctx, models.AuditUserDeleted, "user", id, nil)
+	return nil
+}
+
+// ListUserTrash returns a page of deleted users (admin only)
+func ListUserTrash(ctx context.Context, page, perPage int) (_ []models.User, _ int64, err error) {
+	ctx, span := tracing.Start(ctx, "services.ListUserTrash")
+	defer func() { tracing.End(span, err) }()
+
+	return repositories.ListTrashedUsers(ctx, page, perPage)
+}
+
+// RestoreUser takes a user out of the trash (admin only)
+func RestoreUser(ctx context.Context, id uint) (_ models.User, err error) {
+	ctx, span := tracing.Start(ctx, "services.RestoreUser")
+	defer func() { tracing.End(span, err) }()
+
+	err = repositories.Transaction(ctx, func(ctx context.Context) error {
+		if err := repositories.RestoreUser(ctx, id); err != nil {
+			return err
+		}
+		return publishEvent(ctx, UserRestored{UserID: id})
+	})
+	if err != nil {
+		return models.User{}, err
+	}
+
+	auditTarget(ctx, models.AuditUserRestored, "user", id, nil)
+	return repositories.GetUserByID(ctx, id)
+}
+
+// PurgeUser permanently deletes a user, whether or not they are in the trash (admin only)
+func PurgeUser(ctx context.Context, actor Actor, id uint) (err error) {
+	ctx, span := tracing.Start(ctx, "services.PurgeUser")
+	defer func() { tracing.End(span, err) }()
+
+	if id == actor.ID {
+		return ErrCannotDeleteSelf
+	}
+	if err := purgeUser(ctx, id, PurgeReasonAdmin); err != nil {
+		return err
+	}
+
+	auditTarget(ctx, models.AuditUserPurged, "user", id, models.JSONMap{"reason": PurgeReasonAdmin})
+	return nil
+}
+
+// purgeUser permanently deletes a user, then their avatar files
+func purgeUser(ctx context.Context, id uint, reason string) error {
+	avatarKey, err := repositories.GetUserAvatarKey(ctx, id)
+	if err != nil {
+		return err
+	}
+	err = repositories.Transaction(ctx, func(ctx context.Context) error {
+		if err := repositories.PurgeUser(ctx, id); err != nil {
+			return err
+		}
+		return publishEvent(ctx, UserPurged{UserID: id, Reason: reason})
+	})
+	if err != nil {
+		return err
+	}
+	deleteAvatarFiles(ctx, avatarKey)
+	return nil
+}
+
+// PurgeExpiredTrash permanently deletes posts and users that have been in the
+// trash for longer than the retention period, and returns how many it removed
+func PurgeExpiredTrash(ctx context.Context) (posts, users int, err error) {
+	ctx, span := tracing.Start(ctx, "services.PurgeExpiredTrash")
+	defer func() { tracing.End(span, err) }()
+
+	retention := TrashRetention()
+	if retention <= 0 {
+		return 0, 0, nil
+	}
+	cutoff := time.Now().Add(-retention)
+	metadata := models.JSONMap{"reason": PurgeReasonRetention, "cutoff": cutoff}
+
+	postIDs, err := repositories.TrashedPostIDsBefore(ctx, cutoff)
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, id := range postIDs {
+		if err := purgePost(ctx, id, PurgeReasonRetention); err != nil {
+			return posts, users, err
+		}
+		auditTarget(ctx, models.AuditPostPurged, "post", id, metadata)
+		posts++
+	}
+
+	userIDs, err := repositories.TrashedUserIDsBefore(ctx, cutoff)
+	if err != nil {
+		return posts, 0, err
+	}
+	for _, id := range userIDs {
+		if err := purgeUser(ctx, id, PurgeReasonRetention); err != nil {
+			return posts, users, err
+		}
+		auditTarget(ctx, models.AuditUserPurged, "user", id, metadata)
+		users++
+	}
+
+	return posts, users, nil
+}
+
+func init() {
+	RegisterJob(JobTrashPurge, func(ctx context.Context, _ struct{}) error {
+		posts, users, err := PurgeExpiredTrash(ctx)
+		if posts+users > 0 {
+			slog.InfoContext(ctx, "purged expired trash", slog.Int("posts", posts), slog.Int("users", users))
+		}
+		return err
+	})
+}
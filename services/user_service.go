@@ -0,0 +1,16 @@
+package services
+
+import (
+	"context"
+	"go-gin-auth-api-starter-kit/models"
+	"go-gin-auth-api-starter-kit/pkg/tracing"
+	"go-gin-auth-api-starter-kit/repositories"
+)
+
+// ListUsers handles business logic for listing users
+func ListUsers(ctx context.Context) (_ []models.User, err error) {
+	ctx, span := tracing.Start(ctx, "services.ListUsers")
+	defer func() { tracing.End(span, err) }()
+
+	return repositories.ListUsers(ctx)
+}
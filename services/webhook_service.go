@@ -0,0 +1,686 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-gin-auth-api-starter-kit/config"
+	"go-gin-auth-api-starter-kit/models"
+	"go-gin-auth-api-starter-kit/pkg/tracing"
+	"go-gin-auth-api-starter-kit/pkg/webhook"
+	"go-gin-auth-api-starter-kit/repositories"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidWebhookURL is returned for webhook URLs that are not absolute http(s) URLs
+	ErrInvalidWebhookURL = errors.New("webhook url must be an absolute http or https URL")
+	// ErrInvalidWebhookEvents is returned when a webhook has no events or an unknown one
+	ErrInvalidWebhookEvents = errors.New("webhook events must be one or more known events, or *")
+	// ErrWebhookDisabled is returned when redelivering to a disabled webhook
+	ErrWebhookDisabled = errors.New("webhook is disabled")
+	// ErrWebhookTargetNotAllowed is returned for webhook URLs that point to a
+	// loopback, private or link-local address, unless WEBHOOK_ALLOW_PRIVATE_TARGETS is set
+	ErrWebhookTargetNotAllowed = errors.New("webhook url must not point to a loopback, private or link-local address")
+)
+
+const (
+	// webhookBatchSize is how many deliveries a worker claims at once
+	webhookBatchSize = 20
+	// webhookResponseLimit is how much of a response body the delivery log keeps
+	webhookResponseLimit = 1024
+	// webhookMaxRetryDelay caps the backoff between attempts
+	webhookMaxRetryDelay = 6 * time.Hour
+	// webhookUserAgent identifies deliveries to receivers
+	webhookUserAgent = "go-gin-auth-api-webhooks/1.0"
+)
+
+// WebhookTimeout is how long a receiver has to answer a delivery
+func WebhookTimeout() time.Duration {
+	return config.GetEnvDuration("WEBHOOK_TIMEOUT", 10*time.Second)
+}
+
+// WebhookMaxAttempts is how often a delivery is tried before it fails for good
+func WebhookMaxAttempts() int {
+	return config.GetEnvInt("WEBHOOK_MAX_ATTEMPTS", 8)
+}
+
+// WebhookRetryBase is the wait before the first retry. It doubles with every
+// further attempt.
+func WebhookRetryBase() time.Duration {
+	return config.GetEnvDuration("WEBHOOK_RETRY_BASE", 30*time.Second)
+}
+
+// WebhookAllowPrivateTargets reports whether webhooks may point to loopback,
+// private and link-local addresses. It is off by default, so webhooks can't
+// be used to reach internal services and read their responses in the delivery log.
+func WebhookAllowPrivateTargets() bool {
+	return config.GetEnvBool("WEBHOOK_ALLOW_PRIVATE_TARGETS", false)
+}
+
+// WebhookDisableAfter is how many deliveries in a row may fail for good
+// before their webhook is disabled; 0 never disables webhooks
+func WebhookDisableAfter() int {
+	return config.GetEnvInt("WEBHOOK_DISABLE_AFTER", 5)
+}
+
+// webhookClient sends deliveries. Redirects are not followed: a receiver
+// that moved must be updated by an admin. The address is checked again when
+// connecting, so a host that resolves to an internal address after the
+// webhook was saved is still refused. Proxies are not used, because the
+// check would then apply to the proxy instead of the receiver.
+var webhookClient = &http.Client{
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+			Control:   checkWebhookDial,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	},
+	CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// checkWebhookDial refuses connections to addresses webhooks may not reach
+func checkWebhookDial(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	if !webhookAddrAllowed(ip) {
+		return ErrWebhookTargetNotAllowed
+	}
+	return nil
+}
+
+// webhookAddrAllowed reports whether webhooks may connect to ip
+func webhookAddrAllowed(ip netip.Addr) bool {
+	if WebhookAllowPrivateTargets() {
+		return true
+	}
+	ip = ip.Unmap()
+	return ip.IsGlobalUnicast() && !ip.IsPrivate() && !sharedAddressSpace.Contains(ip)
+}
+
+// sharedAddressSpace is the carrier-grade NAT range, which isn't reachable from the internet either
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// webhookWake lets new deliveries start right away instead of at the next poll
+var webhookWake = make(chan struct{}, 1)
+
+// WebhookInput holds the fields of a webhook an admin can set
+type WebhookInput struct {
+	URL         string
+	Description string
+	// Secret signs deliveries. A random secret is generated for new webhooks
+	// when it is empty, and updates keep the current secret.
+	Secret string
+	Events []string
+	// Active is true for new webhooks when nil, and left unchanged by updates.
+	// Turning a webhook back on resets its failure count.
+	Active *bool
+}
+
+// validate checks the URL and events of a webhook
+func (input WebhookInput) validate(ctx context.Context) error {
+	target, err := url.Parse(input.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Hostname() == "" {
+		return ErrInvalidWebhookURL
+	}
+	if err := checkWebhookHost(ctx, target.Hostname()); err != nil {
+		return err
+	}
+	if len(input.Events) == 0 {
+		return ErrInvalidWebhookEvents
+	}
+	for _, event := range input.Events {
+		if event != models.WebhookAllEvents && !slices.Contains(models.WebhookEvents, event) {
+			return ErrInvalidWebhookEvents
+		}
+	}
+	return nil
+}
+
+// checkWebhookHost rejects hosts that are, or resolve to, an address
+// webhooks may not reach. A host that can't be resolved yet is accepted;
+// every delivery checks the address it connects to.
+func checkWebhookHost(ctx context.Context, host string) error {
+	if WebhookAllowPrivateTargets() {
+		return nil
+	}
+	if ip, err := netip.ParseAddr(host); err == nil {
+		if !webhookAddrAllowed(ip) {
+			return ErrWebhookTargetNotAllowed
+		}
+		return nil
+	}
+
+	ips, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return nil
+	}
+	for _, ip := range ips {
+		if !webhookAddrAllowed(ip) {
+			return ErrWebhookTargetNotAllowed
+		}
+	}
+	return nil
+}
+
+// newWebhookSecret generates a secret for a webhook
+func newWebhookSecret() string {
+	b := make([]byte, 32)
+	_, _ = rand.Read(b)
+	return "whsec_" + hex.EncodeToString(b)
+}
+
+// newEventID generates the ID of a webhook event
+func newEventID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// CreateWebhook adds a webhook (admin only). The returned webhook includes
+// its secret, which is not shown again.
+func CreateWebhook(ctx context.Context, actor Actor, input WebhookInput) (_ models.Webhook, err error) {
+	ctx, span := tracing.Start(ctx, "services.CreateWebhook")
+	defer func() { tracing.End(span, err) }()
+
+	if err := input.validate(ctx); err != nil {
+		return models.Webhook{}, err
+	}
+	webhook := models.Webhook{
+		URL:         input.URL,
+		Description: strings.TrimSpace(input.Description),
+		Secret:      input.Secret,
+		Events:      slices.Compact(slices.Sorted(slices.Values(input.Events))),
+		Active:      input.Active == nil || *input.Active,
+		CreatedByID: &actor.ID,
+	}
+	if webhook.Secret == "" {
+		webhook.Secret = newWebhookSecret()
+	}
+	if err := repositories.CreateWebhook(ctx, &webhook); err != nil {
+		return models.Webhook{}, err
+	}
+
+	auditTarget(ctx, models.AuditWebhookCreated, "webhook", webhook.ID, models.JSONMap{"url": webhook.URL, "events": webhook.Events})
+	return webhook, nil
+}
+
+// ListWebhooks returns every webhook (admin only)
+func ListWebhooks(ctx context.Context) (_ []models.Webhook, err error) {
+	ctx, span := tracing.Start(ctx, "services.ListWebhooks")
+	defer func() { tracing.End(span, err) }()
+
+	return repositories.ListWebhooks(ctx)
+}
+
+// GetWebhook returns a webhook (admin only)
+func GetWebhook(ctx context.Context, id uint) (_ models.Webhook, err error) {
+	ctx, span := tracing.Start(ctx, "services.GetWebhook")
+	defer func() { tracing.End(span, err) }()
+
+	return repositories.GetWebhook(ctx, id)
+}
+
+// UpdateWebhook replaces the URL, description and events of a webhook, and
+// its secret and state when given (admin only)
+func UpdateWebhook(ctx context.Context, id uint, input WebhookInput) (_ models.Webhook, err error) {
+	ctx, span := tracing.Start(ctx, "services.UpdateWebhook")
+	defer func() { tracing.End(span, err) }()
+
+	if err := input.validate(ctx); err != nil {
+		return models.Webhook{}, err
+	}
+	webhook, err := repositories.GetWebhook(ctx, id)
+	if err != nil {
+		return models.Webhook{}, err
+	}
+
+	webhook.URL = input.URL
+	webhook.Description = strings.TrimSpace(input.Description)
+	webhook.Events = slices.Compact(slices.Sorted(slices.Values(input.Events)))
+	if input.Secret != "" {
+		webhook.Secret = input.Secret
+	}
+	if input.Active != nil {
+		if *input.Active && !webhook.Active {
+			webhook.ConsecutiveFailures = 0
+			webhook.DisabledAt = nil
+		}
+		webhook.Active = *input.Active
+	}
+	if err := repositories.UpdateWebhook(ctx, webhook); err != nil {
+		return models.Webhook{}, err
+	}
+
+	auditTarget(ctx, models.AuditWebhookUpdated, "webhook", id, models.JSONMap{
+		"url": webhook.URL, "events": webhook.Events, "active": webhook.Active, "secret_changed": input.Secret != "",
+	})
+	return repositories.GetWebhook(ctx, id)
+}
+
+// DeleteWebhook removes a webhook and its delivery log (admin only)
+func DeleteWebhook(ctx context.Context, id uint) (err error) {
+	ctx, span := tracing.Start(ctx, "services.DeleteWebhook")
+	defer func() { tracing.End(span, err) }()
+
+	if err := repositories.DeleteWebhook(ctx, id); err != nil {
+		return err
+	}
+
+	auditTarget(ctx, models.AuditWebhookDeleted, "webhook", id, nil)
+	return nil
+}
+
+// ListWebhookDeliveries returns a page of a webhook's delivery log, newest
+// first, optionally only the deliveries in status (admin only)
+func ListWebhookDeliveries(ctx context.Context, webhookID uint, status string, page, perPage int) (_ []models.WebhookDelivery, _ int64, err error) {
+	ctx, span := tracing.Start(ctx, "services.ListWebhookDeliveries")
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := repositories.GetWebhook(ctx, webhookID); err != nil {
+		return nil, 0, err
+	}
+	return repositories.ListWebhookDeliveries(ctx, webhookID, status, page, perPage)
+}
+
+// RedeliverWebhook queues a delivery again, whatever became of it, as a new
+// delivery with the same event ID and payload (admin only)
+func RedeliverWebhook(ctx context.Context, webhookID, deliveryID uint) (_ models.WebhookDelivery, err error) {
+	ctx, span := tracing.Start(ctx, "services.RedeliverWebhook")
+	defer func() { tracing.End(span, err) }()
+
+	webhook, err := repositories.GetWebhook(ctx, webhookID)
+	if err != nil {
+		return models.WebhookDelivery{}, err
+	}
+	if !webhook.Active {
+		return models.WebhookDelivery{}, ErrWebhookDisabled
+	}
+	original, err := repositories.GetWebhookDelivery(ctx, webhookID, deliveryID)
+	if err != nil {
+		return models.WebhookDelivery{}, err
+	}
+
+	now := time.Now()
+	deliveries := []models.WebhookDelivery{{
+		WebhookID:     webhookID,
+		EventID:       original.EventID,
+		Event:         original.Event,
+		Payload:       original.Payload,
+		Status:        models.WebhookDeliveryPending,
+		NextAttemptAt: &now,
+		RedeliveryOf:  &original.ID,
+	}}
+	if err := repositories.CreateWebhookDeliveries(ctx, deliveries); err != nil {
+		return models.WebhookDelivery{}, err
+	}
+	wakeWebhookWorker()
+
+	auditTarget(ctx, models.AuditWebhookRedelivered, "webhook", webhookID, models.JSONMap{"delivery_id": deliveryID})
+	return deliveries[0], nil
+}
+
+// queueWebhookEvent queues event for every active webhook that subscribes to
+// it. The payload is {"id", "event", "created_at", "data"}. It is called by
+// the handlers of domain events, so the deliveries are queued exactly once
+// for every committed change.
+func queueWebhookEvent(ctx context.Context, event string, data models.JSONMap) error {
+	webhooks, err := repositories.ListActiveWebhooks(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	payload := models.JSONMap{"id": newEventID(), "event": event, "created_at": now.UTC(), "data": data}
+	var deliveries []models.WebhookDelivery
+	for _, webhook := range webhooks {
+		if webhook.Subscribes(event) {
+			deliveries = append(deliveries, models.WebhookDelivery{
+				WebhookID:     webhook.ID,
+				EventID:       payload["id"].(string),
+				Event:         event,
+				Payload:       payload,
+				Status:        models.WebhookDeliveryPending,
+				NextAttemptAt: &now,
+			})
+		}
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+	if err := repositories.CreateWebhookDeliveries(ctx, deliveries); err != nil {
+		return err
+	}
+	repositories.AfterCommit(ctx, wakeWebhookWorker)
+	return nil
+}
+
+func init() {
+	// Every webhook comes from the outbox, so it is queued exactly once even
+	// if the process dies right after the change
+	Subscribe("webhooks", func(ctx context.Context, event UserRegistered) error {
+		return queueUserWebhook(ctx, models.WebhookUserRegistered, event.UserID)
+	})
+	Subscribe("webhooks", func(ctx context.Context, event UserDeleted) error {
+		return queueWebhookEvent(ctx, models.WebhookUserDeleted, models.JSONMap{"id": event.UserID})
+	})
+	Subscribe("webhooks", func(ctx context.Context, event UserRestored) error {
+		return queueUserWebhook(ctx, models.WebhookUserRestored, event.UserID)
+	})
+	Subscribe("webhooks", func(ctx context.Context, event UserPurged) error {
+		return queueWebhookEvent(ctx, models.WebhookUserPurged, models.JSONMap{"id": event.UserID, "reason": event.Reason})
+	})
+
+	Subscribe("webhooks", func(ctx context.Context, event PostCreated) error {
+		return queuePostWebhook(ctx, models.WebhookPostCreated, event.PostID)
+	})
+	Subscribe("webhooks", func(ctx context.Context, event PostUpdated) error {
+		return queuePostWebhook(ctx, models.WebhookPostUpdated, event.PostID)
+	})
+	Subscribe("webhooks", func(ctx context.Context, event PostPublished) error {
+		return queuePostWebhook(ctx, models.WebhookPostPublished, event.PostID)
+	})
+	Subscribe("webhooks", func(ctx context.Context, event PostStatusChanged) error {
+		return queuePostWebhook(ctx, models.WebhookPostUpdated, event.PostID)
+	})
+	Subscribe("webhooks", func(ctx context.Context, event PostDeleted) error {
+		post, err := repositories.GetTrashedPost(ctx, event.PostID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// Restored or purged since; only the ID is left to report
+			return queueWebhookEvent(ctx, models.WebhookPostDeleted, models.JSONMap{"id": event.PostID})
+		} else if err != nil {
+			return err
+		}
+		return queueWebhookEvent(ctx, models.WebhookPostDeleted, webhookPost(post))
+	})
+	Subscribe("webhooks", func(ctx context.Context, event PostRestored) error {
+		return queuePostWebhook(ctx, models.WebhookPostRestored, event.PostID)
+	})
+	Subscribe("webhooks", func(ctx context.Context, event PostPurged) error {
+		return queueWebhookEvent(ctx, models.WebhookPostPurged, models.JSONMap{"id": event.PostID, "reason": event.Reason})
+	})
+
+	Subscribe("webhooks", func(ctx context.Context, event CommentCreated) error {
+		return queueCommentWebhook(ctx, models.WebhookCommentCreated, event.PostID, event.CommentID)
+	})
+	Subscribe("webhooks", func(ctx context.Context, event CommentUpdated) error {
+		return queueCommentWebhook(ctx, models.WebhookCommentUpdated, event.PostID, event.CommentID)
+	})
+	Subscribe("webhooks", func(ctx context.Context, event CommentDeleted) error {
+		return queueWebhookEvent(ctx, models.WebhookCommentDeleted, models.JSONMap{
+			"id": event.CommentID, "post_id": event.PostID, "moderated": event.Moderated,
+		})
+	})
+}
+
+// queueUserWebhook queues event with the current state of a user, unless the
+// user has been deleted since
+func queueUserWebhook(ctx context.Context, event string, userID uint) error {
+	user, err := repositories.GetUserByID(ctx, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// user.deleted follows
+		return nil
+	} else if err != nil {
+		return err
+	}
+	return queueWebhookEvent(ctx, event, webhookUser(user))
+}
+
+// queueCommentWebhook queues event with the current state of a comment,
+// unless the comment has been deleted since
+func queueCommentWebhook(ctx context.Context, event string, postID, commentID uint) error {
+	comment, err := repositories.GetComment(ctx, postID, commentID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// comment.deleted follows, or the post was deleted
+		return nil
+	} else if err != nil {
+		return err
+	}
+	return queueWebhookEvent(ctx, event, webhookComment(comment))
+}
+
+// queuePostWebhook queues event with the current state of a post, unless the
+// post has been deleted since
+func queuePostWebhook(ctx context.Context, event string, postID uint) error {
+	post, err := repositories.GetPostByID(ctx, postID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// post.deleted follows
+		return nil
+	} else if err != nil {
+		return err
+	}
+	return queueWebhookEvent(ctx, event, webhookPost(post))
+}
+
+// webhookUser is how a user appears in webhook payloads
+func webhookUser(user models.User) models.JSONMap {
+	return models.JSONMap{"id": user.ID, "username": user.Username, "email": user.Email, "created_at": user.CreatedAt}
+}
+
+// webhookPost is how a post appears in webhook payloads
+func webhookPost(post models.Post) models.JSONMap {
+	return models.JSONMap{
+		"id":           post.ID,
+		"user_id":      post.UserID,
+		"title":        post.Title,
+		"slug":         post.Slug,
+		"excerpt":      post.Excerpt,
+		"status":       post.Status,
+		"visibility":   post.Visibility,
+		"published_at": post.PublishedAt,
+		"updated_at":   post.UpdatedAt,
+		"version":      post.Version,
+	}
+}
+
+// webhookComment is how a comment appears in webhook payloads
+func webhookComment(comment models.Comment) models.JSONMap {
+	return models.JSONMap{
+		"id":         comment.ID,
+		"post_id":    comment.PostID,
+		"user_id":    comment.UserID,
+		"parent_id":  comment.ParentID,
+		"body":       comment.Body,
+		"created_at": comment.CreatedAt,
+	}
+}
+
+// wakeWebhookWorker tells the delivery worker of this replica there is work
+func wakeWebhookWorker() {
+	select {
+	case webhookWake <- struct{}{}:
+	default:
+	}
+}
+
+// DeliverDueWebhooks sends the deliveries whose next attempt is due, a batch
+// at a time, until none are left, and returns how many attempts it made.
+// StartWebhookDelivery calls it in the background; tests can call it directly.
+func DeliverDueWebhooks(ctx context.Context) (attempts int, err error) {
+	ctx, span := tracing.Start(ctx, "services.DeliverDueWebhooks")
+	defer func() { tracing.End(span, err) }()
+
+	// The lease outlasts an attempt, so no other worker picks the delivery up meanwhile
+	lease := WebhookTimeout() + time.Minute
+	for ctx.Err() == nil {
+		deliveries, err := repositories.ClaimDueWebhookDeliveries(ctx, time.Now(), lease, webhookBatchSize)
+		if err != nil {
+			return attempts, err
+		}
+
+		var wg sync.WaitGroup
+		for _, delivery := range deliveries {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				attemptWebhookDelivery(ctx, delivery)
+			}()
+		}
+		wg.Wait()
+		attempts += len(deliveries)
+
+		if len(deliveries) < webhookBatchSize {
+			break
+		}
+	}
+	return attempts, nil
+}
+
+// attemptWebhookDelivery sends a delivery once and records the outcome,
+// scheduling a retry with exponential backoff when it fails
+func attemptWebhookDelivery(ctx context.Context, delivery models.WebhookDelivery) {
+	now := time.Now()
+	delivery.LastAttemptAt = &now
+	delivery.ResponseStatus, delivery.ResponseBody, delivery.Error = nil, "", ""
+
+	if !delivery.Webhook.Active {
+		delivery.Status = models.WebhookDeliveryFailed
+		delivery.NextAttemptAt = nil
+		delivery.Error = ErrWebhookDisabled.Error()
+		if err := repositories.SaveWebhookAttempt(ctx, delivery); err != nil {
+			slog.ErrorContext(ctx, "saving webhook delivery failed", slog.Uint64("delivery_id", uint64(delivery.ID)), slog.Any("error", err))
+		}
+		return
+	}
+
+	delivery.Attempts++
+	status, body, err := sendWebhook(ctx, delivery, now)
+	delivery.DurationMs = time.Since(now).Milliseconds()
+	if ctx.Err() != nil {
+		// Shutting down: the lease runs out and the attempt is repeated, uncounted
+		return
+	}
+	if status != 0 {
+		delivery.ResponseStatus = &status
+		delivery.ResponseBody = body
+	}
+
+	succeeded := err == nil && status >= 200 && status < 300
+	switch {
+	case succeeded:
+		delivery.Status = models.WebhookDeliverySucceeded
+		delivery.NextAttemptAt = nil
+	case delivery.Attempts >= WebhookMaxAttempts():
+		delivery.Status = models.WebhookDeliveryFailed
+		delivery.NextAttemptAt = nil
+	default:
+		next := now.Add(webhookRetryDelay(delivery.Attempts))
+		delivery.NextAttemptAt = &next
+	}
+	if err != nil {
+		delivery.Error = err.Error()
+	} else if !succeeded {
+		delivery.Error = fmt.Sprintf("receiver answered %d", status)
+	}
+
+	if err := repositories.SaveWebhookAttempt(ctx, delivery); err != nil {
+		slog.ErrorContext(ctx, "saving webhook delivery failed", slog.Uint64("delivery_id", uint64(delivery.ID)), slog.Any("error", err))
+		return
+	}
+	switch delivery.Status {
+	case models.WebhookDeliverySucceeded:
+		err = repositories.ResetWebhookFailures(ctx, delivery.WebhookID)
+	case models.WebhookDeliveryFailed:
+		var disabled bool
+		disabled, err = repositories.RecordWebhookFailure(ctx, delivery.WebhookID, WebhookDisableAfter(), time.Now())
+		if disabled {
+			slog.WarnContext(ctx, "disabled failing webhook", slog.Uint64("webhook_id", uint64(delivery.WebhookID)))
+			auditTarget(ctx, models.AuditWebhookDisabled, "webhook", delivery.WebhookID, models.JSONMap{"delivery_id": delivery.ID})
+		}
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "updating webhook failures failed", slog.Uint64("webhook_id", uint64(delivery.WebhookID)), slog.Any("error", err))
+	}
+}
+
+// webhookRetryDelay is the wait after the given number of failed attempts
+func webhookRetryDelay(attempts int) time.Duration {
+	delay := WebhookRetryBase()
+	for i := 1; i < attempts && delay < webhookMaxRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, webhookMaxRetryDelay)
+}
+
+// sendWebhook POSTs a signed delivery and returns the response status and
+// the start of the response body
+func sendWebhook(ctx context.Context, delivery models.WebhookDelivery, now time.Time) (int, string, error) {
+	body, err := json.Marshal(delivery.Payload)
+	if err != nil {
+		return 0, "", err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, WebhookTimeout())
+	defer cancel()
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.Webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, "", err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", webhookUserAgent)
+	request.Header.Set(webhook.EventHeader, delivery.Event)
+	request.Header.Set(webhook.EventIDHeader, delivery.EventID)
+	request.Header.Set(webhook.DeliveryHeader, uintToString(delivery.ID))
+	webhook.SetHeaders(request.Header, delivery.Webhook.Secret, now, body)
+
+	response, err := webhookClient.Do(request)
+	if err != nil {
+		return 0, "", err
+	}
+	defer response.Body.Close()
+	responseBody, err := io.ReadAll(io.LimitReader(response.Body, webhookResponseLimit))
+	return response.StatusCode, strings.ToValidUTF8(string(responseBody), "�"), err
+}
+
+// StartWebhookDelivery sends due webhook deliveries every
+// WEBHOOK_POLL_INTERVAL, and right away when events are queued on this
+// replica, until ctx is cancelled. It is meant to run in its own goroutine,
+// and can run on every replica at once because deliveries are claimed with
+// SKIP LOCKED.
+func StartWebhookDelivery(ctx context.Context) {
+	ticker := time.NewTicker(config.GetEnvDuration("WEBHOOK_POLL_INTERVAL", 5*time.Second))
+	defer ticker.Stop()
+
+	for {
+		if _, err := DeliverDueWebhooks(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			slog.ErrorContext(ctx, "delivering webhooks failed", slog.Any("error", err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-webhookWake:
+		}
+	}
+}
@@ -0,0 +1,405 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"go-gin-auth-api-starter-kit/config"
+	"go-gin-auth-api-starter-kit/models"
+	"go-gin-auth-api-starter-kit/pkg/webhook"
+	"go-gin-auth-api-starter-kit/repositories"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"sync"
+	"testing"
+	"time"
+)
+
+// webhookReceiver is an httptest server that records the deliveries it gets
+// and answers with the next of its statuses (the last one repeats)
+type webhookReceiver struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	statuses []int
+	requests []receivedWebhook
+}
+
+type receivedWebhook struct {
+	header http.Header
+	body   []byte
+}
+
+func newWebhookReceiver(t *testing.T, statuses ...int) *webhookReceiver {
+	t.Helper()
+	receiver := &webhookReceiver{statuses: statuses}
+	receiver.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		receiver.mu.Lock()
+		receiver.requests = append(receiver.requests, receivedWebhook{header: r.Header.Clone(), body: body})
+		status := receiver.statuses[0]
+		if len(receiver.statuses) > 1 {
+			receiver.statuses = receiver.statuses[1:]
+		}
+		receiver.mu.Unlock()
+
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte("ok"))
+	}))
+	t.Cleanup(receiver.Close)
+	return receiver
+}
+
+func (r *webhookReceiver) received() []receivedWebhook {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]receivedWebhook(nil), r.requests...)
+}
+
+func TestWebhookAddrAllowed(t *testing.T) {
+	tests := []struct {
+		addr    string
+		allowed bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"0.0.0.0", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"100.64.0.1", false},
+		{"fd00::1", false},
+		{"fe80::1", false},
+		{"::ffff:127.0.0.1", false},
+		{"::ffff:169.254.169.254", false},
+		{"224.0.0.1", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			if got := webhookAddrAllowed(netip.MustParseAddr(tt.addr)); got != tt.allowed {
+				t.Fatalf("webhookAddrAllowed(%s) = %v, want %v", tt.addr, got, tt.allowed)
+			}
+		})
+	}
+
+	t.Run("opt-in", func(t *testing.T) {
+		t.Setenv("WEBHOOK_ALLOW_PRIVATE_TARGETS", "true")
+		if !webhookAddrAllowed(netip.MustParseAddr("127.0.0.1")) {
+			t.Fatal("loopback refused with WEBHOOK_ALLOW_PRIVATE_TARGETS=true")
+		}
+	})
+}
+
+func TestWebhookInputValidate(t *testing.T) {
+	events := []string{models.WebhookPostCreated}
+	tests := []struct {
+		name  string
+		input WebhookInput
+		want  error
+	}{
+		{"public", WebhookInput{URL: "https://93.184.216.34/hook", Events: events}, nil},
+		{"not http", WebhookInput{URL: "ftp://93.184.216.34/hook", Events: events}, ErrInvalidWebhookURL},
+		{"relative", WebhookInput{URL: "/hook", Events: events}, ErrInvalidWebhookURL},
+		{"loopback", WebhookInput{URL: "http://127.0.0.1:8080/hook", Events: events}, ErrWebhookTargetNotAllowed},
+		{"localhost", WebhookInput{URL: "http://localhost/hook", Events: events}, ErrWebhookTargetNotAllowed},
+		{"metadata", WebhookInput{URL: "http://169.254.169.254/latest/meta-data", Events: events}, ErrWebhookTargetNotAllowed},
+		{"private", WebhookInput{URL: "http://10.0.0.5/hook", Events: events}, ErrWebhookTargetNotAllowed},
+		{"ipv6 loopback", WebhookInput{URL: "http://[::1]/hook", Events: events}, ErrWebhookTargetNotAllowed},
+		{"no events", WebhookInput{URL: "https://93.184.216.34/hook"}, ErrInvalidWebhookEvents},
+		{"unknown event", WebhookInput{URL: "https://93.184.216.34/hook", Events: []string{"post.exploded"}}, ErrInvalidWebhookEvents},
+		{"all events", WebhookInput{URL: "https://93.184.216.34/hook", Events: []string{models.WebhookAllEvents}}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.input.validate(context.Background()); !errors.Is(err, tt.want) {
+				t.Fatalf("validate() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestWebhookRetryDelay(t *testing.T) {
+	t.Setenv("WEBHOOK_RETRY_BASE", "30s")
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{5, 8 * time.Minute},
+		{20, webhookMaxRetryDelay},
+	}
+	for _, tt := range tests {
+		if got := webhookRetryDelay(tt.attempts); got != tt.want {
+			t.Errorf("webhookRetryDelay(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestSendWebhookSignsRequests(t *testing.T) {
+	t.Setenv("WEBHOOK_ALLOW_PRIVATE_TARGETS", "true")
+	receiver := newWebhookReceiver(t, http.StatusOK)
+
+	delivery := models.WebhookDelivery{
+		ID:      42,
+		EventID: "0123456789abcdef0123456789abcdef",
+		Event:   models.WebhookPostCreated,
+		Payload: models.JSONMap{"id": "0123456789abcdef0123456789abcdef", "event": models.WebhookPostCreated, "data": models.JSONMap{"id": 1}},
+		Webhook: models.Webhook{URL: receiver.URL, Secret: "whsec_test"},
+	}
+	status, body, err := sendWebhook(context.Background(), delivery, time.Now())
+	if err != nil || status != http.StatusOK || body != "ok" {
+		t.Fatalf("sendWebhook() = %d, %q, %v", status, body, err)
+	}
+
+	requests := receiver.received()
+	if len(requests) != 1 {
+		t.Fatalf("receiver got %d requests, want 1", len(requests))
+	}
+	request := requests[0]
+	if err := webhook.Verify("whsec_test", request.header, request.body, time.Minute); err != nil {
+		t.Fatalf("Verify() = %v", err)
+	}
+	if err := webhook.Verify("whsec_other", request.header, request.body, time.Minute); !errors.Is(err, webhook.ErrInvalidSignature) {
+		t.Fatalf("Verify() with another secret = %v, want ErrInvalidSignature", err)
+	}
+	for header, want := range map[string]string{
+		webhook.EventHeader:    models.WebhookPostCreated,
+		webhook.EventIDHeader:  delivery.EventID,
+		webhook.DeliveryHeader: "42",
+		"Content-Type":         "application/json",
+	} {
+		if got := request.header.Get(header); got != want {
+			t.Errorf("%s = %q, want %q", header, got, want)
+		}
+	}
+}
+
+func TestSendWebhookRefusesPrivateTargetsWhenConnecting(t *testing.T) {
+	receiver := newWebhookReceiver(t, http.StatusOK)
+
+	// Saved while private targets were allowed, or a host that resolved elsewhere then
+	delivery := models.WebhookDelivery{Payload: models.JSONMap{}, Webhook: models.Webhook{URL: receiver.URL, Secret: "whsec_test"}}
+	if _, _, err := sendWebhook(context.Background(), delivery, time.Now()); !errors.Is(err, ErrWebhookTargetNotAllowed) {
+		t.Fatalf("sendWebhook() = %v, want ErrWebhookTargetNotAllowed", err)
+	}
+	if requests := receiver.received(); len(requests) != 0 {
+		t.Fatalf("receiver got %d requests, want none", len(requests))
+	}
+}
+
+// setUpWebhook creates a webhook for every event that delivers to receiver
+func setUpWebhook(t *testing.T, receiver *webhookReceiver) models.Webhook {
+	t.Helper()
+	openTestDB(t, &models.User{}, &models.Webhook{}, &models.WebhookDelivery{}, &models.AuditLog{})
+	t.Setenv("WEBHOOK_ALLOW_PRIVATE_TARGETS", "true")
+
+	admin := models.User{Username: "admin", Email: "admin@example.com", Password: "x", Role: models.RoleAdmin}
+	if err := config.DB.Create(&admin).Error; err != nil {
+		t.Fatal(err)
+	}
+	hook, err := CreateWebhook(context.Background(), Actor{ID: admin.ID, Role: admin.Role}, WebhookInput{
+		URL:    receiver.URL,
+		Secret: "whsec_test",
+		Events: []string{models.WebhookAllEvents},
+	})
+	if err != nil {
+		t.Fatalf("CreateWebhook() = %v", err)
+	}
+	return hook
+}
+
+// deliverWebhooks sends the due deliveries and returns the webhook's delivery log, oldest first
+func deliverWebhooks(t *testing.T, webhookID uint) []models.WebhookDelivery {
+	t.Helper()
+	ctx := context.Background()
+	if _, err := DeliverDueWebhooks(ctx); err != nil {
+		t.Fatalf("DeliverDueWebhooks() = %v", err)
+	}
+	deliveries, _, err := repositories.ListWebhookDeliveries(ctx, webhookID, "", 1, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, j := 0, len(deliveries)-1; i < j; i, j = i+1, j-1 {
+		deliveries[i], deliveries[j] = deliveries[j], deliveries[i]
+	}
+	return deliveries
+}
+
+// makeDue moves the next attempt of every pending delivery into the past
+func makeDue(t *testing.T) {
+	t.Helper()
+	err := config.DB.Model(&models.WebhookDelivery{}).
+		Where("status = ?", models.WebhookDeliveryPending).
+		Update("next_attempt_at", time.Now().Add(-time.Second)).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDeliverDueWebhooksSignsDeliveries(t *testing.T) {
+	receiver := newWebhookReceiver(t, http.StatusNoContent)
+	hook := setUpWebhook(t, receiver)
+
+	if err := queueWebhookEvent(context.Background(), models.WebhookPostCreated, models.JSONMap{"id": 1}); err != nil {
+		t.Fatal(err)
+	}
+	deliveries := deliverWebhooks(t, hook.ID)
+
+	if len(deliveries) != 1 || deliveries[0].Status != models.WebhookDeliverySucceeded || deliveries[0].Attempts != 1 {
+		t.Fatalf("deliveries = %+v, want one that succeeded on the first attempt", deliveries)
+	}
+	requests := receiver.received()
+	if len(requests) != 1 {
+		t.Fatalf("receiver got %d requests, want 1", len(requests))
+	}
+	if err := webhook.Verify("whsec_test", requests[0].header, requests[0].body, time.Minute); err != nil {
+		t.Fatalf("Verify() = %v", err)
+	}
+}
+
+func TestDeliverDueWebhooksRetriesWithBackoff(t *testing.T) {
+	t.Setenv("WEBHOOK_RETRY_BASE", "1h")
+	receiver := newWebhookReceiver(t, http.StatusInternalServerError, http.StatusOK)
+	hook := setUpWebhook(t, receiver)
+
+	if err := queueWebhookEvent(context.Background(), models.WebhookPostCreated, models.JSONMap{"id": 1}); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	deliveries := deliverWebhooks(t, hook.ID)
+
+	first := deliveries[0]
+	if first.Status != models.WebhookDeliveryPending || first.Attempts != 1 {
+		t.Fatalf("after a 500: status %q, attempts %d; want pending after 1 attempt", first.Status, first.Attempts)
+	}
+	if first.ResponseStatus == nil || *first.ResponseStatus != http.StatusInternalServerError {
+		t.Fatalf("response status = %v, want 500", first.ResponseStatus)
+	}
+	if first.NextAttemptAt == nil || first.NextAttemptAt.Before(start.Add(time.Hour)) || first.NextAttemptAt.After(time.Now().Add(time.Hour)) {
+		t.Fatalf("next attempt at %v, want WEBHOOK_RETRY_BASE after the attempt", first.NextAttemptAt)
+	}
+
+	// Not due yet
+	if deliveries := deliverWebhooks(t, hook.ID); deliveries[0].Attempts != 1 {
+		t.Fatalf("retried before the backoff ran out")
+	}
+
+	makeDue(t)
+	deliveries = deliverWebhooks(t, hook.ID)
+	if deliveries[0].Status != models.WebhookDeliverySucceeded || deliveries[0].Attempts != 2 {
+		t.Fatalf("after the retry: status %q, attempts %d; want succeeded after 2 attempts", deliveries[0].Status, deliveries[0].Attempts)
+	}
+	if requests := receiver.received(); len(requests) != 2 || requests[0].header.Get(webhook.EventIDHeader) != requests[1].header.Get(webhook.EventIDHeader) {
+		t.Fatalf("receiver got %d requests, want 2 with the same event ID", len(requests))
+	}
+}
+
+func TestDeliverDueWebhooksFailsAfterMaxAttempts(t *testing.T) {
+	t.Setenv("WEBHOOK_MAX_ATTEMPTS", "2")
+	t.Setenv("WEBHOOK_DISABLE_AFTER", "0")
+	receiver := newWebhookReceiver(t, http.StatusInternalServerError)
+	hook := setUpWebhook(t, receiver)
+
+	if err := queueWebhookEvent(context.Background(), models.WebhookPostCreated, models.JSONMap{"id": 1}); err != nil {
+		t.Fatal(err)
+	}
+	deliverWebhooks(t, hook.ID)
+	makeDue(t)
+	deliveries := deliverWebhooks(t, hook.ID)
+
+	delivery := deliveries[0]
+	if delivery.Status != models.WebhookDeliveryFailed || delivery.Attempts != 2 || delivery.NextAttemptAt != nil {
+		t.Fatalf("status %q, attempts %d, next attempt %v; want failed after 2 attempts", delivery.Status, delivery.Attempts, delivery.NextAttemptAt)
+	}
+	if delivery.Error == "" {
+		t.Fatal("failed delivery has no error")
+	}
+
+	// Nothing is left to retry
+	makeDue(t)
+	deliverWebhooks(t, hook.ID)
+	if requests := receiver.received(); len(requests) != 2 {
+		t.Fatalf("receiver got %d requests, want 2", len(requests))
+	}
+}
+
+func TestDeliverDueWebhooksDisablesFailingWebhook(t *testing.T) {
+	t.Setenv("WEBHOOK_MAX_ATTEMPTS", "1")
+	t.Setenv("WEBHOOK_DISABLE_AFTER", "2")
+	receiver := newWebhookReceiver(t, http.StatusInternalServerError)
+	hook := setUpWebhook(t, receiver)
+	ctx := context.Background()
+
+	if err := queueWebhookEvent(ctx, models.WebhookPostCreated, models.JSONMap{"id": 1}); err != nil {
+		t.Fatal(err)
+	}
+	deliverWebhooks(t, hook.ID)
+	if hook, _ := GetWebhook(ctx, hook.ID); !hook.Active || hook.ConsecutiveFailures != 1 {
+		t.Fatalf("after one failure: active %v, failures %d; want active with 1 failure", hook.Active, hook.ConsecutiveFailures)
+	}
+
+	if err := queueWebhookEvent(ctx, models.WebhookPostUpdated, models.JSONMap{"id": 1}); err != nil {
+		t.Fatal(err)
+	}
+	deliverWebhooks(t, hook.ID)
+	hook, err := GetWebhook(ctx, hook.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hook.Active || hook.DisabledAt == nil || hook.ConsecutiveFailures != 2 {
+		t.Fatalf("after two failures: active %v, disabled at %v, failures %d; want disabled", hook.Active, hook.DisabledAt, hook.ConsecutiveFailures)
+	}
+
+	// Disabled webhooks get no new events, and can't be redelivered to
+	if err := queueWebhookEvent(ctx, models.WebhookPostCreated, models.JSONMap{"id": 2}); err != nil {
+		t.Fatal(err)
+	}
+	if deliveries := deliverWebhooks(t, hook.ID); len(deliveries) != 2 {
+		t.Fatalf("%d deliveries, want no new ones for a disabled webhook", len(deliveries))
+	}
+	if _, err := RedeliverWebhook(ctx, hook.ID, 1); !errors.Is(err, ErrWebhookDisabled) {
+		t.Fatalf("RedeliverWebhook() = %v, want ErrWebhookDisabled", err)
+	}
+}
+
+func TestRedeliverWebhook(t *testing.T) {
+	receiver := newWebhookReceiver(t, http.StatusOK)
+	hook := setUpWebhook(t, receiver)
+	ctx := context.Background()
+
+	if err := queueWebhookEvent(ctx, models.WebhookPostCreated, models.JSONMap{"id": 1}); err != nil {
+		t.Fatal(err)
+	}
+	original := deliverWebhooks(t, hook.ID)[0]
+
+	redelivery, err := RedeliverWebhook(ctx, hook.ID, original.ID)
+	if err != nil {
+		t.Fatalf("RedeliverWebhook() = %v", err)
+	}
+	if redelivery.RedeliveryOf == nil || *redelivery.RedeliveryOf != original.ID || redelivery.EventID != original.EventID {
+		t.Fatalf("redelivery = %+v, want a copy of delivery %d", redelivery, original.ID)
+	}
+
+	deliveries := deliverWebhooks(t, hook.ID)
+	if len(deliveries) != 2 || deliveries[1].Status != models.WebhookDeliverySucceeded {
+		t.Fatalf("deliveries = %+v, want the redelivery to succeed", deliveries)
+	}
+	requests := receiver.received()
+	if len(requests) != 2 {
+		t.Fatalf("receiver got %d requests, want 2", len(requests))
+	}
+	if requests[0].header.Get(webhook.EventIDHeader) != requests[1].header.Get(webhook.EventIDHeader) ||
+		string(requests[0].body) != string(requests[1].body) {
+		t.Fatal("redelivery differs from the original delivery")
+	}
+	if requests[1].header.Get(webhook.DeliveryHeader) == requests[0].header.Get(webhook.DeliveryHeader) {
+		t.Fatal("redelivery reuses the delivery ID")
+	}
+}
@@ -0,0 +1,115 @@
+package utils
+
+import (
+	"bytes"
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+// markdown renders CommonMark with the GitHub Flavored Markdown extensions
+// (tables, strikethrough, autolinks and task lists). Raw HTML in the source
+// is dropped rather than passed through.
+var markdown = goldmark.New(goldmark.WithExtensions(
+	// Column alignment as an align attribute, which the sanitizer allows, instead of inline styles
+	extension.NewTable(extension.WithTableCellAlignMethod(extension.TableCellAlignAttribute)),
+	extension.Strikethrough,
+	extension.Linkify,
+	extension.TaskList,
+))
+
+// htmlPolicy is the allowlist every rendered post goes through. Anything not
+// listed here is removed: scripts and styles with their content, event
+// handler attributes, and URLs with schemes other than http, https and mailto.
+var htmlPolicy = newHTMLPolicy()
+
+// paragraphBreak separates paragraphs in plain text
+var paragraphBreak = regexp.MustCompile(`\n\s*\n`)
+
+// textPolicy strips all markup, leaving the text
+var textPolicy = bluemonday.StrictPolicy()
+
+func newHTMLPolicy() *bluemonday.Policy {
+	policy := bluemonday.NewPolicy()
+	policy.AllowElements(
+		"p", "br", "hr", "h1", "h2", "h3", "h4", "h5", "h6",
+		"blockquote", "pre", "code", "em", "strong", "del", "sup", "sub",
+		"ul", "ol", "li", "table", "thead", "tbody", "tr", "th", "td",
+	)
+	policy.AllowAttrs("href", "title").OnElements("a")
+	policy.AllowAttrs("src", "alt", "title").OnElements("img")
+	policy.AllowAttrs("start").Matching(bluemonday.Integer).OnElements("ol")
+	policy.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[\w+#-]+$`)).OnElements("code")
+	policy.AllowAttrs("align").Matching(regexp.MustCompile(`^(left|center|right)$`)).OnElements("th", "td")
+	// Task list items render as disabled checkboxes
+	policy.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	policy.AllowAttrs("checked", "disabled").OnElements("input")
+	policy.AllowURLSchemes("http", "https", "mailto")
+	policy.AllowRelativeURLs(true)
+	policy.RequireNoFollowOnLinks(true)
+	return policy
+}
+
+// RenderMarkdown converts Markdown to sanitized HTML
+func RenderMarkdown(source string) (string, error) {
+	var buf bytes.Buffer
+	if err := markdown.Convert([]byte(source), &buf); err != nil {
+		return "", err
+	}
+	return SanitizeHTML(buf.String()), nil
+}
+
+// RenderPlainText converts plain text to HTML: blank lines separate
+// paragraphs and single line breaks are kept
+func RenderPlainText(text string) string {
+	var b strings.Builder
+	for _, paragraph := range paragraphBreak.Split(strings.ReplaceAll(text, "\r\n", "\n"), -1) {
+		paragraph = strings.TrimSpace(paragraph)
+		if paragraph == "" {
+			continue
+		}
+		b.WriteString("<p>")
+		b.WriteString(strings.ReplaceAll(html.EscapeString(paragraph), "\n", "<br>\n"))
+		b.WriteString("</p>\n")
+	}
+	return b.String()
+}
+
+// SanitizeHTML removes every element, attribute and URL that is not on the allowlist
+func SanitizeHTML(untrusted string) string {
+	return htmlPolicy.Sanitize(untrusted)
+}
+
+// HTMLToText returns the text of an HTML fragment with whitespace collapsed
+func HTMLToText(fragment string) string {
+	return strings.Join(strings.Fields(html.UnescapeString(textPolicy.Sanitize(fragment))), " ")
+}
+
+// Excerpt shortens text to at most limit characters, cutting at a word
+// boundary and marking the cut with an ellipsis
+func Excerpt(text string, limit int) string {
+	if utf8.RuneCountInString(text) <= limit {
+		return text
+	}
+	runes := []rune(text)[:limit]
+	if cut := strings.LastIndexFunc(string(runes), unicode.IsSpace); cut > 0 {
+		return strings.TrimRightFunc(string(runes)[:cut], unicode.IsPunct) + "…"
+	}
+	return string(runes) + "…"
+}
+
+// ReadingTime estimates how many minutes reading text takes, rounding up.
+// Text with any words takes at least a minute.
+func ReadingTime(text string, wordsPerMinute int) int {
+	words := len(strings.Fields(text))
+	if words == 0 || wordsPerMinute <= 0 {
+		return 0
+	}
+	return (words + wordsPerMinute - 1) / wordsPerMinute
+}
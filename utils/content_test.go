@@ -0,0 +1,151 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSanitizeHTML(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"allowed markup", `<p><strong>bold</strong> <em>it</em></p>`, `<p><strong>bold</strong> <em>it</em></p>`},
+		{"script with content", `<p>hi</p><script>alert(1)</script>`, `<p>hi</p>`},
+		{"style with content", `<style>p{color:red}</style><p>hi</p>`, `<p>hi</p>`},
+		{"event handler", `<p onclick="alert(1)">hi</p>`, `<p>hi</p>`},
+		{"image event handler", `<img src="/a.png" onerror="alert(1)">`, `<img src="/a.png">`},
+		{"javascript url", `<a href="javascript:alert(1)">x</a>`, `x`},
+		{"obfuscated javascript url", `<a href="jAvAsCrIpT:alert(1)">x</a>`, `x`},
+		{"data url", `<img src="data:image/png;base64,AAAA">`, ``},
+		{"http link gets nofollow", `<a href="https://example.com">x</a>`, `<a href="https://example.com" rel="nofollow">x</a>`},
+		{"relative link", `<a href="/posts/1">x</a>`, `<a href="/posts/1" rel="nofollow">x</a>`},
+		{"mailto link", `<a href="mailto:a@example.com">x</a>`, `<a href="mailto:a@example.com" rel="nofollow">x</a>`},
+		{"iframe", `<iframe src="https://example.com"></iframe>`, ``},
+		{"inline style", `<p style="color:red">hi</p>`, `<p>hi</p>`},
+		{"language class", `<code class="language-go">x</code>`, `<code class="language-go">x</code>`},
+		{"other class", `<code class="evil">x</code>`, `<code>x</code>`},
+		{"cell alignment", `<table><tr><td align="center">x</td><td align="justify">y</td></tr></table>`, `<table><tr><td align="center">x</td><td>y</td></tr></table>`},
+		{"checkbox", `<input type="checkbox" checked disabled>`, `<input type="checkbox" checked="" disabled="">`},
+		{"text input", `<input type="text" value="x">`, ``},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SanitizeHTML(tt.input); got != tt.want {
+				t.Errorf("SanitizeHTML(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderMarkdown(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		contains []string
+		excludes []string
+	}{
+		{"emphasis", "**bold** and ~~gone~~", []string{"<strong>bold</strong>", "<del>gone</del>"}, nil},
+		{"raw html is dropped", "hi <script>alert(1)</script>", []string{"hi"}, []string{"<script"}},
+		{"javascript link", "[x](javascript:alert(1))", []string{"x"}, []string{"javascript:"}},
+		{"autolink", "see https://example.com", []string{`<a href="https://example.com" rel="nofollow">https://example.com</a>`}, nil},
+		{"fenced code", "```go\nfmt.Println()\n```", []string{`<code class="language-go">`}, nil},
+		{"table", "| a |\n|:-:|\n| b |", []string{`<th align="center">a</th>`}, []string{"style="}},
+		{"task list", "- [x] done", []string{`<input checked="" disabled="" type="checkbox">`}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RenderMarkdown(tt.source)
+			if err != nil {
+				t.Fatalf("RenderMarkdown: %v", err)
+			}
+			for _, want := range tt.contains {
+				if !strings.Contains(got, want) {
+					t.Errorf("RenderMarkdown(%q) = %q, want it to contain %q", tt.source, got, want)
+				}
+			}
+			for _, unwanted := range tt.excludes {
+				if strings.Contains(got, unwanted) {
+					t.Errorf("RenderMarkdown(%q) = %q, want it not to contain %q", tt.source, got, unwanted)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderPlainText(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"empty", "", ""},
+		{"one paragraph", "hello", "<p>hello</p>\n"},
+		{"line break", "a\nb", "<p>a<br>\nb</p>\n"},
+		{"paragraphs", "a\r\n\r\nb\n \n\nc", "<p>a</p>\n<p>b</p>\n<p>c</p>\n"},
+		{"markup is escaped", "<b>x</b> & y", "<p>&lt;b&gt;x&lt;/b&gt; &amp; y</p>\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RenderPlainText(tt.text); got != tt.want {
+				t.Errorf("RenderPlainText(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHTMLToText(t *testing.T) {
+	tests := []struct {
+		fragment string
+		want     string
+	}{
+		{"<p>a <strong>b</strong></p>\n<p>c</p>", "a b c"},
+		{"<p>fish &amp; chips</p>", "fish & chips"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := HTMLToText(tt.fragment); got != tt.want {
+			t.Errorf("HTMLToText(%q) = %q, want %q", tt.fragment, got, tt.want)
+		}
+	}
+}
+
+func TestExcerpt(t *testing.T) {
+	tests := []struct {
+		text  string
+		limit int
+		want  string
+	}{
+		{"short", 10, "short"},
+		{"exactly ten", 11, "exactly ten"},
+		{"hello world, again", 13, "hello world…"},
+		{"unbrokenword", 5, "unbro…"},
+		{"héllo wörld", 8, "héllo…"},
+	}
+	for _, tt := range tests {
+		if got := Excerpt(tt.text, tt.limit); got != tt.want {
+			t.Errorf("Excerpt(%q, %d) = %q, want %q", tt.text, tt.limit, got, tt.want)
+		}
+	}
+}
+
+func TestReadingTime(t *testing.T) {
+	tests := []struct {
+		words int
+		wpm   int
+		want  int
+	}{
+		{0, 200, 0},
+		{1, 200, 1},
+		{200, 200, 1},
+		{201, 200, 2},
+		{10, 0, 0},
+	}
+	for _, tt := range tests {
+		text := strings.Repeat("word ", tt.words)
+		if got := ReadingTime(text, tt.wpm); got != tt.want {
+			t.Errorf("ReadingTime(%d words, %d) = %d, want %d", tt.words, tt.wpm, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package utils
+
+import "strings"
+
+// Kinds of DiffLine
+const (
+	DiffEqual  = "equal"
+	DiffInsert = "insert"
+	DiffDelete = "delete"
+)
+
+// maxDiffCells bounds the size of the LCS table. Larger inputs are still
+// diffed, but their changed middle section is reported as a whole block.
+const maxDiffCells = 4_000_000
+
+// DiffLine is one line of a line-level diff
+type DiffLine struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+// DiffLines returns the line-level differences that turn a into b,
+// based on the longest common subsequence of their lines
+func DiffLines(a, b string) []DiffLine {
+	oldLines, newLines := splitLines(a), splitLines(b)
+
+	// Common prefix and suffix don't need the LCS table
+	prefix := 0
+	for prefix < len(oldLines) && prefix < len(newLines) && oldLines[prefix] == newLines[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(oldLines)-prefix && suffix < len(newLines)-prefix &&
+		oldLines[len(oldLines)-1-suffix] == newLines[len(newLines)-1-suffix] {
+		suffix++
+	}
+
+	diff := make([]DiffLine, 0, len(oldLines)+len(newLines))
+	for _, line := range oldLines[:prefix] {
+		diff = append(diff, DiffLine{Op: DiffEqual, Text: line})
+	}
+	diff = append(diff, diffMiddle(oldLines[prefix:len(oldLines)-suffix], newLines[prefix:len(newLines)-suffix])...)
+	for _, line := range oldLines[len(oldLines)-suffix:] {
+		diff = append(diff, DiffLine{Op: DiffEqual, Text: line})
+	}
+	return diff
+}
+
+// diffMiddle diffs the part of the input that differs, using an LCS table
+func diffMiddle(a, b []string) []DiffLine {
+	var diff []DiffLine
+	if len(a)*len(b) > maxDiffCells {
+		for _, line := range a {
+			diff = append(diff, DiffLine{Op: DiffDelete, Text: line})
+		}
+		for _, line := range b {
+			diff = append(diff, DiffLine{Op: DiffInsert, Text: line})
+		}
+		return diff
+	}
+
+	// lcs[i][j] is the length of the LCS of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			diff = append(diff, DiffLine{Op: DiffEqual, Text: a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			diff = append(diff, DiffLine{Op: DiffDelete, Text: a[i]})
+			i++
+		default:
+			diff = append(diff, DiffLine{Op: DiffInsert, Text: b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		diff = append(diff, DiffLine{Op: DiffDelete, Text: a[i]})
+	}
+	for ; j < len(b); j++ {
+		diff = append(diff, DiffLine{Op: DiffInsert, Text: b[j]})
+	}
+	return diff
+}
+
+// splitLines splits text into lines; empty text has no lines
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+}
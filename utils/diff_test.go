@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiffLines(t *testing.T) {
+	eq := func(text string) DiffLine { return DiffLine{Op: DiffEqual, Text: text} }
+	ins := func(text string) DiffLine { return DiffLine{Op: DiffInsert, Text: text} }
+	del := func(text string) DiffLine { return DiffLine{Op: DiffDelete, Text: text} }
+
+	tests := []struct {
+		name string
+		a, b string
+		want []DiffLine
+	}{
+		{"both empty", "", "", []DiffLine{}},
+		{"unchanged", "a\nb", "a\nb", []DiffLine{eq("a"), eq("b")}},
+		{"everything added", "", "a\nb", []DiffLine{ins("a"), ins("b")}},
+		{"everything removed", "a\nb", "", []DiffLine{del("a"), del("b")}},
+		{"line changed", "a\nb\nc", "a\nB\nc", []DiffLine{eq("a"), del("b"), ins("B"), eq("c")}},
+		{"line inserted", "a\nc", "a\nb\nc", []DiffLine{eq("a"), ins("b"), eq("c")}},
+		{"line removed", "a\nb\nc", "a\nc", []DiffLine{eq("a"), del("b"), eq("c")}},
+		{"lines moved", "a\nb\nc", "c\na\nb", []DiffLine{ins("c"), eq("a"), eq("b"), del("c")}},
+		{"crlf is the same as lf", "a\r\nb", "a\nb", []DiffLine{eq("a"), eq("b")}},
+		{"trailing newline", "a", "a\n", []DiffLine{eq("a"), ins("")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DiffLines(tt.a, tt.b); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DiffLines(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestDiffLinesLargeInput checks that inputs too large for the LCS table are
+// still diffed, with their changed middle reported as one block
+func TestDiffLinesLargeInput(t *testing.T) {
+	lines := 2100 // 2100 * 2100 cells is more than maxDiffCells
+	a := make([]string, lines)
+	b := make([]string, lines)
+	for i := range lines {
+		a[i] = "old " + strings.Repeat("x", i%7)
+		b[i] = "new " + strings.Repeat("x", i%7)
+	}
+	a = append([]string{"same"}, a...)
+	b = append([]string{"same"}, b...)
+
+	diff := DiffLines(strings.Join(a, "\n"), strings.Join(b, "\n"))
+	if len(diff) != 1+2*lines {
+		t.Fatalf("got %d lines, want %d", len(diff), 1+2*lines)
+	}
+	if diff[0] != (DiffLine{Op: DiffEqual, Text: "same"}) {
+		t.Errorf("first line = %v, want the common prefix", diff[0])
+	}
+	for i, line := range diff[1:] {
+		want := DiffDelete
+		if i >= lines {
+			want = DiffInsert
+		}
+		if line.Op != want {
+			t.Fatalf("line %d is %s, want %s", i+1, line.Op, want)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	_ "image/png" // Register PNG for DecodeImage
+	"io"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // Register WebP for DecodeImage
+)
+
+var (
+	// ErrUnsupportedImage is returned for data that is not a JPEG, PNG or WebP image
+	ErrUnsupportedImage = errors.New("image must be a JPEG, PNG or WebP file")
+	// ErrImageTooLarge is returned for images with more pixels than allowed
+	ErrImageTooLarge = errors.New("image dimensions are too large")
+)
+
+// CheckImage reads only the header of an image and returns its format. It
+// fails unless data is a JPEG, PNG or WebP image of at most maxPixels pixels.
+func CheckImage(data []byte, maxPixels int) (string, error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return "", ErrUnsupportedImage
+	}
+	switch format {
+	case "jpeg", "png", "webp":
+	default:
+		return "", ErrUnsupportedImage
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > maxPixels {
+		return "", ErrImageTooLarge
+	}
+	return format, nil
+}
+
+// DecodeImage decodes a JPEG, PNG or WebP image of at most maxPixels pixels.
+// The dimensions are checked before decoding, so a small file can't claim a
+// huge canvas. It also returns the EXIF orientation of JPEG images (1 when
+// there is none), which is lost once the pixels are re-encoded.
+func DecodeImage(data []byte, maxPixels int) (image.Image, int, error) {
+	format, err := CheckImage(data, maxPixels)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, 0, ErrUnsupportedImage
+	}
+
+	orientation := 1
+	if format == "jpeg" {
+		orientation = jpegOrientation(data)
+	}
+	return img, orientation, nil
+}
+
+// CenterSquare returns the largest square in the middle of img
+func CenterSquare(img image.Image) image.Rectangle {
+	bounds := img.Bounds()
+	side := min(bounds.Dx(), bounds.Dy())
+	x := bounds.Min.X + (bounds.Dx()-side)/2
+	y := bounds.Min.Y + (bounds.Dy()-side)/2
+	return image.Rect(x, y, x+side, y+side)
+}
+
+// ResizeSquare scales the square area of img to size x size pixels and turns
+// it upright according to an EXIF orientation. Transparent pixels become white.
+func ResizeSquare(img image.Image, area image.Rectangle, size, orientation int) *image.RGBA {
+	scaled := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.Draw(scaled, scaled.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(scaled, scaled.Bounds(), img, area, draw.Over, nil)
+	return orient(scaled, orientation)
+}
+
+// EncodeJPEG writes img as a JPEG. Only the pixels are written, so no
+// metadata such as EXIF location data survives.
+func EncodeJPEG(w io.Writer, img image.Image) error {
+	return jpeg.Encode(w, img, &jpeg.Options{Quality: 85})
+}
+
+// orient applies an EXIF orientation (1-8) to a square image
+func orient(img *image.RGBA, orientation int) *image.RGBA {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+
+	n := img.Bounds().Dx() - 1
+	out := image.NewRGBA(img.Bounds())
+	for y := 0; y <= n; y++ {
+		for x := 0; x <= n; x++ {
+			// (sx, sy) is the stored pixel that belongs at (x, y) when displayed
+			var sx, sy int
+			switch orientation {
+			case 2: // mirrored horizontally
+				sx, sy = n-x, y
+			case 3: // rotated 180°
+				sx, sy = n-x, n-y
+			case 4: // mirrored vertically
+				sx, sy = x, n-y
+			case 5: // mirrored along the main diagonal
+				sx, sy = y, x
+			case 6: // needs a 90° clockwise turn
+				sx, sy = y, n-x
+			case 7: // mirrored along the anti-diagonal
+				sx, sy = n-y, n-x
+			case 8: // needs a 90° counter-clockwise turn
+				sx, sy = n-y, x
+			}
+			out.SetRGBA(x, y, img.RGBAAt(sx, sy))
+		}
+	}
+	return out
+}
+
+// jpegOrientation reads the orientation tag from the EXIF data of a JPEG,
+// returning 1 (upright) when there is none
+func jpegOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+
+	// Walk the marker segments up to the start of the image data
+	for pos := 2; pos+4 <= len(data); {
+		if data[pos] != 0xFF {
+			return 1
+		}
+		marker := data[pos+1]
+		if marker == 0xDA || marker == 0xD9 {
+			return 1
+		}
+		length := int(binary.BigEndian.Uint16(data[pos+2:]))
+		end := pos + 2 + length
+		if length < 2 || end > len(data) {
+			return 1
+		}
+		segment := data[pos+4 : end]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return exifOrientation(segment[6:])
+		}
+		pos = end
+	}
+	return 1
+}
+
+// exifOrientation finds the orientation tag in the first IFD of a TIFF structure
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 1
+	}
+	entries := int(order.Uint16(tiff[ifd:]))
+	for i := 0; i < entries; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		// Tag 0x0112 is the orientation, stored as a SHORT
+		if order.Uint16(tiff[entry:]) == 0x0112 && order.Uint16(tiff[entry+2:]) == 3 {
+			if value := int(order.Uint16(tiff[entry+8:])); value >= 1 && value <= 8 {
+				return value
+			}
+			return 1
+		}
+	}
+	return 1
+}
+
+// Identicon draws a symmetric 5x5 pattern derived from seed, the same seed
+// always giving the same picture
+func Identicon(seed string, size int) *image.RGBA {
+	hash := sha256.Sum256([]byte(seed))
+	foreground := color.RGBA{R: hash[0]/2 + 64, G: hash[1]/2 + 64, B: hash[2]/2 + 64, A: 255}
+	background := color.RGBA{R: 240, G: 240, B: 240, A: 255}
+
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.Draw(img, img.Bounds(), image.NewUniform(background), image.Point{}, draw.Src)
+
+	// A 5x5 grid with half a cell of margin on every side
+	cell := float64(size) / 6
+	for row := 0; row < 5; row++ {
+		for col := 0; col < 3; col++ {
+			if hash[3+row*3+col]%2 == 0 {
+				continue
+			}
+			for _, c := range []int{col, 4 - col} {
+				rect := image.Rect(
+					int(cell/2+float64(c)*cell), int(cell/2+float64(row)*cell),
+					int(cell/2+float64(c+1)*cell), int(cell/2+float64(row+1)*cell),
+				)
+				draw.Draw(img, rect, image.NewUniform(foreground), image.Point{}, draw.Src)
+			}
+		}
+	}
+	return img
+}
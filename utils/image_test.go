@@ -0,0 +1,169 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+// exifSegment builds an APP1 segment holding a TIFF structure with a single
+// orientation entry in the given byte order
+func exifSegment(order binary.ByteOrder, orientation uint16) []byte {
+	tiff := make([]byte, 26)
+	if order == binary.LittleEndian {
+		copy(tiff, "II")
+	} else {
+		copy(tiff, "MM")
+	}
+	order.PutUint16(tiff[2:], 42)
+	order.PutUint32(tiff[4:], 8) // first IFD right after the header
+	order.PutUint16(tiff[8:], 1) // one entry
+	order.PutUint16(tiff[10:], 0x0112)
+	order.PutUint16(tiff[12:], 3) // SHORT
+	order.PutUint32(tiff[14:], 1)
+	order.PutUint16(tiff[18:], orientation)
+
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	segment := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+	return append(segment, payload...)
+}
+
+// withSegment inserts a marker segment right after the SOI marker of a JPEG
+func withSegment(jpeg, segment []byte) []byte {
+	out := append([]byte{}, jpeg[:2]...)
+	out = append(out, segment...)
+	return append(out, jpeg[2:]...)
+}
+
+func testJPEG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := EncodeJPEG(&buf, image.NewRGBA(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestJPEGOrientation(t *testing.T) {
+	plain := testJPEG(t, 4, 4)
+	comment := []byte{0xFF, 0xFE, 0x00, 0x05, 'h', 'i', '!'}
+
+	tests := []struct {
+		name string
+		data []byte
+		want int
+	}{
+		{"no exif", plain, 1},
+		{"little endian", withSegment(plain, exifSegment(binary.LittleEndian, 6)), 6},
+		{"big endian", withSegment(plain, exifSegment(binary.BigEndian, 8)), 8},
+		{"after another segment", withSegment(withSegment(plain, exifSegment(binary.BigEndian, 3)), comment), 3},
+		{"out of range", withSegment(plain, exifSegment(binary.LittleEndian, 9)), 1},
+		{"truncated segment", withSegment(plain, exifSegment(binary.LittleEndian, 6))[:20], 1},
+		{"not a jpeg", []byte("\x89PNG\r\n\x1a\n"), 1},
+		{"empty", nil, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := jpegOrientation(tt.data); got != tt.want {
+				t.Errorf("jpegOrientation = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOrient(t *testing.T) {
+	// Stored pixels are A B / C D, told apart by their red value
+	stored := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	for i, p := range []image.Point{{0, 0}, {1, 0}, {0, 1}, {1, 1}} {
+		stored.SetRGBA(p.X, p.Y, color.RGBA{R: uint8('A' + i), A: 255})
+	}
+
+	tests := []struct {
+		orientation int
+		want        string // displayed pixels, row by row
+	}{
+		{0, "ABCD"},
+		{1, "ABCD"},
+		{2, "BADC"},
+		{3, "DCBA"},
+		{4, "CDAB"},
+		{5, "ACBD"},
+		{6, "CADB"},
+		{7, "DBCA"},
+		{8, "BDAC"},
+		{9, "ABCD"},
+	}
+	for _, tt := range tests {
+		out := orient(stored, tt.orientation)
+		got := string([]byte{out.RGBAAt(0, 0).R, out.RGBAAt(1, 0).R, out.RGBAAt(0, 1).R, out.RGBAAt(1, 1).R})
+		if got != tt.want {
+			t.Errorf("orient(%d) = %s, want %s", tt.orientation, got, tt.want)
+		}
+	}
+}
+
+func TestCenterSquare(t *testing.T) {
+	tests := []struct {
+		name   string
+		bounds image.Rectangle
+		want   image.Rectangle
+	}{
+		{"square", image.Rect(0, 0, 10, 10), image.Rect(0, 0, 10, 10)},
+		{"landscape", image.Rect(0, 0, 30, 10), image.Rect(10, 0, 20, 10)},
+		{"portrait", image.Rect(0, 0, 10, 31), image.Rect(0, 10, 10, 20)},
+		{"offset bounds", image.Rect(5, 5, 25, 15), image.Rect(10, 5, 20, 15)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CenterSquare(image.NewRGBA(tt.bounds)); got != tt.want {
+				t.Errorf("CenterSquare(%v) = %v, want %v", tt.bounds, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeImage(t *testing.T) {
+	var pngData bytes.Buffer
+	if err := png.Encode(&pngData, image.NewRGBA(image.Rect(0, 0, 20, 10))); err != nil {
+		t.Fatal(err)
+	}
+	jpegData := testJPEG(t, 8, 8)
+
+	tests := []struct {
+		name        string
+		data        []byte
+		maxPixels   int
+		format      string
+		orientation int
+		err         error
+	}{
+		{"png", pngData.Bytes(), 200, "png", 1, nil},
+		{"jpeg", jpegData, 64, "jpeg", 1, nil},
+		{"rotated jpeg", withSegment(jpegData, exifSegment(binary.BigEndian, 6)), 64, "jpeg", 6, nil},
+		{"too many pixels", pngData.Bytes(), 199, "", 0, ErrImageTooLarge},
+		{"not an image", []byte("hello"), 200, "", 0, ErrUnsupportedImage},
+		{"truncated", pngData.Bytes()[:pngData.Len()-20], 200, "png", 0, ErrUnsupportedImage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// A truncated file still has a valid header, so only decoding fails
+			format, err := CheckImage(tt.data, tt.maxPixels)
+			if tt.format != "" && (err != nil || format != tt.format) {
+				t.Errorf("CheckImage = %q, %v, want %q", format, err, tt.format)
+			}
+
+			_, orientation, err := DecodeImage(tt.data, tt.maxPixels)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("DecodeImage error = %v, want %v", err, tt.err)
+			}
+			if orientation != tt.orientation {
+				t.Errorf("DecodeImage orientation = %d, want %d", orientation, tt.orientation)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package utils
+
+import (
+	"html"
+	"regexp"
+	"slices"
+	"strings"
+
+	xhtml "golang.org/x/net/html"
+)
+
+// mentionPattern matches @username. Usernames in mentions are letters, digits
+// and underscores, with dots and hyphens allowed inside. The @ may not follow
+// a letter or digit, so email addresses don't count.
+var mentionPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_.@/])(@([\p{L}\p{N}_](?:[\p{L}\p{N}_.-]*[\p{L}\p{N}_])?))`)
+
+// hashtagPattern matches #hashtag. Hashtags start with a letter, so "#1"
+// stays a number, and the # may not follow a letter or digit, so URL
+// fragments don't count.
+var hashtagPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_#/])(#(\p{L}[\p{L}\p{N}_-]*))`)
+
+// unlinkedElements are the elements whose text is never searched for
+// references: existing links and code
+var unlinkedElements = map[string]bool{"a": true, "code": true, "pre": true}
+
+// References are the @mentions and #hashtags in a piece of content
+type References struct {
+	// Mentions are the mentioned usernames, without the @
+	Mentions []string
+	// Hashtags are the hashtags, without the #
+	Hashtags []string
+}
+
+// FindReferences returns the distinct @mentions and #hashtags in the text of
+// an HTML fragment, in order of appearance. Text in links and code is skipped.
+func FindReferences(fragment string) References {
+	var references References
+	seen := map[string]bool{}
+	walkText(fragment, func(text string) string {
+		for _, match := range mentionPattern.FindAllStringSubmatch(text, -1) {
+			if !seen[match[1]] {
+				seen[match[1]] = true
+				references.Mentions = append(references.Mentions, match[2])
+			}
+		}
+		for _, match := range hashtagPattern.FindAllStringSubmatch(text, -1) {
+			if !seen[match[1]] {
+				seen[match[1]] = true
+				references.Hashtags = append(references.Hashtags, match[2])
+			}
+		}
+		return ""
+	})
+	return references
+}
+
+// LinkReferences turns the @mentions and #hashtags in the text of a sanitized
+// HTML fragment into links. mentionURL and hashtagURL return the URL for a
+// username or hashtag, or "" to leave it as text. Text in links and code is
+// left alone.
+func LinkReferences(fragment string, mentionURL, hashtagURL func(string) string) string {
+	linked := walkText(fragment, func(text string) string {
+		type link struct {
+			start, end int
+			href       string
+		}
+		var links []link
+		for _, match := range mentionPattern.FindAllStringSubmatchIndex(text, -1) {
+			if href := mentionURL(text[match[4]:match[5]]); href != "" {
+				links = append(links, link{match[2], match[3], href})
+			}
+		}
+		for _, match := range hashtagPattern.FindAllStringSubmatchIndex(text, -1) {
+			if href := hashtagURL(text[match[4]:match[5]]); href != "" {
+				links = append(links, link{match[2], match[3], href})
+			}
+		}
+		if len(links) == 0 {
+			return ""
+		}
+
+		// Mentions and hashtags never overlap, but were found separately
+		slices.SortFunc(links, func(a, b link) int { return a.start - b.start })
+
+		var b strings.Builder
+		last := 0
+		for _, link := range links {
+			b.WriteString(html.EscapeString(text[last:link.start]))
+			b.WriteString(`<a href="` + html.EscapeString(link.href) + `">`)
+			b.WriteString(html.EscapeString(text[link.start:link.end]))
+			b.WriteString("</a>")
+			last = link.end
+		}
+		b.WriteString(html.EscapeString(text[last:]))
+		return b.String()
+	})
+	// The sanitizer adds rel="nofollow" to the new links
+	return SanitizeHTML(linked)
+}
+
+// walkText calls replace with the unescaped text of fragment outside links
+// and code, and returns fragment with each text replaced by the HTML replace
+// returns. Texts for which replace returns "" are kept as they are.
+func walkText(fragment string, replace func(text string) string) string {
+	var b strings.Builder
+	tokenizer := xhtml.NewTokenizer(strings.NewReader(fragment))
+	skipping := 0
+	for {
+		tokenType := tokenizer.Next()
+		if tokenType == xhtml.ErrorToken {
+			// The fragment was read to the end; sanitized HTML has no other errors
+			return b.String()
+		}
+
+		raw := string(tokenizer.Raw())
+		switch tokenType {
+		case xhtml.StartTagToken, xhtml.EndTagToken:
+			name, _ := tokenizer.TagName()
+			if unlinkedElements[string(name)] {
+				if tokenType == xhtml.StartTagToken {
+					skipping++
+				} else if skipping > 0 {
+					skipping--
+				}
+			}
+		case xhtml.TextToken:
+			if skipping == 0 {
+				if replaced := replace(html.UnescapeString(raw)); replaced != "" {
+					raw = replaced
+				}
+			}
+		}
+		b.WriteString(raw)
+	}
+}
@@ -0,0 +1,67 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFindReferences(t *testing.T) {
+	tests := []struct {
+		name     string
+		fragment string
+		want     References
+	}{
+		{"none", "<p>hello world</p>", References{}},
+		{"mention", "<p>hi @alice</p>", References{Mentions: []string{"alice"}}},
+		{"hashtag", "<p>#golang rocks</p>", References{Hashtags: []string{"golang"}}},
+		{"in order and distinct", "<p>@bob @alice @bob #b #a #b</p>", References{Mentions: []string{"bob", "alice"}, Hashtags: []string{"b", "a"}}},
+		{"dots and hyphens inside", "<p>@jane.doe-smith.</p>", References{Mentions: []string{"jane.doe-smith"}}},
+		{"unicode", "<p>@zoë #café</p>", References{Mentions: []string{"zoë"}, Hashtags: []string{"café"}}},
+		{"email address", "<p>mail alice@example.com</p>", References{}},
+		{"url fragment", "<p>see example.com/page#section</p>", References{}},
+		{"number", "<p>issue #1</p>", References{}},
+		{"hashtag with digits", "<p>#go2 #web-dev</p>", References{Hashtags: []string{"go2", "web-dev"}}},
+		{"after punctuation", "<p>(@alice, #go)</p>", References{Mentions: []string{"alice"}, Hashtags: []string{"go"}}},
+		{"inside a link", `<p><a href="/x">@alice #go</a> @bob</p>`, References{Mentions: []string{"bob"}}},
+		{"inside code", "<pre><code>@alice #go</code></pre><p><code>@bob</code></p>", References{}},
+		{"escaped text", "<p>&lt;@alice&gt;</p>", References{Mentions: []string{"alice"}}},
+		{"across paragraphs", "<p>@alice</p><p>@alice #go</p>", References{Mentions: []string{"alice"}, Hashtags: []string{"go"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FindReferences(tt.fragment); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FindReferences(%q) = %+v, want %+v", tt.fragment, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLinkReferences(t *testing.T) {
+	mentionURL := func(username string) string {
+		if username == "ghost" {
+			return ""
+		}
+		return "/users/" + username
+	}
+	hashtagURL := func(tag string) string { return "/tags/" + tag }
+
+	tests := []struct {
+		name     string
+		fragment string
+		want     string
+	}{
+		{"plain", "<p>hello</p>", "<p>hello</p>"},
+		{"mention and hashtag", "<p>hi @alice #go</p>", `<p>hi <a href="/users/alice" rel="nofollow">@alice</a> <a href="/tags/go" rel="nofollow">#go</a></p>`},
+		{"unknown user stays text", "<p>@ghost</p>", "<p>@ghost</p>"},
+		{"existing link is kept", `<p><a href="https://example.com" rel="nofollow">@alice</a></p>`, `<p><a href="https://example.com" rel="nofollow">@alice</a></p>`},
+		{"code is kept", "<p><code>#go</code></p>", "<p><code>#go</code></p>"},
+		{"surrounding text is escaped", "<p>a &lt; b @alice</p>", `<p>a &lt; b <a href="/users/alice" rel="nofollow">@alice</a></p>`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := LinkReferences(tt.fragment, mentionURL, hashtagURL); got != tt.want {
+				t.Errorf("LinkReferences(%q) = %q, want %q", tt.fragment, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidSignedToken is returned for tokens that are malformed or carry a wrong signature
+	ErrInvalidSignedToken = errors.New("invalid token")
+	// ErrExpiredSignedToken is returned for well-formed tokens past their expiry
+	ErrExpiredSignedToken = errors.New("token has expired")
+)
+
+// signingKey loads the secret used for signed links, falling back to the JWT secret
+// It is read on every call because the .env file is loaded after package initialization
+func signingKey() []byte {
+	if secret := os.Getenv("SIGNING_SECRET"); secret != "" {
+		return []byte(secret)
+	}
+	return jwtKey()
+}
+
+// SignToken creates a URL-safe token carrying payload until expires.
+// purpose is mixed into the signature, so a token made for one purpose
+// (e.g. "post-share") is never accepted for another.
+func SignToken(purpose, payload string, expires time.Time) string {
+	body := payload + "|" + strconv.FormatInt(expires.Unix(), 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(body)) + "." +
+		base64.RawURLEncoding.EncodeToString(tokenSignature(purpose, body))
+}
+
+// VerifyToken checks a token made by SignToken for purpose and returns its payload and expiry
+func VerifyToken(purpose, token string) (string, time.Time, error) {
+	encodedBody, encodedSignature, ok := strings.Cut(token, ".")
+	if !ok {
+		return "", time.Time{}, ErrInvalidSignedToken
+	}
+	body, err := base64.RawURLEncoding.DecodeString(encodedBody)
+	if err != nil {
+		return "", time.Time{}, ErrInvalidSignedToken
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(encodedSignature)
+	if err != nil || !hmac.Equal(signature, tokenSignature(purpose, string(body))) {
+		return "", time.Time{}, ErrInvalidSignedToken
+	}
+
+	separator := strings.LastIndexByte(string(body), '|')
+	if separator < 0 {
+		return "", time.Time{}, ErrInvalidSignedToken
+	}
+	unix, err := strconv.ParseInt(string(body[separator+1:]), 10, 64)
+	if err != nil {
+		return "", time.Time{}, ErrInvalidSignedToken
+	}
+
+	expires := time.Unix(unix, 0)
+	if time.Now().After(expires) {
+		return "", expires, ErrExpiredSignedToken
+	}
+	return string(body[:separator]), expires, nil
+}
+
+// tokenSignature is the HMAC-SHA256 of purpose and body
+func tokenSignature(purpose, body string) []byte {
+	mac := hmac.New(sha256.New, signingKey())
+	mac.Write([]byte(purpose))
+	mac.Write([]byte{0})
+	mac.Write([]byte(body))
+	return mac.Sum(nil)
+}
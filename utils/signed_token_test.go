@@ -0,0 +1,66 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerifyToken(t *testing.T) {
+	t.Setenv("SIGNING_SECRET", "test-secret")
+	expires := time.Now().Add(time.Hour).Truncate(time.Second)
+	valid := SignToken("post-share", "42", expires)
+	body, signature, _ := strings.Cut(valid, ".")
+
+	tests := []struct {
+		name    string
+		purpose string
+		token   string
+		secret  string
+		want    string
+		err     error
+	}{
+		{name: "valid", purpose: "post-share", token: valid, want: "42"},
+		{name: "payload with separator", purpose: "post-share", token: SignToken("post-share", "a|b", expires), want: "a|b"},
+		{name: "expired", purpose: "post-share", token: SignToken("post-share", "42", time.Now().Add(-time.Minute)), err: ErrExpiredSignedToken},
+		{name: "other purpose", purpose: "password-reset", token: valid, err: ErrInvalidSignedToken},
+		{name: "other secret", purpose: "post-share", token: valid, secret: "rotated", err: ErrInvalidSignedToken},
+		{name: "tampered payload", purpose: "post-share", token: SignToken("post-share", "43", expires)[:len(body)] + "." + signature, err: ErrInvalidSignedToken},
+		{name: "truncated signature", purpose: "post-share", token: valid[:len(valid)-2], err: ErrInvalidSignedToken},
+		{name: "no separator", purpose: "post-share", token: body, err: ErrInvalidSignedToken},
+		{name: "not base64", purpose: "post-share", token: "!!!." + signature, err: ErrInvalidSignedToken},
+		{name: "empty", purpose: "post-share", token: "", err: ErrInvalidSignedToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.secret != "" {
+				t.Setenv("SIGNING_SECRET", tt.secret)
+			}
+			payload, gotExpires, err := VerifyToken(tt.purpose, tt.token)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("VerifyToken error = %v, want %v", err, tt.err)
+			}
+			if tt.err != nil {
+				return
+			}
+			if payload != tt.want {
+				t.Errorf("VerifyToken payload = %q, want %q", payload, tt.want)
+			}
+			if !gotExpires.Equal(expires) {
+				t.Errorf("VerifyToken expires = %v, want %v", gotExpires, expires)
+			}
+		})
+	}
+}
+
+func TestSigningKeyFallsBackToJWTSecret(t *testing.T) {
+	t.Setenv("SIGNING_SECRET", "")
+	t.Setenv("JWT_SECRET", "jwt-secret")
+	token := SignToken("post-share", "42", time.Now().Add(time.Hour))
+
+	t.Setenv("SIGNING_SECRET", "jwt-secret")
+	if _, _, err := VerifyToken("post-share", token); err != nil {
+		t.Errorf("VerifyToken with the JWT secret as signing secret: %v", err)
+	}
+}
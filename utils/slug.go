@@ -0,0 +1,45 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/gosimple/unidecode"
+)
+
+// Slugify turns text into a lowercase, URL friendly identifier such as "hello-world"
+// Letters and digits are kept, everything else becomes a single dash
+func Slugify(text string) string {
+	var b strings.Builder
+	pendingDash := false
+
+	for _, r := range strings.ToLower(text) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if pendingDash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			pendingDash = false
+			continue
+		}
+		pendingDash = true
+	}
+
+	return b.String()
+}
+
+// TransliteratedSlug is like Slugify, but first transliterates text to
+// ASCII, so "Straße über Café" becomes "strasse-uber-cafe". The result is at
+// most maxLength bytes long and never ends in a dash.
+func TransliteratedSlug(text string, maxLength int) string {
+	slug := Slugify(unidecode.Unidecode(text))
+	if len(slug) <= maxLength {
+		return slug
+	}
+	slug = slug[:maxLength]
+	// Prefer cutting at a word boundary
+	if cut := strings.LastIndexByte(slug, '-'); cut > maxLength/2 {
+		slug = slug[:cut]
+	}
+	return strings.TrimRight(slug, "-")
+}
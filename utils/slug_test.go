@@ -0,0 +1,36 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTransliteratedSlug(t *testing.T) {
+	tests := []struct {
+		name      string
+		text      string
+		maxLength int
+		want      string
+	}{
+		{"ascii", "Hello, World!", 100, "hello-world"},
+		{"accents and ligatures", "Straße über Café", 100, "strasse-uber-cafe"},
+		{"cyrillic", "Привет мир", 100, "privet-mir"},
+		{"punctuation only", "?!…", 100, ""},
+		{"runs of separators", "  a -- b  ", 100, "a-b"},
+		{"cut at a word boundary", "the quick brown fox", 14, "the-quick"},
+		{"cut inside a long word", "supercalifragilistic", 10, "supercalif"},
+		{"no trailing dash", "abcd efgh", 5, "abcd"},
+		{"exact fit", "abc def", 7, "abc-def"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := TransliteratedSlug(tt.text, tt.maxLength)
+			if got != tt.want {
+				t.Errorf("TransliteratedSlug(%q, %d) = %q, want %q", tt.text, tt.maxLength, got, tt.want)
+			}
+			if len(got) > tt.maxLength || strings.HasSuffix(got, "-") {
+				t.Errorf("TransliteratedSlug(%q, %d) = %q is too long or ends in a dash", tt.text, tt.maxLength, got)
+			}
+		})
+	}
+}
@@ -9,26 +9,33 @@ import (
 	"github.com/dgrijalva/jwt-go" // For JWT operations
 )
 
-// Load the JWT secret key from environment variables
+// jwtKey loads the JWT secret key from environment variables
 // This key is used to sign and verify tokens
-var jwtKey = []byte(os.Getenv("JWT_SECRET"))
+// It is read on every call because the .env file is loaded after package initialization
+func jwtKey() []byte {
+	return []byte(os.Getenv("JWT_SECRET"))
+}
 
 // Claims represents the data stored in the JWT token
 type Claims struct {
+	UserID             uint   // The ID of the authenticated user
 	Username           string // The username of the authenticated user
+	Role               string // The role of the authenticated user (user or admin)
 	jwt.StandardClaims        // Standard JWT claims like expiration time
 }
 
 // GenerateJWT creates a new JWT token for an authenticated user
-// username: The username to include in the token
+// userID, username, role: The user details to include in the token
 // Returns: The signed JWT token and any error that occurred
-func GenerateJWT(username string) (string, error) {
+func GenerateJWT(userID uint, username, role string) (string, error) {
 	// Set token to expire in 24 hours
 	expirationTime := time.Now().Add(24 * time.Hour)
 
-	// Create the JWT claims, which includes the username and expiration time
+	// Create the JWT claims, which includes the user and expiration time
 	claims := &Claims{
+		UserID:   userID,
 		Username: username,
+		Role:     role,
 		StandardClaims: jwt.StandardClaims{
 			ExpiresAt: expirationTime.Unix(), // Convert to Unix timestamp
 		},
@@ -36,14 +43,14 @@ func GenerateJWT(username string) (string, error) {
 
 	// Create the token with our claims and sign it with our secret key
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtKey)
+	return token.SignedString(jwtKey())
 }
 
 // ValidateToken parses and validates a JWT token string.
 // Returns the claims if the token is valid, or an error otherwise.
 func ValidateToken(tokenString string) (*Claims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		return jwtKey, nil
+		return jwtKey(), nil
 	})
 	if err != nil {
 		return nil, err